@@ -2,6 +2,9 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -25,19 +28,21 @@ func main() {
 }
 
 func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	ctx := context.Background()
 	fs := flag.NewFlagSet("adduser", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 
 	username := fs.String("user", "", "Username")
 	passwordFlag := fs.String("password", "", "Password (optional, will prompt if omitted)")
-	dbPath := fs.String("db", "expenses.db", "Path to database file")
+	dbPath := fs.String("db", "expenses.db", "Path to database file or postgres:// URL")
+	householdName := fs.String("household", "", "Household to join, created if missing (default: a new household named after the user)")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
 	if *username == "" {
-		fmt.Fprintln(stdout, "Usage: adduser -user <username> [-password <password>] [-db <db_path>]")
+		fmt.Fprintln(stdout, "Usage: adduser -user <username> [-password <password>] [-household <name>] [-db <db_path>]")
 		fs.PrintDefaults()
 		return fmt.Errorf("missing required flags: user")
 	}
@@ -69,7 +74,7 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	defer db.Close()
 
 	// Check if user already exists
-	existingUser, err := db.GetUserByUsername(*username)
+	existingUser, err := db.GetUserByUsername(ctx, *username)
 	if err == nil && existingUser != nil {
 		return fmt.Errorf("user %s already exists", *username)
 	}
@@ -79,12 +84,26 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	user, err := db.CreateUser(*username, hash)
+	user, err := db.CreateUser(ctx, *username, hash)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
-	fmt.Fprintf(stdout, "User %s created successfully with ID %d\n", user.Username, user.ID)
+	if *householdName == "" {
+		*householdName = user.Username
+	}
+	household, err := db.GetHouseholdByName(ctx, *householdName)
+	if errors.Is(err, sql.ErrNoRows) {
+		household, err = db.CreateHousehold(ctx, *householdName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set up household: %w", err)
+	}
+	if err := db.AddHouseholdMember(ctx, household.ID, user.ID); err != nil {
+		return fmt.Errorf("failed to join household: %w", err)
+	}
+
+	fmt.Fprintf(stdout, "User %s created successfully with ID %d in household %s\n", user.Username, user.ID, household.Name)
 	return nil
 }
 
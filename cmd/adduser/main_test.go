@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"expense-tracker/internal/storage"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -138,3 +140,32 @@ func TestRun_InvalidFlag(t *testing.T) {
 	require.Error(t, err, "expected error for invalid flag")
 	assert.Contains(t, err.Error(), "flag provided but not defined")
 }
+
+func TestRun_SharedHousehold(t *testing.T) {
+	ctx := t.Context()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_household.db")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	stdin := new(bytes.Buffer)
+
+	err := run([]string{"-user", "alice", "-password", "secret", "-household", "Home", "-db", dbPath}, stdin, stdout, stderr)
+	require.NoError(t, err)
+	err = run([]string{"-user", "bob", "-password", "secret", "-household", "Home", "-db", dbPath}, stdin, stdout, stderr)
+	require.NoError(t, err)
+	err = run([]string{"-user", "carol", "-password", "secret", "-db", dbPath}, stdin, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "in household carol")
+
+	db, err := storage.NewDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	home, err := db.GetHouseholdByName(ctx, "Home")
+	require.NoError(t, err)
+	members, err := db.ListHouseholdMembers(ctx, home.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "alice", members[0].Username)
+	assert.Equal(t, "bob", members[1].Username)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"expense-tracker/internal/auth"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("apitoken", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dbPath := fs.String("db", "expenses.db", "Path to database file or postgres:// URL")
+	username := fs.String("user", "", "Username owning the tokens")
+	name := fs.String("name", "", "Name of the new token (create only)")
+	scope := fs.String("scope", string(models.ScopeRead), "Scope of the new token: read or read-write (create only)")
+	id := fs.Int64("id", 0, "ID of the token to revoke (revoke only)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stdout, "Usage: apitoken [-db <db_path>] <create|list|revoke> -user <username> [-name <name>] [-scope read|read-write] [-id <id>]")
+		fs.PrintDefaults()
+		return fmt.Errorf("missing command")
+	}
+	command := fs.Arg(0)
+
+	// Allow flags after the command, e.g. "apitoken create -user alice"
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("missing required flags: user")
+	}
+
+	// Allow overriding db path via env var if not explicitly set via flag (flag default is used)
+	if path := os.Getenv("DB_PATH"); path != "" && *dbPath == "expenses.db" {
+		*dbPath = path
+	}
+
+	db, err := storage.NewDB(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	user, err := db.GetUserByUsername(ctx, *username)
+	if err != nil {
+		return fmt.Errorf("user %s not found", *username)
+	}
+
+	switch command {
+	case "create":
+		return createToken(ctx, db, user, *name, *scope, stdout)
+	case "list":
+		return listTokens(ctx, db, user, stdout)
+	case "revoke":
+		if err := db.RevokeAPIToken(ctx, user.ID, *id); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("user %s has no token with ID %d", user.Username, *id)
+			}
+			return err
+		}
+		fmt.Fprintf(stdout, "Token %d revoked\n", *id)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func createToken(ctx context.Context, db *storage.DB, user *models.User, name, scope string, stdout io.Writer) error {
+	if name == "" {
+		return fmt.Errorf("missing required flags: name")
+	}
+	tokenScope, err := models.ParseTokenScope(scope)
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.GenerateAPIToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+	created, err := db.CreateAPIToken(ctx, user.ID, name, tokenScope, auth.HashAPIToken(token))
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	fmt.Fprintf(stdout, "Token %s (%s) created with ID %d. It will not be shown again:\n%s\n", created.Name, created.Scope, created.ID, token)
+	return nil
+}
+
+func listTokens(ctx context.Context, db *storage.DB, user *models.User, stdout io.Writer) error {
+	tokens, err := db.ListAPITokens(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSCOPE\tCREATED AT\tLAST USED AT")
+	for _, t := range tokens {
+		lastUsed := "never"
+		if t.LastUsedAt != nil {
+			lastUsed = t.LastUsedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Scope, t.CreatedAt.Format("2006-01-02 15:04:05"), lastUsed)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/auth"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDB(t *testing.T) string {
+	ctx := t.Context()
+	dbPath := filepath.Join(t.TempDir(), "test_apitoken.db")
+	db, err := storage.NewDB(dbPath)
+	require.NoError(t, err)
+	_, err = db.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	return dbPath
+}
+
+func TestRun_CreateListRevoke(t *testing.T) {
+	ctx := t.Context()
+	dbPath := setupDB(t)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "create", "-user", "alice", "-name", "Backup", "-scope", "read-write"}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Token Backup (read-write) created with ID 1")
+	token := regexp.MustCompile(`et_\S+`).FindString(stdout.String())
+	require.NotEmpty(t, token)
+
+	// The printed token authenticates its owner
+	db, err := storage.NewDB(dbPath)
+	require.NoError(t, err)
+	user, _, err := db.UseAPIToken(ctx, auth.HashAPIToken(token), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	require.NoError(t, db.Close())
+
+	stdout.Reset()
+	err = run([]string{"-db", dbPath, "list", "-user", "alice"}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Backup")
+	assert.Contains(t, stdout.String(), "read-write")
+	assert.NotContains(t, stdout.String(), "never")
+
+	stdout.Reset()
+	err = run([]string{"-db", dbPath, "revoke", "-user", "alice", "-id", "1"}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Token 1 revoked")
+
+	err = run([]string{"-db", dbPath, "revoke", "-user", "alice", "-id", "1"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no token with ID 1")
+}
+
+func TestRun_Errors(t *testing.T) {
+	dbPath := setupDB(t)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing command")
+	assert.Contains(t, stdout.String(), "Usage:")
+
+	err = run([]string{"-db", dbPath, "create", "-user", "bob", "-name", "x"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user bob not found")
+
+	err = run([]string{"-db", dbPath, "create", "-user", "alice", "-name", "x", "-scope", "admin"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid token scope")
+
+	err = run([]string{"-db", dbPath, "create", "-user", "alice"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required flags: name")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"expense-tracker/internal/storage"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dbPath := fs.String("db", "expenses.db", "Path to database file or postgres:// URL")
+	output := fs.String("o", "", "Archive file (default: standard output)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Allow overriding db path via env var if not explicitly set via flag (flag default is used)
+	if path := os.Getenv("DB_PATH"); path != "" && *dbPath == "expenses.db" {
+		*dbPath = path
+	}
+
+	// The schema is left as it is; an archive is only written from an
+	// up-to-date one
+	db, err := storage.Open(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	statuses, err := db.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, st := range statuses {
+		if !st.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("database has %d pending migration(s); run migrate up first", pending)
+	}
+
+	archive, err := db.Backup(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to back up: %w", err)
+	}
+
+	if *output == "" {
+		return writeArchive(stdout, archive)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	if err := writeArchive(f, archive); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Backed up %d user(s), %d household(s) and %d transaction(s) to %s\n",
+		len(archive.Users), len(archive.Households), len(archive.Expenses), *output)
+	return nil
+}
+
+func writeArchive(w io.Writer, archive *storage.Archive) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(archive)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDB(t *testing.T) string {
+	ctx := t.Context()
+	dbPath := filepath.Join(t.TempDir(), "test_backup.db")
+	db, err := storage.NewDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	household, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, household.ID, user.ID))
+	require.NoError(t, db.CreateExpense(ctx, user.ID, &models.Expense{Amount: 1250, Description: "Bread", Category: "Groceries", Date: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)}))
+	return dbPath
+}
+
+func TestRun_Backup(t *testing.T) {
+	dbPath := setupDB(t)
+	output := filepath.Join(t.TempDir(), "backup.json")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "-o", output}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Backed up 1 user(s), 1 household(s) and 1 transaction(s)")
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	var archive storage.Archive
+	require.NoError(t, json.Unmarshal(data, &archive))
+	assert.Equal(t, storage.ArchiveVersion, archive.Version)
+	require.Len(t, archive.Users, 1)
+	assert.Equal(t, "hash", archive.Users[0].PasswordHash)
+	assert.NotEmpty(t, archive.Categories)
+
+	stdout.Reset()
+	require.NoError(t, run([]string{"-db", dbPath}, stdout, stderr))
+	assert.Contains(t, stdout.String(), `"version": 7`)
+}
+
+func TestRun_PendingMigrations(t *testing.T) {
+	ctx := t.Context()
+	dbPath := setupDB(t)
+	db, err := storage.Open(dbPath)
+	require.NoError(t, err)
+	_, err = db.MigrateDown(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	err = run([]string{"-db", dbPath}, new(bytes.Buffer), new(bytes.Buffer))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 pending migration(s)")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"expense-tracker/internal/export"
+	"expense-tracker/internal/storage"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	now := time.Now()
+	dbPath := fs.String("db", "expenses.db", "Path to database file or postgres:// URL")
+	username := fs.String("user", "", "Export the expenses visible to this user")
+	view := fs.String("view", "month", "Period to export: month or year")
+	year := fs.Int("year", now.Year(), "Year to export")
+	month := fs.Int("month", int(now.Month()), "Month to export (month view only)")
+	category := fs.String("category", "", "Only this category and its subcategories")
+	member := fs.String("member", "", "Only expenses recorded by this user")
+	delimiter := fs.String("delimiter", ",", `Field delimiter: ",", ";", "|" or "tab"`)
+	decimal := fs.String("decimal", ".", `Decimal separator: "." or ","`)
+	dateFormat := fs.String("date-format", "YYYY-MM-DD", "Date format made of YYYY, YY, MM and DD")
+	format := fs.String("format", "csv", "Output format: csv, ledger, hledger or beancount")
+	from := fs.String("from", "", "First day to export, YYYY-MM-DD (replaces -view, -year and -month)")
+	to := fs.String("to", "", "Last day to export, YYYY-MM-DD (replaces -view, -year and -month)")
+	funding := fs.String("funding", export.DefaultFundingAccount, "Account expenses are paid from (journal formats)")
+	output := fs.String("o", "", "Output file (default: standard output)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" {
+		fmt.Fprintln(stdout, "Usage: export -user <username> [-format csv|ledger|hledger|beancount] [-view month|year] [-year <year>] [-month <month>] [-from <date>] [-to <date>] [-category <name>] [-member <username>] [-o <file>] [-db <db_path>]")
+		fs.PrintDefaults()
+		return fmt.Errorf("missing required flags: user")
+	}
+	if *view != "month" && *view != "year" {
+		return fmt.Errorf("invalid view %q", *view)
+	}
+	if *month < 1 || *month > 12 {
+		return fmt.Errorf("invalid month %d", *month)
+	}
+	opts, err := export.ParseCSVOptions(*delimiter, *decimal, *dateFormat)
+	if err != nil {
+		return err
+	}
+	var journal export.JournalFormat
+	if *format != "csv" {
+		if journal, err = export.ParseJournalFormat(*format); err != nil {
+			return err
+		}
+	}
+	filter, _ := export.PeriodFilter(*view, *year, *month)
+	if *from != "" || *to != "" {
+		if filter, _, err = export.RangeFilter(*from, *to); err != nil {
+			return err
+		}
+	}
+
+	// Allow overriding db path via env var if not explicitly set via flag (flag default is used)
+	if path := os.Getenv("DB_PATH"); path != "" && *dbPath == "expenses.db" {
+		*dbPath = path
+	}
+
+	db, err := storage.NewDB(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	user, err := db.GetUserByUsername(ctx, *username)
+	if err != nil {
+		return fmt.Errorf("user %s not found", *username)
+	}
+
+	filter.Category = *category
+	if *member != "" {
+		m, err := db.GetUserByUsername(ctx, *member)
+		if err != nil {
+			return fmt.Errorf("user %s not found", *member)
+		}
+		filter.UserID = m.ID
+	}
+	scope := storage.UserScope(user.ID)
+
+	write := func(w io.Writer) error {
+		return export.WriteCSV(ctx, w, db, scope, filter, opts)
+	}
+	if journal != "" {
+		householdID, err := db.DefaultHouseholdID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		categories, err := db.ListCategories(ctx, householdID, true)
+		if err != nil {
+			return err
+		}
+		household, err := db.GetHousehold(ctx, householdID)
+		if err != nil {
+			return err
+		}
+		journalOpts := export.JournalOptions{Format: journal, FundingAccount: *funding, Currency: household.Currency, Categories: categories}
+		write = func(w io.Writer) error {
+			return export.WriteJournal(ctx, w, db, scope, filter, journalOpts)
+		}
+	}
+
+	if *output == "" {
+		return write(stdout)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to export: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDB(t *testing.T) string {
+	ctx := t.Context()
+	dbPath := filepath.Join(t.TempDir(), "test_export.db")
+	db, err := storage.NewDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	household, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, household.ID, user.ID))
+	require.NoError(t, db.CreateExpense(ctx, user.ID, &models.Expense{Amount: 1250, Description: "Bread", Category: "Groceries", Date: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)}))
+	require.NoError(t, db.CreateExpense(ctx, user.ID, &models.Expense{Amount: 300, Description: "Bus", Category: "Transport", Date: time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)}))
+	return dbPath
+}
+
+func TestRun_Month(t *testing.T) {
+	dbPath := setupDB(t)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "-user", "alice", "-year", "2026", "-month", "1", "-delimiter", ";", "-decimal", ","}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "Date;Kind;Category;Description;Amount;User\n2026-01-10;expense;Groceries;Bread;12,50;alice\n", stdout.String())
+}
+
+func TestRun_YearToFile(t *testing.T) {
+	dbPath := setupDB(t)
+	output := filepath.Join(t.TempDir(), "out.csv")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "-user", "alice", "-view", "year", "-year", "2026", "-category", "Transport", "-o", output}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Empty(t, stdout.String())
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "Date,Kind,Category,Description,Amount,User\n2026-02-01,expense,Transport,Bus,3.00,alice\n", string(data))
+}
+
+func TestRun_Errors(t *testing.T) {
+	dbPath := setupDB(t)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, stdout.String(), "Usage:")
+
+	err = run([]string{"-db", dbPath, "-user", "bob"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user bob not found")
+
+	err = run([]string{"-db", dbPath, "-user", "alice", "-date-format", "nonsense"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid date format")
+}
+
+func TestRun_Journal(t *testing.T) {
+	dbPath := setupDB(t)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "-user", "alice", "-format", "hledger", "-from", "2026-01-01", "-to", "2026-12-31", "-funding", "Assets:Cash"}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-01-10 Bread\n    ; user: alice\n    Expenses:Groceries  12.50 EUR\n    Assets:Cash\n\n"+
+		"2026-02-01 Bus\n    ; user: alice\n    Expenses:Transport  3.00 EUR\n    Assets:Cash\n\n", stdout.String())
+
+	err = run([]string{"-db", dbPath, "-user", "alice", "-format", "gnucash"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid journal format")
+
+	err = run([]string{"-db", dbPath, "-user", "alice", "-format", "ledger", "-from", "01/01/2026"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid from date")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"expense-tracker/internal/importer"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dbPath := fs.String("db", "expenses.db", "Path to database file or postgres:// URL")
+	username := fs.String("user", "", "Import into this user's household")
+	format := fs.String("format", "", "Statement format: csv, ofx, camt or mt940 (default: from the file extension)")
+	profileName := fs.String("profile", "", "Saved CSV column mapping to use (csv only)")
+	category := fs.String("category", "", "Category for transactions without a known one (default: the profile's, or Other)")
+	dryRun := fs.Bool("dry-run", false, "List the transactions without importing them")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" || fs.NArg() != 1 {
+		fmt.Fprintln(stdout, "Usage: import -user <username> [-format csv|ofx|camt|mt940] [-profile <name>] [-category <name>] [-dry-run] [-db <db_path>] <statement>")
+		fs.PrintDefaults()
+		return fmt.Errorf("missing required flags or statement file")
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = "csv"
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ofx", ".qfx":
+			*format = "ofx"
+		case ".xml":
+			*format = "camt"
+		case ".sta", ".mt940", ".940":
+			*format = "mt940"
+		}
+	}
+
+	// Allow overriding db path via env var if not explicitly set via flag (flag default is used)
+	if path := os.Getenv("DB_PATH"); path != "" && *dbPath == "expenses.db" {
+		*dbPath = path
+	}
+
+	db, err := storage.NewDB(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	user, err := db.GetUserByUsername(ctx, *username)
+	if err != nil {
+		return fmt.Errorf("user %s not found", *username)
+	}
+	householdID, err := db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var rows []importer.Row
+	switch *format {
+	case "csv":
+		var profile *models.ImportProfile
+		if profile, err = findProfile(ctx, db, householdID, *profileName); err == nil {
+			rows, err = importer.ParseCSV(f, *profile)
+			if *category == "" {
+				*category = profile.DefaultCategory
+			}
+		}
+	default:
+		rows, err = importer.Parse(*format, f)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read statement: %w", err)
+	}
+
+	categories, err := db.ListCategories(ctx, householdID, false)
+	if err != nil {
+		return err
+	}
+	if *category == "" {
+		*category = "Other"
+	}
+	importer.Categorize(rows, categories, *category)
+
+	var valid []models.Expense
+	for _, row := range rows {
+		if row.Valid() {
+			valid = append(valid, row.Expense)
+		} else {
+			fmt.Fprintf(stdout, "Line %d: %s\n", row.Line, row.Error)
+		}
+	}
+
+	if *dryRun {
+		duplicates, err := db.FindDuplicates(ctx, householdID, valid)
+		if err != nil {
+			return err
+		}
+		for i, e := range valid {
+			status := "new"
+			if duplicates[i] {
+				status = "already recorded"
+			}
+			fmt.Fprintf(stdout, "%s  %-7s %10s  %-16s %s (%s)\n", e.Date.Format("2006-01-02"), e.Kind, e.Amount, e.Category, e.Description, status)
+		}
+		return nil
+	}
+
+	imported, err := db.ImportExpenses(ctx, user.ID, valid)
+	if err != nil {
+		return fmt.Errorf("failed to import: %w", err)
+	}
+	fmt.Fprintf(stdout, "Imported %d transaction(s), skipped %d already recorded and %d unreadable\n", imported, len(valid)-imported, len(rows)-len(valid))
+	return nil
+}
+
+func findProfile(ctx context.Context, db *storage.DB, householdID int64, name string) (*models.ImportProfile, error) {
+	if name == "" {
+		return nil, fmt.Errorf("csv statements need -profile, the name of a mapping saved on the import page")
+	}
+	profiles, err := db.ListImportProfiles(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, name) {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("no import profile named %s", name)
+}
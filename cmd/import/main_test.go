@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statement = `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><ACCTID>DE001234</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20260105<TRNAMT>-23.40<FITID>1<NAME>Supermarket</STMTTRN>
+<STMTTRN><DTPOSTED>20260106<TRNAMT>2500.00<FITID>2<NAME>Salary</STMTTRN>
+<STMTTRN><DTPOSTED>2026<TRNAMT>1.00<FITID>3</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+func setupDB(t *testing.T) string {
+	ctx := t.Context()
+	dbPath := filepath.Join(t.TempDir(), "test_import.db")
+	db, err := storage.NewDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	household, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, household.ID, user.ID))
+	require.NoError(t, db.SaveImportProfile(ctx, &models.ImportProfile{
+		HouseholdID: household.ID, Name: "Bank", Delimiter: ";", DateColumn: 1, AmountColumn: 2,
+		DescriptionColumn: 3, DateFormat: "DD.MM.YYYY", DecimalSeparator: ",",
+		SignConvention: models.SignNegativeOut, DefaultCategory: "Groceries",
+	}))
+	return dbPath
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestRun_OFX(t *testing.T) {
+	ctx := t.Context()
+	dbPath := setupDB(t)
+	path := writeFile(t, "statement.qfx", statement)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "-user", "alice", "-dry-run", path}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Line 10: invalid date")
+	assert.Contains(t, stdout.String(), "Supermarket (new)")
+
+	err = run([]string{"-db", dbPath, "-user", "alice", path}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Imported 2 transaction(s), skipped 0 already recorded and 1 unreadable")
+
+	stdout.Reset()
+	err = run([]string{"-db", dbPath, "-user", "alice", path}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Imported 0 transaction(s), skipped 2 already recorded and 1 unreadable")
+
+	db, err := storage.NewDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	expenses, err := db.ListExpenses(ctx, storage.HouseholdScope(1), 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, expenses, 2)
+}
+
+func TestRun_CSV(t *testing.T) {
+	dbPath := setupDB(t)
+	path := writeFile(t, "statement.csv", "05.01.2026;-12,50;Bakery\n")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "-user", "alice", path}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "need -profile")
+
+	err = run([]string{"-db", dbPath, "-user", "alice", "-profile", "bank", "-dry-run", path}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "12.50  Groceries        Bakery (new)", "the profile's default category is used")
+
+	err = run([]string{"-db", dbPath, "-user", "alice", "-profile", "bank", path}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Imported 1 transaction(s)")
+}
+
+func TestRun_MT940(t *testing.T) {
+	dbPath := setupDB(t)
+	path := writeFile(t, "statement.sta", ":25:10020030/1234567\n:61:2601050105D12,NMSCNONREF//R1\n:86:Bakery\n-\n")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "-user", "alice", "-category", "Groceries", "-dry-run", path}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "2026-01-05  expense      12.00  Groceries        Bakery (new)")
+}
+
+func TestRun_Errors(t *testing.T) {
+	dbPath := setupDB(t)
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, stdout.String(), "Usage:")
+
+	err = run([]string{"-db", dbPath, "-user", "bob", "statement.ofx"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user bob not found")
+
+	path := writeFile(t, "statement.txt", "hello")
+	err = run([]string{"-db", dbPath, "-user", "alice", "-format", "ofx", path}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read statement")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"expense-tracker/internal/storage"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dbPath := fs.String("db", "expenses.db", "Path to database file or postgres:// URL")
+	steps := fs.Int("steps", 1, "Number of migrations to revert (down only)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stdout, "Usage: migrate [-db <db_path>] <status|up|down> [-steps <n>]")
+		fs.PrintDefaults()
+		return fmt.Errorf("missing command")
+	}
+	command := fs.Arg(0)
+
+	// Allow flags after the command, e.g. "migrate down -steps 2"
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+
+	// Allow overriding db path via env var if not explicitly set via flag (flag default is used)
+	if path := os.Getenv("DB_PATH"); path != "" && *dbPath == "expenses.db" {
+		*dbPath = path
+	}
+
+	db, err := storage.Open(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	switch command {
+	case "status":
+		return printStatus(ctx, db, stdout)
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Applied %d migration(s)\n", applied)
+		return nil
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("steps must be at least 1")
+		}
+		reverted, err := db.MigrateDown(ctx, *steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Reverted %d migration(s)\n", reverted)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func printStatus(ctx context.Context, db *storage.DB, stdout io.Writer) error {
+	statuses, err := db.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, st := range statuses {
+		state, appliedAt := "pending", ""
+		if st.Applied {
+			state = "applied"
+			if !st.ChecksumOK {
+				state = "checksum mismatch"
+			}
+			appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_StatusUpDown(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test_migrate.db")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "status"}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "initial schema")
+	assert.Contains(t, stdout.String(), "pending")
+
+	stdout.Reset()
+	err = run([]string{"-db", dbPath, "up"}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Applied")
+
+	stdout.Reset()
+	err = run([]string{"status", "-db", dbPath}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "applied")
+	assert.NotContains(t, stdout.String(), "pending")
+
+	stdout.Reset()
+	err = run([]string{"-db", dbPath, "down", "-steps", "1"}, stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Reverted 1 migration(s)")
+}
+
+func TestRun_MissingCommand(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{}, stdout, stderr)
+	require.Error(t, err, "expected error for missing command")
+	assert.Contains(t, err.Error(), "missing command")
+	assert.Contains(t, stdout.String(), "Usage:")
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test_unknown.db")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "sideways"}, stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown command")
+}
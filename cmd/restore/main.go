@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"expense-tracker/internal/storage"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	dbPath := fs.String("db", "expenses.db", "Path to database file or postgres:// URL; it must be new or empty")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stdout, "Usage: restore [-db <db_path>] <archive.json|->")
+		fs.PrintDefaults()
+		return fmt.Errorf("missing archive file")
+	}
+
+	// Allow overriding db path via env var if not explicitly set via flag (flag default is used)
+	if path := os.Getenv("DB_PATH"); path != "" && *dbPath == "expenses.db" {
+		*dbPath = path
+	}
+
+	in := stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	var archive storage.Archive
+	if err := json.NewDecoder(in).Decode(&archive); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	db, err := storage.NewDB(*dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.Restore(ctx, &archive); err != nil {
+		if errors.Is(err, storage.ErrNotEmpty) {
+			return fmt.Errorf("%s already has data; restore into a new database", *dbPath)
+		}
+		return fmt.Errorf("failed to restore: %w", err)
+	}
+	fmt.Fprintf(stdout, "Restored %d user(s), %d household(s) and %d transaction(s)\n",
+		len(archive.Users), len(archive.Households), len(archive.Expenses))
+	return nil
+}
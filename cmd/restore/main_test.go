@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func archiveJSON(t *testing.T) string {
+	ctx := t.Context()
+	db, err := storage.NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	household, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, household.ID, user.ID))
+	require.NoError(t, db.CreateExpense(ctx, user.ID, &models.Expense{Amount: 1250, Description: "Bread", Category: "Groceries", Date: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)}))
+
+	archive, err := db.Backup(ctx)
+	require.NoError(t, err)
+	data, err := json.Marshal(archive)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRun_Restore(t *testing.T) {
+	ctx := t.Context()
+	dbPath := filepath.Join(t.TempDir(), "restored.db")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{"-db", dbPath, "-"}, strings.NewReader(archiveJSON(t)), stdout, stderr)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Restored 1 user(s), 1 household(s) and 1 transaction(s)")
+
+	db, err := storage.NewDB(dbPath)
+	require.NoError(t, err)
+	user, err := db.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", user.PasswordHash)
+	expenses, err := db.ListExpenses(ctx, storage.UserScope(user.ID), 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, expenses, 1)
+	require.NoError(t, db.Close())
+
+	// The database now has data
+	err = run([]string{"-db", dbPath, "-"}, strings.NewReader(archiveJSON(t)), stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already has data")
+}
+
+func TestRun_Errors(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "restored.db")
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	err := run([]string{}, strings.NewReader(""), stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, stdout.String(), "Usage:")
+
+	err = run([]string{"-db", dbPath, "-"}, strings.NewReader("not json"), stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read archive")
+
+	err = run([]string{"-db", dbPath, "-"}, strings.NewReader(`{"version": 99}`), stdout, stderr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported archive version 99")
+}
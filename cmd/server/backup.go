@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBackupInterval is how often snapshots are taken when
+	// BACKUP_INTERVAL is not set.
+	defaultBackupInterval = 24 * time.Hour
+	// defaultBackupKeep is how many snapshots are kept when BACKUP_KEEP is
+	// not set.
+	defaultBackupKeep = 7
+	// backupLayout names snapshot files; names sort by the time they were
+	// taken, to the millisecond.
+	backupLayout = "expenses-20060102-150405.000.db"
+)
+
+// backupConfig configures the scheduled snapshots of the database.
+type backupConfig struct {
+	Dir      string        // Directory the snapshots are written to; empty disables backups
+	Interval time.Duration // Time between snapshots
+	Keep     int           // Number of snapshots kept, oldest are removed first
+}
+
+// backupConfigFromEnv reads the backup configuration from BACKUP_DIR,
+// BACKUP_INTERVAL (a duration such as 6h) and BACKUP_KEEP.
+func backupConfigFromEnv(getenv func(string) string) (backupConfig, error) {
+	cfg := backupConfig{
+		Dir:      getenv("BACKUP_DIR"),
+		Interval: defaultBackupInterval,
+		Keep:     defaultBackupKeep,
+	}
+	if v := getenv("BACKUP_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return cfg, fmt.Errorf("invalid BACKUP_INTERVAL %q", v)
+		}
+		cfg.Interval = interval
+	}
+	if v := getenv("BACKUP_KEEP"); v != "" {
+		keep, err := strconv.Atoi(v)
+		if err != nil || keep < 1 {
+			return cfg, fmt.Errorf("invalid BACKUP_KEEP %q", v)
+		}
+		cfg.Keep = keep
+	}
+	return cfg, nil
+}
+
+// backupper writes verified snapshots of a database to a directory and
+// removes the oldest beyond the number to keep.
+type backupper struct {
+	db   *storage.DB
+	dir  string
+	keep int
+	mu   sync.Mutex // Serializes scheduled and on-demand backups
+}
+
+func newBackupper(db *storage.DB, cfg backupConfig) *backupper {
+	return &backupper{db: db, dir: cfg.Dir, keep: cfg.Keep}
+}
+
+// Backup snapshots the database with VACUUM INTO, checks the integrity of the
+// copy and returns its path. The snapshot is written under a temporary name
+// and only renamed once verified, so the directory never holds a partial or
+// corrupt backup.
+func (b *backupper) Backup(ctx context.Context, now time.Time) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return "", err
+	}
+	// Snapshots taken within the same millisecond get the next free name
+	path := filepath.Join(b.dir, now.UTC().Format(backupLayout))
+	for {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		now = now.Add(time.Millisecond)
+		path = filepath.Join(b.dir, now.UTC().Format(backupLayout))
+	}
+	tmp := path + ".tmp"
+	os.Remove(tmp) // Left over from a crash; VACUUM INTO needs a new file
+
+	if err := b.db.Snapshot(ctx, tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("snapshot: %w", err)
+	}
+	if err := verifySnapshot(ctx, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	if err := b.rotate(); err != nil {
+		log.Printf("Backup rotation error: %v", err)
+	}
+	return path, nil
+}
+
+// Snapshots returns the paths of the snapshots in the directory, oldest first.
+func (b *backupper) Snapshots() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(b.dir, "expenses-*.db"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Latest returns when the newest snapshot was taken, or the zero time if there
+// is none.
+func (b *backupper) Latest() time.Time {
+	paths, err := b.Snapshots()
+	if err != nil || len(paths) == 0 {
+		return time.Time{}
+	}
+	taken, err := time.Parse(backupLayout, filepath.Base(paths[len(paths)-1]))
+	if err != nil {
+		return time.Time{}
+	}
+	return taken
+}
+
+func (b *backupper) rotate() error {
+	paths, err := b.Snapshots()
+	if err != nil {
+		return err
+	}
+	for len(paths) > b.keep {
+		if err := os.Remove(paths[0]); err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// verifySnapshot opens a snapshot on its own, read-only so the check leaves
+// the file as it was, and runs an integrity check.
+func verifySnapshot(ctx context.Context, path string) error {
+	db, err := storage.OpenWithOptions(path, storage.Options{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer db.Close()
+	if err := db.IntegrityCheck(ctx); err != nil {
+		return fmt.Errorf("verify snapshot: %w", err)
+	}
+	return nil
+}
+
+// runBackups takes a snapshot every interval until ctx is cancelled. At
+// start-up one is taken right away unless the newest is recent enough, so
+// restarting the server does not pile up snapshots.
+func runBackups(ctx context.Context, b *backupper, interval time.Duration) {
+	wait := interval - time.Since(b.Latest())
+	if wait < 0 {
+		wait = 0
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if path, err := b.Backup(ctx, time.Now()); err != nil {
+			log.Printf("Backup error: %v", err)
+		} else {
+			log.Printf("Backed up database to %s", path)
+		}
+		timer.Reset(interval)
+	}
+}
+
+// adminUsers returns the usernames allowed to use the admin endpoints: those
+// listed in ADMIN_USERS, separated by commas, or else the bootstrap admin.
+func adminUsers(getenv func(string) string) []string {
+	var users []string
+	for _, name := range strings.Split(getenv("ADMIN_USERS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			users = append(users, name)
+		}
+	}
+	if len(users) > 0 {
+		return users
+	}
+	if name := getenv("ADMIN_USER"); name != "" {
+		return []string{name}
+	}
+	return []string{"admin"}
+}
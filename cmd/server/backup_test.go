@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupper(t *testing.T) {
+	ctx := t.Context()
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "expenses.db"))
+	require.NoError(t, err, "failed to create database")
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "testuser", "hash")
+	require.NoError(t, err)
+	household, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, household.ID, user.ID))
+	require.NoError(t, db.CreateExpense(ctx, user.ID, &models.Expense{
+		Amount: 1250, Description: "Lunch", Category: "Food",
+		Date: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}))
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	b := newBackupper(db, backupConfig{Dir: dir, Keep: 2})
+	assert.True(t, b.Latest().IsZero())
+
+	start := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		_, err := b.Backup(ctx, start.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+
+	// Only the newest two are kept
+	paths, err := b.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	assert.Equal(t, "expenses-20260301-040000.000.db", filepath.Base(paths[0]))
+	assert.Equal(t, "expenses-20260301-050000.000.db", filepath.Base(paths[1]))
+	assert.Equal(t, start.Add(2*time.Hour), b.Latest())
+	// Verifying leaves no temporary, journal or WAL files behind
+	leftover, err := filepath.Glob(filepath.Join(dir, "*[^b]"))
+	require.NoError(t, err)
+	assert.Empty(t, leftover)
+	header, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	assert.Equal(t, byte(1), header[18], "verifying must not switch the snapshot to WAL")
+
+	// Snapshots are complete databases
+	snapshot, err := storage.Open(paths[1])
+	require.NoError(t, err)
+	defer snapshot.Close()
+	expenses, err := snapshot.ListExpenses(ctx, storage.UserScope(user.ID), 10, 0)
+	require.NoError(t, err)
+	require.Len(t, expenses, 1)
+	assert.Equal(t, "Lunch", expenses[0].Description)
+}
+
+func TestBackupper_SameSecond(t *testing.T) {
+	ctx := t.Context()
+	db, err := storage.NewDB(filepath.Join(t.TempDir(), "expenses.db"))
+	require.NoError(t, err, "failed to create database")
+	defer db.Close()
+
+	b := newBackupper(db, backupConfig{Dir: t.TempDir(), Keep: 5})
+	now := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	first, err := b.Backup(ctx, now)
+	require.NoError(t, err)
+	second, err := b.Backup(ctx, now.Add(300*time.Millisecond))
+	require.NoError(t, err)
+	third, err := b.Backup(ctx, now.Add(300*time.Millisecond))
+	require.NoError(t, err)
+
+	// No snapshot replaces another
+	assert.Equal(t, "expenses-20260301-030000.000.db", filepath.Base(first))
+	assert.Equal(t, "expenses-20260301-030000.300.db", filepath.Base(second))
+	assert.Equal(t, "expenses-20260301-030000.301.db", filepath.Base(third))
+	paths, err := b.Snapshots()
+	require.NoError(t, err)
+	assert.Equal(t, []string{first, second, third}, paths)
+	assert.Equal(t, now.Add(301*time.Millisecond), b.Latest())
+}
+
+func TestVerifySnapshot_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrupt.db")
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o600))
+	assert.Error(t, verifySnapshot(t.Context(), path))
+}
+
+func TestBackupConfigFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	cfg, err := backupConfigFromEnv(env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, backupConfig{Interval: defaultBackupInterval, Keep: defaultBackupKeep}, cfg)
+
+	cfg, err = backupConfigFromEnv(env(map[string]string{
+		"BACKUP_DIR": "/data/backups", "BACKUP_INTERVAL": "6h", "BACKUP_KEEP": "28",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, backupConfig{Dir: "/data/backups", Interval: 6 * time.Hour, Keep: 28}, cfg)
+
+	_, err = backupConfigFromEnv(env(map[string]string{"BACKUP_INTERVAL": "daily"}))
+	assert.Error(t, err)
+	_, err = backupConfigFromEnv(env(map[string]string{"BACKUP_KEEP": "0"}))
+	assert.Error(t, err)
+}
+
+func TestAdminUsers(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	assert.Equal(t, []string{"admin"}, adminUsers(env(nil)))
+	assert.Equal(t, []string{"alice"}, adminUsers(env(map[string]string{"ADMIN_USER": "alice"})))
+	assert.Equal(t, []string{"alice", "bob"}, adminUsers(env(map[string]string{
+		"ADMIN_USER": "root", "ADMIN_USERS": "alice, bob,",
+	})))
+}
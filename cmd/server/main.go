@@ -5,10 +5,13 @@ import (
 	"expense-tracker/internal/auth"
 	"expense-tracker/internal/handlers"
 	"expense-tracker/internal/storage"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
@@ -38,17 +41,106 @@ func setupRouter(h *handlers.Handlers, staticDir string) *http.ServeMux {
 	mux.Handle("GET /expenses", h.AuthMiddleware(http.HandlerFunc(h.ListExpenses)))
 	mux.Handle("GET /expenses/create", h.AuthMiddleware(http.HandlerFunc(h.CreateExpenseForm)))
 	mux.Handle("POST /expenses", h.AuthMiddleware(http.HandlerFunc(h.CreateExpense)))
+	mux.Handle("GET /expenses/export.csv", h.AuthMiddleware(http.HandlerFunc(h.ExportCSV)))
+	mux.Handle("GET /expenses/journal", h.AuthMiddleware(http.HandlerFunc(h.ExportJournal)))
 	mux.Handle("GET /expenses/{id}/edit", h.AuthMiddleware(http.HandlerFunc(h.EditExpenseForm)))
 	mux.Handle("POST /expenses/{id}", h.AuthMiddleware(http.HandlerFunc(h.UpdateExpense)))
 	mux.Handle("DELETE /expenses/{id}", h.AuthMiddleware(http.HandlerFunc(h.DeleteExpense)))
 	mux.Handle("GET /statistics", h.AuthMiddleware(http.HandlerFunc(h.Statistics)))
+	mux.Handle("GET /budgets", h.AuthMiddleware(http.HandlerFunc(h.Budgets)))
+	mux.Handle("POST /budgets", h.AuthMiddleware(http.HandlerFunc(h.SetBudget)))
+	mux.Handle("GET /recurring", h.AuthMiddleware(http.HandlerFunc(h.Recurring)))
+	mux.Handle("POST /recurring", h.AuthMiddleware(http.HandlerFunc(h.CreateRecurring)))
+	mux.Handle("DELETE /recurring/{id}", h.AuthMiddleware(http.HandlerFunc(h.DeleteRecurring)))
+	mux.Handle("GET /accounts", h.AuthMiddleware(http.HandlerFunc(h.Accounts)))
+	mux.Handle("POST /accounts", h.AuthMiddleware(http.HandlerFunc(h.CreateAccount)))
+	mux.Handle("GET /accounts/{id}", h.AuthMiddleware(http.HandlerFunc(h.AccountLedger)))
+	mux.Handle("POST /accounts/{id}/archive", h.AuthMiddleware(http.HandlerFunc(h.ArchiveAccount)))
+	mux.Handle("POST /accounts/transfers", h.AuthMiddleware(http.HandlerFunc(h.CreateTransfer)))
+	mux.Handle("DELETE /accounts/transfers/{id}", h.AuthMiddleware(http.HandlerFunc(h.DeleteTransfer)))
+	mux.Handle("GET /balances", h.AuthMiddleware(http.HandlerFunc(h.Balances)))
+	mux.Handle("POST /balances/settle", h.AuthMiddleware(http.HandlerFunc(h.SettleUp)))
+	mux.Handle("POST /balances/settlements", h.AuthMiddleware(http.HandlerFunc(h.CreateSettlement)))
+	mux.Handle("DELETE /balances/settlements/{id}", h.AuthMiddleware(http.HandlerFunc(h.DeleteSettlement)))
+	mux.Handle("GET /import", h.AuthMiddleware(http.HandlerFunc(h.Import)))
+	mux.Handle("POST /import/preview", h.AuthMiddleware(http.HandlerFunc(h.PreviewImport)))
+	mux.Handle("POST /import/commit", h.AuthMiddleware(http.HandlerFunc(h.CommitImport)))
+	mux.Handle("DELETE /import/profiles/{id}", h.AuthMiddleware(http.HandlerFunc(h.DeleteImportProfile)))
+	mux.Handle("GET /settings/categories", h.AuthMiddleware(http.HandlerFunc(h.CategorySettings)))
+	mux.Handle("POST /settings/categories", h.AuthMiddleware(http.HandlerFunc(h.CreateCategory)))
+	mux.Handle("POST /settings/categories/{id}", h.AuthMiddleware(http.HandlerFunc(h.UpdateCategory)))
+	mux.Handle("POST /settings/categories/{id}/archive", h.AuthMiddleware(http.HandlerFunc(h.ArchiveCategory)))
+	mux.Handle("POST /settings/categories/{id}/move", h.AuthMiddleware(http.HandlerFunc(h.MoveCategory)))
+	mux.Handle("GET /settings/tokens", h.AuthMiddleware(http.HandlerFunc(h.APITokens)))
+	mux.Handle("POST /settings/tokens", h.AuthMiddleware(http.HandlerFunc(h.CreateAPIToken)))
+	mux.Handle("DELETE /settings/tokens/{id}", h.AuthMiddleware(http.HandlerFunc(h.RevokeAPIToken)))
+	mux.Handle("GET /settings/currencies", h.AuthMiddleware(http.HandlerFunc(h.CurrencySettings)))
+	mux.Handle("POST /settings/currencies", h.AuthMiddleware(http.HandlerFunc(h.SetBaseCurrency)))
+	mux.Handle("POST /settings/currencies/rates", h.AuthMiddleware(http.HandlerFunc(h.AddExchangeRate)))
+	mux.Handle("POST /settings/currencies/rates/import", h.AuthMiddleware(http.HandlerFunc(h.ImportExchangeRates)))
+
+	// JSON API, authenticated by API token or session cookie
+	api := func(handler http.HandlerFunc) http.Handler { return h.APIAuthMiddleware(handler) }
+	mux.Handle("GET /api/v1/expenses", api(h.APIListExpenses))
+	mux.Handle("POST /api/v1/expenses", api(h.APICreateExpense))
+	mux.Handle("GET /api/v1/expenses/{id}", api(h.APIGetExpense))
+	mux.Handle("PUT /api/v1/expenses/{id}", api(h.APIUpdateExpense))
+	mux.Handle("DELETE /api/v1/expenses/{id}", api(h.APIDeleteExpense))
+	mux.Handle("GET /api/v1/categories", api(h.APICategories))
+	mux.Handle("GET /api/v1/statistics", api(h.APIStatistics))
+	mux.Handle("POST /api/v1/tokens", api(h.APICreateToken))
+	mux.Handle("POST /api/v1/admin/backup", h.APIAuthMiddleware(h.AdminMiddleware(http.HandlerFunc(h.APIAdminBackup))))
+	mux.HandleFunc("GET /api/", h.APINotFound)
 
 	return mux
 }
 
+// dbOptionsFromEnv reads the SQLite tuning from SQLITE_JOURNAL_MODE,
+// SQLITE_SYNCHRONOUS, SQLITE_BUSY_TIMEOUT (a duration such as 5s),
+// SQLITE_FOREIGN_KEYS, SQLITE_READ_CONNS and DB_QUERY_TIMEOUT, starting from
+// the defaults.
+func dbOptionsFromEnv(getenv func(string) string) (storage.Options, error) {
+	opts := storage.DefaultOptions()
+	if v := getenv("SQLITE_JOURNAL_MODE"); v != "" {
+		opts.JournalMode = v
+	}
+	if v := getenv("SQLITE_SYNCHRONOUS"); v != "" {
+		opts.Synchronous = v
+	}
+	if v := getenv("SQLITE_BUSY_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid SQLITE_BUSY_TIMEOUT %q", v)
+		}
+		opts.BusyTimeout = timeout
+	}
+	if v := getenv("SQLITE_FOREIGN_KEYS"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid SQLITE_FOREIGN_KEYS %q", v)
+		}
+		opts.ForeignKeys = enabled
+	}
+	if v := getenv("SQLITE_READ_CONNS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid SQLITE_READ_CONNS %q", v)
+		}
+		opts.ReadConns = n
+	}
+	if v := getenv("DB_QUERY_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout < 0 {
+			return opts, fmt.Errorf("invalid DB_QUERY_TIMEOUT %q", v)
+		}
+		opts.QueryTimeout = timeout
+	}
+	return opts, nil
+}
+
 // bootstrapUser creates a default user if none exist and credentials are provided via env vars.
-func bootstrapUser(db *storage.DB) {
-	count, err := db.UserCount()
+func bootstrapUser(ctx context.Context, db *storage.DB) {
+	count, err := db.UserCount(ctx)
 	if err != nil {
 		log.Printf("Warning: could not check user count: %v", err)
 		return
@@ -82,11 +174,22 @@ func bootstrapUser(db *storage.DB) {
 		return
 	}
 
-	if _, err := db.CreateUser(username, hash); err != nil {
+	user, err := db.CreateUser(ctx, username, hash)
+	if err != nil {
 		log.Printf("Failed to create admin user: %v", err)
 		return
 	}
 
+	household, err := db.CreateHousehold(ctx, "Home")
+	if err != nil {
+		log.Printf("Failed to create household: %v", err)
+		return
+	}
+	if err := db.AddHouseholdMember(ctx, household.ID, user.ID); err != nil {
+		log.Printf("Failed to add admin to household: %v", err)
+		return
+	}
+
 	log.Printf("Created admin user: %s", username)
 }
 
@@ -96,19 +199,45 @@ func main() {
 		dbPath = "expenses.db"
 	}
 
-	db, err := storage.NewDB(dbPath)
+	opts, err := dbOptionsFromEnv(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid database configuration: %v", err)
+	}
+	db, err := storage.NewDBWithOptions(dbPath, opts)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
 	defer db.Close()
 
+	// Cancelled on shutdown, stopping background jobs and, once the grace
+	// period is over, the queries of requests still running
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
 	// Create initial user if needed
-	bootstrapUser(db)
+	bootstrapUser(ctx, db)
 
 	// Use secure cookies when running with HTTPS (production)
 	secureCookie := os.Getenv("SECURE_COOKIE") == "true"
 
+	// Create recurring transactions in the background
+	go runRecurring(ctx, db, recurringInterval)
+
 	h := handlers.NewHandlers(db, "web/templates", secureCookie)
+	h.SetAdmins(adminUsers(os.Getenv))
+
+	// Snapshot the database in the background when a backup directory is set
+	backups, err := backupConfigFromEnv(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid backup configuration: %v", err)
+	}
+	if backups.Dir != "" {
+		b := newBackupper(db, backups)
+		h.SetBackup(func(ctx context.Context) (string, error) { return b.Backup(ctx, time.Now()) })
+		go runBackups(ctx, b, backups.Interval)
+		log.Printf("Backing up to %s every %s, keeping %d", backups.Dir, backups.Interval, backups.Keep)
+	}
+
 	mux := setupRouter(h, "web/static")
 
 	port := os.Getenv("PORT")
@@ -123,6 +252,7 @@ func main() {
 		Addr:              port,
 		Handler:           mux,
 		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
 	}
 
 	// Channel to listen for errors coming from the listener.
@@ -148,12 +278,13 @@ func main() {
 		log.Println("Starting shutdown...")
 
 		// Create a context with a timeout for shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
 		// Attempt graceful shutdown
-		if err := srv.Shutdown(ctx); err != nil {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
 			log.Printf("Could not stop server gracefully: %v", err)
+			stop() // Abort the queries of requests still running
 			if err = srv.Close(); err != nil {
 				log.Printf("Could not stop http server: %v", err)
 			}
@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"expense-tracker/internal/handlers"
 	"expense-tracker/internal/storage"
@@ -57,6 +58,24 @@ func TestSetupRouter(t *testing.T) {
 			path:       "/expenses",
 			wantStatus: http.StatusFound, // Should redirect to login
 		},
+		{
+			name:       "API requires a token",
+			method:     "GET",
+			path:       "/api/v1/expenses",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Admin backup requires a token",
+			method:     "POST",
+			path:       "/api/v1/admin/backup",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Unknown API path",
+			method:     "GET",
+			path:       "/api/v1/unknown",
+			wantStatus: http.StatusNotFound,
+		},
 	}
 
 	for _, tt := range tests {
@@ -78,3 +97,27 @@ func TestSetupRouter(t *testing.T) {
 		})
 	}
 }
+
+func TestDBOptionsFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	opts, err := dbOptionsFromEnv(env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, storage.DefaultOptions(), opts)
+
+	opts, err = dbOptionsFromEnv(env(map[string]string{
+		"SQLITE_JOURNAL_MODE": "DELETE", "SQLITE_SYNCHRONOUS": "FULL", "SQLITE_BUSY_TIMEOUT": "30s",
+		"SQLITE_FOREIGN_KEYS": "false", "SQLITE_READ_CONNS": "0", "DB_QUERY_TIMEOUT": "0s",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, storage.Options{JournalMode: "DELETE", Synchronous: "FULL", BusyTimeout: 30 * time.Second}, opts)
+
+	for key, value := range map[string]string{
+		"SQLITE_BUSY_TIMEOUT": "5", "SQLITE_FOREIGN_KEYS": "maybe", "SQLITE_READ_CONNS": "-1", "DB_QUERY_TIMEOUT": "-1s",
+	} {
+		_, err := dbOptionsFromEnv(env(map[string]string{key: value}))
+		assert.Error(t, err, key)
+	}
+}
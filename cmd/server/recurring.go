@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/internal/storage"
+	"log"
+	"time"
+)
+
+// recurringInterval is how often the scheduler looks for due recurring transactions.
+const recurringInterval = 15 * time.Minute
+
+// runRecurring creates due recurring transactions at start-up, catching up on
+// any missed while the server was down, and then every interval until ctx is
+// cancelled.
+func runRecurring(ctx context.Context, db *storage.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n := materializeRecurring(ctx, db, time.Now()); n > 0 {
+			log.Printf("Created %d recurring transaction(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// materializeRecurring creates the expenses of all occurrences due at now and
+// returns how many were created. Each template's next date only advances once
+// its occurrence is stored, and an occurrence that already exists counts as
+// stored, so running it again after a crash or restart creates no duplicates.
+// Expenses go to the template's household. Templates whose next occurrence
+// falls after their end date are deleted, as they will not create any more.
+func materializeRecurring(ctx context.Context, db *storage.DB, now time.Time) int {
+	due, err := db.DueRecurring(ctx, now)
+	if err != nil {
+		log.Printf("DueRecurring error: %v", err)
+		return 0
+	}
+
+	created := 0
+	for _, r := range due {
+		next := r.NextDate
+		for _, date := range r.Occurrences(now) {
+			e := r.Expense(date)
+			err := db.CreateHouseholdExpense(ctx, r.HouseholdID, r.UserID, &e)
+			if err != nil && !errors.Is(err, storage.ErrDuplicateExpense) {
+				log.Printf("CreateExpense error for recurring %d: %v", r.ID, err)
+				break
+			}
+			if err == nil {
+				created++
+			}
+			if err := db.SetRecurringNext(ctx, r.ID, r.After(date)); err != nil {
+				log.Printf("SetRecurringNext error for recurring %d: %v", r.ID, err)
+				break
+			}
+			next = r.After(date)
+		}
+		if r.Ended(next) {
+			if err := db.DeleteRecurring(ctx, r.HouseholdID, r.ID); err != nil {
+				log.Printf("DeleteRecurring error for recurring %d: %v", r.ID, err)
+			}
+		}
+	}
+	return created
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaterializeRecurring(t *testing.T) {
+	ctx := t.Context()
+	db, err := storage.NewDB(":memory:")
+	require.NoError(t, err, "failed to create database")
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "testuser", "hash")
+	require.NoError(t, err)
+	household, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, household.ID, user.ID))
+
+	end := time.Date(2026, 4, 30, 0, 0, 0, 0, time.UTC)
+	rent := &models.Recurring{
+		Amount: 95000, Description: "Rent", Category: "Housing",
+		Frequency: models.FrequencyMonthly, Day: 1,
+		StartDate: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), EndDate: &end,
+	}
+	require.NoError(t, db.CreateRecurring(ctx, user.ID, rent))
+
+	// Catches up on the months missed while the server was down
+	now := time.Date(2026, 3, 15, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3, materializeRecurring(ctx, db, now))
+	assert.Equal(t, 0, materializeRecurring(ctx, db, now), "running again creates nothing")
+
+	// A crash after storing an occurrence but before advancing the template
+	// must not create a duplicate
+	require.NoError(t, db.SetRecurringNext(ctx, rent.ID, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 0, materializeRecurring(ctx, db, now))
+
+	// Stops at the end date
+	assert.Equal(t, 1, materializeRecurring(ctx, db, time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)))
+
+	expenses, err := db.ListExpenses(ctx, storage.UserScope(user.ID), 100, 0)
+	require.NoError(t, err)
+	require.Len(t, expenses, 4)
+	assert.Equal(t, time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC), expenses[0].Date.UTC())
+	assert.Equal(t, "Rent", expenses[0].Description)
+
+	// The ended template is removed rather than found due on every run
+	templates, err := db.ListRecurring(ctx, household.ID)
+	require.NoError(t, err)
+	assert.Empty(t, templates)
+	due, err := db.DueRecurring(ctx, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestMaterializeRecurring_SecondHousehold(t *testing.T) {
+	ctx := t.Context()
+	db, err := storage.NewDB(":memory:")
+	require.NoError(t, err, "failed to create database")
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "testuser", "hash")
+	require.NoError(t, err)
+	club, err := db.CreateHousehold(ctx, "Club")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, club.ID, user.ID))
+	fee := &models.Recurring{
+		Amount: 2000, Description: "Club fee", Category: "Other",
+		Frequency: models.FrequencyMonthly, Day: 1,
+		StartDate: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, db.CreateRecurring(ctx, user.ID, fee))
+
+	// The user's default household is now another one
+	home, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, home.ID, user.ID))
+	require.NoError(t, db.RemoveHouseholdMember(ctx, club.ID, user.ID))
+
+	assert.Equal(t, 1, materializeRecurring(ctx, db, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)))
+
+	expenses, err := db.ListExpenses(ctx, storage.HouseholdScope(club.ID), 10, 0)
+	require.NoError(t, err)
+	require.Len(t, expenses, 1)
+	assert.Equal(t, "Club fee", expenses[0].Description)
+	assert.Equal(t, club.ID, expenses[0].HouseholdID)
+
+	expenses, err = db.ListExpenses(ctx, storage.HouseholdScope(home.ID), 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, expenses)
+}
@@ -45,7 +45,7 @@ func (s *E2ETestSuite) SetupTest() {
 	// Clear the database before each test
 	db, err := storage.NewDB(dbPath)
 	s.Require().NoError(err, "could not open database for cleanup")
-	err = db.ClearExpenses()
+	err = db.ClearExpenses(s.T().Context())
 	s.Require().NoError(err, "could not clear expenses")
 	db.Close()
 
@@ -2,7 +2,9 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -12,6 +14,8 @@ const (
 	SessionTokenLength = 32
 	// BcryptCost is the cost factor for bcrypt hashing.
 	BcryptCost = 12
+	// APITokenPrefix marks API tokens so they are easy to recognize in scripts and logs.
+	APITokenPrefix = "et_"
 )
 
 // HashPassword hashes a password using bcrypt.
@@ -46,3 +50,19 @@ func GenerateRandomPassword() (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(b)[:20], nil
 }
+
+// GenerateAPIToken creates a cryptographically secure random API token.
+func GenerateAPIToken() (string, error) {
+	token, err := GenerateSessionToken()
+	if err != nil {
+		return "", err
+	}
+	return APITokenPrefix + token, nil
+}
+
+// HashAPIToken returns the hash under which an API token is stored. Tokens are
+// random and long, so a fast hash suffices and allows lookups by hash.
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,168 @@
+// Package export writes expenses in formats other programs can read.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+)
+
+// CSVOptions controls how values are written to CSV so that spreadsheets in
+// different locales read them correctly.
+type CSVOptions struct {
+	Delimiter        rune   // Field separator
+	DecimalSeparator string // "." or ","
+	DateFormat       string // Pattern made of YYYY, YY, MM and DD, e.g. "DD.MM.YYYY"
+}
+
+// DefaultCSVOptions are comma separated fields, a decimal point and ISO dates.
+var DefaultCSVOptions = CSVOptions{Delimiter: ',', DecimalSeparator: ".", DateFormat: "YYYY-MM-DD"}
+
+// csvHeader names the columns of exported files.
+var csvHeader = []string{"Date", "Kind", "Category", "Description", "Amount", "User"}
+
+// ParseCSVOptions builds options from their textual form. Empty values keep
+// the defaults. The delimiter may be given as "tab".
+func ParseCSVOptions(delimiter, decimalSeparator, dateFormat string) (CSVOptions, error) {
+	opts := DefaultCSVOptions
+	switch delimiter {
+	case "":
+	case "tab", `\t`, "\t":
+		opts.Delimiter = '\t'
+	case ",", ";", "|":
+		opts.Delimiter = rune(delimiter[0])
+	default:
+		return opts, fmt.Errorf("invalid delimiter %q", delimiter)
+	}
+	switch decimalSeparator {
+	case "":
+	case ".", ",":
+		opts.DecimalSeparator = decimalSeparator
+	default:
+		return opts, fmt.Errorf("invalid decimal separator %q", decimalSeparator)
+	}
+	if dateFormat != "" {
+		if _, err := models.DateLayout(dateFormat, true); err != nil {
+			return opts, err
+		}
+		opts.DateFormat = dateFormat
+	}
+	return opts, nil
+}
+
+// CSVWriter writes expenses as CSV rows after a header row.
+type CSVWriter struct {
+	w      *csv.Writer
+	opts   CSVOptions
+	layout string
+}
+
+// NewCSVWriter returns a writer that formats rows according to opts. The
+// header row is written right away.
+func NewCSVWriter(w io.Writer, opts CSVOptions) (*CSVWriter, error) {
+	layout, err := models.DateLayout(opts.DateFormat, true)
+	if err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.Delimiter
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &CSVWriter{w: cw, opts: opts, layout: layout}, nil
+}
+
+// Write writes one expense, as a row per category of a split expense.
+func (cw *CSVWriter) Write(row storage.ExpenseRow) error {
+	for _, part := range rowParts(row) {
+		amount := part.Amount.String()
+		if cw.opts.DecimalSeparator != "." {
+			amount = strings.Replace(amount, ".", cw.opts.DecimalSeparator, 1)
+		}
+		if err := cw.w.Write([]string{
+			row.Date.Format(cw.layout),
+			string(row.Kind),
+			part.Category,
+			row.Description,
+			amount,
+			row.Username,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowParts returns the parts of an expense, or the expense as a single part
+// when they are not filled in.
+func rowParts(row storage.ExpenseRow) []storage.ExpensePart {
+	if len(row.Parts) == 0 {
+		return []storage.ExpensePart{{Category: row.Category, Amount: row.Amount}}
+	}
+	return row.Parts
+}
+
+// Flush writes buffered rows to the underlying writer.
+func (cw *CSVWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// PeriodFilter returns a filter for the expenses of a month, or of a year in
+// the "year" view, and a file name without extension for exporting them.
+func PeriodFilter(viewMode string, year, month int) (storage.ExpenseFilter, string) {
+	if viewMode == "year" {
+		from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return storage.ExpenseFilter{From: from, To: from.AddDate(1, 0, 0)}, fmt.Sprintf("expenses-%d", year)
+	}
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return storage.ExpenseFilter{From: from, To: from.AddDate(0, 1, 0)}, fmt.Sprintf("expenses-%d-%02d", year, month)
+}
+
+// RangeFilter returns a filter for the expenses from one date to another,
+// both given as YYYY-MM-DD and both included, and a file name without
+// extension for exporting them. Either date may be empty to leave the range
+// open on that side.
+func RangeFilter(from, to string) (storage.ExpenseFilter, string, error) {
+	var f storage.ExpenseFilter
+	name := "expenses"
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return f, "", fmt.Errorf("invalid from date %q", from)
+		}
+		f.From = t
+		name += "-from-" + from
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return f, "", fmt.Errorf("invalid to date %q", to)
+		}
+		f.To = t.AddDate(0, 0, 1)
+		name += "-to-" + to
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return f, "", fmt.Errorf("from date %s is after to date %s", from, to)
+	}
+	return f, name, nil
+}
+
+// WriteCSV streams the expenses visible in the scope that match the filter
+// to w, oldest first.
+func WriteCSV(ctx context.Context, w io.Writer, db storage.Store, scope storage.Scope, filter storage.ExpenseFilter, opts CSVOptions) error {
+	cw, err := NewCSVWriter(w, opts)
+	if err != nil {
+		return err
+	}
+	if err := db.EachExpense(ctx, scope, filter, cw.Write); err != nil {
+		return err
+	}
+	return cw.Flush()
+}
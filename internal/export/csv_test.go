@@ -0,0 +1,98 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSVOptions(t *testing.T) {
+	opts, err := ParseCSVOptions("", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCSVOptions, opts)
+
+	opts, err = ParseCSVOptions("tab", ",", "dd.mm.yyyy")
+	require.NoError(t, err)
+	assert.Equal(t, '\t', opts.Delimiter)
+	assert.Equal(t, ",", opts.DecimalSeparator)
+
+	for _, args := range [][3]string{{"x", "", ""}, {"", ";", ""}, {"", "", "YYYY-MM-DD hh:mm"}} {
+		_, err := ParseCSVOptions(args[0], args[1], args[2])
+		assert.Error(t, err, "options %q", args)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	rows := []storage.ExpenseRow{
+		{Expense: models.Expense{Kind: models.KindExpense, Amount: 123456, Description: `Sofa; "Klippan"`, Category: "Home", Date: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)}, Username: "alice"},
+		{Expense: models.Expense{Kind: models.KindIncome, Amount: 50, Description: "Interest", Category: "Other", Date: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	var buf bytes.Buffer
+	cw, err := NewCSVWriter(&buf, CSVOptions{Delimiter: ';', DecimalSeparator: ",", DateFormat: "DD.MM.YYYY"})
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, cw.Write(row))
+	}
+	require.NoError(t, cw.Flush())
+
+	assert.Equal(t, "Date;Kind;Category;Description;Amount;User\n"+
+		"05.01.2026;expense;Home;\"Sofa; \"\"Klippan\"\"\";1234,56;alice\n"+
+		"31.01.2026;income;Other;Interest;0,50;\n", buf.String())
+}
+
+func TestWriteCSV(t *testing.T) {
+	ctx := t.Context()
+	db, err := storage.NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	household, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, household.ID, user.ID))
+	require.NoError(t, db.CreateExpense(ctx, user.ID, &models.Expense{Amount: 250, Description: "Bus", Category: "Transport", Date: time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC)}))
+	require.NoError(t, db.CreateExpense(ctx, user.ID, &models.Expense{Amount: 1000, Description: "Bread", Category: "Groceries", Date: time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)}))
+
+	var buf bytes.Buffer
+	err = WriteCSV(ctx, &buf, db, storage.UserScope(user.ID), storage.ExpenseFilter{}, DefaultCSVOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "Date,Kind,Category,Description,Amount,User\n"+
+		"2026-01-01,expense,Groceries,Bread,10.00,alice\n"+
+		"2026-02-01,expense,Transport,Bus,2.50,alice\n", buf.String())
+}
+
+func TestWriteCSV_SplitExpense(t *testing.T) {
+	ctx := t.Context()
+	db, err := storage.NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	user, err := db.CreateUser(ctx, "alice", "hash")
+	require.NoError(t, err)
+	household, err := db.CreateHousehold(ctx, "Home")
+	require.NoError(t, err)
+	require.NoError(t, db.AddHouseholdMember(ctx, household.ID, user.ID))
+	require.NoError(t, db.CreateExpense(ctx, user.ID, &models.Expense{
+		Amount: 5000, Description: "Supermarket", Date: time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC),
+		Splits: []models.Split{{Category: "Groceries", Amount: 3000}, {Category: "Housing", Amount: 2000}},
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(ctx, &buf, db, storage.UserScope(user.ID), storage.ExpenseFilter{}, DefaultCSVOptions))
+	assert.Equal(t, "Date,Kind,Category,Description,Amount,User\n"+
+		"2026-01-10,expense,Groceries,Supermarket,30.00,alice\n"+
+		"2026-01-10,expense,Housing,Supermarket,20.00,alice\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteCSV(ctx, &buf, db, storage.UserScope(user.ID), storage.ExpenseFilter{Category: "Housing"}, DefaultCSVOptions))
+	assert.Equal(t, "Date,Kind,Category,Description,Amount,User\n"+
+		"2026-01-10,expense,Housing,Supermarket,20.00,alice\n", buf.String(), "only the part in the category is exported")
+}
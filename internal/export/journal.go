@@ -0,0 +1,220 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+)
+
+// JournalFormat is a plain-text accounting format.
+type JournalFormat string
+
+const (
+	FormatLedger    JournalFormat = "ledger"
+	FormatHledger   JournalFormat = "hledger"
+	FormatBeancount JournalFormat = "beancount"
+)
+
+// ParseJournalFormat returns the journal format with the given name.
+func ParseJournalFormat(s string) (JournalFormat, error) {
+	switch f := JournalFormat(strings.ToLower(s)); f {
+	case FormatLedger, FormatHledger, FormatBeancount:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid journal format %q", s)
+}
+
+// Extension returns the customary file extension of the format.
+func (f JournalFormat) Extension() string {
+	switch f {
+	case FormatHledger:
+		return ".journal"
+	case FormatBeancount:
+		return ".beancount"
+	}
+	return ".ledger"
+}
+
+// DefaultFundingAccount is the account expenses are paid from when no other
+// is configured.
+const DefaultFundingAccount = "Assets:Checking"
+
+// JournalOptions controls how expenses are written as journal entries.
+type JournalOptions struct {
+	Format         JournalFormat
+	FundingAccount string // Account expenses are paid from and income is paid into
+	Currency       string // The household's base currency, which all amounts are in
+	// Categories provide the account of each category. Categories without
+	// one, and categories not listed, are booked to Expenses:<Category>, or
+	// Income:<Category> for income; subcategories go below their parent.
+	Categories []models.Category
+}
+
+// JournalWriter writes expenses as journal entries with a posting to the
+// account of each category and one to the funding account.
+type JournalWriter struct {
+	w        *bufio.Writer
+	opts     JournalOptions
+	accounts map[string]string // Configured account by category name
+	paths    map[string]string // Parent:Child path by category name
+	opened   map[string]bool   // Beancount accounts opened so far
+}
+
+// NewJournalWriter returns a writer that formats entries according to opts.
+// An empty funding account is replaced by the default.
+func NewJournalWriter(w io.Writer, opts JournalOptions) *JournalWriter {
+	if opts.FundingAccount == "" {
+		opts.FundingAccount = DefaultFundingAccount
+	}
+	jw := &JournalWriter{
+		w:        bufio.NewWriter(w),
+		opts:     opts,
+		accounts: make(map[string]string),
+		paths:    make(map[string]string),
+		opened:   make(map[string]bool),
+	}
+
+	names := make(map[int64]string, len(opts.Categories))
+	for _, c := range opts.Categories {
+		names[c.ID] = c.Name
+	}
+	for _, c := range opts.Categories {
+		if c.Account != "" {
+			jw.accounts[c.Name] = c.Account
+		}
+		if c.ParentID != nil && names[*c.ParentID] != "" {
+			jw.paths[c.Name] = names[*c.ParentID] + ":" + c.Name
+		}
+	}
+	return jw
+}
+
+// Write writes one expense as a journal entry, with a posting per category
+// of a split expense.
+func (jw *JournalWriter) Write(row storage.ExpenseRow) error {
+	funding := jw.opts.FundingAccount
+	if jw.opts.Format == FormatBeancount {
+		funding = beancountAccount(funding)
+	}
+
+	// Spending is debited to the category; income and refunds credit it
+	type posting struct {
+		account string
+		amount  models.Money
+	}
+	parts := rowParts(row)
+	postings := make([]posting, 0, len(parts))
+	var total models.Money
+	for _, part := range parts {
+		account := jw.categoryAccount(row.Kind, part.Category)
+		if jw.opts.Format == FormatBeancount {
+			account = beancountAccount(account)
+		}
+		amount := part.Amount
+		if row.Kind != models.KindExpense {
+			amount = -amount
+		}
+		postings = append(postings, posting{account, amount})
+		total += amount
+	}
+	description := strings.Join(strings.Fields(row.Description), " ")
+
+	switch jw.opts.Format {
+	case FormatBeancount:
+		date := row.Date.Format("2006-01-02")
+		for _, p := range append(postings, posting{account: funding}) {
+			if !jw.opened[p.account] {
+				jw.opened[p.account] = true
+				fmt.Fprintf(jw.w, "%s open %s\n\n", date, p.account)
+			}
+		}
+		fmt.Fprintf(jw.w, "%s * %s\n", date, beancountString(description))
+		if row.Username != "" {
+			fmt.Fprintf(jw.w, "  user: %s\n", beancountString(row.Username))
+		}
+		for _, p := range postings {
+			fmt.Fprintf(jw.w, "  %s  %s %s\n", p.account, p.amount, jw.opts.Currency)
+		}
+		fmt.Fprintf(jw.w, "  %s  %s %s\n", funding, -total, jw.opts.Currency)
+	default:
+		layout := "2006/01/02"
+		if jw.opts.Format == FormatHledger {
+			layout = "2006-01-02"
+		}
+		fmt.Fprintf(jw.w, "%s %s\n", row.Date.Format(layout), description)
+		if row.Username != "" {
+			fmt.Fprintf(jw.w, "    ; user: %s\n", row.Username)
+		}
+		for _, p := range postings {
+			fmt.Fprintf(jw.w, "    %s  %s %s\n", p.account, p.amount, jw.opts.Currency)
+		}
+		fmt.Fprintf(jw.w, "    %s\n", funding)
+	}
+	_, err := jw.w.WriteString("\n")
+	return err
+}
+
+// Flush writes buffered entries to the underlying writer.
+func (jw *JournalWriter) Flush() error {
+	return jw.w.Flush()
+}
+
+func (jw *JournalWriter) categoryAccount(kind models.ExpenseKind, category string) string {
+	if account, ok := jw.accounts[category]; ok {
+		return account
+	}
+	path := jw.paths[category]
+	if path == "" {
+		path = category
+	}
+	if kind == models.KindIncome {
+		return "Income:" + path
+	}
+	return "Expenses:" + path
+}
+
+// beancountAccount adapts an account name to beancount's rules: every part
+// starts with a capital letter or digit and holds only letters, digits and
+// dashes.
+func beancountAccount(account string) string {
+	parts := strings.Split(account, ":")
+	for i, part := range parts {
+		var b strings.Builder
+		for _, r := range part {
+			switch {
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				if b.Len() == 0 {
+					r = unicode.ToUpper(r)
+				}
+				b.WriteRune(r)
+			case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+				b.WriteByte('-')
+			}
+		}
+		parts[i] = strings.TrimSuffix(b.String(), "-")
+		if parts[i] == "" {
+			parts[i] = "Other"
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+func beancountString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// WriteJournal streams the expenses visible in the scope that match the
+// filter to w as journal entries, oldest first.
+func WriteJournal(ctx context.Context, w io.Writer, db storage.Store, scope storage.Scope, filter storage.ExpenseFilter, opts JournalOptions) error {
+	jw := NewJournalWriter(w, opts)
+	if err := db.EachExpense(ctx, scope, filter, jw.Write); err != nil {
+		return err
+	}
+	return jw.Flush()
+}
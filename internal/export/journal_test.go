@@ -0,0 +1,148 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func journalRows() []storage.ExpenseRow {
+	return []storage.ExpenseRow{
+		{Expense: models.Expense{Kind: models.KindExpense, Amount: 1250, Description: `Bread "rye"`, Category: "Bakery", Date: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)}, Username: "alice"},
+		{Expense: models.Expense{Kind: models.KindIncome, Amount: 250000, Description: "Salary", Category: "Salary", Date: time.Date(2026, 1, 28, 12, 0, 0, 0, time.UTC)}},
+		{Expense: models.Expense{Kind: models.KindRefund, Amount: 300, Description: "Bottle deposit", Category: "Eating Out", Date: time.Date(2026, 1, 30, 12, 0, 0, 0, time.UTC)}},
+	}
+}
+
+func journalCategories() []models.Category {
+	groceries := int64(1)
+	return []models.Category{
+		{ID: 1, Name: "Groceries"},
+		{ID: 2, Name: "Bakery", ParentID: &groceries},
+		{ID: 3, Name: "Eating Out", Account: "Expenses:Food:Restaurants"},
+	}
+}
+
+func writeJournalRows(t *testing.T, opts JournalOptions) string {
+	var buf bytes.Buffer
+	jw := NewJournalWriter(&buf, opts)
+	for _, row := range journalRows() {
+		require.NoError(t, jw.Write(row))
+	}
+	require.NoError(t, jw.Flush())
+	return buf.String()
+}
+
+func TestParseJournalFormat(t *testing.T) {
+	f, err := ParseJournalFormat("Beancount")
+	require.NoError(t, err)
+	assert.Equal(t, FormatBeancount, f)
+	assert.Equal(t, ".beancount", f.Extension())
+	assert.Equal(t, ".journal", FormatHledger.Extension())
+
+	_, err = ParseJournalFormat("gnucash")
+	assert.Error(t, err)
+}
+
+func TestJournalWriter_Ledger(t *testing.T) {
+	out := writeJournalRows(t, JournalOptions{Format: FormatLedger, Currency: "EUR", Categories: journalCategories()})
+	assert.Equal(t, `2026/01/05 Bread "rye"
+    ; user: alice
+    Expenses:Groceries:Bakery  12.50 EUR
+    Assets:Checking
+
+2026/01/28 Salary
+    Income:Salary  -2500.00 EUR
+    Assets:Checking
+
+2026/01/30 Bottle deposit
+    Expenses:Food:Restaurants  -3.00 EUR
+    Assets:Checking
+
+`, out)
+
+	out = writeJournalRows(t, JournalOptions{Format: FormatHledger, FundingAccount: "Liabilities:Visa", Currency: "USD"})
+	assert.Contains(t, out, "2026-01-05 Bread \"rye\"\n    ; user: alice\n    Expenses:Bakery  12.50 USD\n    Liabilities:Visa\n")
+}
+
+func TestJournalWriter_Beancount(t *testing.T) {
+	out := writeJournalRows(t, JournalOptions{Format: FormatBeancount, FundingAccount: "Assets:Bank:checking account", Currency: "EUR", Categories: journalCategories()})
+	assert.Equal(t, `2026-01-05 open Expenses:Groceries:Bakery
+
+2026-01-05 open Assets:Bank:Checking-account
+
+2026-01-05 * "Bread \"rye\""
+  user: "alice"
+  Expenses:Groceries:Bakery  12.50 EUR
+  Assets:Bank:Checking-account  -12.50 EUR
+
+2026-01-28 open Income:Salary
+
+2026-01-28 * "Salary"
+  Income:Salary  -2500.00 EUR
+  Assets:Bank:Checking-account  2500.00 EUR
+
+2026-01-30 open Expenses:Food:Restaurants
+
+2026-01-30 * "Bottle deposit"
+  Expenses:Food:Restaurants  -3.00 EUR
+  Assets:Bank:Checking-account  3.00 EUR
+
+`, out)
+}
+
+func TestJournalWriter_SplitExpense(t *testing.T) {
+	row := storage.ExpenseRow{
+		Expense: models.Expense{Kind: models.KindExpense, Amount: 5000, Description: "Supermarket", Category: "Groceries", Date: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)},
+		Parts:   []storage.ExpensePart{{Category: "Groceries", Amount: 3000}, {Category: "Eating Out", Amount: 2000}},
+	}
+	for format, want := range map[JournalFormat]string{
+		FormatLedger: `2026/01/10 Supermarket
+    Expenses:Groceries  30.00 EUR
+    Expenses:Food:Restaurants  20.00 EUR
+    Assets:Checking
+
+`,
+		FormatBeancount: `2026-01-10 open Expenses:Groceries
+
+2026-01-10 open Expenses:Food:Restaurants
+
+2026-01-10 open Assets:Checking
+
+2026-01-10 * "Supermarket"
+  Expenses:Groceries  30.00 EUR
+  Expenses:Food:Restaurants  20.00 EUR
+  Assets:Checking  -50.00 EUR
+
+`,
+	} {
+		var buf bytes.Buffer
+		jw := NewJournalWriter(&buf, JournalOptions{Format: format, Currency: "EUR", Categories: journalCategories()})
+		require.NoError(t, jw.Write(row))
+		require.NoError(t, jw.Flush())
+		assert.Equal(t, want, buf.String(), format)
+	}
+}
+
+func TestRangeFilter(t *testing.T) {
+	f, name, err := RangeFilter("2026-01-01", "2026-01-31")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), f.From)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), f.To, "the last day is included")
+	assert.Equal(t, "expenses-from-2026-01-01-to-2026-01-31", name)
+
+	f, _, err = RangeFilter("", "2026-01-31")
+	require.NoError(t, err)
+	assert.True(t, f.From.IsZero())
+
+	for _, args := range [][2]string{{"2026-13-01", ""}, {"", "tomorrow"}, {"2026-02-01", "2026-01-01"}} {
+		_, _, err := RangeFilter(args[0], args[1])
+		assert.Error(t, err, "range %q", args)
+	}
+}
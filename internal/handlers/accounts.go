@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"errors"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ledgerEntriesShown is how many of the latest entries the account page lists.
+const ledgerEntriesShown = 100
+
+// accountFilter reads the account query parameter that limits the expense
+// list and statistics to one account. Missing or invalid values mean all
+// accounts.
+func accountFilter(r *http.Request) int64 {
+	id, err := strconv.ParseInt(r.URL.Query().Get("account"), 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
+
+// Accounts renders the household's accounts with their balances and the
+// forms for adding accounts and transfers.
+func (h *Handlers) Accounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("DefaultHouseholdID error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	balances, err := h.db.ListAccountBalances(ctx, householdID)
+	if errors.Is(err, storage.ErrNoExchangeRate) {
+		http.Error(w, "Add exchange rates first: "+err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("ListAccountBalances error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, r, "accounts.html", AccountsViewModel{
+		Accounts: balances,
+		Types:    models.AccountTypes,
+		Today:    time.Now().Format("2006-01-02"),
+	})
+}
+
+// AccountLedger renders the transactions and transfers of an account with
+// its running balance.
+func (h *Handlers) AccountLedger(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+	account, err := h.db.GetAccount(ctx, householdID, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Account not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("GetAccount error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	entries, err := h.db.GetAccountLedger(ctx, householdID, id, ledgerEntriesShown)
+	if errors.Is(err, storage.ErrNoExchangeRate) {
+		http.Error(w, "Add exchange rates first: "+err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("GetAccountLedger error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	vm := AccountViewModel{Account: *account, Balance: account.OpeningBalance, Entries: entries}
+	if len(entries) > 0 {
+		vm.Balance = entries[0].Balance
+	}
+	h.render(w, r, "account.html", vm)
+}
+
+// CreateAccount adds an account to the user's household.
+func (h *Handlers) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	account, err := parseAccountForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	account.HouseholdID, err = h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.CreateAccount(ctx, account); err != nil {
+		if errors.Is(err, storage.ErrAccountExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("CreateAccount error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/accounts", "target":"#content"}`)
+}
+
+// ArchiveAccount archives an account, or restores it when the form sets archived=false.
+func (h *Handlers) ArchiveAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	archived := r.FormValue("archived") != "false"
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.SetAccountArchived(ctx, householdID, id, archived); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Account not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("SetAccountArchived error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/accounts", "target":"#content"}`)
+}
+
+// CreateTransfer records a transfer between two accounts of the user's household.
+func (h *Handlers) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transfer, err := parseTransferForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.CreateTransfer(ctx, user.ID, transfer); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNoHousehold):
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		case errors.Is(err, storage.ErrInvalidAccount):
+			http.Error(w, "Choose two different accounts", http.StatusBadRequest)
+		case errors.Is(err, storage.ErrNoExchangeRate):
+			http.Error(w, "Enter the amount received: "+err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("CreateTransfer error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("HX-Location", fmt.Sprintf(`{"path":"/accounts/%d", "target":"#content"}`, transfer.FromAccountID))
+}
+
+// DeleteTransfer removes a transfer.
+func (h *Handlers) DeleteTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.DeleteTransfer(ctx, householdID, id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Transfer not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("DeleteTransfer error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/accounts", "target":"#content"}`)
+}
+
+func parseAccountForm(r *http.Request) (*models.Account, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	a := &models.Account{Name: strings.TrimSpace(r.FormValue("name"))}
+	if a.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	var err error
+	if a.Type, err = models.ParseAccountType(r.FormValue("type")); err != nil {
+		return nil, err
+	}
+	// An empty currency is the household's base currency
+	if s := r.FormValue("currency"); s != "" {
+		if a.Currency, err = models.ParseCurrency(s); err != nil {
+			return nil, err
+		}
+	}
+	if s := r.FormValue("opening_balance"); s != "" {
+		if a.OpeningBalance, err = models.ParseMoney(s); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func parseTransferForm(r *http.Request) (*models.Transfer, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	t := &models.Transfer{Description: strings.TrimSpace(r.FormValue("description"))}
+	var err error
+	if t.FromAccountID, err = strconv.ParseInt(r.FormValue("from"), 10, 64); err != nil {
+		return nil, errors.New("choose the account to transfer from")
+	}
+	if t.ToAccountID, err = strconv.ParseInt(r.FormValue("to"), 10, 64); err != nil {
+		return nil, errors.New("choose the account to transfer to")
+	}
+	if t.Amount, err = models.ParseMoney(r.FormValue("amount")); err != nil {
+		return nil, err
+	}
+	if t.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	// The amount received is only needed between currencies without a stored rate
+	if s := r.FormValue("to_amount"); s != "" {
+		if t.ToAmount, err = models.ParseMoney(s); err != nil {
+			return nil, err
+		}
+		if t.ToAmount <= 0 {
+			return nil, errors.New("amount received must be positive")
+		}
+	}
+	// Transfers are dated at noon so that they sort among the day's expenses
+	if t.Date, err = time.Parse("2006-01-02", r.FormValue("date")); err != nil {
+		return nil, errors.New("invalid date")
+	}
+	t.Date = t.Date.Add(12 * time.Hour)
+	return t, nil
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+)
+
+func (s *ExpenseHandlerTestSuite) TestAccounts_CreateAndTransfer() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	w := s.postCurrencyForm("/accounts", url.Values{"name": {"Joint card"}, "type": {"card"}}, h.CreateAccount)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal(`{"path":"/accounts", "target":"#content"}`, w.Header().Get("HX-Location"))
+	w = s.postCurrencyForm("/accounts", url.Values{"name": {"Wallet"}, "type": {"cash"}, "opening_balance": {"50"}}, h.CreateAccount)
+	s.Require().Equal(http.StatusOK, w.Code)
+	w = s.postCurrencyForm("/accounts", url.Values{"name": {"Wallet"}}, h.CreateAccount)
+	s.Equal(http.StatusConflict, w.Code)
+	w = s.postCurrencyForm("/accounts", url.Values{"name": {"Piggy bank"}, "type": {"jar"}}, h.CreateAccount)
+	s.Equal(http.StatusBadRequest, w.Code)
+
+	accounts, err := s.db.ListAccounts(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	s.Require().Len(accounts, 2)
+	card, wallet := accounts[0], accounts[1]
+	s.Equal(models.AccountCard, card.Type)
+	s.Equal("EUR", card.Currency)
+
+	form := url.Values{
+		"from":   {fmt.Sprint(card.ID)},
+		"to":     {fmt.Sprint(wallet.ID)},
+		"amount": {"20"},
+		"date":   {"2026-01-10"},
+	}
+	w = s.postCurrencyForm("/accounts/transfers", form, h.CreateTransfer)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal(fmt.Sprintf(`{"path":"/accounts/%d", "target":"#content"}`, card.ID), w.Header().Get("HX-Location"))
+
+	form.Set("to", form.Get("from"))
+	w = s.postCurrencyForm("/accounts/transfers", form, h.CreateTransfer)
+	s.Equal(http.StatusBadRequest, w.Code)
+
+	// The transfer moves money between the accounts without being spending
+	req := httptest.NewRequest("GET", "/accounts", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.Accounts(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, "-€20.00")
+	s.Contains(body, "€70.00")
+	s.Contains(body, `hx-post="/accounts/transfers"`)
+
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Empty(expenses)
+}
+
+func (s *ExpenseHandlerTestSuite) TestAccountLedger() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	card := &models.Account{HouseholdID: s.household.ID, Name: "Joint card"}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, card))
+	wallet := &models.Account{HouseholdID: s.household.ID, Name: "Wallet", Type: models.AccountCash}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, wallet))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{AccountID: &card.ID, Amount: 1250, Description: "Bread", Category: "Groceries", Date: parseTestDate("2026-01-10T09:00:00")}))
+	s.Require().NoError(s.db.CreateTransfer(s.ctx, s.user.ID, &models.Transfer{FromAccountID: card.ID, ToAccountID: wallet.ID, Amount: 3000, Description: "ATM", Date: parseTestDate("2026-01-11T12:00:00")}))
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/accounts/%d", card.ID), http.NoBody)
+	req.SetPathValue("id", fmt.Sprint(card.ID))
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.AccountLedger(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, "→ Wallet · ATM")
+	s.Contains(body, "balance €-12.50")
+	s.Contains(body, "balance €-42.50")
+	s.Contains(body, "Bread")
+	s.Contains(body, fmt.Sprintf("/expenses?account=%d", card.ID))
+
+	req = httptest.NewRequest("GET", "/accounts/999", http.NoBody)
+	req.SetPathValue("id", "999")
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.AccountLedger(w, req)
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func (s *ExpenseHandlerTestSuite) TestListExpenses_AccountFilter() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	card := &models.Account{HouseholdID: s.household.ID, Name: "Joint card"}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, card))
+	now := time.Now()
+
+	form := url.Values{
+		"amount":   {"12.50"},
+		"category": {"Groceries"},
+		"account":  {fmt.Sprint(card.ID)},
+		"date":     {now.Format("2006-01-02T15:04:05")},
+	}
+	w := s.postCurrencyForm("/expenses", form, h.CreateExpense)
+	s.Require().Equal(http.StatusOK, w.Code)
+	form.Set("account", "999")
+	w = s.postCurrencyForm("/expenses", form, h.CreateExpense)
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 700, Description: "Taxi", Category: "Transport", Date: now}))
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/expenses?account=%d", card.ID), http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, "Groceries")
+	s.NotContains(body, "Taxi")
+	s.Contains(body, fmt.Sprintf(`data-account="%d"`, card.ID))
+	s.Contains(body, fmt.Sprintf(`<option value="%d" selected>Joint card</option>`, card.ID))
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/statistics?view=month&account=%d", card.ID), http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.Statistics(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body = w.Body.String()
+	s.Contains(body, "12.50")
+	s.NotContains(body, "19.50")
+	s.Contains(body, fmt.Sprintf("&account=%d", card.ID))
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"expense-tracker/internal/models"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// APIBackup describes a database backup written on demand.
+type APIBackup struct {
+	File      string    `json:"file"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetAdmins sets the usernames allowed to use the admin endpoints.
+func (h *Handlers) SetAdmins(usernames []string) {
+	h.admins = make(map[string]bool, len(usernames))
+	for _, name := range usernames {
+		h.admins[name] = true
+	}
+}
+
+// SetBackup sets the function APIAdminBackup uses to back up the database.
+// It returns the path of the file written.
+func (h *Handlers) SetBackup(backup func(context.Context) (string, error)) {
+	h.backup = backup
+}
+
+// AdminMiddleware only lets admins through. It must be wrapped by
+// APIAuthMiddleware, which provides the user.
+func (h *Handlers) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(UserContextKey).(*models.User)
+		if !ok {
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+			return
+		}
+		if !h.admins[user.Username] {
+			writeAPIError(w, http.StatusForbidden, "forbidden", "Admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// APIAdminBackup writes a verified snapshot of the database right away.
+func (h *Handlers) APIAdminBackup(w http.ResponseWriter, r *http.Request) {
+	if h.backup == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "unavailable", "Backups are not configured")
+		return
+	}
+
+	path, err := h.backup(r.Context())
+	if err != nil {
+		writeAPIInternalError(w, "Backup", err)
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		writeAPIInternalError(w, "Backup", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, APIBackup{
+		File:      filepath.Base(path),
+		Size:      info.Size(),
+		CreatedAt: info.ModTime().UTC(),
+	})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense-tracker/internal/models"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+func (s *ExpenseHandlerTestSuite) TestAPIAdminBackup() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	path := filepath.Join(s.T().TempDir(), "expenses-20260301-120000.db")
+	h.SetBackup(func(context.Context) (string, error) {
+		return path, os.WriteFile(path, []byte("snapshot"), 0o600)
+	})
+	token := s.apiToken(models.ScopeReadWrite)
+	handler := h.AdminMiddleware(http.HandlerFunc(h.APIAdminBackup)).ServeHTTP
+
+	// Only admins may trigger a backup
+	w := s.apiRequest(h, handler, "POST", "/api/v1/admin/backup", token, "", "")
+	s.Equal(http.StatusForbidden, w.Code)
+	s.Equal("forbidden", s.decodeAPIError(w).Code)
+	s.NoFileExists(path)
+
+	h.SetAdmins([]string{"root", s.user.Username})
+	w = s.apiRequest(h, handler, "POST", "/api/v1/admin/backup", token, "", "")
+	s.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var backup APIBackup
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&backup))
+	s.Equal("expenses-20260301-120000.db", backup.File)
+	s.Equal(int64(len("snapshot")), backup.Size)
+
+	// Failed backups are reported without details
+	h.SetBackup(func(context.Context) (string, error) { return "", errors.New("disk full") })
+	w = s.apiRequest(h, handler, "POST", "/api/v1/admin/backup", token, "", "")
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.NotContains(w.Body.String(), "disk full")
+
+	// Without a backup directory the endpoint is unavailable
+	h.SetBackup(nil)
+	w = s.apiRequest(h, handler, "POST", "/api/v1/admin/backup", token, "", "")
+	s.Equal(http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"expense-tracker/internal/auth"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// apiDefaultLimit is the page size of list endpoints when none is given.
+	apiDefaultLimit = 50
+	// apiMaxLimit is the largest page size a client may request.
+	apiMaxLimit = 200
+	// apiMaxBodySize limits the size of JSON request bodies.
+	apiMaxBodySize = 1 << 20
+)
+
+// APIError is the body of every error response of the JSON API.
+type APIError struct {
+	Error APIErrorDetail `json:"error"`
+}
+
+// APIErrorDetail describes an API error. Code is stable and meant for
+// programs; Message is meant for people.
+type APIErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIExpenseList is a page of expenses. NextCursor is empty on the last page.
+type APIExpenseList struct {
+	Expenses   []models.Expense `json:"expenses"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// APIStatistics holds the totals of a month, or of a year when Month is 0.
+type APIStatistics struct {
+	Year       int                     `json:"year"`
+	Month      int                     `json:"month,omitempty"`
+	Income     models.Money            `json:"income"`
+	Spending   models.Money            `json:"spending"`
+	Net        models.Money            `json:"net"`
+	Categories []storage.CategoryTotal `json:"categories"`
+	Tags       []storage.TagTotal      `json:"tags"`
+}
+
+// APINewToken is returned once when an API token is created.
+type APINewToken struct {
+	models.APIToken
+	Token string `json:"token"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("JSON encode error: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, APIError{Error: APIErrorDetail{Code: code, Message: message}})
+}
+
+func writeAPIInternalError(w http.ResponseWriter, op string, err error) {
+	log.Printf("%s error: %v", op, err)
+	writeAPIError(w, http.StatusInternalServerError, "internal", "Internal server error")
+}
+
+// APIAuthMiddleware authenticates API requests by a bearer token in the
+// Authorization header or, failing that, by the session cookie. Unlike
+// AuthMiddleware it answers with a JSON error instead of redirecting.
+func (h *Handlers) APIAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user *models.User
+		if header := r.Header.Get("Authorization"); header != "" {
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authorization header must be a bearer token")
+				return
+			}
+			var apiToken *models.APIToken
+			var err error
+			user, apiToken, err = h.db.UseAPIToken(r.Context(), auth.HashAPIToken(strings.TrimSpace(token)), time.Now())
+			if errors.Is(err, storage.ErrNotFound) {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Invalid API token")
+				return
+			}
+			if err != nil {
+				writeAPIInternalError(w, "UseAPIToken", err)
+				return
+			}
+			if !apiToken.CanWrite() && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				writeAPIError(w, http.StatusForbidden, "forbidden", "This API token is read-only")
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), APITokenContextKey, apiToken))
+		} else if cookie, err := r.Cookie(SessionCookieName); err == nil && cookie.Value != "" {
+			user, _ = h.db.ValidateSession(r.Context(), cookie.Value)
+		}
+		if user == nil {
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), UserContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// APINotFound answers requests to unknown API paths.
+func (h *Handlers) APINotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, "not_found", "No such endpoint")
+}
+
+// APIListExpenses returns a page of expenses, newest first. Query parameters:
+// from and to (dates, to exclusive), category, kind, account (ID), tag, q
+// (description search), limit and cursor (the next_cursor of the previous
+// page).
+func (h *Handlers) APIListExpenses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	filter, err := parseExpenseFilter(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	// Fetch one more than requested to learn whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+	expenses, err := h.db.FilterExpenses(ctx, storage.UserScope(user.ID).WithAccount(accountFilter(r)).WithTag(tagFilter(r)), filter)
+	if err != nil {
+		writeAPIInternalError(w, "FilterExpenses", err)
+		return
+	}
+
+	list := APIExpenseList{Expenses: []models.Expense{}}
+	if len(expenses) > limit {
+		expenses = expenses[:limit]
+		last := expenses[limit-1]
+		list.NextCursor = encodeCursor(storage.Cursor{Date: last.Date, ID: last.ID})
+	}
+	list.Expenses = append(list.Expenses, expenses...)
+	writeJSON(w, http.StatusOK, list)
+}
+
+// APIGetExpense returns a single expense.
+func (h *Handlers) APIGetExpense(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	expense, err := h.db.GetExpense(ctx, storage.UserScope(user.ID), id)
+	if err != nil {
+		apiExpenseError(w, "GetExpense", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, expense)
+}
+
+// APICreateExpense records a transaction in the user's household.
+func (h *Handlers) APICreateExpense(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	expense, err := decodeExpense(w, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	if err := h.db.CreateExpense(ctx, user.ID, expense); err != nil {
+		apiExpenseError(w, "CreateExpense", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, expense)
+}
+
+// APIUpdateExpense replaces the kind, amount, currency, description, category,
+// date and account of an expense.
+func (h *Handlers) APIUpdateExpense(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	expense, err := decodeExpense(w, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if expense.Date.IsZero() {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", "date is required")
+		return
+	}
+	expense.ID, _ = strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	scope := storage.UserScope(user.ID)
+	if err := h.db.UpdateExpense(ctx, scope, expense); err != nil {
+		apiExpenseError(w, "UpdateExpense", err)
+		return
+	}
+	updated, err := h.db.GetExpense(ctx, scope, expense.ID)
+	if err != nil {
+		apiExpenseError(w, "GetExpense", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// APIDeleteExpense deletes an expense.
+func (h *Handlers) APIDeleteExpense(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err := h.db.DeleteExpense(ctx, storage.UserScope(user.ID), id); err != nil {
+		apiExpenseError(w, "DeleteExpense", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APICategories returns the categories of the user's household. Archived
+// ones are included when archived=true.
+func (h *Handlers) APICategories(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	_, categories, err := h.householdCategories(ctx, user.ID, r.URL.Query().Get("archived") == "true")
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			writeAPIError(w, http.StatusForbidden, "no_household", "You are not a member of any household")
+			return
+		}
+		writeAPIInternalError(w, "ListCategories", err)
+		return
+	}
+	if categories == nil {
+		categories = []models.Category{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]models.Category{"categories": categories})
+}
+
+// APIStatistics returns income, spending and spending by category and by tag
+// for the month given by the year and month query parameters, or for the
+// whole year when month is omitted. The year defaults to the current one. The
+// account query parameter limits them to one account.
+func (h *Handlers) APIStatistics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	stats := APIStatistics{Year: time.Now().Year()}
+	if s := r.URL.Query().Get("year"); s != "" {
+		year, err := strconv.Atoi(s)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "bad_request", "invalid year")
+			return
+		}
+		stats.Year = year
+	}
+	if s := r.URL.Query().Get("month"); s != "" {
+		month, err := strconv.Atoi(s)
+		if err != nil || month < 1 || month > 12 {
+			writeAPIError(w, http.StatusBadRequest, "bad_request", "month must be between 1 and 12")
+			return
+		}
+		stats.Month = month
+	}
+
+	scope := storage.UserScope(user.ID).WithAccount(accountFilter(r))
+	totals, err := h.db.GetTotalForPeriod(ctx, scope, stats.Year, stats.Month)
+	if err != nil {
+		writeAPIInternalError(w, "GetTotalForPeriod", err)
+		return
+	}
+	stats.Income, stats.Spending, stats.Net = totals.Income, totals.Spending, totals.Net()
+
+	if stats.Month == 0 {
+		stats.Categories, err = h.db.GetCategoryTotalsByYear(ctx, scope, stats.Year)
+	} else {
+		stats.Categories, err = h.db.GetCategoryTotalsByMonth(ctx, scope, stats.Year, stats.Month)
+	}
+	if err != nil {
+		writeAPIInternalError(w, "GetCategoryTotals", err)
+		return
+	}
+	if stats.Categories == nil {
+		stats.Categories = []storage.CategoryTotal{}
+	}
+
+	if stats.Month == 0 {
+		stats.Tags, err = h.db.GetTagTotalsByYear(ctx, scope, stats.Year)
+	} else {
+		stats.Tags, err = h.db.GetTagTotalsByMonth(ctx, scope, stats.Year, stats.Month)
+	}
+	if err != nil {
+		writeAPIInternalError(w, "GetTagTotals", err)
+		return
+	}
+	if stats.Tags == nil {
+		stats.Tags = []storage.TagTotal{}
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// APICreateToken creates an API token for the user from a JSON body with a
+// name and an optional scope, read-write by default. The token is only ever
+// returned in this response.
+func (h *Handlers) APICreateToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Authentication required")
+		return
+	}
+
+	var body struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, apiMaxBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", "invalid JSON body")
+		return
+	}
+	if body.Scope == "" {
+		body.Scope = string(models.ScopeReadWrite)
+	}
+	name, scope, err := parseTokenRequest(body.Name, body.Scope)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	created, err := h.issueAPIToken(ctx, user.ID, name, scope)
+	if err != nil {
+		writeAPIInternalError(w, "CreateAPIToken", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func apiExpenseError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeAPIError(w, http.StatusNotFound, "not_found", "Expense not found")
+	case errors.Is(err, storage.ErrNoHousehold):
+		writeAPIError(w, http.StatusForbidden, "no_household", "You are not a member of any household")
+	case errors.Is(err, storage.ErrDuplicateExpense):
+		writeAPIError(w, http.StatusConflict, "duplicate", "An expense with the same date, amount and description exists")
+	case errors.Is(err, storage.ErrInvalidAccount):
+		writeAPIError(w, http.StatusBadRequest, "bad_request", "Account not found")
+	case errors.Is(err, storage.ErrNoExchangeRate), errors.Is(err, storage.ErrInvalidSplit), errors.Is(err, storage.ErrInvalidShare), errors.Is(err, storage.ErrInvalidTag):
+		writeAPIError(w, http.StatusBadRequest, "bad_request", err.Error())
+	default:
+		writeAPIInternalError(w, op, err)
+	}
+}
+
+// decodeExpense reads a transaction from a JSON request body. Only kind,
+// amount, currency, description, category, date, account_id, splits, sharing
+// and tags are taken from it. The amounts of splits and shares are in currency
+// and splits replace category. Unlike the form, #words in the description are
+// left as they are.
+func decodeExpense(w http.ResponseWriter, r *http.Request) (*models.Expense, error) {
+	var in models.Expense
+	r.Body = http.MaxBytesReader(w, r.Body, apiMaxBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	kind, err := models.ParseExpenseKind(string(in.Kind))
+	if err != nil {
+		return nil, err
+	}
+	if in.Amount < 0 || in.OriginalAmount < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+	// The amount is in currency unless original_amount, as returned by
+	// the API, is given too
+	if in.OriginalAmount == 0 {
+		in.OriginalAmount = in.Amount
+	}
+	if in.Currency != "" {
+		if in.Currency, err = models.ParseCurrency(in.Currency); err != nil {
+			return nil, err
+		}
+	}
+	e := &models.Expense{
+		Kind:           kind,
+		Amount:         in.OriginalAmount,
+		Currency:       in.Currency,
+		OriginalAmount: in.OriginalAmount,
+		Description:    in.Description,
+		Category:       in.Category,
+		Date:           in.Date,
+		AccountID:      in.AccountID,
+		Splits:         in.Splits,
+		Sharing:        in.Sharing,
+		Tags:           in.Tags,
+	}
+	if len(e.Splits) > 0 {
+		e.Category = e.Splits[0].Category
+	}
+	if e.Description == "" {
+		e.Description = e.Category
+	}
+	return e, nil
+}
+
+// parseExpenseFilter reads the filter of APIListExpenses from the query string.
+func parseExpenseFilter(r *http.Request) (storage.ExpenseFilter, error) {
+	q := r.URL.Query()
+	f := storage.ExpenseFilter{
+		Category: q.Get("category"),
+		Search:   q.Get("q"),
+		Limit:    apiDefaultLimit,
+	}
+
+	var err error
+	if f.From, err = parseAPIDate(q.Get("from")); err != nil {
+		return f, fmt.Errorf("invalid from: %w", err)
+	}
+	if f.To, err = parseAPIDate(q.Get("to")); err != nil {
+		return f, fmt.Errorf("invalid to: %w", err)
+	}
+	if s := q.Get("kind"); s != "" {
+		if f.Kind, err = models.ParseExpenseKind(s); err != nil {
+			return f, err
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		if f.Limit, err = strconv.Atoi(s); err != nil || f.Limit < 1 || f.Limit > apiMaxLimit {
+			return f, fmt.Errorf("limit must be between 1 and %d", apiMaxLimit)
+		}
+	}
+	if s := q.Get("cursor"); s != "" {
+		cursor, err := decodeCursor(s)
+		if err != nil {
+			return f, errors.New("invalid cursor")
+		}
+		f.After = &cursor
+	}
+	return f, nil
+}
+
+// parseAPIDate accepts a date (2006-01-02) or an RFC 3339 timestamp. An empty
+// string yields the zero time.
+func parseAPIDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// encodeCursor turns a cursor into an opaque string for clients.
+func encodeCursor(c storage.Cursor) string {
+	raw := c.Date.Format(time.RFC3339Nano) + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (storage.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return storage.Cursor{}, err
+	}
+	date, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return storage.Cursor{}, errors.New("malformed cursor")
+	}
+	var c storage.Cursor
+	if c.Date, err = time.Parse(time.RFC3339Nano, date); err != nil {
+		return storage.Cursor{}, err
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return storage.Cursor{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense-tracker/internal/auth"
+	"expense-tracker/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// apiRequest sends a request through APIAuthMiddleware with the given bearer
+// token and returns the recorded response.
+func (s *ExpenseHandlerTestSuite) apiRequest(h *Handlers, handler http.HandlerFunc, method, target, token, body string, pathID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if pathID != "" {
+		req.SetPathValue("id", pathID)
+	}
+	w := httptest.NewRecorder()
+	h.APIAuthMiddleware(handler).ServeHTTP(w, req)
+	return w
+}
+
+func (s *ExpenseHandlerTestSuite) apiToken(scope models.TokenScope) string {
+	token, err := auth.GenerateAPIToken()
+	s.Require().NoError(err)
+	_, err = s.db.CreateAPIToken(s.ctx, s.user.ID, "test", scope, auth.HashAPIToken(token))
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *ExpenseHandlerTestSuite) decodeAPIError(w *httptest.ResponseRecorder) APIErrorDetail {
+	var body APIError
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&body))
+	return body.Error
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_Unauthorized() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	w := s.apiRequest(h, h.APIListExpenses, "GET", "/api/v1/expenses", "", "", "")
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.Equal("application/json", w.Header().Get("Content-Type"))
+	s.Equal("unauthorized", s.decodeAPIError(w).Code)
+
+	w = s.apiRequest(h, h.APIListExpenses, "GET", "/api/v1/expenses", "et_wrong", "", "")
+	s.Equal(http.StatusUnauthorized, w.Code)
+	s.Equal("Invalid API token", s.decodeAPIError(w).Message)
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_ReadOnlyToken() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	token := s.apiToken(models.ScopeRead)
+
+	w := s.apiRequest(h, h.APIListExpenses, "GET", "/api/v1/expenses", token, "", "")
+	s.Equal(http.StatusOK, w.Code)
+
+	w = s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token, `{"amount": 1, "category": "Groceries"}`, "")
+	s.Equal(http.StatusForbidden, w.Code)
+	s.Equal("forbidden", s.decodeAPIError(w).Code)
+
+	tokens, err := s.db.ListAPITokens(s.ctx, s.user.ID)
+	s.Require().NoError(err)
+	s.Require().Len(tokens, 1)
+	s.NotNil(tokens[0].LastUsedAt, "every use is recorded")
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_ExpenseLifecycle() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	token := s.apiToken(models.ScopeReadWrite)
+
+	w := s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token,
+		`{"kind": "expense", "amount": "12.30", "description": "Lunch", "category": "Eating Out", "date": "2026-01-15T12:30:00Z"}`, "")
+	s.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var created models.Expense
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&created))
+	s.NotZero(created.ID)
+	s.Equal(models.Money(1230), created.Amount)
+	s.Equal(s.household.ID, created.HouseholdID)
+
+	id := created.ID
+	path := "/api/v1/expenses/" + jsonID(id)
+	w = s.apiRequest(h, h.APIGetExpense, "GET", path, token, "", jsonID(id))
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"amount":12.30`)
+
+	w = s.apiRequest(h, h.APIUpdateExpense, "PUT", path, token,
+		`{"kind": "refund", "amount": 5, "description": "Lunch refund", "category": "Eating Out", "date": "2026-01-16T09:00:00Z"}`, jsonID(id))
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var updated models.Expense
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&updated))
+	s.Equal(models.KindRefund, updated.Kind)
+	s.Equal(models.Money(500), updated.Amount)
+
+	w = s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token, `{"amount": -1}`, "")
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Equal("bad_request", s.decodeAPIError(w).Code)
+
+	w = s.apiRequest(h, h.APIDeleteExpense, "DELETE", path, token, "", jsonID(id))
+	s.Equal(http.StatusNoContent, w.Code)
+
+	w = s.apiRequest(h, h.APIGetExpense, "GET", path, token, "", jsonID(id))
+	s.Equal(http.StatusNotFound, w.Code)
+	s.Equal("not_found", s.decodeAPIError(w).Code)
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_Splits() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	token := s.apiToken(models.ScopeReadWrite)
+
+	w := s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token,
+		`{"amount": 45, "description": "Supermarket", "date": "2026-01-15T12:30:00Z", "splits": [{"category": "Groceries", "amount": 30}, {"category": "Housing", "amount": 15}]}`, "")
+	s.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var created models.Expense
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&created))
+	s.Equal("Groceries", created.Category)
+
+	w = s.apiRequest(h, h.APIGetExpense, "GET", "/api/v1/expenses/"+jsonID(created.ID), token, "", jsonID(created.ID))
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"splits":[{"category":"Groceries","amount":30.00},{"category":"Housing","amount":15.00}]`)
+
+	w = s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token,
+		`{"amount": 50, "date": "2026-01-15T12:30:00Z", "splits": [{"category": "Groceries", "amount": 30}, {"category": "Housing", "amount": 15}]}`, "")
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Equal("invalid split: parts add up to 45.00, not 50.00", s.decodeAPIError(w).Message)
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_ForeignCurrency() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	token := s.apiToken(models.ScopeReadWrite)
+	_, err := s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: "EUR", Currency: "USD", Date: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Rate: 1.25}})
+	s.Require().NoError(err)
+
+	w := s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token,
+		`{"amount": 10, "currency": "usd", "category": "Transport", "date": "2026-01-15T12:30:00Z"}`, "")
+	s.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var created models.Expense
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&created))
+	s.Equal("USD", created.Currency)
+	s.Equal(models.Money(1000), created.OriginalAmount)
+	s.Equal(models.Money(800), created.Amount)
+
+	// An expense as returned by the API can be sent back unchanged
+	body, err := json.Marshal(created)
+	s.Require().NoError(err)
+	w = s.apiRequest(h, h.APIUpdateExpense, "PUT", "/api/v1/expenses/"+jsonID(created.ID), token, string(body), jsonID(created.ID))
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var updated models.Expense
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&updated))
+	s.Equal(models.Money(1000), updated.OriginalAmount)
+	s.Equal(models.Money(800), updated.Amount)
+
+	w = s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token,
+		`{"amount": 10, "currency": "dollar", "category": "Transport"}`, "")
+	s.Equal(http.StatusBadRequest, w.Code)
+
+	w = s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token,
+		`{"amount": 1000, "currency": "JPY", "category": "Transport"}`, "")
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Equal("no exchange rate from JPY to EUR", s.decodeAPIError(w).Message)
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_ListExpensesPages() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	token := s.apiToken(models.ScopeReadWrite)
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	for i, desc := range []string{"Bread", "Milk", "Bus", "Cinema"} {
+		category := "Groceries"
+		if i >= 2 {
+			category = "Transport"
+		}
+		s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 100, Description: desc, Category: category, Date: date.Add(time.Duration(i) * time.Hour)}))
+	}
+
+	var seen []string
+	target := "/api/v1/expenses?limit=3"
+	for pages := 0; target != ""; pages++ {
+		s.Require().Less(pages, 3)
+		w := s.apiRequest(h, h.APIListExpenses, "GET", target, token, "", "")
+		s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+		var list APIExpenseList
+		s.Require().NoError(json.NewDecoder(w.Body).Decode(&list))
+		for _, e := range list.Expenses {
+			seen = append(seen, e.Description)
+		}
+		target = ""
+		if list.NextCursor != "" {
+			target = "/api/v1/expenses?limit=3&cursor=" + list.NextCursor
+		}
+	}
+	s.Equal([]string{"Cinema", "Bus", "Milk", "Bread"}, seen)
+
+	w := s.apiRequest(h, h.APIListExpenses, "GET", "/api/v1/expenses?category=Groceries&from=2026-01-15&to=2026-01-16", token, "", "")
+	s.Require().Equal(http.StatusOK, w.Code)
+	var list APIExpenseList
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&list))
+	s.Len(list.Expenses, 2)
+	s.Empty(list.NextCursor)
+
+	w = s.apiRequest(h, h.APIListExpenses, "GET", "/api/v1/expenses?cursor=garbage", token, "", "")
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Equal("invalid cursor", s.decodeAPIError(w).Message)
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_CategoriesAndStatistics() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	token := s.apiToken(models.ScopeReadWrite)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 2500, Description: "Bread", Category: "Groceries", Date: parseTestDate("2026-01-10T12:00:00")}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Kind: models.KindIncome, Amount: 10000, Description: "Salary", Category: "Other", Date: parseTestDate("2026-01-11T12:00:00")}))
+
+	w := s.apiRequest(h, h.APICategories, "GET", "/api/v1/categories", token, "", "")
+	s.Require().Equal(http.StatusOK, w.Code)
+	var categories map[string][]models.Category
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&categories))
+	s.Equal("Groceries", categories["categories"][0].Name)
+
+	w = s.apiRequest(h, h.APIStatistics, "GET", "/api/v1/statistics?year=2026&month=1", token, "", "")
+	s.Require().Equal(http.StatusOK, w.Code)
+	var stats APIStatistics
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&stats))
+	s.Equal(models.Money(2500), stats.Spending)
+	s.Equal(models.Money(10000), stats.Income)
+	s.Equal(models.Money(7500), stats.Net)
+	s.Require().Len(stats.Categories, 1)
+	s.Equal("Groceries", stats.Categories[0].Category)
+
+	w = s.apiRequest(h, h.APIStatistics, "GET", "/api/v1/statistics?month=13", token, "", "")
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_CreateTokenWithSession() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	s.Require().NoError(s.db.CreateSession(s.ctx, "session-token", s.user.ID, time.Now().Add(time.Hour)))
+
+	req := httptest.NewRequest("POST", "/api/v1/tokens", strings.NewReader(`{"name": "Shortcuts", "scope": "read"}`))
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: "session-token"})
+	w := httptest.NewRecorder()
+	h.APIAuthMiddleware(http.HandlerFunc(h.APICreateToken)).ServeHTTP(w, req)
+	s.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+
+	var created APINewToken
+	s.Require().NoError(json.NewDecoder(w.Body).Decode(&created))
+	s.True(strings.HasPrefix(created.Token, auth.APITokenPrefix))
+	s.Equal("Shortcuts", created.Name)
+	s.Equal(models.ScopeRead, created.Scope)
+
+	// The new token works and is not stored in plain text
+	w = s.apiRequest(h, h.APIListExpenses, "GET", "/api/v1/expenses", created.Token, "", "")
+	s.Equal(http.StatusOK, w.Code)
+	_, _, err := s.db.UseAPIToken(s.ctx, created.Token, time.Now())
+	s.Error(err)
+}
+
+func jsonID(id int64) string {
+	b, _ := json.Marshal(id)
+	return string(b)
+}
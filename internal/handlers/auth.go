@@ -20,7 +20,7 @@ func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		sessionInfo, err := h.db.ValidateSessionWithInfo(cookie.Value)
+		sessionInfo, err := h.db.ValidateSessionWithInfo(r.Context(), cookie.Value)
 		if err != nil {
 			// Invalid or expired session, clear the cookie
 			h.clearSessionCookie(w)
@@ -37,7 +37,7 @@ func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
 		if timeUntilExpiry < halfSessionDuration {
 			// Session is in the second half of its lifetime, renew it
 			newExpiresAt := now.Add(SessionDuration)
-			if err := h.db.RenewSession(cookie.Value, newExpiresAt); err == nil {
+			if err := h.db.RenewSession(r.Context(), cookie.Value, newExpiresAt); err == nil {
 				// Update the cookie expiration too
 				http.SetCookie(w, &http.Cookie{
 					Name:     SessionCookieName,
@@ -62,7 +62,7 @@ func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
 func (h *Handlers) LoginForm(w http.ResponseWriter, r *http.Request) {
 	// If already logged in, redirect to expenses
 	if cookie, err := r.Cookie(SessionCookieName); err == nil && cookie.Value != "" {
-		if _, err := h.db.ValidateSession(cookie.Value); err == nil {
+		if _, err := h.db.ValidateSession(r.Context(), cookie.Value); err == nil {
 			http.Redirect(w, r, "/expenses", http.StatusFound)
 			return
 		}
@@ -72,6 +72,7 @@ func (h *Handlers) LoginForm(w http.ResponseWriter, r *http.Request) {
 
 // Login handles the login form submission.
 func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	if err := r.ParseForm(); err != nil {
 		h.render(w, r, "login.html", LoginViewModel{Error: "Invalid form submission"})
 		return
@@ -85,7 +86,7 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.db.GetUserByUsername(username)
+	user, err := h.db.GetUserByUsername(ctx, username)
 	if err != nil || !auth.CheckPassword(password, user.PasswordHash) {
 		h.render(w, r, "login.html", LoginViewModel{Error: "Invalid username or password"})
 		return
@@ -101,7 +102,7 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Create session in database
 	expiresAt := time.Now().Add(SessionDuration)
-	if err := h.db.CreateSession(token, user.ID, expiresAt); err != nil {
+	if err := h.db.CreateSession(ctx, token, user.ID, expiresAt); err != nil {
 		log.Printf("Failed to create session: %v", err)
 		h.render(w, r, "login.html", LoginViewModel{Error: "An error occurred. Please try again."})
 		return
@@ -123,8 +124,9 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 
 // Logout handles user logout.
 func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	if cookie, err := r.Cookie(SessionCookieName); err == nil {
-		if err := h.db.DeleteSession(cookie.Value); err != nil {
+		if err := h.db.DeleteSession(ctx, cookie.Value); err != nil {
 			log.Printf("Failed to delete session: %v", err)
 		}
 	}
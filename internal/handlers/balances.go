@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// settlementsShown is how many of the latest settlements the balances page lists.
+const settlementsShown = 50
+
+// Balances renders what the members of the household owe each other from
+// their shared expenses, the payments that would settle it and the latest
+// settlements.
+func (h *Handlers) Balances(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("DefaultHouseholdID error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	balances, err := h.db.GetBalances(ctx, householdID)
+	if err != nil {
+		log.Printf("GetBalances error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	settlements, err := h.db.ListSettlements(ctx, householdID, settlementsShown)
+	if err != nil {
+		log.Printf("ListSettlements error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, r, "balances.html", BalancesViewModel{
+		Balances:    balances,
+		Plan:        storage.SettleUp(balances),
+		Settlements: settlements,
+		UserID:      user.ID,
+		Today:       time.Now().Format("2006-01-02"),
+	})
+}
+
+// SettleUp records the payments that settle all balances of the household,
+// as if every one of them had been made.
+func (h *Handlers) SettleUp(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+	balances, err := h.db.GetBalances(ctx, householdID)
+	if err != nil {
+		log.Printf("GetBalances error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var settlements []models.Settlement
+	for _, p := range storage.SettleUp(balances) {
+		settlements = append(settlements, p.Settlement)
+	}
+	if err := h.db.RecordSettlements(ctx, householdID, settlements); err != nil {
+		// Former members cannot settle here
+		if errors.Is(err, storage.ErrInvalidSettlement) {
+			http.Error(w, "Balances with former members must be settled one by one", http.StatusBadRequest)
+			return
+		}
+		log.Printf("RecordSettlements error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/balances", "target":"#content"}`)
+}
+
+// CreateSettlement records a payment from one member of the household to another.
+func (h *Handlers) CreateSettlement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settlement, err := parseSettlementForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.RecordSettlements(ctx, householdID, []models.Settlement{*settlement}); err != nil {
+		if errors.Is(err, storage.ErrInvalidSettlement) {
+			http.Error(w, "Choose two different members", http.StatusBadRequest)
+			return
+		}
+		log.Printf("RecordSettlements error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/balances", "target":"#content"}`)
+}
+
+// DeleteSettlement removes a settlement, bringing back the debt it paid off.
+func (h *Handlers) DeleteSettlement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.DeleteSettlement(ctx, householdID, id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Settlement not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("DeleteSettlement error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/balances", "target":"#content"}`)
+}
+
+func parseSettlementForm(r *http.Request) (*models.Settlement, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	s := &models.Settlement{}
+	var err error
+	if s.FromUserID, err = strconv.ParseInt(r.FormValue("from"), 10, 64); err != nil {
+		return nil, errors.New("choose who paid")
+	}
+	if s.ToUserID, err = strconv.ParseInt(r.FormValue("to"), 10, 64); err != nil {
+		return nil, errors.New("choose who was paid")
+	}
+	if s.Amount, err = models.ParseMoney(r.FormValue("amount")); err != nil {
+		return nil, err
+	}
+	if s.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	// Settlements are dated at noon like transfers
+	if s.Date, err = time.Parse("2006-01-02", r.FormValue("date")); err != nil {
+		return nil, errors.New("invalid date")
+	}
+	s.Date = s.Date.Add(12 * time.Hour)
+	return s, nil
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+func (s *ExpenseHandlerTestSuite) TestCreateExpense_Shared() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	bob := s.addMember("bob")
+
+	form := url.Values{
+		"amount":       {"30"},
+		"description":  {"Dinner"},
+		"category":     {"Eating Out"},
+		"date":         {"2026-01-10T19:00:00"},
+		"share_method": {"percent"},
+		"share_user":   {fmt.Sprint(s.user.ID), fmt.Sprint(bob.ID)},
+	}
+	form.Set(fmt.Sprintf("share_value_%d", s.user.ID), "40")
+	form.Set(fmt.Sprintf("share_value_%d", bob.ID), "60")
+	w := s.postCurrencyForm("/expenses", form, h.CreateExpense)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Require().NotNil(expenses[0].Sharing)
+	s.Equal(models.SharePercent, expenses[0].Sharing.Method)
+	balances, err := s.db.GetBalances(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Money(-1800), balances[0].Balance, "bob owes his 60%")
+
+	// The feed marks the expense and the form offers the members
+	req := httptest.NewRequest("GET", "/expenses", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, "👥 2")
+	s.Contains(body, fmt.Sprintf(`name="share_value_%d"`, bob.ID))
+	s.Contains(body, `hx-get="/balances"`)
+
+	form.Set(fmt.Sprintf("share_value_%d", bob.ID), "50")
+	w = s.postCurrencyForm(fmt.Sprintf("/expenses/%d", expenses[0].ID), form, func(w http.ResponseWriter, r *http.Request) {
+		r.SetPathValue("id", fmt.Sprint(expenses[0].ID))
+		h.UpdateExpense(w, r)
+	})
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), "percentages add up to 90, not 100")
+}
+
+func (s *ExpenseHandlerTestSuite) TestListExpenses_NoSharingAlone() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	req := httptest.NewRequest("GET", "/expenses", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.NotContains(w.Body.String(), `id="modal-sharing"`)
+	s.NotContains(w.Body.String(), `hx-get="/balances"`)
+}
+
+func (s *ExpenseHandlerTestSuite) TestBalances_SettleUp() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	bob := s.addMember("bob")
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{
+		Amount: 4000, Description: "Groceries", Category: "Groceries", Date: parseTestDate("2026-01-10T09:00:00"),
+		Sharing: &models.Sharing{Shares: []models.Share{{UserID: s.user.ID}, {UserID: bob.ID}}},
+	}))
+
+	balancesPage := func() string {
+		req := httptest.NewRequest("GET", "/balances", http.NoBody)
+		req = s.addUserContext(req)
+		w := httptest.NewRecorder()
+		h.Balances(w, req)
+		s.Require().Equal(http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+	body := balancesPage()
+	s.Contains(body, "is owed")
+	s.Contains(body, "-€20.00")
+	s.Contains(body, "bob → testuser")
+	s.Contains(body, `hx-post="/balances/settle"`)
+
+	w := s.postCurrencyForm("/balances/settle", url.Values{}, h.SettleUp)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal(`{"path":"/balances", "target":"#content"}`, w.Header().Get("HX-Location"))
+
+	body = balancesPage()
+	s.Contains(body, "settled up")
+	s.NotContains(body, `hx-post="/balances/settle"`)
+	settlements, err := s.db.ListSettlements(s.ctx, s.household.ID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(settlements, 1)
+	s.Equal(models.Money(2000), settlements[0].Amount)
+
+	// Deleting the payment brings the debt back
+	req := httptest.NewRequest("DELETE", fmt.Sprintf("/balances/settlements/%d", settlements[0].ID), http.NoBody)
+	req.SetPathValue("id", fmt.Sprint(settlements[0].ID))
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.DeleteSettlement(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Contains(balancesPage(), "bob → testuser")
+}
+
+func (s *ExpenseHandlerTestSuite) TestCreateSettlement() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	bob := s.addMember("bob")
+
+	form := url.Values{"from": {fmt.Sprint(bob.ID)}, "to": {fmt.Sprint(s.user.ID)}, "amount": {"12,50"}, "date": {"2026-01-12"}}
+	w := s.postCurrencyForm("/balances/settlements", form, h.CreateSettlement)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	balances, err := s.db.GetBalances(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Money(1250), balances[0].Balance, "bob paid and is owed it back")
+
+	form.Set("to", form.Get("from"))
+	w = s.postCurrencyForm("/balances/settlements", form, h.CreateSettlement)
+	s.Equal(http.StatusBadRequest, w.Code)
+	form.Set("amount", "0")
+	w = s.postCurrencyForm("/balances/settlements", form, h.CreateSettlement)
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_Sharing() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	token := s.apiToken(models.ScopeReadWrite)
+	bob := s.addMember("bob")
+
+	body := fmt.Sprintf(`{"amount": 10, "description": "Taxi", "category": "Transport", "sharing": {"method": "exact", "shares": [{"user_id": %d, "amount": 10}]}}`, bob.ID)
+	w := s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token, body, "")
+	s.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	s.Contains(w.Body.String(), fmt.Sprintf(`"sharing":{"method":"exact","shares":[{"user_id":%d,"amount":10.00}]}`, bob.ID))
+
+	body = fmt.Sprintf(`{"amount": 10, "description": "Bus", "category": "Transport", "sharing": {"shares": [{"user_id": %d}, {"user_id": 9999}]}}`, bob.ID)
+	w = s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token, body, "")
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(s.decodeAPIError(w).Message, "not a member")
+}
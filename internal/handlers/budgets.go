@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Budgets renders the page for setting the monthly budgets of the household's
+// categories. The year and month query parameters pick the month, the current
+// one by default.
+func (h *Handlers) Budgets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	year, month := now.Year(), int(now.Month())
+	if y, err := strconv.Atoi(r.URL.Query().Get("year")); err == nil {
+		year = y
+	}
+	if m, err := strconv.Atoi(r.URL.Query().Get("month")); err == nil && m >= 1 && m <= 12 {
+		month = m
+	}
+
+	householdID, categories, err := h.householdCategories(ctx, user.ID, false)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("ListCategories error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	statuses, err := h.db.GetBudgetStatuses(ctx, householdID, year, month)
+	if err != nil {
+		log.Printf("GetBudgetStatuses error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, r, "budgets.html", newBudgetsViewModel(categories, statuses, year, month))
+}
+
+// SetBudget sets a category's budget from the posted month on. An empty or
+// zero amount ends the budget.
+func (h *Handlers) SetBudget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	budget, err := parseBudgetForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	budget.HouseholdID, err = h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.SetBudget(ctx, budget); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Category not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("SetBudget error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", fmt.Sprintf(`{"path":"/budgets?year=%d&month=%d", "target":"#content"}`, budget.Year, budget.Month))
+}
+
+func newBudgetsViewModel(categories []models.Category, statuses []storage.BudgetStatus, year, month int) BudgetsViewModel {
+	byName := make(map[string]storage.BudgetStatus, len(statuses))
+	for _, bs := range statuses {
+		byName[bs.Category] = bs
+	}
+
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	prev, next := first.AddDate(0, -1, 0), first.AddDate(0, 1, 0)
+	vm := BudgetsViewModel{
+		Year:      year,
+		Month:     month,
+		MonthName: first.Month().String(),
+		PrevYear:  prev.Year(),
+		PrevMonth: int(prev.Month()),
+		NextYear:  next.Year(),
+		NextMonth: int(next.Month()),
+	}
+
+	// Subcategories are listed right after their parent
+	children := make(map[int64][]models.Category)
+	for _, c := range categories {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+	add := func(c models.Category) {
+		item := BudgetItem{
+			Category:      c,
+			CategoryStyle: CategoryStyle{Icon: c.Icon, Color: c.Color},
+			Child:         c.ParentID != nil,
+		}
+		if bs, ok := byName[c.Name]; ok {
+			item.HasBudget = true
+			item.Amount = bs.Budget.Amount
+			item.Rollover = bs.Budget.Rollover
+			item.Carried = bs.Carried
+			item.Spent = bs.Spent
+			item.Remaining = bs.Remaining()
+		}
+		vm.Items = append(vm.Items, item)
+	}
+	for _, c := range categories {
+		if c.ParentID != nil {
+			continue
+		}
+		add(c)
+		for _, child := range children[c.ID] {
+			add(child)
+		}
+	}
+	return vm
+}
+
+func parseBudgetForm(r *http.Request) (*models.Budget, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	b := &models.Budget{Rollover: r.FormValue("rollover") != ""}
+
+	var err error
+	if b.CategoryID, err = strconv.ParseInt(r.FormValue("category_id"), 10, 64); err != nil {
+		return nil, errors.New("invalid category")
+	}
+	if b.Year, err = strconv.Atoi(r.FormValue("year")); err != nil {
+		return nil, errors.New("invalid year")
+	}
+	if b.Month, err = strconv.Atoi(r.FormValue("month")); err != nil || b.Month < 1 || b.Month > 12 {
+		return nil, errors.New("invalid month")
+	}
+	if amount := r.FormValue("amount"); amount != "" {
+		if b.Amount, err = models.ParseMoney(amount); err != nil {
+			return nil, err
+		}
+		if b.Amount < 0 {
+			return nil, errors.New("amount must not be negative")
+		}
+	}
+	return b, nil
+}
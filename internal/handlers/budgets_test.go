@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func (s *ExpenseHandlerTestSuite) setBudget(category string, year, month int, amount models.Money, rollover bool) {
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	for _, c := range categories {
+		if c.Name == category {
+			s.Require().NoError(s.db.SetBudget(s.ctx, &models.Budget{HouseholdID: s.household.ID, CategoryID: c.ID, Year: year, Month: month, Amount: amount, Rollover: rollover}))
+			return
+		}
+	}
+	s.FailNow("unknown category " + category)
+}
+
+func (s *ExpenseHandlerTestSuite) TestSetBudget() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	groceries := categories[0]
+
+	form := url.Values{
+		"category_id": {strconv.FormatInt(groceries.ID, 10)},
+		"year":        {"2026"},
+		"month":       {"1"},
+		"amount":      {"400,50"},
+		"rollover":    {"1"},
+	}
+	resp := s.postCategoryForm("/budgets", form, h.SetBudget, 0)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(`{"path":"/budgets?year=2026&month=1", "target":"#content"}`, resp.Header.Get("HX-Location"))
+
+	budgets, err := s.db.ListBudgets(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Require().Len(budgets, 1)
+	s.Equal(models.Money(40050), budgets[0].Amount)
+	s.True(budgets[0].Rollover)
+
+	// The page shows the budget in the following months too
+	req := httptest.NewRequest("GET", "/budgets?year=2026&month=3", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.Budgets(w, req)
+	s.Equal(http.StatusOK, w.Result().StatusCode)
+	body := w.Body.String()
+	s.Contains(body, "March 2026")
+	s.Contains(body, `value="400.50"`)
+	s.Contains(body, "rolled over", "unspent January and February budgets carry over")
+
+	form.Set("amount", "-5")
+	resp = s.postCategoryForm("/budgets", form, h.SetBudget, 0)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	form.Set("amount", "5")
+	form.Set("category_id", "9999")
+	resp = s.postCategoryForm("/budgets", form, h.SetBudget, 0)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *ExpenseHandlerTestSuite) TestStatistics_BudgetVsActual() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	s.setBudget("Groceries", 2025, 12, 10000, false)
+	s.setBudget("Transport", 2025, 12, 5000, false)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 12000, Description: "Supermarket", Category: "Groceries", Date: parseTestDate("2026-01-10T12:00:00")}))
+
+	req := httptest.NewRequest("GET", "/statistics?view=month&year=2026&month=1", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.Statistics(w, req)
+	s.Equal(http.StatusOK, w.Result().StatusCode)
+
+	body := w.Body.String()
+	s.Contains(body, "€20.00 over €100.00")
+	s.Contains(body, "€50.00 left of €50.00", "budgeted categories without spending are listed")
+	s.Contains(body, "BUDGET LEFT")
+	s.Contains(body, `hx-get="/budgets?year=2026&month=1"`)
+}
+
+func (s *ExpenseHandlerTestSuite) TestBuildMonthView_ProjectedOverspend() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	s.setBudget("Groceries", 2026, 1, 20000, false)
+	s.setBudget("Transport", 2026, 1, 50000, false)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 8000, Description: "Supermarket", Category: "Groceries", Date: parseTestDate("2026-01-02T12:00:00")}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 4000, Description: "Bus pass", Category: "Transport", Date: parseTestDate("2026-01-09T12:00:00")}))
+
+	now := time.Date(2026, 1, 10, 18, 0, 0, 0, time.UTC)
+	vm := h.buildMonthView(s.ctx, storage.UserScope(s.user.ID), s.household.ID, h.categoryStyles(s.ctx, s.user.ID), 2026, 1, now)
+
+	// 120 spent in 10 days is on pace for 372 in January
+	s.Equal(models.Money(37200), vm.ProjectedSpending)
+	s.Equal(models.Money(70000), vm.BudgetTotal)
+	s.Equal(models.Money(58000), vm.BudgetRemaining)
+
+	s.Require().Len(vm.Categories, 2)
+	groceries, transport := vm.Categories[0], vm.Categories[1]
+	s.True(groceries.HasBudget)
+	s.Equal(models.Money(12000), groceries.Remaining)
+	s.Equal(models.Money(4800), groceries.ProjectedOverspend, "on pace for 248 of 200")
+	s.Zero(transport.ProjectedOverspend)
+	s.Equal(models.Money(4800), vm.ProjectedOverspend)
+
+	// Past months are not extrapolated
+	vm = h.buildMonthView(s.ctx, storage.UserScope(s.user.ID), s.household.ID, h.categoryStyles(s.ctx, s.user.ID), 2026, 1, now.AddDate(0, 2, 0))
+	s.Equal(models.Money(12000), vm.ProjectedSpending)
+	s.Zero(vm.ProjectedOverspend)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// accountPattern matches account names of two or more colon separated parts.
+var accountPattern = regexp.MustCompile(`^[^:\s]+( [^:\s]+)*(:[^:\s]+( [^:\s]+)*)+$`)
+
+// CategorySettings renders the page for managing the household's categories.
+func (h *Handlers) CategorySettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	_, categories, err := h.householdCategories(ctx, user.ID, true)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("ListCategories error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, r, "categories.html", newCategoriesViewModel(categories))
+}
+
+// CreateCategory adds a category to the user's household.
+func (h *Handlers) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	category, err := parseCategoryForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	category.HouseholdID, err = h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.CreateCategory(ctx, category); err != nil {
+		h.categoryError(w, "CreateCategory", err)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/settings/categories", "target":"#content"}`)
+}
+
+// UpdateCategory renames, re-icons, recolors or re-parents a category.
+func (h *Handlers) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	category, err := parseCategoryForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	category.ID, _ = strconv.ParseInt(r.PathValue("id"), 10, 64)
+	category.HouseholdID, err = h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.UpdateCategory(ctx, category); err != nil {
+		h.categoryError(w, "UpdateCategory", err)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/settings/categories", "target":"#content"}`)
+}
+
+// ArchiveCategory archives a category, or restores it when the form sets archived=false.
+func (h *Handlers) ArchiveCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	archived := r.FormValue("archived") != "false"
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.SetCategoryArchived(ctx, householdID, id, archived); err != nil {
+		h.categoryError(w, "SetCategoryArchived", err)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/settings/categories", "target":"#content"}`)
+}
+
+// MoveCategory moves a category one place up or down in the list.
+func (h *Handlers) MoveCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	step := 0
+	switch r.FormValue("direction") {
+	case "up":
+		step = -1
+	case "down":
+		step = 1
+	default:
+		http.Error(w, "direction must be up or down", http.StatusBadRequest)
+		return
+	}
+
+	householdID, categories, err := h.householdCategories(ctx, user.ID, true)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("ListCategories error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int64, len(categories))
+	from := -1
+	for i, c := range categories {
+		ids[i] = c.ID
+		if c.ID == id {
+			from = i
+		}
+	}
+	if from < 0 {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+	if to := from + step; to >= 0 && to < len(ids) {
+		ids[from], ids[to] = ids[to], ids[from]
+		if err := h.db.ReorderCategories(ctx, householdID, ids); err != nil {
+			h.categoryError(w, "ReorderCategories", err)
+			return
+		}
+	}
+	w.Header().Set("HX-Location", `{"path":"/settings/categories", "target":"#content"}`)
+}
+
+func newCategoriesViewModel(categories []models.Category) CategoriesViewModel {
+	hasChildren := make(map[int64]bool)
+	for _, c := range categories {
+		if c.ParentID != nil {
+			hasChildren[*c.ParentID] = true
+		}
+	}
+
+	var vm CategoriesViewModel
+	for _, c := range categories {
+		item := CategorySettingsItem{Category: c, HasChildren: hasChildren[c.ID]}
+		if c.ParentID != nil {
+			item.Parent = *c.ParentID
+		} else if !c.Archived {
+			vm.Parents = append(vm.Parents, c)
+		}
+		vm.Categories = append(vm.Categories, item)
+	}
+	return vm
+}
+
+func (h *Handlers) categoryError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Category not found", http.StatusNotFound)
+	case errors.Is(err, storage.ErrCategoryExists):
+		http.Error(w, "Category already exists", http.StatusConflict)
+	case errors.Is(err, storage.ErrInvalidParent):
+		http.Error(w, "Subcategories can only be placed under a top-level category", http.StatusBadRequest)
+	default:
+		log.Printf("%s error: %v", op, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func parseCategoryForm(r *http.Request) (*models.Category, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	c := &models.Category{
+		Name:    strings.TrimSpace(r.FormValue("name")),
+		Icon:    strings.TrimSpace(r.FormValue("icon")),
+		Color:   strings.TrimSpace(r.FormValue("color")),
+		Account: strings.TrimSpace(r.FormValue("account")),
+	}
+	if c.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if c.Icon == "" {
+		c.Icon = defaultCategoryStyle.Icon
+	}
+	if c.Color == "" {
+		c.Color = defaultCategoryStyle.Color
+	}
+	if !colorPattern.MatchString(c.Color) {
+		return nil, errors.New("color must look like #rrggbb")
+	}
+	if c.Account != "" && !accountPattern.MatchString(c.Account) {
+		return nil, errors.New("account must look like Expenses:Groceries")
+	}
+	if parent := r.FormValue("parent_id"); parent != "" && parent != "0" {
+		id, err := strconv.ParseInt(parent, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid parent category")
+		}
+		c.ParentID = &id
+	}
+	return c, nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"expense-tracker/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func (s *ExpenseHandlerTestSuite) postCategoryForm(path string, form url.Values, handler http.HandlerFunc, id int64) *http.Response {
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if id != 0 {
+		req.SetPathValue("id", strconv.FormatInt(id, 10))
+	}
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w.Result()
+}
+
+func (s *ExpenseHandlerTestSuite) TestCategorySettings() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	req := httptest.NewRequest("GET", "/settings/categories", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+
+	h.CategorySettings(w, req)
+	s.Equal(http.StatusOK, w.Result().StatusCode)
+
+	body := w.Body.String()
+	s.Contains(body, `value="Groceries"`)
+	s.Contains(body, `window.CATEGORIES = [{`, "picker categories are embedded as JSON")
+}
+
+func (s *ExpenseHandlerTestSuite) TestCreateCategory() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	form := url.Values{"name": {"Pets"}, "icon": {"🐶"}, "color": {"#a3e635"}}
+	resp := s.postCategoryForm("/settings/categories", form, h.CreateCategory, 0)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(`{"path":"/settings/categories", "target":"#content"}`, resp.Header.Get("HX-Location"))
+
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	s.Equal("Pets", categories[len(categories)-1].Name)
+
+	// The new category shows up in the form's picker
+	req := httptest.NewRequest("GET", "/expenses", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	s.Contains(w.Body.String(), `"name":"Pets"`)
+
+	resp = s.postCategoryForm("/settings/categories", form, h.CreateCategory, 0)
+	s.Equal(http.StatusConflict, resp.StatusCode)
+
+	form.Set("color", "red; background: url(x)")
+	form.Set("name", "Plants")
+	resp = s.postCategoryForm("/settings/categories", form, h.CreateCategory, 0)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *ExpenseHandlerTestSuite) TestUpdateCategory_RenameKeepsExpenses() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1500, Description: "Bus", Category: "Transport", Date: parseTestDate("2026-01-10T12:00:00")}))
+
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	transport := categories[2]
+	s.Require().Equal("Transport", transport.Name)
+
+	form := url.Values{"name": {"Commute"}, "icon": {"🚆"}, "color": {"#a78bfa"}}
+	resp := s.postCategoryForm("/settings/categories/x", form, h.UpdateCategory, transport.ID)
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	// Statistics use the renamed category's style
+	req := httptest.NewRequest("GET", "/statistics?year=2026&month=1", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.Statistics(w, req)
+	body := w.Body.String()
+	s.Contains(body, "Commute")
+	s.Contains(body, "🚆")
+
+	resp = s.postCategoryForm("/settings/categories/x", form, h.UpdateCategory, 9999)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *ExpenseHandlerTestSuite) TestArchiveAndMoveCategory() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	groceries, eatingOut := categories[0], categories[1]
+
+	resp := s.postCategoryForm("/settings/categories/x/move", url.Values{"direction": {"down"}}, h.MoveCategory, groceries.ID)
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	categories, err = s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	s.Equal(eatingOut.ID, categories[0].ID)
+	s.Equal(groceries.ID, categories[1].ID)
+
+	resp = s.postCategoryForm("/settings/categories/x/move", url.Values{"direction": {"sideways"}}, h.MoveCategory, groceries.ID)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = s.postCategoryForm("/settings/categories/x/archive", url.Values{"archived": {"true"}}, h.ArchiveCategory, groceries.ID)
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	active, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	for _, c := range active {
+		s.NotEqual("Groceries", c.Name, "archived categories are hidden from the picker")
+	}
+
+	resp = s.postCategoryForm("/settings/categories/x/archive", url.Values{"archived": {"false"}}, h.ArchiveCategory, groceries.ID)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	c, err := s.db.GetCategory(s.ctx, s.household.ID, groceries.ID)
+	s.Require().NoError(err)
+	s.False(c.Archived)
+}
+
+func (s *ExpenseHandlerTestSuite) TestCategories_OtherHousehold() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	otherCategories, err := s.db.ListCategories(s.ctx, other.ID, false)
+	s.Require().NoError(err)
+
+	form := url.Values{"name": {"Hijacked"}}
+	resp := s.postCategoryForm("/settings/categories/x", form, h.UpdateCategory, otherCategories[0].ID)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	resp = s.postCategoryForm("/settings/categories/x/archive", url.Values{}, h.ArchiveCategory, otherCategories[0].ID)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	c, err := s.db.GetCategory(s.ctx, other.ID, otherCategories[0].ID)
+	s.Require().NoError(err)
+	s.Equal(otherCategories[0].Name, c.Name)
+	s.False(c.Archived)
+}
+
+func (s *ExpenseHandlerTestSuite) TestStatistics_SubcategoryRollUp() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	eatingOut := categories[1]
+
+	form := url.Values{"name": {"Coffee"}, "icon": {"☕"}, "color": {"#60a5fa"}, "parent_id": {strconv.FormatInt(eatingOut.ID, 10)}}
+	resp := s.postCategoryForm("/settings/categories", form, h.CreateCategory, 0)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 450, Description: "Flat white", Category: "Coffee", Date: parseTestDate("2026-01-10T09:00:00")}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 2550, Description: "Pizza", Category: "Eating Out", Date: parseTestDate("2026-01-10T19:00:00")}))
+
+	// Collapsed: the parent row carries the rolled-up total
+	req := httptest.NewRequest("GET", "/statistics?year=2026&month=1", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.Statistics(w, req)
+	body := w.Body.String()
+	s.Contains(body, "€30.00")
+	s.Contains(body, `class="category-children" data-parent="Eating Out"`)
+
+	// Expanded: children are shown and navigation keeps the expansion
+	req = httptest.NewRequest("GET", "/statistics?year=2026&month=1&expand=Eating+Out", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.Statistics(w, req)
+	body = w.Body.String()
+	s.Contains(body, `class="category-children expanded"`)
+	s.Contains(body, "€4.50")
+	s.Contains(body, "month=12&expand=Eating&#43;Out")
+}
+
+func (s *ExpenseHandlerTestSuite) TestUpdateCategory_InvalidParent() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	groceries := categories[0]
+
+	form := url.Values{"name": {"Groceries"}, "parent_id": {strconv.FormatInt(groceries.ID, 10)}}
+	resp := s.postCategoryForm("/settings/categories/x", form, h.UpdateCategory, groceries.ID)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
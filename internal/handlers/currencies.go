@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"expense-tracker/internal/importer"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// currencyRatesShown is how many of the latest exchange rates the
+// currencies page lists.
+const currencyRatesShown = 30
+
+// commonCurrencies are offered in the currency pickers next to the base
+// currency.
+var commonCurrencies = []string{
+	"EUR", "USD", "GBP", "CHF", "JPY", "CNY", "CAD", "AUD", "NZD", "SEK", "NOK", "DKK",
+	"PLN", "CZK", "HUF", "RON", "BGN", "TRY", "UAH", "ILS", "INR", "THB", "SGD", "HKD",
+}
+
+// currencyChoices returns the base currency followed by the other common
+// currencies.
+func currencyChoices(base string) []string {
+	choices := []string{base}
+	for _, c := range commonCurrencies {
+		if c != base {
+			choices = append(choices, c)
+		}
+	}
+	return choices
+}
+
+// CurrencySettings renders the page for choosing the household's base
+// currency and managing exchange rates.
+func (h *Handlers) CurrencySettings(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.renderCurrencies(w, r, user.ID, "")
+}
+
+// SetBaseCurrency changes the base currency of the user's default household.
+// Its expenses are converted at the stored exchange rates.
+func (h *Handlers) SetBaseCurrency(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	currency, err := models.ParseCurrency(r.FormValue("currency"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("DefaultHouseholdID error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.SetHouseholdCurrency(ctx, householdID, currency); err != nil {
+		if errors.Is(err, storage.ErrNoExchangeRate) {
+			http.Error(w, "Add exchange rates first: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("SetHouseholdCurrency error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/settings/currencies", "target":"#content"}`)
+}
+
+// AddExchangeRate stores a rate entered by hand for the user's default
+// household: one unit of base is worth rate units of currency on date.
+func (h *Handlers) AddExchangeRate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	rate, err := parseExchangeRateForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("DefaultHouseholdID error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.db.SaveExchangeRates(ctx, householdID, []models.ExchangeRate{rate}); err != nil {
+		log.Printf("SaveExchangeRates error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/settings/currencies", "target":"#content"}`)
+}
+
+// ImportExchangeRates stores the rates of an uploaded file of the European
+// Central Bank's reference rates, in XML or CSV, for the user's default
+// household.
+func (h *Handlers) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxFileSize)
+	if err := r.ParseMultipartForm(importMaxFileSize); err != nil {
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("rates")
+	if err != nil {
+		http.Error(w, "Choose a file of exchange rates", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	rates, err := importer.ParseECBRates(file)
+	if err != nil {
+		http.Error(w, "Could not read exchange rates: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("DefaultHouseholdID error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	saved, err := h.db.SaveExchangeRates(ctx, householdID, rates)
+	if err != nil {
+		log.Printf("SaveExchangeRates error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.renderCurrencies(w, r, user.ID, fmt.Sprintf("Imported %d exchange rates.", saved))
+}
+
+// parseExchangeRateForm reads an exchange rate from the manual entry form.
+func parseExchangeRateForm(r *http.Request) (models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	var err error
+	if rate.Base, err = models.ParseCurrency(r.FormValue("base")); err != nil {
+		return rate, err
+	}
+	if rate.Currency, err = models.ParseCurrency(r.FormValue("currency")); err != nil {
+		return rate, err
+	}
+	if rate.Base == rate.Currency {
+		return rate, errors.New("currencies must differ")
+	}
+	if rate.Date, err = time.Parse("2006-01-02", r.FormValue("date")); err != nil {
+		return rate, errors.New("invalid date")
+	}
+	value := strings.Replace(strings.TrimSpace(r.FormValue("rate")), ",", ".", 1)
+	if rate.Rate, err = strconv.ParseFloat(value, 64); err != nil || rate.Rate <= 0 {
+		return rate, errors.New("rate must be a positive number")
+	}
+	return rate, nil
+}
+
+func (h *Handlers) renderCurrencies(w http.ResponseWriter, r *http.Request, userID int64, message string) {
+	ctx := r.Context()
+	householdID, err := h.db.DefaultHouseholdID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("DefaultHouseholdID error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	household, err := h.db.GetHousehold(ctx, householdID)
+	if err != nil {
+		log.Printf("GetHousehold error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	rates, err := h.db.ListExchangeRates(ctx, householdID, currencyRatesShown)
+	if err != nil {
+		log.Printf("ListExchangeRates error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, r, "currencies.html", CurrencySettingsViewModel{
+		Currency:   household.Currency,
+		Currencies: currencyChoices(household.Currency),
+		Rates:      rates,
+		Message:    message,
+	})
+}
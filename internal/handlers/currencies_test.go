@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// postCurrencyForm posts a form to one of the currency settings handlers.
+func (s *ExpenseHandlerTestSuite) postCurrencyForm(path string, form url.Values, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func (s *ExpenseHandlerTestSuite) TestCurrencySettings_BaseCurrencyAndRates() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	w := s.postCurrencyForm("/settings/currencies", url.Values{"currency": {"usd"}}, h.SetBaseCurrency)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal(`{"path":"/settings/currencies", "target":"#content"}`, w.Header().Get("HX-Location"))
+	household, err := s.db.GetHousehold(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Equal("USD", household.Currency)
+
+	form := url.Values{"base": {"USD"}, "currency": {"eur"}, "date": {"2026-01-02"}, "rate": {"0,92"}}
+	w = s.postCurrencyForm("/settings/currencies/rates", form, h.AddExchangeRate)
+	s.Require().Equal(http.StatusOK, w.Code)
+
+	req := httptest.NewRequest("GET", "/settings/currencies", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.CurrencySettings(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, `<option value="USD" selected>USD</option>`)
+	s.Contains(body, "1 USD = 0.92 EUR")
+	s.Contains(body, "2026-01-02")
+}
+
+func (s *ExpenseHandlerTestSuite) TestCurrencySettings_Validation() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	w := s.postCurrencyForm("/settings/currencies", url.Values{"currency": {"euro"}}, h.SetBaseCurrency)
+	s.Equal(http.StatusBadRequest, w.Code)
+
+	for _, form := range []url.Values{
+		{"base": {"EUR"}, "currency": {"EUR"}, "date": {"2026-01-02"}, "rate": {"1"}},
+		{"base": {"EUR"}, "currency": {"USD"}, "date": {"yesterday"}, "rate": {"1.1"}},
+		{"base": {"EUR"}, "currency": {"USD"}, "date": {"2026-01-02"}, "rate": {"0"}},
+		{"base": {"EUR"}, "currency": {"US"}, "date": {"2026-01-02"}, "rate": {"1.1"}},
+	} {
+		w := s.postCurrencyForm("/settings/currencies/rates", form, h.AddExchangeRate)
+		s.Equal(http.StatusBadRequest, w.Code, form.Encode())
+	}
+	rates, err := s.db.ListExchangeRates(s.ctx, s.household.ID, 10)
+	s.Require().NoError(err)
+	s.Empty(rates)
+}
+
+func (s *ExpenseHandlerTestSuite) TestImportExchangeRates() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("rates", "eurofxref-hist.csv")
+	s.Require().NoError(err)
+	_, err = part.Write([]byte("Date,USD,JPY,\n2026-01-02,1.25,N/A,\n2026-01-01,1.2,160,\n"))
+	s.Require().NoError(err)
+	s.Require().NoError(mw.Close())
+
+	req := httptest.NewRequest("POST", "/settings/currencies/rates/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("HX-Request", "true")
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.ImportExchangeRates(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "Imported 3 exchange rates.")
+
+	rates, err := s.db.ListExchangeRates(s.ctx, s.household.ID, 10)
+	s.Require().NoError(err)
+	s.Len(rates, 3)
+}
+
+func (s *ExpenseHandlerTestSuite) TestCreateExpense_ForeignCurrency() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	now := time.Now()
+	_, err := s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: "EUR", Currency: "USD", Date: now, Rate: 1.25}})
+	s.Require().NoError(err)
+
+	form := url.Values{
+		"amount":   {"10"},
+		"currency": {"usd"},
+		"category": {"Transport"},
+		"date":     {now.Format("2006-01-02T15:04:05")},
+	}
+	req := httptest.NewRequest("POST", "/expenses", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.CreateExpense(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal("USD", expenses[0].Currency)
+	s.Equal(models.Money(1000), expenses[0].OriginalAmount)
+	s.Equal(models.Money(800), expenses[0].Amount)
+
+	// The list shows the converted amount and the amount as paid
+	req = httptest.NewRequest("GET", "/expenses", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	body := w.Body.String()
+	s.Contains(body, "-€8.00")
+	s.Contains(body, "10.00 USD")
+	s.Contains(body, `data-currency="USD"`)
+
+	form.Set("currency", "dollars")
+	req = httptest.NewRequest("POST", "/expenses", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.CreateExpense(w, req)
+	s.Equal(http.StatusBadRequest, w.Code)
+
+	// A currency without a rate is refused rather than taken at face value
+	form.Set("currency", "JPY")
+	w = s.postCurrencyForm("/expenses", form, h.CreateExpense)
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), "no exchange rate from JPY to EUR")
+}
+
+func (s *ExpenseHandlerTestSuite) TestListExpenses_BaseCurrencySymbol() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	s.Require().NoError(s.db.SetHouseholdCurrency(s.ctx, s.household.ID, "GBP"))
+
+	req := httptest.NewRequest("GET", "/expenses", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, `<span class="currency">£</span>`)
+	s.NotContains(body, "€")
+	s.Contains(body, `<option value="GBP">GBP</option>`)
+}
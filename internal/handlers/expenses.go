@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
 	"log"
 	"net/http"
 	"sort"
@@ -12,6 +14,7 @@ const pageSize = 50
 
 // ListExpenses renders the list of expenses with infinite scroll support.
 func (h *Handlers) ListExpenses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	user, ok := r.Context().Value(UserContextKey).(*models.User)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -26,8 +29,11 @@ func (h *Handlers) ListExpenses(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	accountID, tag := accountFilter(r), tagFilter(r)
+	scope := storage.UserScope(user.ID).WithAccount(accountID).WithTag(tag)
+
 	// Fetch one extra to check if there are more items
-	expenses, err := h.db.ListExpenses(pageSize+1, offset)
+	expenses, err := h.db.ListExpenses(ctx, scope, pageSize+1, offset)
 	if err != nil {
 		log.Printf("ListExpenses error: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -40,6 +46,8 @@ func (h *Handlers) ListExpenses(w http.ResponseWriter, r *http.Request) {
 		expenses = expenses[:pageSize] // Trim to actual page size
 	}
 
+	styles := h.categoryStyles(ctx, user.ID)
+
 	// Group expenses by date
 	groupsMap := make(map[string]*ExpenseGroup)
 	for _, e := range expenses {
@@ -48,21 +56,12 @@ func (h *Handlers) ListExpenses(w http.ResponseWriter, r *http.Request) {
 			groupsMap[dateStr] = &ExpenseGroup{Date: dateStr, Title: formatGroupTitle(e.Date)}
 		}
 		group := groupsMap[dateStr]
-		group.Total += e.Amount
+		group.Total += e.Spending()
 
+		item := newExpenseItem(e, "15:04", styles)
 		// Check if this expense was created by a different user
-		isOtherUser := e.UserID != nil && *e.UserID != user.ID
-
-		group.Items = append(group.Items, ExpenseItem{
-			ID:            e.ID,
-			Amount:        e.Amount,
-			Description:   e.Description,
-			Category:      e.Category,
-			Time:          e.Date.Format("15:04"),
-			DateTime:      e.Date.Format("2006-01-02T15:04:05"),
-			CategoryStyle: getCategoryStyle(e.Category),
-			IsOtherUser:   isOtherUser,
-		})
+		item.IsOtherUser = e.UserID != nil && *e.UserID != user.ID
+		group.Items = append(group.Items, item)
 	}
 
 	groups := make([]ExpenseGroup, 0, len(groupsMap))
@@ -81,6 +80,8 @@ func (h *Handlers) ListExpenses(w http.ResponseWriter, r *http.Request) {
 		Groups:     groups,
 		NextOffset: nextOffset,
 		HasMore:    hasMore,
+		AccountID:  accountID,
+		Tag:        tag,
 	}
 
 	// For HTMX requests loading more items, return only the fragment
@@ -90,12 +91,14 @@ func (h *Handlers) ListExpenses(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// For full page load, get the current month total separately
-	totalSpent, err := h.db.GetCurrentMonthTotal()
+	totals, err := h.db.GetCurrentMonthTotal(ctx, scope)
 	if err != nil {
 		log.Printf("GetCurrentMonthTotal error: %v", err)
 		// Continue with 0 total rather than failing
 	}
-	viewModel.Total = totalSpent
+	viewModel.Total = totals.Spending
+	viewModel.Income = totals.Income
+	viewModel.Net = totals.Net()
 
 	h.render(w, r, "list.html", viewModel)
 }
@@ -104,19 +107,26 @@ func (h *Handlers) ListExpenses(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) CreateExpenseForm(w http.ResponseWriter, r *http.Request) {
 	h.render(w, r, "create.html", FormViewModel{
 		IsEdit:     false,
-		Categories: categories,
+		Categories: h.pickerCategories(r),
 	})
 }
 
 // EditExpenseForm renders the form to edit an existing expense.
 func (h *Handlers) EditExpenseForm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	if expense, err := h.db.GetExpense(id); err == nil {
+	if expense, err := h.db.GetExpense(ctx, storage.UserScope(user.ID), id); err == nil {
 		h.render(w, r, "create.html", FormViewModel{
 			Expense:       expense,
 			IsEdit:        true,
 			FormattedDate: expense.Date.Format("2006-01-02T15:04:05"),
-			Categories:    categories,
+			Categories:    h.pickerCategories(r),
 		})
 	} else {
 		http.Error(w, "Expense not found", http.StatusNotFound)
@@ -125,7 +135,8 @@ func (h *Handlers) EditExpenseForm(w http.ResponseWriter, r *http.Request) {
 
 // CreateExpense handles the creation of a new expense.
 func (h *Handlers) CreateExpense(w http.ResponseWriter, r *http.Request) {
-	amount, desc, cat, date, err := parseForm(r)
+	ctx := r.Context()
+	expense, err := parseForm(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -137,7 +148,23 @@ func (h *Handlers) CreateExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.CreateExpense(amount, desc, cat, date, user.ID); err != nil {
+	if err := h.db.CreateExpense(ctx, user.ID, expense); err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidAccount) {
+			http.Error(w, "Account not found", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storage.ErrDuplicateExpense) {
+			http.Error(w, "An expense with the same date, amount and description exists", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, storage.ErrNoExchangeRate) || errors.Is(err, storage.ErrInvalidSplit) || errors.Is(err, storage.ErrInvalidShare) || errors.Is(err, storage.ErrInvalidTag) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Printf("CreateExpense error: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -147,15 +174,37 @@ func (h *Handlers) CreateExpense(w http.ResponseWriter, r *http.Request) {
 
 // UpdateExpense handles the update of an existing expense.
 func (h *Handlers) UpdateExpense(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	amount, desc, cat, date, err := parseForm(r)
+	expense, err := parseForm(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if err := h.db.UpdateExpense(&models.Expense{
-		ID: id, Amount: amount, Description: desc, Category: cat, Date: date,
-	}); err != nil {
+	expense.ID = id
+	if err := h.db.UpdateExpense(ctx, storage.UserScope(user.ID), expense); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Expense not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrInvalidAccount) {
+			http.Error(w, "Account not found", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storage.ErrDuplicateExpense) {
+			http.Error(w, "An expense with the same date, amount and description exists", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, storage.ErrNoExchangeRate) || errors.Is(err, storage.ErrInvalidSplit) || errors.Is(err, storage.ErrInvalidShare) || errors.Is(err, storage.ErrInvalidTag) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Printf("UpdateExpense error: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -165,8 +214,19 @@ func (h *Handlers) UpdateExpense(w http.ResponseWriter, r *http.Request) {
 
 // DeleteExpense handles the deletion of an expense.
 func (h *Handlers) DeleteExpense(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	if err := h.db.DeleteExpense(id); err != nil {
+	if err := h.db.DeleteExpense(ctx, storage.UserScope(user.ID), id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Expense not found", http.StatusNotFound)
+			return
+		}
 		log.Printf("DeleteExpense error: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
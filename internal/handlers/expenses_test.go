@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -18,16 +19,27 @@ import (
 // ExpenseHandlerTestSuite provides a test suite for expense handler tests
 type ExpenseHandlerTestSuite struct {
 	suite.Suite
+	ctx         context.Context
 	db          *storage.DB
 	templateDir string
+	user        *models.User
+	household   *models.Household
 }
 
 // SetupTest runs before each test
 func (s *ExpenseHandlerTestSuite) SetupTest() {
+	s.ctx = context.Background()
 	db, err := storage.NewDB(":memory:")
 	s.Require().NoError(err, "failed to create test database")
 	s.db = db
 
+	// Create the current user in its own household
+	s.user, err = s.db.CreateUser(s.ctx, "testuser", "password123")
+	s.Require().NoError(err, "failed to create test user")
+	s.household, err = s.db.CreateHousehold(s.ctx, "Test")
+	s.Require().NoError(err, "failed to create test household")
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+
 	s.templateDir = "../../web/templates"
 	if _, err := os.Stat(s.templateDir); os.IsNotExist(err) {
 		s.T().Skip("Template directory not found, skipping handler integration test")
@@ -42,10 +54,18 @@ func (s *ExpenseHandlerTestSuite) TearDownTest() {
 }
 
 func (s *ExpenseHandlerTestSuite) addUserContext(req *http.Request) *http.Request {
-	ctx := context.WithValue(req.Context(), UserContextKey, &models.User{ID: 1, Username: "testuser"})
+	ctx := context.WithValue(req.Context(), UserContextKey, s.user)
 	return req.WithContext(ctx)
 }
 
+// addMember creates another user in the current user's household.
+func (s *ExpenseHandlerTestSuite) addMember(username string) *models.User {
+	user, err := s.db.CreateUser(s.ctx, username, "password456")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, user.ID))
+	return user
+}
+
 func (s *ExpenseHandlerTestSuite) TestListExpenses() {
 	h := NewHandlers(s.db, s.templateDir, false)
 
@@ -79,23 +99,19 @@ func (s *ExpenseHandlerTestSuite) TestListExpenses_Unauthorized() {
 func (s *ExpenseHandlerTestSuite) TestListExpenses_HighlightOtherUsersExpenses() {
 	h := NewHandlers(s.db, s.templateDir, false)
 
-	// Create user 1 first (the current user)
-	user1, err := s.db.CreateUser("testuser", "password123")
-	s.Require().NoError(err)
-
-	// Create user 2 (the other user)
-	user2, err := s.db.CreateUser("otheruser", "password456")
-	s.Require().NoError(err)
+	// User 1 is the current user, user 2 shares the household
+	user1 := s.user
+	user2 := s.addMember("otheruser")
 
 	// Create expenses for both users
 	date := parseTestDate("2026-01-15T12:00:00")
 
 	// Expense by user 1 (current user in context)
-	err = s.db.CreateExpense(50.00, "My Expense", "groceries", date, user1.ID)
+	err := s.db.CreateExpense(s.ctx, user1.ID, &models.Expense{Amount: 5000, Description: "My Expense", Category: "groceries", Date: date})
 	s.Require().NoError(err)
 
 	// Expense by user 2 (other user)
-	err = s.db.CreateExpense(30.00, "Other User Expense", "transport", date.Add(time.Hour), user2.ID)
+	err = s.db.CreateExpense(s.ctx, user2.ID, &models.Expense{Amount: 3000, Description: "Other User Expense", Category: "transport", Date: date.Add(time.Hour)})
 	s.Require().NoError(err)
 
 	// Request as user 1
@@ -161,11 +177,11 @@ func (s *ExpenseHandlerTestSuite) TestCreateExpense() {
 	s.Equal(expectedLoc, resp.Header.Get("HX-Location"))
 
 	// Verify DB insertion
-	expenses, err := s.db.ListExpenses(100, 0)
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 100, 0)
 	s.Require().NoError(err)
 	s.Require().Len(expenses, 1, "expected exactly 1 expense")
 	s.Equal("Lunch Test", expenses[0].Description)
-	s.InDelta(15.00, expenses[0].Amount, 0.001)
+	s.Equal(models.Money(1500), expenses[0].Amount)
 }
 
 func (s *ExpenseHandlerTestSuite) TestCreateExpense_LegacyFormat() {
@@ -187,7 +203,7 @@ func (s *ExpenseHandlerTestSuite) TestCreateExpense_LegacyFormat() {
 	resp := w.Result()
 	s.Equal(http.StatusOK, resp.StatusCode)
 
-	expenses, err := s.db.ListExpenses(100, 0)
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 100, 0)
 	s.Require().NoError(err)
 	s.Require().Len(expenses, 1)
 	s.Equal("Fallback Test", expenses[0].Description)
@@ -213,11 +229,144 @@ func (s *ExpenseHandlerTestSuite) TestCreateExpense_MissingDate() {
 	s.Equal(http.StatusBadRequest, resp.StatusCode)
 }
 
+func (s *ExpenseHandlerTestSuite) TestCreateExpense_InvalidAmount() {
+	h := NewHandlers(s.db, "dummy_path", false)
+
+	form := url.Values{}
+	form.Add("amount", "12.345")
+	form.Add("description", "Too precise")
+	form.Add("category", "food")
+	form.Add("date", "2026-01-09T12:00:00")
+
+	req := httptest.NewRequest("POST", "/expenses", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+
+	h.CreateExpense(w, req)
+
+	resp := w.Result()
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 100, 0)
+	s.Require().NoError(err)
+	s.Empty(expenses)
+}
+
+func (s *ExpenseHandlerTestSuite) TestCreateExpense_Income() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	form := url.Values{}
+	form.Add("kind", "income")
+	form.Add("amount", "2500")
+	form.Add("description", "Salary")
+	form.Add("category", "Other")
+	form.Add("date", "2026-01-09T12:00:00")
+
+	req := httptest.NewRequest("POST", "/expenses", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+
+	h.CreateExpense(w, req)
+	s.Equal(http.StatusOK, w.Result().StatusCode)
+
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 100, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal(models.KindIncome, expenses[0].Kind)
+	s.Equal("Salary", expenses[0].Description)
+}
+
+func (s *ExpenseHandlerTestSuite) TestCreateExpense_InvalidKind() {
+	h := NewHandlers(s.db, "dummy_path", false)
+
+	form := url.Values{}
+	form.Add("kind", "transfer")
+	form.Add("amount", "10")
+	form.Add("category", "Other")
+	form.Add("date", "2026-01-09T12:00:00")
+
+	req := httptest.NewRequest("POST", "/expenses", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+
+	h.CreateExpense(w, req)
+	s.Equal(http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func (s *ExpenseHandlerTestSuite) TestCreateExpense_Split() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	now := time.Now()
+
+	form := url.Values{
+		"amount":         {"45"},
+		"category":       {"Transport"},
+		"description":    {"Supermarket"},
+		"date":           {now.Format("2006-01-02T15:04:05")},
+		"split_category": {"Groceries", "Housing", ""},
+		"split_amount":   {"30", "15", ""},
+	}
+	req := httptest.NewRequest("POST", "/expenses", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.CreateExpense(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal("Groceries", expenses[0].Category)
+	s.Equal([]models.Split{{Category: "Groceries", Amount: 3000}, {Category: "Housing", Amount: 1500}}, expenses[0].Splits)
+
+	// The feed shows one row naming the categories, and the edit modal gets the parts
+	req = httptest.NewRequest("GET", "/expenses", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	body := w.Body.String()
+	s.Equal(1, strings.Count(body, `class="expense-item"`))
+	s.Contains(body, "Groceries, Housing")
+	s.Contains(body, `&#34;category&#34;:&#34;Housing&#34;,&#34;amount&#34;:15.00`)
+
+	form.Set("amount", "50")
+	req = httptest.NewRequest("POST", "/expenses", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.CreateExpense(w, req)
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), "parts add up to 45.00, not 50.00")
+}
+
+func (s *ExpenseHandlerTestSuite) TestStatistics_SplitExpense() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	now := time.Now()
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{
+		Amount: 4500, Description: "Supermarket", Date: now,
+		Splits: []models.Split{{Category: "Groceries", Amount: 3000}, {Category: "Housing", Amount: 1500}},
+	}))
+
+	req := httptest.NewRequest("GET", "/statistics?view=month", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.Statistics(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, `data-category="Groceries"`)
+	s.Contains(body, `data-category="Housing"`)
+	s.Contains(body, "45.00")
+	s.Contains(body, `splits:[{"category":"Groceries","amount":30.00},{"category":"Housing","amount":15.00}]`)
+}
+
 func (s *ExpenseHandlerTestSuite) TestStatistics_CurrentMonth() {
 	h := NewHandlers(s.db, s.templateDir, false)
 
 	// No query params should default to current month
 	req := httptest.NewRequest("GET", "/statistics", http.NoBody)
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.Statistics(w, req)
@@ -235,15 +384,15 @@ func (s *ExpenseHandlerTestSuite) TestStatistics_WithExpenses() {
 
 	// Create test expenses for January 2026
 	testExpenses := []struct {
-		amount      float64
+		amount      models.Money
 		description string
 		category    string
 		date        string
 	}{
-		{100.00, "Groceries", "groceries", "2026-01-15T12:00:00"},
-		{50.00, "Bus", "transport", "2026-01-16T12:00:00"},
-		{75.00, "Restaurant", "eating out", "2026-01-17T12:00:00"},
-		{25.00, "More Groceries", "groceries", "2026-01-18T12:00:00"},
+		{10000, "Groceries", "groceries", "2026-01-15T12:00:00"},
+		{5000, "Bus", "transport", "2026-01-16T12:00:00"},
+		{7500, "Restaurant", "eating out", "2026-01-17T12:00:00"},
+		{2500, "More Groceries", "groceries", "2026-01-18T12:00:00"},
 	}
 
 	for _, exp := range testExpenses {
@@ -251,12 +400,13 @@ func (s *ExpenseHandlerTestSuite) TestStatistics_WithExpenses() {
 		form.Add("amount", strings.TrimSpace(strings.Split(strings.TrimPrefix(http.StatusText(int(exp.amount*100)), ""), " ")[0]))
 		form.Add("amount", http.StatusText(int(exp.amount)))
 		// Let's use a simpler approach
-		err := s.db.CreateExpense(exp.amount, exp.description, exp.category, parseTestDate(exp.date), 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: exp.amount, Description: exp.description, Category: exp.category, Date: parseTestDate(exp.date)})
 		s.Require().NoError(err, "failed to create test expense")
 	}
 
 	// Request statistics for January 2026
 	req := httptest.NewRequest("GET", "/statistics?year=2026&month=1", http.NoBody)
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.Statistics(w, req)
@@ -274,11 +424,39 @@ func (s *ExpenseHandlerTestSuite) TestStatistics_WithExpenses() {
 	s.Contains(body, "Groceries", "should show expense descriptions")
 }
 
+func (s *ExpenseHandlerTestSuite) TestStatistics_IncomeAndNet() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	transactions := []models.Expense{
+		{Kind: models.KindIncome, Amount: 100000, Description: "Salary", Category: "Other", Date: parseTestDate("2026-01-01T09:00:00")},
+		{Kind: models.KindExpense, Amount: 30000, Description: "Rent", Category: "Housing", Date: parseTestDate("2026-01-02T09:00:00")},
+		{Kind: models.KindRefund, Amount: 5000, Description: "Deposit back", Category: "Housing", Date: parseTestDate("2026-01-03T09:00:00")},
+	}
+	for i := range transactions {
+		s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &transactions[i]))
+	}
+
+	req := httptest.NewRequest("GET", "/statistics?year=2026&month=1", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+
+	h.Statistics(w, req)
+	s.Equal(http.StatusOK, w.Result().StatusCode)
+
+	body := w.Body.String()
+	s.Contains(body, ">250<", "spending is net of refunds and excludes income")
+	s.Contains(body, "INCOME")
+	s.Contains(body, ">1000<", "should show income")
+	s.Contains(body, ">750<", "should show net cash flow")
+	s.Contains(body, `kind:"income"`)
+}
+
 func (s *ExpenseHandlerTestSuite) TestStatistics_EmptyMonth() {
 	h := NewHandlers(s.db, s.templateDir, false)
 
 	// Request statistics for a month with no expenses
 	req := httptest.NewRequest("GET", "/statistics?year=2025&month=5", http.NoBody)
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.Statistics(w, req)
@@ -297,6 +475,7 @@ func (s *ExpenseHandlerTestSuite) TestStatistics_MonthNavigation() {
 
 	// Request statistics for November 2025 (a past month)
 	req := httptest.NewRequest("GET", "/statistics?year=2025&month=11", http.NoBody)
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.Statistics(w, req)
@@ -317,21 +496,22 @@ func (s *ExpenseHandlerTestSuite) TestStatistics_CategoryPercentages() {
 	// Create expenses with known percentages
 	// Total will be 100, so percentages are easy to verify
 	testExpenses := []struct {
-		amount   float64
+		amount   models.Money
 		category string
 		date     string
 	}{
-		{50.00, "groceries", "2026-03-15T12:00:00"},  // 50%
-		{30.00, "transport", "2026-03-16T12:00:00"},  // 30%
-		{20.00, "eating out", "2026-03-17T12:00:00"}, // 20%
+		{5000, "groceries", "2026-03-15T12:00:00"},  // 50%
+		{3000, "transport", "2026-03-16T12:00:00"},  // 30%
+		{2000, "eating out", "2026-03-17T12:00:00"}, // 20%
 	}
 
 	for _, exp := range testExpenses {
-		err := s.db.CreateExpense(exp.amount, "Test", exp.category, parseTestDate(exp.date), 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: exp.amount, Description: "Test", Category: exp.category, Date: parseTestDate(exp.date)})
 		s.Require().NoError(err)
 	}
 
 	req := httptest.NewRequest("GET", "/statistics?year=2026&month=3", http.NoBody)
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.Statistics(w, req)
@@ -350,6 +530,7 @@ func (s *ExpenseHandlerTestSuite) TestStatistics_InvalidMonth() {
 
 	// Request with invalid month should default to current month
 	req := httptest.NewRequest("GET", "/statistics?year=2026&month=13", http.NoBody)
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.Statistics(w, req)
@@ -364,11 +545,12 @@ func (s *ExpenseHandlerTestSuite) TestStatistics_TransactionCount() {
 
 	// Create multiple expenses in same category
 	for i := 1; i <= 3; i++ {
-		err := s.db.CreateExpense(10.00, "Coffee", "eating out", parseTestDate("2026-04-15T12:00:00").Add(time.Duration(i)*time.Hour), 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1000, Description: "Coffee", Category: "eating out", Date: parseTestDate("2026-04-15T12:00:00").Add(time.Duration(i) * time.Hour)})
 		s.Require().NoError(err)
 	}
 
 	req := httptest.NewRequest("GET", "/statistics?year=2026&month=4", http.NoBody)
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.Statistics(w, req)
@@ -384,11 +566,11 @@ func (s *ExpenseHandlerTestSuite) TestDeleteExpense() {
 	h := NewHandlers(s.db, s.templateDir, false)
 
 	// Create an expense first
-	err := s.db.CreateExpense(50.00, "To Delete", "food", parseTestDate("2026-01-10T12:00:00"), 1)
+	err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 5000, Description: "To Delete", Category: "food", Date: parseTestDate("2026-01-10T12:00:00")})
 	s.Require().NoError(err)
 
 	// Get the expense ID
-	expenses, err := s.db.ListExpenses(100, 0)
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 100, 0)
 	s.Require().NoError(err)
 	s.Require().Len(expenses, 1)
 	expenseID := expenses[0].ID
@@ -400,6 +582,7 @@ func (s *ExpenseHandlerTestSuite) TestDeleteExpense() {
 	// Use a proper path value approach
 	req = httptest.NewRequest("DELETE", "/expenses/1", http.NoBody)
 	req.SetPathValue("id", "1")
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.DeleteExpense(w, req)
@@ -412,7 +595,7 @@ func (s *ExpenseHandlerTestSuite) TestDeleteExpense() {
 	s.Equal(expectedLoc, resp.Header.Get("HX-Location"))
 
 	// Verify expense is deleted
-	expenses, err = s.db.ListExpenses(100, 0)
+	expenses, err = s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 100, 0)
 	s.Require().NoError(err)
 	s.Empty(expenses, "expected expense to be deleted")
 }
@@ -423,33 +606,31 @@ func (s *ExpenseHandlerTestSuite) TestDeleteExpense_NonExistent() {
 	// Send DELETE request for non-existent expense
 	req := httptest.NewRequest("DELETE", "/expenses/99999", http.NoBody)
 	req.SetPathValue("id", "99999")
+	req = s.addUserContext(req)
 	w := httptest.NewRecorder()
 
 	h.DeleteExpense(w, req)
 
-	// Should still return OK (no-op for non-existent)
+	// Indistinguishable from another household's expense
 	resp := w.Result()
-	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
 }
 
 func (s *ExpenseHandlerTestSuite) TestIsOtherUserLogic() {
-	// Create two users
-	user1, err := s.db.CreateUser("user1", "pass1")
-	s.Require().NoError(err)
-
-	user2, err := s.db.CreateUser("user2", "pass2")
-	s.Require().NoError(err)
+	// Create two users sharing a household
+	user1 := s.addMember("user1")
+	user2 := s.addMember("user2")
 
 	// Create expenses for both users
 	date := parseTestDate("2026-01-15T12:00:00")
-	err = s.db.CreateExpense(50.00, "User1 Expense", "groceries", date, user1.ID)
+	err := s.db.CreateExpense(s.ctx, user1.ID, &models.Expense{Amount: 5000, Description: "User1 Expense", Category: "groceries", Date: date})
 	s.Require().NoError(err)
 
-	err = s.db.CreateExpense(30.00, "User2 Expense", "transport", date.Add(time.Hour), user2.ID)
+	err = s.db.CreateExpense(s.ctx, user2.ID, &models.Expense{Amount: 3000, Description: "User2 Expense", Category: "transport", Date: date.Add(time.Hour)})
 	s.Require().NoError(err)
 
 	// Get all expenses
-	expenses, err := s.db.ListExpenses(100, 0)
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 100, 0)
 	s.Require().NoError(err)
 	s.Require().Len(expenses, 2)
 
@@ -471,6 +652,73 @@ func (s *ExpenseHandlerTestSuite) TestIsOtherUserLogic() {
 	}
 }
 
+func (s *ExpenseHandlerTestSuite) TestOtherHouseholdExpense_NotFound() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	// An expense in a household the current user does not belong to
+	outsider, err := s.db.CreateUser(s.ctx, "outsider", "password789")
+	s.Require().NoError(err)
+	otherHousehold, err := s.db.CreateHousehold(s.ctx, "Elsewhere")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, otherHousehold.ID, outsider.ID))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, outsider.ID, &models.Expense{Amount: 9900, Description: "Private", Category: "Housing", Date: parseTestDate("2026-01-10T12:00:00")}))
+
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(outsider.ID), 1, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	id := strconv.FormatInt(expenses[0].ID, 10)
+
+	// Edit form
+	req := httptest.NewRequest("GET", "/expenses/"+id+"/edit", http.NoBody)
+	req.SetPathValue("id", id)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.EditExpenseForm(w, req)
+	s.Equal(http.StatusNotFound, w.Result().StatusCode)
+
+	// Update
+	form := url.Values{}
+	form.Add("amount", "1.00")
+	form.Add("description", "Hijacked")
+	form.Add("category", "Other")
+	form.Add("date", "2026-01-10T12:00:00")
+	req = httptest.NewRequest("POST", "/expenses/"+id, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", id)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.UpdateExpense(w, req)
+	s.Equal(http.StatusNotFound, w.Result().StatusCode)
+
+	// Delete
+	req = httptest.NewRequest("DELETE", "/expenses/"+id, http.NoBody)
+	req.SetPathValue("id", id)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.DeleteExpense(w, req)
+	s.Equal(http.StatusNotFound, w.Result().StatusCode)
+
+	// The outsider's expense is untouched and invisible to the current user
+	expense, err := s.db.GetExpense(s.ctx, storage.UserScope(outsider.ID), expenses[0].ID)
+	s.Require().NoError(err)
+	s.Equal("Private", expense.Description)
+
+	mine, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 100, 0)
+	s.Require().NoError(err)
+	s.Empty(mine)
+}
+
+func (s *ExpenseHandlerTestSuite) TestStatistics_Unauthorized() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	req := httptest.NewRequest("GET", "/statistics", http.NoBody)
+	w := httptest.NewRecorder()
+
+	h.Statistics(w, req)
+
+	s.Equal(http.StatusUnauthorized, w.Result().StatusCode)
+}
+
 // Helper function to parse test dates
 func parseTestDate(dateStr string) time.Time {
 	t, _ := time.Parse("2006-01-02T15:04:05", dateStr)
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/internal/export"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ExportCSV streams the expenses of a period as a CSV download. It takes the
+// period parameters of the statistics page (view, year, month), optional
+// category and user (a username) filters, and the delimiter, decimal and
+// date_format options of export.ParseCSVOptions.
+func (h *Handlers) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	opts, err := export.ParseCSVOptions(query.Get("delimiter"), query.Get("decimal"), query.Get("date_format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viewMode, year, month := parsePeriod(r, time.Now())
+	filter, filename := export.PeriodFilter(viewMode, year, month)
+	filter.Category = query.Get("category")
+	if username := query.Get("user"); username != "" {
+		member, err := h.householdMember(ctx, user.ID, username)
+		if err != nil {
+			log.Printf("householdMember error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if member == nil {
+			http.Error(w, "Unknown user", http.StatusBadRequest)
+			return
+		}
+		filter.UserID = member.ID
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+	if err := export.WriteCSV(ctx, w, h.db, storage.UserScope(user.ID), filter, opts); err != nil {
+		// The response has started, so the download is cut short
+		log.Printf("ExportCSV error: %v", err)
+	}
+}
+
+// householdMember returns the member called username of one of the
+// households userID belongs to, or nil if there is none. Users outside them
+// are not looked up, so the filter does not tell whether an account exists.
+func (h *Handlers) householdMember(ctx context.Context, userID int64, username string) (*models.User, error) {
+	households, err := h.db.ListUserHouseholds(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, household := range households {
+		members, err := h.db.ListHouseholdMembers(ctx, household.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range members {
+			if members[i].Username == username {
+				return &members[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ExportJournal downloads expenses as a ledger, hledger or beancount journal,
+// picked by the format parameter. The from and to parameters (YYYY-MM-DD,
+// both included) select a date range; without them the period parameters of
+// the statistics page apply. Categories are booked to the accounts set on
+// the category settings page and funding sets the account expenses are paid
+// from. Amounts are in the household's base currency.
+func (h *Handlers) ExportJournal(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	format, err := export.ParseJournalFormat(query.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var filter storage.ExpenseFilter
+	var filename string
+	if query.Has("from") || query.Has("to") {
+		if filter, filename, err = export.RangeFilter(query.Get("from"), query.Get("to")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		viewMode, year, month := parsePeriod(r, time.Now())
+		filter, filename = export.PeriodFilter(viewMode, year, month)
+	}
+
+	_, categories, err := h.householdCategories(ctx, user.ID, true)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("ListCategories error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	opts := export.JournalOptions{
+		Format:         format,
+		FundingAccount: strings.TrimSpace(query.Get("funding")),
+		Currency:       h.baseCurrency(r),
+		Categories:     categories,
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, filename, format.Extension()))
+	if err := export.WriteJournal(ctx, w, h.db, storage.UserScope(user.ID), filter, opts); err != nil {
+		// The response has started, so the download is cut short
+		log.Printf("ExportJournal error: %v", err)
+	}
+}
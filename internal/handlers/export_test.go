@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"expense-tracker/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+func (s *ExpenseHandlerTestSuite) TestExportCSV() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	partner := s.addMember("partner")
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1250, Description: "Bread", Category: "Groceries", Date: parseTestDate("2026-01-10T12:00:00")}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, partner.ID, &models.Expense{Amount: 4000, Description: "Dinner", Category: "Eating Out", Date: parseTestDate("2026-01-12T20:00:00")}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 300, Description: "Bus", Category: "Transport", Date: parseTestDate("2026-02-01T08:00:00")}))
+
+	req := httptest.NewRequest("GET", "/expenses/export.csv?view=month&year=2026&month=1&delimiter=%3B&decimal=%2C&date_format=DD.MM.YYYY", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.ExportCSV(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal("text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	s.Equal(`attachment; filename="expenses-2026-01.csv"`, w.Header().Get("Content-Disposition"))
+	s.Equal("Date;Kind;Category;Description;Amount;User\n"+
+		"10.01.2026;expense;Groceries;Bread;12,50;testuser\n"+
+		"12.01.2026;expense;Eating Out;Dinner;40,00;partner\n", w.Body.String())
+
+	req = httptest.NewRequest("GET", "/expenses/export.csv?view=year&year=2026&user=testuser", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.ExportCSV(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal(`attachment; filename="expenses-2026.csv"`, w.Header().Get("Content-Disposition"))
+	s.Equal("Date,Kind,Category,Description,Amount,User\n"+
+		"2026-01-10,expense,Groceries,Bread,12.50,testuser\n"+
+		"2026-02-01,expense,Transport,Bus,3.00,testuser\n", w.Body.String())
+
+	for _, query := range []string{"delimiter=x", "user=nobody", "date_format=YYYY-MM-DDTHH"} {
+		req = httptest.NewRequest("GET", "/expenses/export.csv?"+query, http.NoBody)
+		req = s.addUserContext(req)
+		w = httptest.NewRecorder()
+		h.ExportCSV(w, req)
+		s.Equal(http.StatusBadRequest, w.Code, query)
+	}
+
+	// Users of other households are refused like unknown ones
+	_, err := s.db.CreateUser(s.ctx, "outsider", "hash")
+	s.Require().NoError(err)
+	var bodies []string
+	for _, username := range []string{"outsider", "nobody"} {
+		req = httptest.NewRequest("GET", "/expenses/export.csv?user="+username, http.NoBody)
+		req = s.addUserContext(req)
+		w = httptest.NewRecorder()
+		h.ExportCSV(w, req)
+		s.Equal(http.StatusBadRequest, w.Code, username)
+		bodies = append(bodies, w.Body.String())
+	}
+	s.Equal(bodies[0], bodies[1])
+}
+
+func (s *ExpenseHandlerTestSuite) TestExportJournal() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1250, Description: "Bread", Category: "Groceries", Date: parseTestDate("2026-01-10T12:00:00")}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 300, Description: "Bus", Category: "Transport", Date: parseTestDate("2026-02-01T08:00:00")}))
+
+	// Groceries are booked to the account set on the settings page
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	form := url.Values{"name": {"Groceries"}, "icon": {"🛒"}, "color": {"#60a5fa"}, "account": {"Expenses:Food:Groceries"}}
+	resp := s.postCategoryForm("/settings/categories/x", form, h.UpdateCategory, categories[0].ID)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	req := httptest.NewRequest("GET", "/expenses/journal?format=beancount&from=2026-01-01&to=2026-01-31&funding=Liabilities:Visa", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.ExportJournal(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal(`attachment; filename="expenses-from-2026-01-01-to-2026-01-31.beancount"`, w.Header().Get("Content-Disposition"))
+	s.Contains(w.Body.String(), "2026-01-10 * \"Bread\"\n  user: \"testuser\"\n  Expenses:Food:Groceries  12.50 EUR\n  Liabilities:Visa  -12.50 EUR\n")
+	s.NotContains(w.Body.String(), "Bus")
+
+	req = httptest.NewRequest("GET", "/expenses/journal?format=ledger&view=month&year=2026&month=2", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.ExportJournal(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal(`attachment; filename="expenses-2026-02.ledger"`, w.Header().Get("Content-Disposition"))
+	s.Equal("2026/02/01 Bus\n    ; user: testuser\n    Expenses:Transport  3.00 EUR\n    Assets:Checking\n\n", w.Body.String())
+
+	for _, query := range []string{"format=qif", "format=ledger&from=yesterday", "format=ledger&from=2026-02-01&to=2026-01-01"} {
+		req = httptest.NewRequest("GET", "/expenses/journal?"+query, http.NoBody)
+		req = s.addUserContext(req)
+		w = httptest.NewRecorder()
+		h.ExportJournal(w, req)
+		s.Equal(http.StatusBadRequest, w.Code, query)
+	}
+
+	form.Set("account", "Groceries")
+	resp = s.postCategoryForm("/settings/categories/x", form, h.UpdateCategory, categories[0].ID)
+	s.Equal(http.StatusBadRequest, resp.StatusCode, "accounts need at least two parts")
+}
+
+func (s *ExpenseHandlerTestSuite) TestExportJournal_BaseCurrency() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	s.Require().NoError(s.db.SetHouseholdCurrency(s.ctx, s.household.ID, "GBP"))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 300, Description: "Bus", Category: "Transport", Date: parseTestDate("2026-02-01T08:00:00")}))
+
+	// The amounts are in the base currency, so a currency parameter does not relabel them
+	req := httptest.NewRequest("GET", "/expenses/journal?format=ledger&from=2026-02-01&to=2026-02-28&currency=USD", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.ExportJournal(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Equal("2026/02/01 Bus\n    ; user: testuser\n    Expenses:Transport  3.00 GBP\n    Assets:Checking\n\n", w.Body.String())
+}
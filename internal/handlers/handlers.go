@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"expense-tracker/internal/models"
 	"expense-tracker/internal/storage"
 	"time"
@@ -12,6 +13,9 @@ type contextKey string
 const (
 	// UserContextKey is the context key for the authenticated user.
 	UserContextKey contextKey = "user"
+	// APITokenContextKey is the context key for the API token a request was
+	// authenticated with. It is absent for requests authenticated by session.
+	APITokenContextKey contextKey = "api_token"
 	// SessionCookieName is the name of the session cookie.
 	SessionCookieName = "session"
 	// SessionDuration is how long sessions last (30 days).
@@ -20,70 +24,84 @@ const (
 
 // Handlers holds dependencies for HTTP handlers.
 type Handlers struct {
-	db           *storage.DB
+	db           storage.Store
 	templateDir  string
 	secureCookie bool
+	admins       map[string]bool                       // Usernames allowed to use the admin endpoints
+	backup       func(context.Context) (string, error) // Writes a database backup, nil when disabled
 }
 
 // NewHandlers creates a new Handlers instance.
-func NewHandlers(db *storage.DB, templateDir string, secureCookie bool) *Handlers {
+func NewHandlers(db storage.Store, templateDir string, secureCookie bool) *Handlers {
 	return &Handlers{db: db, templateDir: templateDir, secureCookie: secureCookie}
 }
 
-// CategoryDef defines the properties of a category.
-type CategoryDef struct {
-	Name  string
+// CategoryStyle defines the visual style for a category.
+type CategoryStyle struct {
 	Icon  string
 	Color string
 }
 
-var categories = []CategoryDef{
-	{"Groceries", "🛒", "#60a5fa"},
-	{"Eating Out", "🍴", "#60a5fa"},
-	{"Transport", "🚌", "#a78bfa"},
-	{"Housing", "🏠", "#818cf8"},
-	{"Utilities", "💡", "#fbbf24"},
-	{"Sport", "🏋️‍♂️", "#fbbf24"},
-	{"Health", "🚑", "#fbbf24"},
-	{"Entertainment", "🎮", "#f472b6"},
-	{"Travel", "✈️", "#f472b6"},
-	{"Gifts", "🎁", "#fb7185"},
-	{"Other", "📦", "#94a3b8"},
+// defaultCategoryStyle is used for categories that are not defined in the household.
+var defaultCategoryStyle = CategoryStyle{Icon: "📦", Color: "#94a3b8"}
+
+// categoryStyles maps category names to their style.
+type categoryStyles map[string]CategoryStyle
+
+func newCategoryStyles(categories []models.Category) categoryStyles {
+	styles := make(categoryStyles, len(categories))
+	for _, c := range categories {
+		styles[c.Name] = CategoryStyle{Icon: c.Icon, Color: c.Color}
+	}
+	return styles
 }
 
-// CategoryStyle defines the visual style for a category.
-type CategoryStyle struct {
-	Icon  string
-	Color string
+func (cs categoryStyles) get(category string) CategoryStyle {
+	if style, ok := cs[category]; ok {
+		return style
+	}
+	return defaultCategoryStyle
 }
 
 // ExpenseItem represents an expense in the list view.
 type ExpenseItem struct {
-	ID            int64
-	Amount        float64
-	Description   string
-	Category      string
-	Time          string
-	DateTime      string // Full datetime for edit modal (2006-01-02T15:04:05)
-	CategoryStyle CategoryStyle
-	IsIncome      bool
-	IsOtherUser   bool // True if this expense was created by a different user
+	ID             int64
+	Kind           models.ExpenseKind
+	Amount         models.Money // In the household's base currency
+	Currency       string
+	OriginalAmount models.Money // As paid, in Currency
+	Description    string
+	Category       string
+	Time           string
+	DateTime       string // Full datetime for edit modal (2006-01-02T15:04:05)
+	CategoryStyle  CategoryStyle
+	IsIncome       bool
+	IsRefund       bool
+	IsOtherUser    bool            // True if this expense was created by a different user
+	AccountID      int64           // Zero when not linked to an account
+	Splits         []models.Split  // Parts of a split expense, in Currency
+	Sharing        *models.Sharing // Members a shared expense is divided between
+	Tags           []string
 }
 
-// ExpenseGroup groups expenses by date.
+// ExpenseGroup groups expenses by date. Total is the day's spending.
 type ExpenseGroup struct {
 	Title string
 	Date  string
-	Total float64
+	Total models.Money
 	Items []ExpenseItem
 }
 
 // ListViewModel is the data passed to the list view template.
 type ListViewModel struct {
-	Total      float64
+	Total      models.Money // Spending this month
+	Income     models.Money // Income this month
+	Net        models.Money // Income minus spending this month
 	Groups     []ExpenseGroup
-	NextOffset int  // Offset for loading more items (0 means no more)
-	HasMore    bool // Whether there are more items to load
+	NextOffset int    // Offset for loading more items (0 means no more)
+	HasMore    bool   // Whether there are more items to load
+	AccountID  int64  // Account the list is limited to, zero for all
+	Tag        string // Tag the list is limited to, empty for all
 }
 
 // FormViewModel is the data passed to the create/edit form template.
@@ -91,7 +109,142 @@ type FormViewModel struct {
 	Expense       *models.Expense
 	IsEdit        bool
 	FormattedDate string
-	Categories    []CategoryDef
+	Categories    []models.Category
+}
+
+// CategorySettingsItem is a category row on the settings page.
+type CategorySettingsItem struct {
+	models.Category
+	Parent      int64 // ID of the parent category, 0 for top-level ones
+	HasChildren bool
+}
+
+// CategoriesViewModel is the data passed to the category settings template.
+type CategoriesViewModel struct {
+	Categories []CategorySettingsItem // Including archived ones
+	Parents    []models.Category      // Categories that can have subcategories
+}
+
+// BudgetItem is a category row on the budgets page.
+type BudgetItem struct {
+	models.Category
+	CategoryStyle CategoryStyle
+	Child         bool // The category is a subcategory
+	HasBudget     bool
+	Amount        models.Money // Monthly budget in effect
+	Rollover      bool
+	Carried       models.Money // Rolled over from earlier months
+	Spent         models.Money
+	Remaining     models.Money
+}
+
+// BudgetsViewModel is the data passed to the budgets template.
+type BudgetsViewModel struct {
+	Year      int
+	Month     int
+	MonthName string
+	PrevYear  int
+	PrevMonth int
+	NextYear  int
+	NextMonth int
+	Items     []BudgetItem
+}
+
+// RecurringItem is a recurring template on the recurring page.
+type RecurringItem struct {
+	models.Recurring
+	Rule          string // Human-readable schedule, e.g. "Monthly on day 5"
+	CategoryStyle CategoryStyle
+}
+
+// UpcomingItem is a future occurrence of a recurring template.
+type UpcomingItem struct {
+	Date          string
+	Kind          models.ExpenseKind
+	Amount        models.Money
+	Description   string
+	CategoryStyle CategoryStyle
+}
+
+// RecurringViewModel is the data passed to the recurring template.
+type RecurringViewModel struct {
+	Upcoming   []UpcomingItem
+	Templates  []RecurringItem
+	Categories []models.Category // Offered in the new template form
+	Today      string            // Default start date of the new template form
+}
+
+// TokenItem is an API token in the token list.
+type TokenItem struct {
+	models.APIToken
+	Created  string
+	LastUsed string
+}
+
+// TokensViewModel is the data passed to the API tokens template.
+type TokensViewModel struct {
+	Tokens   []TokenItem
+	NewToken *APINewToken // Set right after a token is created
+}
+
+// CurrencySettingsViewModel is the data passed to the currencies template.
+type CurrencySettingsViewModel struct {
+	Currency   string   // Base currency of the household
+	Currencies []string // Offered as base currency
+	Rates      []models.ExchangeRate
+	Message    string // Outcome of the last rate import
+}
+
+// AccountsViewModel is the data passed to the accounts template.
+type AccountsViewModel struct {
+	Accounts []storage.AccountBalance
+	Types    []models.AccountType
+	Today    string // Default date of a new transfer (2006-01-02)
+}
+
+// AccountViewModel is the data passed to the account template.
+type AccountViewModel struct {
+	Account models.Account
+	Balance models.Money
+	Entries []storage.LedgerEntry // Newest first
+}
+
+// BalancesViewModel is the data passed to the balances template.
+type BalancesViewModel struct {
+	Balances    []storage.MemberBalance
+	Plan        []storage.SettlementRow // Payments that settle all balances
+	Settlements []storage.SettlementRow // Newest first
+	UserID      int64                   // The member viewing the page
+	Today       string                  // Default date of a new settlement (2006-01-02)
+}
+
+// ImportViewModel is the data passed to the import template.
+type ImportViewModel struct {
+	Profiles    []models.ImportProfile
+	Profile     models.ImportProfile // Prefills the column mapping
+	Categories  []models.Category    // Offered as default category
+	DateFormats []string
+	Message     string // Outcome of the last import
+}
+
+// ImportRowItem is a statement row in the import preview.
+type ImportRowItem struct {
+	models.Expense
+	Index         int // Position in the preview data; valid rows only
+	Line          int
+	Date          string
+	CategoryStyle CategoryStyle
+	Duplicate     bool   // Already recorded, or repeated in the statement
+	Error         string // Why the row cannot be imported
+}
+
+// ImportPreviewViewModel is the data passed to the import preview template.
+type ImportPreviewViewModel struct {
+	Rows       []ImportRowItem
+	Data       string // JSON of the valid transactions, posted back to commit them
+	New        int
+	Duplicates int
+	Invalid    int
 }
 
 // LoginViewModel holds data for the login page.
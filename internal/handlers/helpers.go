@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
 	"html/template"
 	"log"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,45 +23,357 @@ func GetUserFromContext(r *http.Request) *models.User {
 	return nil
 }
 
-func getCategoryStyle(category string) CategoryStyle {
-	for _, c := range categories {
-		if c.Name == category {
-			return CategoryStyle{Icon: c.Icon, Color: c.Color}
+// householdCategories returns the user's default household and its categories.
+func (h *Handlers) householdCategories(ctx context.Context, userID int64, includeArchived bool) (int64, []models.Category, error) {
+	householdID, err := h.db.DefaultHouseholdID(ctx, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	categories, err := h.db.ListCategories(ctx, householdID, includeArchived)
+	return householdID, categories, err
+}
+
+// categoryStyles returns the styles of all categories of the user's default
+// household, archived ones included. Errors are logged and yield no styles.
+func (h *Handlers) categoryStyles(ctx context.Context, userID int64) categoryStyles {
+	_, categories, err := h.householdCategories(ctx, userID, true)
+	if err != nil && !errors.Is(err, storage.ErrNoHousehold) {
+		log.Printf("ListCategories error: %v", err)
+	}
+	return newCategoryStyles(categories)
+}
+
+// pickerCategories returns the active categories offered in the expense form.
+func (h *Handlers) pickerCategories(r *http.Request) []models.Category {
+	categories := []models.Category{}
+	user := GetUserFromContext(r)
+	if user == nil {
+		return categories
+	}
+	_, list, err := h.householdCategories(r.Context(), user.ID, false)
+	if err != nil && !errors.Is(err, storage.ErrNoHousehold) {
+		log.Printf("ListCategories error: %v", err)
+	}
+	return append(categories, list...)
+}
+
+// pickerAccounts returns the active accounts offered in the expense form.
+func (h *Handlers) pickerAccounts(r *http.Request) []models.Account {
+	user := GetUserFromContext(r)
+	if user == nil {
+		return nil
+	}
+	householdID, err := h.db.DefaultHouseholdID(r.Context(), user.ID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNoHousehold) {
+			log.Printf("DefaultHouseholdID error: %v", err)
+		}
+		return nil
+	}
+	accounts, err := h.db.ListAccounts(r.Context(), householdID, false)
+	if err != nil {
+		log.Printf("ListAccounts error: %v", err)
+	}
+	return accounts
+}
+
+// pickerMembers returns the members of the user's household offered in the
+// sharing panel of the expense form, or nil when there is no one to share
+// with.
+func (h *Handlers) pickerMembers(r *http.Request) []models.User {
+	user := GetUserFromContext(r)
+	if user == nil {
+		return nil
+	}
+	householdID, err := h.db.DefaultHouseholdID(r.Context(), user.ID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNoHousehold) {
+			log.Printf("DefaultHouseholdID error: %v", err)
+		}
+		return nil
+	}
+	members, err := h.db.ListHouseholdMembers(r.Context(), householdID)
+	if err != nil {
+		log.Printf("ListHouseholdMembers error: %v", err)
+	}
+	if len(members) < 2 {
+		return nil
+	}
+	return members
+}
+
+// pickerTags returns the tags of the user's household suggested in the
+// expense form, the most used first.
+func (h *Handlers) pickerTags(r *http.Request) []string {
+	user := GetUserFromContext(r)
+	if user == nil {
+		return nil
+	}
+	householdID, err := h.db.DefaultHouseholdID(r.Context(), user.ID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNoHousehold) {
+			log.Printf("DefaultHouseholdID error: %v", err)
 		}
+		return nil
+	}
+	tags, err := h.db.ListTags(r.Context(), householdID)
+	if err != nil {
+		log.Printf("ListTags error: %v", err)
 	}
-	return CategoryStyle{Icon: "📦", Color: "#94a3b8"}
+	return tags
 }
 
-func parseForm(r *http.Request) (amount float64, desc, category string, date time.Time, err error) {
+// tagFilter returns the tag the list is filtered by from the tag query
+// parameter, or "" when there is none or it is not a valid tag.
+func tagFilter(r *http.Request) string {
+	tag, err := models.NormalizeTag(r.URL.Query().Get("tag"))
+	if err != nil {
+		return ""
+	}
+	return tag
+}
+
+// baseCurrency returns the base currency of the user's default household.
+// Errors are logged and yield the default currency.
+func (h *Handlers) baseCurrency(r *http.Request) string {
+	user := GetUserFromContext(r)
+	if user == nil {
+		return models.DefaultCurrency
+	}
+	householdID, err := h.db.DefaultHouseholdID(r.Context(), user.ID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNoHousehold) {
+			log.Printf("DefaultHouseholdID error: %v", err)
+		}
+		return models.DefaultCurrency
+	}
+	household, err := h.db.GetHousehold(r.Context(), householdID)
+	if err != nil {
+		log.Printf("GetHousehold error: %v", err)
+		return models.DefaultCurrency
+	}
+	return household.Currency
+}
+
+// parseForm reads a transaction from the create/edit form. The amount is in
+// the form's currency; storage converts it to the base currency. The ID, user
+// and household are left for the caller to fill in; storage checks that the
+// account belongs to the household and the shares to its members. Tags come
+// from the repeated tags field and from #words in the description, which are
+// taken out of it.
+func parseForm(r *http.Request) (*models.Expense, error) {
 	if err := r.ParseForm(); err != nil {
-		return 0, "", "", time.Time{}, err
+		return nil, err
+	}
+	amount, err := models.ParseMoney(r.FormValue("amount"))
+	if err != nil {
+		return nil, err
+	}
+	if amount < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+	kind, err := models.ParseExpenseKind(r.FormValue("kind"))
+	if err != nil {
+		return nil, err
+	}
+	// An empty currency is the household's base currency
+	currency := r.FormValue("currency")
+	if currency != "" {
+		if currency, err = models.ParseCurrency(currency); err != nil {
+			return nil, err
+		}
+	}
+	var accountID *int64
+	if s := r.FormValue("account"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid account")
+		}
+		accountID = &id
+	}
+	splits, err := parseSplits(r)
+	if err != nil {
+		return nil, err
+	}
+	sharing, err := parseSharing(r)
+	if err != nil {
+		return nil, err
+	}
+	category := r.FormValue("category")
+	if len(splits) > 0 {
+		category = splits[0].Category
+	}
+	tags, desc := models.ParseTags(r.FormValue("description"))
+	for _, s := range r.Form["tags"] {
+		tag, err := models.NormalizeTag(s)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
 	}
-	amount, _ = strconv.ParseFloat(r.FormValue("amount"), 64)
-	category = r.FormValue("category")
-	desc = r.FormValue("description")
 	if desc == "" {
 		desc = category
 	}
 	dateStr := r.FormValue("date")
 	if dateStr == "" {
-		return 0, "", "", time.Time{}, errors.New("date is required")
+		return nil, errors.New("date is required")
 	}
-	date, err = time.Parse("2006-01-02T15:04:05", dateStr)
+	date, err := time.Parse("2006-01-02T15:04:05", dateStr)
 	if err != nil {
 		// Fallback to minutes if seconds are missing
 		date, err = time.Parse("2006-01-02T15:04", dateStr)
 		if err != nil {
-			return 0, "", "", time.Time{}, err
+			return nil, err
 		}
 	}
-	return amount, desc, category, date, nil
+	return &models.Expense{
+		Kind:           kind,
+		Amount:         amount,
+		Currency:       currency,
+		OriginalAmount: amount,
+		Description:    desc,
+		Category:       category,
+		Date:           date,
+		AccountID:      accountID,
+		Splits:         splits,
+		Sharing:        sharing,
+		Tags:           tags,
+	}, nil
+}
+
+// parseSplits reads the parts of a split expense from the repeated
+// split_category and split_amount fields. Rows left empty are skipped.
+func parseSplits(r *http.Request) ([]models.Split, error) {
+	categories, amounts := r.Form["split_category"], r.Form["split_amount"]
+	if len(categories) != len(amounts) {
+		return nil, errors.New("every part needs a category and an amount")
+	}
+	var splits []models.Split
+	for i, category := range categories {
+		if category == "" && strings.TrimSpace(amounts[i]) == "" {
+			continue
+		}
+		amount, err := models.ParseMoney(amounts[i])
+		if err != nil {
+			return nil, err
+		}
+		splits = append(splits, models.Split{Category: category, Amount: amount})
+	}
+	return splits, nil
+}
+
+// parseSharing reads the sharing of an expense from the share_method field,
+// the repeated share_user field of the members sharing it and, unless it is
+// shared equally, their share_value_<user ID> fields. It returns nil when no
+// member is chosen.
+func parseSharing(r *http.Request) (*models.Sharing, error) {
+	users := r.Form["share_user"]
+	if len(users) == 0 {
+		return nil, nil
+	}
+	method, err := models.ParseShareMethod(r.FormValue("share_method"))
+	if err != nil {
+		return nil, err
+	}
+	sharing := &models.Sharing{Method: method}
+	for _, s := range users {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid member")
+		}
+		share := models.Share{UserID: id}
+		value := strings.TrimSpace(r.FormValue("share_value_" + s))
+		switch method {
+		case models.SharePercent:
+			if share.Percent, err = strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64); err != nil {
+				return nil, errors.New("invalid percentage")
+			}
+		case models.ShareExact:
+			if share.Amount, err = models.ParseMoney(value); err != nil {
+				return nil, err
+			}
+		}
+		sharing.Shares = append(sharing.Shares, share)
+	}
+	return sharing, nil
+}
+
+// newExpenseItem converts a stored transaction to its list representation.
+// timeLayout controls how the time column is formatted.
+func newExpenseItem(e models.Expense, timeLayout string, styles categoryStyles) ExpenseItem {
+	var accountID int64
+	if e.AccountID != nil {
+		accountID = *e.AccountID
+	}
+	return ExpenseItem{
+		ID:             e.ID,
+		Kind:           e.Kind,
+		Amount:         e.Amount,
+		Currency:       e.Currency,
+		OriginalAmount: e.OriginalAmount,
+		Description:    e.Description,
+		Category:       e.Category,
+		Time:           e.Date.Format(timeLayout),
+		DateTime:       e.Date.Format("2006-01-02T15:04:05"),
+		CategoryStyle:  styles.get(e.Category),
+		IsIncome:       e.Kind == models.KindIncome,
+		IsRefund:       e.Kind == models.KindRefund,
+		AccountID:      accountID,
+		Splits:         e.Splits,
+		Sharing:        e.Sharing,
+		Tags:           e.Tags,
+	}
 }
 
 func (h *Handlers) render(w http.ResponseWriter, r *http.Request, viewName string, data any) {
+	var base string
+	baseCurrency := func() string {
+		if base == "" {
+			base = h.baseCurrency(r)
+		}
+		return base
+	}
+	var accounts []models.Account
+	accountsLoaded := false
+	var members []models.User
+	membersLoaded := false
+	pickerAccounts := func() []models.Account {
+		if !accountsLoaded {
+			accounts, accountsLoaded = h.pickerAccounts(r), true
+		}
+		return accounts
+	}
+	pickerMembers := func() []models.User {
+		if !membersLoaded {
+			members, membersLoaded = h.pickerMembers(r), true
+		}
+		return members
+	}
+	funcs := template.FuncMap{
+		// categories feeds the category picker of the expense form
+		"categories": func() []models.Category { return h.pickerCategories(r) },
+		// baseCurrency is the code of the household's base currency and
+		// currency the symbol amounts in it are shown with
+		"baseCurrency": baseCurrency,
+		"currency":     func() string { return models.CurrencySymbol(baseCurrency()) },
+		// currencies feeds the currency picker of the expense form
+		"currencies": func() []string { return currencyChoices(baseCurrency()) },
+		// accounts feeds the account pickers and currencySymbol shows
+		// amounts in an account's own currency
+		"accounts":       pickerAccounts,
+		"currencySymbol": models.CurrencySymbol,
+		// members feeds the sharing panel of the expense form
+		"members": pickerMembers,
+		// tags feeds the tag suggestions of the expense form
+		"tags": func() []string { return h.pickerTags(r) },
+	}
+
 	// For fragment templates (partials), render them directly
 	if viewName == "expense_groups.html" {
 		filePath := filepath.Join(h.templateDir, viewName)
-		tmpl, err := template.ParseFiles(filePath)
+		tmpl, err := template.New(viewName).Funcs(funcs).ParseFiles(filePath)
 		if err != nil {
 			log.Printf("Template parse error for %s: %v", filePath, err)
 			http.Error(w, "Template error", http.StatusInternalServerError)
@@ -82,7 +397,7 @@ func (h *Handlers) render(w http.ResponseWriter, r *http.Request, viewName strin
 		files = append(files, filepath.Join(h.templateDir, "expense_groups.html"))
 	}
 
-	tmpl, err := template.ParseFiles(files...)
+	tmpl, err := template.New(filepath.Base(files[0])).Funcs(funcs).ParseFiles(files...)
 	if err != nil {
 		log.Printf("Template error: %v", err)
 		http.Error(w, "Template error", http.StatusInternalServerError)
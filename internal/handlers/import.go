@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker/internal/importer"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// importMaxFileSize limits the size of uploaded bank statements.
+const importMaxFileSize = 10 << 20
+
+// importDateFormats are the date formats offered for CSV statements.
+var importDateFormats = []string{"YYYY-MM-DD", "DD.MM.YYYY", "DD/MM/YYYY", "MM/DD/YYYY", "DD-MM-YYYY", "DD.MM.YY", "YYYYMMDD"}
+
+// Import renders the page for uploading a bank statement. The profile query
+// parameter prefills the column mapping from a saved profile.
+func (h *Handlers) Import(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profileID, _ := strconv.ParseInt(r.URL.Query().Get("profile"), 10, 64)
+	h.renderImport(w, r, user.ID, profileID, "")
+}
+
+// PreviewImport parses an uploaded statement and renders the transactions
+// found for review. The format field selects the parser: "csv", the default,
+// reads the statement with the posted column mapping; "ofx", "camt" and
+// "mt940" read OFX and QFX, camt.053 and MT940 files. Transactions that are already stored are flagged and left
+// unselected. With save_as, a CSV mapping is saved as a profile under that
+// name.
+func (h *Handlers) PreviewImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxFileSize)
+	if err := r.ParseMultipartForm(importMaxFileSize); err != nil {
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	fallback := r.FormValue("default_category")
+	if fallback == "" {
+		http.Error(w, "default category is required", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("statement")
+	if err != nil {
+		http.Error(w, "Choose a statement to import", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	var rows []importer.Row
+	var profile *models.ImportProfile
+	switch format := r.FormValue("format"); format {
+	case "", "csv":
+		if profile, err = parseImportProfileForm(r); err == nil {
+			rows, err = importer.ParseCSV(file, *profile)
+		}
+	default:
+		rows, err = importer.Parse(format, file)
+	}
+	if err != nil {
+		http.Error(w, "Could not read statement: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	householdID, categories, err := h.householdCategories(ctx, user.ID, false)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("ListCategories error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if name := strings.TrimSpace(r.FormValue("save_as")); profile != nil && name != "" {
+		profile.Name = name
+		profile.HouseholdID = householdID
+		if err := h.db.SaveImportProfile(ctx, profile); err != nil {
+			log.Printf("SaveImportProfile error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	importer.Categorize(rows, categories, fallback)
+	h.renderImportPreview(w, r, householdID, rows, categories)
+}
+
+// CommitImport stores the transactions selected on the preview in a single
+// transaction and reports how many were added.
+func (h *Handlers) CommitImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	expenses, err := parseImportSelection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imported, err := h.db.ImportExpenses(ctx, user.ID, expenses)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, storage.ErrNoExchangeRate) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("ImportExpenses error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	message := fmt.Sprintf("Imported %d transaction(s).", imported)
+	if skipped := len(expenses) - imported; skipped > 0 {
+		message += fmt.Sprintf(" %d already recorded were skipped.", skipped)
+	}
+	h.renderImport(w, r, user.ID, 0, message)
+}
+
+// DeleteImportProfile removes a saved column mapping.
+func (h *Handlers) DeleteImportProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.DeleteImportProfile(ctx, householdID, id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Import profile not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("DeleteImportProfile error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/import", "target":"#content"}`)
+}
+
+func (h *Handlers) renderImport(w http.ResponseWriter, r *http.Request, userID, profileID int64, message string) {
+	ctx := r.Context()
+	householdID, categories, err := h.householdCategories(ctx, userID, false)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("ListCategories error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	profiles, err := h.db.ListImportProfiles(ctx, householdID)
+	if err != nil {
+		log.Printf("ListImportProfiles error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	vm := ImportViewModel{
+		Profiles:    profiles,
+		Profile:     defaultImportProfile(categories),
+		Categories:  categories,
+		DateFormats: importDateFormats,
+		Message:     message,
+	}
+	for _, p := range profiles {
+		if p.ID == profileID {
+			vm.Profile = p
+		}
+	}
+	h.render(w, r, "import.html", vm)
+}
+
+// renderImportPreview lists parsed statement rows for review. Valid rows are
+// flagged when they are already stored or repeat an earlier row of the
+// statement.
+func (h *Handlers) renderImportPreview(w http.ResponseWriter, r *http.Request, householdID int64, rows []importer.Row, categories []models.Category) {
+	ctx := r.Context()
+	var valid []models.Expense
+	for _, row := range rows {
+		if row.Valid() {
+			valid = append(valid, row.Expense)
+		}
+	}
+	duplicates, err := h.db.FindDuplicates(ctx, householdID, valid)
+	if errors.Is(err, storage.ErrNoExchangeRate) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("FindDuplicates error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	data, err := json.Marshal(valid)
+	if err != nil {
+		log.Printf("Import preview encode error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	styles := newCategoryStyles(categories)
+	vm := ImportPreviewViewModel{Data: string(data)}
+	type key struct {
+		date        int64
+		amount      models.Money
+		description string
+	}
+	seen := make(map[key]bool)
+	seenRefs := make(map[string]bool)
+	index := 0
+	for _, row := range rows {
+		item := ImportRowItem{Line: row.Line, Error: row.Error}
+		if !row.Valid() {
+			vm.Invalid++
+			vm.Rows = append(vm.Rows, item)
+			continue
+		}
+		e := row.Expense
+		k := key{e.Date.Unix(), e.Amount, e.Description}
+		item.Index = index
+		item.Expense = e
+		item.Date = e.Date.Format("Jan 2, 2006")
+		item.CategoryStyle = styles.get(e.Category)
+		item.Duplicate = duplicates[index] || seen[k] || e.ImportRef != "" && seenRefs[e.ImportRef]
+		seen[k] = true
+		seenRefs[e.ImportRef] = true
+		if item.Duplicate {
+			vm.Duplicates++
+		} else {
+			vm.New++
+		}
+		vm.Rows = append(vm.Rows, item)
+		index++
+	}
+	h.render(w, r, "import_preview.html", vm)
+}
+
+func defaultImportProfile(categories []models.Category) models.ImportProfile {
+	p := models.ImportProfile{
+		Delimiter:         ",",
+		SkipRows:          1,
+		DateColumn:        1,
+		DescriptionColumn: 2,
+		AmountColumn:      3,
+		DateFormat:        "YYYY-MM-DD",
+		DecimalSeparator:  ".",
+		SignConvention:    models.SignNegativeOut,
+	}
+	for _, c := range categories {
+		if c.Name == "Other" {
+			p.DefaultCategory = c.Name
+		}
+	}
+	if p.DefaultCategory == "" && len(categories) > 0 {
+		p.DefaultCategory = categories[0].Name
+	}
+	return p
+}
+
+func parseImportProfileForm(r *http.Request) (*models.ImportProfile, error) {
+	p := &models.ImportProfile{
+		Delimiter:        r.FormValue("delimiter"),
+		DateFormat:       r.FormValue("date_format"),
+		DecimalSeparator: r.FormValue("decimal_separator"),
+		SignConvention:   models.SignConvention(r.FormValue("sign_convention")),
+		DefaultCategory:  r.FormValue("default_category"),
+	}
+	numbers := []struct {
+		field string
+		dest  *int
+	}{
+		{"skip_rows", &p.SkipRows},
+		{"date_column", &p.DateColumn},
+		{"amount_column", &p.AmountColumn},
+		{"credit_column", &p.CreditColumn},
+		{"description_column", &p.DescriptionColumn},
+		{"category_column", &p.CategoryColumn},
+	}
+	for _, n := range numbers {
+		value := r.FormValue(n.field)
+		if value == "" {
+			continue
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s", strings.ReplaceAll(n.field, "_", " "))
+		}
+		*n.dest = v
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseImportSelection decodes the transactions of a preview and returns the
+// ones whose index was posted as include.
+func parseImportSelection(r *http.Request) ([]models.Expense, error) {
+	var all []models.Expense
+	if err := json.Unmarshal([]byte(r.FormValue("data")), &all); err != nil {
+		return nil, errors.New("invalid import data")
+	}
+
+	var selected []models.Expense
+	for _, value := range r.Form["include"] {
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 || i >= len(all) {
+			return nil, errors.New("invalid selection")
+		}
+		e := all[i]
+		if _, err := models.ParseExpenseKind(string(e.Kind)); err != nil {
+			return nil, err
+		}
+		if e.Amount <= 0 || e.Date.IsZero() || e.Category == "" || e.Description == "" {
+			return nil, errors.New("invalid import data")
+		}
+		selected = append(selected, e)
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("select at least one transaction")
+	}
+	return selected, nil
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"html"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// postStatement uploads a statement with a column mapping to PreviewImport.
+func (s *ExpenseHandlerTestSuite) postStatement(h *Handlers, statement string, fields map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		s.Require().NoError(mw.WriteField(name, value))
+	}
+	part, err := mw.CreateFormFile("statement", "statement.csv")
+	s.Require().NoError(err)
+	_, err = part.Write([]byte(statement))
+	s.Require().NoError(err)
+	s.Require().NoError(mw.Close())
+
+	req := httptest.NewRequest("POST", "/import/preview", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.PreviewImport(w, req)
+	return w
+}
+
+func sparkasseMapping() map[string]string {
+	return map[string]string{
+		"delimiter":          ";",
+		"skip_rows":          "1",
+		"date_column":        "1",
+		"description_column": "2",
+		"amount_column":      "3",
+		"category_column":    "4",
+		"date_format":        "DD.MM.YYYY",
+		"decimal_separator":  ",",
+		"sign_convention":    "negative",
+		"default_category":   "Other",
+	}
+}
+
+func (s *ExpenseHandlerTestSuite) TestImport_PreviewAndCommit() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 2340, Description: "REWE", Category: "Groceries", Date: parseTestDate("2026-01-05T12:00:00")}))
+
+	statement := "Date;Text;Amount;Category\n" +
+		"05.01.2026;REWE;-23,40;Groceries\n" +
+		"06.01.2026;Salary;2.500,00;\n" +
+		"07.01.2026;Cinema;-12,00;unknown\n" +
+		"08.01.2026;Broken;;\n"
+	mapping := sparkasseMapping()
+	mapping["save_as"] = "Sparkasse"
+	w := s.postStatement(h, statement, mapping)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	body := w.Body.String()
+	s.Contains(body, "2 new, 1 already recorded, 1 unreadable")
+	s.Contains(body, "Line 5")
+	s.Contains(body, "amount is zero")
+	s.Contains(body, `<input type="checkbox" name="include" value="0" >`, "duplicates are not selected")
+	s.Contains(body, `<input type="checkbox" name="include" value="1" checked>`)
+	s.Contains(body, "Jan 7, 2026 · Other", "unknown categories fall back to the default")
+
+	profiles, err := s.db.ListImportProfiles(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Require().Len(profiles, 1)
+	s.Equal("Sparkasse", profiles[0].Name)
+	s.Equal(4, profiles[0].CategoryColumn)
+
+	data := html.UnescapeString(regexp.MustCompile(`name="data" value="([^"]*)"`).FindStringSubmatch(body)[1])
+	form := url.Values{"data": {data}, "include": {"0", "1", "2"}}
+	req := httptest.NewRequest("POST", "/import/commit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.CommitImport(w, req)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	s.Contains(w.Body.String(), "Imported 2 transaction(s). 1 already recorded were skipped.")
+
+	expenses, err := s.db.GetExpensesByMonth(s.ctx, storage.UserScope(s.user.ID), 2026, 1)
+	s.Require().NoError(err)
+	s.Len(expenses, 3)
+
+	// The saved profile prefills the mapping
+	req = httptest.NewRequest("GET", "/import?profile="+strconv.FormatInt(profiles[0].ID, 10), http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.Import(w, req)
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `<option value=";" selected>Semicolon</option>`)
+	s.Contains(w.Body.String(), `name="category_column" type="number" min="1" value="4"`)
+}
+
+func (s *ExpenseHandlerTestSuite) TestImport_OFX() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	statement := `<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKACCTFROM><ACCTID>42</BANKACCTFROM><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260105<TRNAMT>-9.99<FITID>A1<NAME>Streaming</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+	fields := map[string]string{"format": "ofx", "default_category": "Entertainment"}
+
+	w := s.postStatement(h, statement, fields)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	s.Contains(w.Body.String(), "1 new, 0 already recorded")
+	data := html.UnescapeString(regexp.MustCompile(`name="data" value="([^"]*)"`).FindStringSubmatch(w.Body.String())[1])
+	s.Contains(data, `"import_ref":"ofx:42:A1"`)
+
+	imported, err := s.db.ImportExpenses(s.ctx, s.user.ID, []models.Expense{{Kind: models.KindExpense, Amount: 999, Description: "Streaming", Category: "Entertainment", Date: parseTestDate("2026-01-05T12:00:00").UTC(), ImportRef: "ofx:42:A1"}})
+	s.Require().NoError(err)
+	s.Equal(1, imported)
+
+	// Uploading the file again finds the transaction by its FITID
+	w = s.postStatement(h, statement, fields)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "0 new, 1 already recorded")
+
+	w = s.postStatement(h, "Date,Amount\n", fields)
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), "not an OFX file")
+}
+
+func (s *ExpenseHandlerTestSuite) TestImport_BankFormats() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	camt := `<Document><BkToCstmrStmt><Stmt><Acct><Id><IBAN>DE02</IBAN></Id></Acct>
+<Ntry><Amt Ccy="EUR">12.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2026-01-05</Dt></BookgDt><AcctSvcrRef>R1</AcctSvcrRef>
+<NtryDtls><TxDtls><RltdPties><Cdtr><Nm>Bakery</Nm></Cdtr></RltdPties></TxDtls></NtryDtls></Ntry>
+</Stmt></BkToCstmrStmt></Document>`
+	mt940 := ":20:STARTUMS\n:25:10020030/1234567\n:61:2601050105D12,00NMSCNONREF//R1\n:86:Bakery\n-\n"
+
+	w := s.postStatement(h, camt, map[string]string{"format": "camt", "default_category": "Groceries"})
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	s.Contains(w.Body.String(), "1 new, 0 already recorded")
+	s.Contains(w.Body.String(), "Bakery")
+
+	w = s.postStatement(h, mt940, map[string]string{"format": "mt940", "default_category": "Groceries"})
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	s.Contains(w.Body.String(), "1 new, 0 already recorded")
+	data := html.UnescapeString(regexp.MustCompile(`name="data" value="([^"]*)"`).FindStringSubmatch(w.Body.String())[1])
+	s.Contains(data, `"import_ref":"mt940:10020030/1234567:R1"`)
+
+	w = s.postStatement(h, camt, map[string]string{"format": "mt940", "default_category": "Groceries"})
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), "not an MT940 statement")
+
+	w = s.postStatement(h, camt, map[string]string{"format": "qif", "default_category": "Groceries"})
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), `unknown format "qif"`)
+}
+
+func (s *ExpenseHandlerTestSuite) TestImport_InvalidRequests() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	mapping := sparkasseMapping()
+	mapping["sign_convention"] = "columns"
+	w := s.postStatement(h, "", mapping)
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), "credit column")
+
+	for _, form := range []url.Values{
+		{"data": {"not json"}, "include": {"0"}},
+		{"data": {`[{"kind":"expense","amount":1,"description":"x","category":"Other","date":"2026-01-01T12:00:00Z"}]`}, "include": {"1"}},
+		{"data": {`[{"kind":"expense","amount":1,"description":"x","category":"Other","date":"2026-01-01T12:00:00Z"}]`}},
+		{"data": {`[{"kind":"gift","amount":1,"description":"x","category":"Other","date":"2026-01-01T12:00:00Z"}]`}, "include": {"0"}},
+	} {
+		req := httptest.NewRequest("POST", "/import/commit", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = s.addUserContext(req)
+		w := httptest.NewRecorder()
+		h.CommitImport(w, req)
+		s.Equal(http.StatusBadRequest, w.Code, form.Encode())
+	}
+}
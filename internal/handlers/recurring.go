@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// upcomingDays is how far ahead the recurring page lists occurrences.
+const upcomingDays = 60
+
+// Recurring renders the household's recurring templates and their upcoming occurrences.
+func (h *Handlers) Recurring(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	householdID, categories, err := h.householdCategories(ctx, user.ID, true)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("ListCategories error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	templates, err := h.db.ListRecurring(ctx, householdID)
+	if err != nil {
+		log.Printf("ListRecurring error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, r, "recurring.html", newRecurringViewModel(templates, categories, time.Now()))
+}
+
+// CreateRecurring adds a recurring template to the user's household.
+func (h *Handlers) CreateRecurring(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	template, err := parseRecurringForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.CreateRecurring(ctx, user.ID, template); err != nil {
+		if errors.Is(err, storage.ErrNoHousehold) {
+			http.Error(w, "You are not a member of any household", http.StatusForbidden)
+			return
+		}
+		log.Printf("CreateRecurring error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/recurring", "target":"#content"}`)
+}
+
+// DeleteRecurring removes a recurring template. Expenses already created from it stay.
+func (h *Handlers) DeleteRecurring(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "You are not a member of any household", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.DeleteRecurring(ctx, householdID, id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Recurring transaction not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("DeleteRecurring error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/recurring", "target":"#content"}`)
+}
+
+func newRecurringViewModel(templates []models.Recurring, categories []models.Category, now time.Time) RecurringViewModel {
+	styles := newCategoryStyles(categories)
+	vm := RecurringViewModel{
+		Upcoming:   []UpcomingItem{},
+		Categories: []models.Category{},
+		Today:      now.Format("2006-01-02"),
+	}
+	for _, c := range categories {
+		if !c.Archived {
+			vm.Categories = append(vm.Categories, c)
+		}
+	}
+
+	var upcoming []struct {
+		date time.Time
+		item UpcomingItem
+	}
+	until := now.AddDate(0, 0, upcomingDays)
+	for _, t := range templates {
+		vm.Templates = append(vm.Templates, RecurringItem{
+			Recurring:     t,
+			Rule:          describeRule(t),
+			CategoryStyle: styles.get(t.Category),
+		})
+		for _, date := range t.Occurrences(until) {
+			upcoming = append(upcoming, struct {
+				date time.Time
+				item UpcomingItem
+			}{date, UpcomingItem{
+				Date:          date.Format("Mon, Jan 2"),
+				Kind:          t.Kind,
+				Amount:        t.Amount,
+				Description:   t.Description,
+				CategoryStyle: styles.get(t.Category),
+			}})
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].date.Before(upcoming[j].date) })
+	for _, u := range upcoming {
+		vm.Upcoming = append(vm.Upcoming, u.item)
+	}
+	return vm
+}
+
+// frequencyNames holds the adverb and plural unit of each frequency.
+var frequencyNames = map[models.Frequency][2]string{
+	models.FrequencyDaily:   {"Daily", "days"},
+	models.FrequencyWeekly:  {"Weekly", "weeks"},
+	models.FrequencyMonthly: {"Monthly", "months"},
+	models.FrequencyYearly:  {"Yearly", "years"},
+}
+
+// describeRule returns a short description of a template's schedule.
+func describeRule(r models.Recurring) string {
+	names := frequencyNames[r.Frequency]
+	rule := names[0]
+	if r.Interval > 1 {
+		rule = fmt.Sprintf("Every %d %s", r.Interval, names[1])
+	}
+
+	switch r.Frequency {
+	case models.FrequencyWeekly:
+		rule += " on " + r.StartDate.Weekday().String()
+	case models.FrequencyMonthly:
+		day := r.Day
+		if day == 0 {
+			day = r.StartDate.Day()
+		}
+		rule += fmt.Sprintf(" on day %d", day)
+	case models.FrequencyYearly:
+		rule += " on " + r.StartDate.Format("Jan 2")
+	}
+
+	if r.EndDate != nil {
+		rule += " until " + r.EndDate.Format("Jan 2, 2006")
+	}
+	return rule
+}
+
+func parseRecurringForm(r *http.Request) (*models.Recurring, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	amount, err := models.ParseMoney(r.FormValue("amount"))
+	if err != nil {
+		return nil, err
+	}
+	if amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	kind, err := models.ParseExpenseKind(r.FormValue("kind"))
+	if err != nil {
+		return nil, err
+	}
+	frequency, err := models.ParseFrequency(r.FormValue("frequency"))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &models.Recurring{
+		Kind:        kind,
+		Amount:      amount,
+		Description: r.FormValue("description"),
+		Category:    r.FormValue("category"),
+		Frequency:   frequency,
+		Interval:    1,
+	}
+	if t.Category == "" {
+		return nil, errors.New("category is required")
+	}
+	if t.Description == "" {
+		t.Description = t.Category
+	}
+	if s := r.FormValue("interval"); s != "" {
+		if t.Interval, err = strconv.Atoi(s); err != nil || t.Interval < 1 {
+			return nil, errors.New("interval must be a positive number")
+		}
+	}
+	if s := r.FormValue("day"); s != "" && frequency == models.FrequencyMonthly {
+		if t.Day, err = strconv.Atoi(s); err != nil || t.Day < 1 || t.Day > 31 {
+			return nil, errors.New("day must be between 1 and 31")
+		}
+	}
+
+	// Occurrences are created at noon so that they sort among the day's expenses
+	if t.StartDate, err = time.Parse("2006-01-02", r.FormValue("start_date")); err != nil {
+		return nil, errors.New("invalid start date")
+	}
+	t.StartDate = t.StartDate.Add(12 * time.Hour)
+	if s := r.FormValue("end_date"); s != "" {
+		end, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, errors.New("invalid end date")
+		}
+		if end.Before(t.StartDate.Truncate(24 * time.Hour)) {
+			return nil, errors.New("end date must not be before the start date")
+		}
+		t.EndDate = &end
+	}
+	return t, nil
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"expense-tracker/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func (s *ExpenseHandlerTestSuite) TestCreateRecurring() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	start := time.Now().Format("2006-01-02")
+
+	form := url.Values{
+		"description": {"Rent"},
+		"amount":      {"950"},
+		"category":    {"Housing"},
+		"frequency":   {"monthly"},
+		"interval":    {"1"},
+		"day":         {"31"},
+		"start_date":  {start},
+	}
+	resp := s.postCategoryForm("/recurring", form, h.CreateRecurring, 0)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(`{"path":"/recurring", "target":"#content"}`, resp.Header.Get("HX-Location"))
+
+	templates, err := s.db.ListRecurring(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Require().Len(templates, 1)
+	s.Equal(models.Money(95000), templates[0].Amount)
+	s.Equal(31, templates[0].Day)
+
+	req := httptest.NewRequest("GET", "/recurring", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.Recurring(w, req)
+	s.Equal(http.StatusOK, w.Result().StatusCode)
+
+	body := w.Body.String()
+	s.Contains(body, "Monthly on day 31")
+	s.Contains(body, "-€950.00", "the next occurrences are listed")
+
+	form.Set("frequency", "hourly")
+	resp = s.postCategoryForm("/recurring", form, h.CreateRecurring, 0)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	form.Set("frequency", "weekly")
+	form.Set("end_date", "2000-01-01")
+	resp = s.postCategoryForm("/recurring", form, h.CreateRecurring, 0)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *ExpenseHandlerTestSuite) TestDeleteRecurring() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	mine := &models.Recurring{
+		Amount: 1299, Description: "Streaming", Category: "Entertainment",
+		Frequency: models.FrequencyWeekly, StartDate: time.Now(),
+	}
+	s.Require().NoError(s.db.CreateRecurring(s.ctx, s.user.ID, mine))
+
+	// Templates of other households cannot be deleted
+	outsider, err := s.db.CreateUser(s.ctx, "outsider", "password456")
+	s.Require().NoError(err)
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, other.ID, outsider.ID))
+	theirs := &models.Recurring{
+		Amount: 5000, Description: "Gym", Category: "Sport",
+		Frequency: models.FrequencyMonthly, StartDate: time.Now(),
+	}
+	s.Require().NoError(s.db.CreateRecurring(s.ctx, outsider.ID, theirs))
+
+	for _, tt := range []struct {
+		id     int64
+		status int
+	}{
+		{theirs.ID, http.StatusNotFound},
+		{mine.ID, http.StatusOK},
+	} {
+		req := httptest.NewRequest("DELETE", "/recurring/x", http.NoBody)
+		req.SetPathValue("id", strconv.FormatInt(tt.id, 10))
+		req = s.addUserContext(req)
+		w := httptest.NewRecorder()
+		h.DeleteRecurring(w, req)
+		s.Equal(tt.status, w.Result().StatusCode)
+	}
+
+	templates, err := s.db.ListRecurring(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Empty(templates)
+	templates, err = s.db.ListRecurring(s.ctx, other.ID)
+	s.Require().NoError(err)
+	s.Len(templates, 1)
+}
@@ -1,27 +1,50 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
 	"log"
 	"math"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 )
 
 // StatsCategoryItem represents a category with its spending statistics.
+// Top-level items include the spending of their subcategories, which are
+// listed in Children.
 type StatsCategoryItem struct {
 	Category      string
-	Total         float64
+	Total         models.Money
 	Count         int
 	Percentage    float64
 	CategoryStyle CategoryStyle
+	Children      []StatsCategoryItem
+	Expanded      bool // Children are shown
+
+	// Month view only, for categories with a budget
+	HasBudget          bool
+	Budget             models.Money // Budget available this month, including rollover
+	Remaining          models.Money // Negative when overspent
+	ProjectedOverspend models.Money // Expected overspend at month end at the current pace
+}
+
+// StatsTagItem represents a tag with the spending on expenses tagged with it.
+// An expense with several tags counts towards each, so percentages of tags
+// may add up to more than 100.
+type StatsTagItem struct {
+	Tag        string
+	Total      models.Money
+	Count      int
+	Percentage float64
 }
 
 // ChartPoint represents a data point in the chart.
 type ChartPoint struct {
 	Label string
-	Value float64
+	Value models.Money
 }
 
 // StatsViewModel is the data passed to the statistics view template.
@@ -30,88 +53,131 @@ type StatsViewModel struct {
 	Year             int
 	Month            int
 	MonthName        string
-	Total            float64
+	Total            models.Money // Spending, net of refunds
+	Income           models.Money
+	Net              models.Money // Income minus spending
 	PercentageChange float64
 	IsIncrease       bool
 	HasChange        bool
-	AverageSpending  float64
+	AverageSpending  models.Money
 	AverageLabel     string
 	Categories       []StatsCategoryItem
+	Tags             []StatsTagItem
 	Expenses         []ExpenseItem
 	ChartData        []ChartPoint
-	MaxChartValue    float64
+	MaxChartValue    models.Money
 	PrevYear         int
 	PrevMonth        int
 	NextYear         int
 	NextMonth        int
 	IsCurrentPeriod  bool
+	Expanded         string // Category whose subcategories are shown
+	AccountID        int64  // Account the statistics are limited to, zero for all
+
+	// Month view only
+	BudgetTotal        models.Money // Sum of the budgets of budgeted categories
+	BudgetRemaining    models.Money
+	ProjectedSpending  models.Money // Expected spending at month end at the current pace
+	ProjectedOverspend models.Money // Expected overspend of budgeted categories
 }
 
 // Statistics renders the statistics page.
 func (h *Handlers) Statistics(w http.ResponseWriter, r *http.Request) {
-	// Get view mode, year, and month from query params
-	viewMode := r.URL.Query().Get("view")
-	if viewMode == "" {
-		viewMode = "month" // Default to month view
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	accountID := accountFilter(r)
+	scope := storage.UserScope(user.ID).WithAccount(accountID)
+	styles := h.categoryStyles(ctx, user.ID)
+	householdID, err := h.db.DefaultHouseholdID(ctx, user.ID)
+	if err != nil && !errors.Is(err, storage.ErrNoHousehold) {
+		log.Printf("DefaultHouseholdID error: %v", err)
+	}
+	// Budgets cover the spending of all accounts
+	if accountID != 0 {
+		householdID = 0
 	}
-
-	yearStr := r.URL.Query().Get("year")
-	monthStr := r.URL.Query().Get("month")
 
 	now := time.Now()
-	year := now.Year()
-	month := int(now.Month())
+	viewMode, year, month := parsePeriod(r, now)
 
-	if yearStr != "" {
+	var viewModel StatsViewModel
+	expanded := r.URL.Query().Get("expand")
+
+	if viewMode == "year" {
+		viewModel = h.buildYearView(ctx, scope, styles, year, now)
+	} else {
+		viewModel = h.buildMonthView(ctx, scope, householdID, styles, year, month, now)
+	}
+	viewModel.expand(expanded)
+	viewModel.AccountID = accountID
+
+	h.render(w, r, "stats.html", viewModel)
+}
+
+// parsePeriod reads the view mode ("month" or "year"), year and month query
+// parameters shared by the statistics page and exports. Missing or invalid
+// values default to the current month.
+func parsePeriod(r *http.Request, now time.Time) (viewMode string, year, month int) {
+	viewMode = r.URL.Query().Get("view")
+	if viewMode == "" {
+		viewMode = "month" // Default to month view
+	}
+
+	year = now.Year()
+	month = int(now.Month())
+	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
 		if y, err := strconv.Atoi(yearStr); err == nil {
 			year = y
 		}
 	}
-	if monthStr != "" {
+	if monthStr := r.URL.Query().Get("month"); monthStr != "" {
 		if m, err := strconv.Atoi(monthStr); err == nil && m >= 1 && m <= 12 {
 			month = m
 		}
 	}
-
-	var viewModel StatsViewModel
-
-	if viewMode == "year" {
-		viewModel = h.buildYearView(year, now)
-	} else {
-		viewModel = h.buildMonthView(year, month, now)
-	}
-
-	h.render(w, r, "stats.html", viewModel)
+	return viewMode, year, month
 }
 
-// buildMonthView builds the view model for month view.
-func (h *Handlers) buildMonthView(year, month int, now time.Time) StatsViewModel {
+// buildMonthView builds the view model for month view. Budgets are those of
+// the given household; zero means none.
+func (h *Handlers) buildMonthView(ctx context.Context, scope storage.Scope, householdID int64, styles categoryStyles, year, month int, now time.Time) StatsViewModel {
 	// Get category totals
-	categoryTotals, err := h.db.GetCategoryTotalsByMonth(year, month)
+	categoryTotals, err := h.db.GetCategoryTotalsByMonth(ctx, scope, year, month)
 	if err != nil {
 		log.Printf("GetCategoryTotalsByMonth error: %v", err)
 		return StatsViewModel{}
 	}
 
 	// Get expenses for the month
-	expenses, err := h.db.GetExpensesByMonth(year, month)
+	expenses, err := h.db.GetExpensesByMonth(ctx, scope, year, month)
 	if err != nil {
 		log.Printf("GetExpensesByMonth error: %v", err)
 		return StatsViewModel{}
 	}
 
+	tagTotals, err := h.db.GetTagTotalsByMonth(ctx, scope, year, month)
+	if err != nil {
+		log.Printf("GetTagTotalsByMonth error: %v", err)
+	}
+
 	// Get daily totals for chart
-	dailyTotals, err := h.db.GetDailyTotalsForMonth(year, month)
+	dailyTotals, err := h.db.GetDailyTotalsForMonth(ctx, scope, year, month)
 	if err != nil {
 		log.Printf("GetDailyTotalsForMonth error: %v", err)
 	}
 
-	// Calculate total
-	total, _ := h.db.GetTotalForPeriod(year, month)
+	// Calculate totals
+	totals, _ := h.db.GetTotalForPeriod(ctx, scope, year, month)
+	total := totals.Spending
 
-	// Get previous month total for percentage change
+	// Get previous month spending for percentage change
 	prevDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
-	prevTotal, _ := h.db.GetTotalForPeriod(prevDate.Year(), int(prevDate.Month()))
+	prevTotals, _ := h.db.GetTotalForPeriod(ctx, scope, prevDate.Year(), int(prevDate.Month()))
+	prevTotal := prevTotals.Spending
 
 	// Calculate percentage change
 	percentageChange := 0.0
@@ -119,24 +185,21 @@ func (h *Handlers) buildMonthView(year, month int, now time.Time) StatsViewModel
 	isIncrease := false
 	if prevTotal > 0 {
 		hasChange = true
-		percentageChange = ((total - prevTotal) / prevTotal) * 100
+		percentageChange = ((total - prevTotal).Float64() / prevTotal.Float64()) * 100
 		isIncrease = percentageChange > 0
 		percentageChange = math.Abs(percentageChange)
 	}
 
 	// Calculate average spending per day
 	daysInMonth := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
-	averageSpending := 0.0
-	if daysInMonth > 0 {
-		averageSpending = total / float64(daysInMonth)
-	}
+	averageSpending := total.Div(daysInMonth)
 
 	// Build chart data
 	chartData := make([]ChartPoint, 0)
-	maxValue := 0.0
+	var maxValue models.Money
 
 	// Create a map for quick lookup
-	dailyMap := make(map[int]float64)
+	dailyMap := make(map[int]models.Money)
 	for _, dt := range dailyTotals {
 		dailyMap[dt.Day] = dt.Total
 		if dt.Total > maxValue {
@@ -158,34 +221,12 @@ func (h *Handlers) buildMonthView(year, month int, now time.Time) StatsViewModel
 	}
 
 	// Prepare category items
-	categoryItems := make([]StatsCategoryItem, 0, len(categoryTotals))
-	for _, ct := range categoryTotals {
-		percentage := 0.0
-		if total > 0 {
-			percentage = (ct.Total / total) * 100
-		}
-		categoryItems = append(categoryItems, StatsCategoryItem{
-			Category:      ct.Category,
-			Total:         ct.Total,
-			Count:         ct.Count,
-			Percentage:    percentage,
-			CategoryStyle: getCategoryStyle(ct.Category),
-		})
-	}
+	categoryItems := newStatsCategoryItems(categoryTotals, total, styles)
 
 	// Prepare expense items
 	expenseItems := make([]ExpenseItem, 0, len(expenses))
 	for _, e := range expenses {
-		expenseItems = append(expenseItems, ExpenseItem{
-			ID:            e.ID,
-			Amount:        e.Amount,
-			Description:   e.Description,
-			Category:      e.Category,
-			Time:          e.Date.Format("Jan 02, 15:04"),
-			DateTime:      e.Date.Format("2006-01-02T15:04:05"),
-			CategoryStyle: getCategoryStyle(e.Category),
-			IsIncome:      strings.Contains(e.Description, "[Income]"),
-		})
+		expenseItems = append(expenseItems, newExpenseItem(e, "Jan 02, 15:04", styles))
 	}
 
 	// Calculate previous and next month
@@ -196,18 +237,21 @@ func (h *Handlers) buildMonthView(year, month int, now time.Time) StatsViewModel
 
 	monthName := time.Month(month).String()
 
-	return StatsViewModel{
+	viewModel := StatsViewModel{
 		ViewMode:         "month",
 		Year:             year,
 		Month:            month,
 		MonthName:        monthName,
 		Total:            total,
+		Income:           totals.Income,
+		Net:              totals.Net(),
 		PercentageChange: percentageChange,
 		IsIncrease:       isIncrease,
 		HasChange:        hasChange,
 		AverageSpending:  averageSpending,
 		AverageLabel:     "SPENT/DAY",
 		Categories:       categoryItems,
+		Tags:             newStatsTagItems(tagTotals, total),
 		Expenses:         expenseItems,
 		ChartData:        chartData,
 		MaxChartValue:    maxValue,
@@ -217,35 +261,129 @@ func (h *Handlers) buildMonthView(year, month int, now time.Time) StatsViewModel
 		NextMonth:        int(nextDate.Month()),
 		IsCurrentPeriod:  isCurrentPeriod,
 	}
+
+	// Project spending to the end of the month from the days elapsed so far
+	elapsed := daysInMonth
+	if isCurrentPeriod {
+		elapsed = now.Day()
+	} else if nextDate.After(now) {
+		elapsed = 0
+	}
+	var spentSoFar models.Money
+	for _, dt := range dailyTotals {
+		if dt.Day <= elapsed {
+			spentSoFar += dt.Total
+		}
+	}
+	viewModel.ProjectedSpending = project(spentSoFar, elapsed, daysInMonth)
+
+	if householdID != 0 {
+		statuses, err := h.db.GetBudgetStatuses(ctx, householdID, year, month)
+		if err != nil {
+			log.Printf("GetBudgetStatuses error: %v", err)
+		}
+		viewModel.applyBudgets(statuses, styles, elapsed, daysInMonth)
+	}
+
+	return viewModel
+}
+
+// project extrapolates the amount spent in the first elapsed days to the
+// whole period.
+func project(spent models.Money, elapsed, days int) models.Money {
+	if elapsed <= 0 {
+		return 0
+	}
+	return models.Money(int64(spent) * int64(days) / int64(elapsed))
+}
+
+// applyBudgets adds budget, remaining amount and projected overspend to the
+// budgeted categories. Budgeted top-level categories without spending are
+// listed too.
+func (vm *StatsViewModel) applyBudgets(statuses []storage.BudgetStatus, styles categoryStyles, elapsed, days int) {
+	byName := make(map[string]storage.BudgetStatus, len(statuses))
+	for _, bs := range statuses {
+		byName[bs.Category] = bs
+	}
+
+	apply := func(item *StatsCategoryItem) bool {
+		bs, ok := byName[item.Category]
+		if !ok {
+			return false
+		}
+		delete(byName, item.Category)
+		item.HasBudget = true
+		item.Budget = bs.Available()
+		item.Remaining = bs.Available() - item.Total
+		item.ProjectedOverspend = max(project(item.Total, elapsed, days)-item.Budget, 0)
+		return true
+	}
+	// A parent's budget covers its subcategories, so theirs only count
+	// towards the totals when the parent has none.
+	count := func(item *StatsCategoryItem) {
+		vm.BudgetTotal += item.Budget
+		vm.BudgetRemaining += item.Remaining
+		vm.ProjectedOverspend += item.ProjectedOverspend
+	}
+
+	for i := range vm.Categories {
+		item := &vm.Categories[i]
+		parentBudgeted := apply(item)
+		if parentBudgeted {
+			count(item)
+		}
+		for j := range item.Children {
+			if apply(&item.Children[j]) && !parentBudgeted {
+				count(&item.Children[j])
+			}
+		}
+	}
+
+	for _, bs := range statuses {
+		if _, ok := byName[bs.Category]; !ok || bs.Subcategory {
+			continue
+		}
+		item := StatsCategoryItem{Category: bs.Category, CategoryStyle: styles.get(bs.Category)}
+		apply(&item)
+		count(&item)
+		vm.Categories = append(vm.Categories, item)
+	}
 }
 
 // buildYearView builds the view model for year view.
-func (h *Handlers) buildYearView(year int, now time.Time) StatsViewModel {
+func (h *Handlers) buildYearView(ctx context.Context, scope storage.Scope, styles categoryStyles, year int, now time.Time) StatsViewModel {
 	// Get category totals for the year
-	categoryTotals, err := h.db.GetCategoryTotalsByYear(year)
+	categoryTotals, err := h.db.GetCategoryTotalsByYear(ctx, scope, year)
 	if err != nil {
 		log.Printf("GetCategoryTotalsByYear error: %v", err)
 		return StatsViewModel{}
 	}
 
 	// Get expenses for the year
-	expenses, err := h.db.GetExpensesByYear(year)
+	expenses, err := h.db.GetExpensesByYear(ctx, scope, year)
 	if err != nil {
 		log.Printf("GetExpensesByYear error: %v", err)
 		return StatsViewModel{}
 	}
 
+	tagTotals, err := h.db.GetTagTotalsByYear(ctx, scope, year)
+	if err != nil {
+		log.Printf("GetTagTotalsByYear error: %v", err)
+	}
+
 	// Get monthly totals for chart
-	monthlyTotals, err := h.db.GetMonthlyTotalsForYear(year)
+	monthlyTotals, err := h.db.GetMonthlyTotalsForYear(ctx, scope, year)
 	if err != nil {
 		log.Printf("GetMonthlyTotalsForYear error: %v", err)
 	}
 
-	// Calculate total
-	total, _ := h.db.GetTotalForPeriod(year, 0)
+	// Calculate totals
+	totals, _ := h.db.GetTotalForPeriod(ctx, scope, year, 0)
+	total := totals.Spending
 
-	// Get previous year total for percentage change
-	prevTotal, _ := h.db.GetTotalForPeriod(year-1, 0)
+	// Get previous year spending for percentage change
+	prevTotals, _ := h.db.GetTotalForPeriod(ctx, scope, year-1, 0)
+	prevTotal := prevTotals.Spending
 
 	// Calculate percentage change
 	percentageChange := 0.0
@@ -253,21 +391,21 @@ func (h *Handlers) buildYearView(year int, now time.Time) StatsViewModel {
 	isIncrease := false
 	if prevTotal > 0 {
 		hasChange = true
-		percentageChange = ((total - prevTotal) / prevTotal) * 100
+		percentageChange = ((total - prevTotal).Float64() / prevTotal.Float64()) * 100
 		isIncrease = percentageChange > 0
 		percentageChange = math.Abs(percentageChange)
 	}
 
 	// Calculate average spending per month
-	averageSpending := total / 12.0
+	averageSpending := total.Div(12)
 
 	// Build chart data
 	monthNames := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
 	chartData := make([]ChartPoint, 12)
-	maxValue := 0.0
+	var maxValue models.Money
 
 	// Create a map for quick lookup
-	monthlyMap := make(map[int]float64)
+	monthlyMap := make(map[int]models.Money)
 	for _, mt := range monthlyTotals {
 		monthlyMap[mt.Month] = mt.Total
 		if mt.Total > maxValue {
@@ -286,34 +424,12 @@ func (h *Handlers) buildYearView(year int, now time.Time) StatsViewModel {
 	}
 
 	// Prepare category items
-	categoryItems := make([]StatsCategoryItem, 0, len(categoryTotals))
-	for _, ct := range categoryTotals {
-		percentage := 0.0
-		if total > 0 {
-			percentage = (ct.Total / total) * 100
-		}
-		categoryItems = append(categoryItems, StatsCategoryItem{
-			Category:      ct.Category,
-			Total:         ct.Total,
-			Count:         ct.Count,
-			Percentage:    percentage,
-			CategoryStyle: getCategoryStyle(ct.Category),
-		})
-	}
+	categoryItems := newStatsCategoryItems(categoryTotals, total, styles)
 
 	// Prepare expense items
 	expenseItems := make([]ExpenseItem, 0, len(expenses))
 	for _, e := range expenses {
-		expenseItems = append(expenseItems, ExpenseItem{
-			ID:            e.ID,
-			Amount:        e.Amount,
-			Description:   e.Description,
-			Category:      e.Category,
-			Time:          e.Date.Format("Jan 02, 15:04"),
-			DateTime:      e.Date.Format("2006-01-02T15:04:05"),
-			CategoryStyle: getCategoryStyle(e.Category),
-			IsIncome:      strings.Contains(e.Description, "[Income]"),
-		})
+		expenseItems = append(expenseItems, newExpenseItem(e, "Jan 02, 15:04", styles))
 	}
 
 	// Check if this is the current year
@@ -325,12 +441,15 @@ func (h *Handlers) buildYearView(year int, now time.Time) StatsViewModel {
 		Month:            0,
 		MonthName:        strconv.Itoa(year),
 		Total:            total,
+		Income:           totals.Income,
+		Net:              totals.Net(),
 		PercentageChange: percentageChange,
 		IsIncrease:       isIncrease,
 		HasChange:        hasChange,
 		AverageSpending:  averageSpending,
 		AverageLabel:     "SPENT/MTH",
 		Categories:       categoryItems,
+		Tags:             newStatsTagItems(tagTotals, total),
 		Expenses:         expenseItems,
 		ChartData:        chartData,
 		MaxChartValue:    maxValue,
@@ -341,3 +460,54 @@ func (h *Handlers) buildYearView(year int, now time.Time) StatsViewModel {
 		IsCurrentPeriod:  isCurrentPeriod,
 	}
 }
+
+// newStatsCategoryItems converts category totals, including their
+// subcategories, to view items. Percentages are relative to total.
+func newStatsCategoryItems(totals []storage.CategoryTotal, total models.Money, styles categoryStyles) []StatsCategoryItem {
+	if len(totals) == 0 {
+		return nil
+	}
+	items := make([]StatsCategoryItem, 0, len(totals))
+	for _, ct := range totals {
+		percentage := 0.0
+		if total > 0 {
+			percentage = (ct.Total.Float64() / total.Float64()) * 100
+		}
+		items = append(items, StatsCategoryItem{
+			Category:      ct.Category,
+			Total:         ct.Total,
+			Count:         ct.Count,
+			Percentage:    percentage,
+			CategoryStyle: styles.get(ct.Category),
+			Children:      newStatsCategoryItems(ct.Children, total, styles),
+		})
+	}
+	return items
+}
+
+// newStatsTagItems converts tag totals to view items. Percentages are
+// relative to total.
+func newStatsTagItems(totals []storage.TagTotal, total models.Money) []StatsTagItem {
+	if len(totals) == 0 {
+		return nil
+	}
+	items := make([]StatsTagItem, 0, len(totals))
+	for _, tt := range totals {
+		percentage := 0.0
+		if total > 0 {
+			percentage = (tt.Total.Float64() / total.Float64()) * 100
+		}
+		items = append(items, StatsTagItem{Tag: tt.Tag, Total: tt.Total, Count: tt.Count, Percentage: percentage})
+	}
+	return items
+}
+
+// expand shows the subcategories of the named category, if it has any.
+func (vm *StatsViewModel) expand(category string) {
+	for i := range vm.Categories {
+		if vm.Categories[i].Category == category && len(vm.Categories[i].Children) > 0 {
+			vm.Categories[i].Expanded = true
+			vm.Expanded = category
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+)
+
+func (s *ExpenseHandlerTestSuite) TestCreateExpense_Tags() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	form := url.Values{
+		"amount":      {"120"},
+		"description": {"Hotel #Vacation-2026 in Rome #business"},
+		"category":    {"Travel"},
+		"date":        {"2026-03-10T12:00:00"},
+		"tags":        {"reimbursable", "business"},
+	}
+	w := s.postCurrencyForm("/expenses", form, h.CreateExpense)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	expenses, err := s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal("Hotel in Rome", expenses[0].Description)
+	s.Equal([]string{"business", "reimbursable", "vacation-2026"}, expenses[0].Tags)
+
+	// A note of only tags falls back to the category
+	form.Set("description", "#vacation-2026")
+	form.Del("tags")
+	w = s.postCurrencyForm("/expenses", form, h.CreateExpense)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	expenses, err = s.db.ListExpenses(s.ctx, storage.UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 2)
+	s.Equal("Travel", expenses[0].Description)
+
+	form.Set("tags", "two words")
+	w = s.postCurrencyForm("/expenses", form, h.CreateExpense)
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *ExpenseHandlerTestSuite) TestListExpenses_TagFilter() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	now := time.Now()
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 12000, Description: "Hotel", Category: "Travel", Date: now, Tags: []string{"vacation"}}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 700, Description: "Taxi", Category: "Transport", Date: now}))
+
+	req := httptest.NewRequest("GET", "/expenses?tag=Vacation", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, "Hotel")
+	s.Contains(body, "#vacation")
+	s.NotContains(body, "Taxi")
+	s.Contains(body, `class="tag-filter"`)
+	s.Contains(body, `data-tag="vacation"`, "the form suggests the household's tags")
+
+	// An invalid tag shows everything
+	req = httptest.NewRequest("GET", "/expenses?tag=a+b", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.ListExpenses(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "Taxi")
+	s.NotContains(w.Body.String(), `class="tag-filter"`)
+}
+
+func (s *ExpenseHandlerTestSuite) TestStatistics_Tags() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	date := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 12000, Description: "Hotel", Category: "Travel", Date: date, Tags: []string{"vacation", "rome"}}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 8000, Description: "Groceries", Category: "Groceries", Date: date}))
+
+	req := httptest.NewRequest("GET", "/statistics?view=month&year=2026&month=3", http.NoBody)
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.Statistics(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	body := w.Body.String()
+	s.Contains(body, "Spending by Tag")
+	s.Contains(body, `data-tag="rome"`)
+	s.Contains(body, "60.0%")
+	s.Contains(body, `hx-get="/expenses?tag=vacation"`)
+
+	req = httptest.NewRequest("GET", "/statistics?view=year&year=2025", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.Statistics(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.NotContains(w.Body.String(), "Spending by Tag")
+}
+
+func (s *ExpenseHandlerTestSuite) TestAPI_Tags() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	token := s.apiToken(models.ScopeReadWrite)
+
+	body := `{"amount": 10, "description": "Taxi #late", "category": "Transport", "date": "2026-03-10T12:00:00Z", "tags": ["Business"]}`
+	w := s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token, body, "")
+	s.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	s.Contains(w.Body.String(), `"description":"Taxi #late"`)
+	s.Contains(w.Body.String(), `"tags":["business"]`)
+
+	w = s.apiRequest(h, h.APIListExpenses, "GET", "/api/v1/expenses?tag=business", token, "", "")
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "Taxi")
+	w = s.apiRequest(h, h.APIListExpenses, "GET", "/api/v1/expenses?tag=other", token, "", "")
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.NotContains(w.Body.String(), "Taxi")
+
+	w = s.apiRequest(h, h.APIStatistics, "GET", "/api/v1/statistics?year=2026&month=3", token, "", "")
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"tags":[{"tag":"business","total":10.00,"count":1}]`)
+
+	body = fmt.Sprintf(`{"amount": 10, "description": "Bus", "category": "Transport", "tags": [%q]}`, "#")
+	w = s.apiRequest(h, h.APICreateExpense, "POST", "/api/v1/expenses", token, body, "")
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(s.decodeAPIError(w).Message, "invalid tag")
+}
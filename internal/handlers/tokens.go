@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/internal/auth"
+	"expense-tracker/internal/models"
+	"expense-tracker/internal/storage"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APITokens renders the page for managing the user's API tokens.
+func (h *Handlers) APITokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.renderTokens(w, r, user.ID, nil)
+}
+
+// CreateAPIToken issues an API token from the settings page. The page is
+// rendered again with the new token, which is shown only this once.
+func (h *Handlers) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	name, scope, err := parseTokenRequest(r.FormValue("name"), r.FormValue("scope"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.issueAPIToken(ctx, user.ID, name, scope)
+	if err != nil {
+		log.Printf("CreateAPIToken error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.renderTokens(w, r, user.ID, created)
+}
+
+// RevokeAPIToken deletes one of the user's API tokens.
+func (h *Handlers) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := r.Context().Value(UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err := h.db.RevokeAPIToken(ctx, user.ID, id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "API token not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("RevokeAPIToken error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Location", `{"path":"/settings/tokens", "target":"#content"}`)
+}
+
+func (h *Handlers) renderTokens(w http.ResponseWriter, r *http.Request, userID int64, created *APINewToken) {
+	ctx := r.Context()
+	tokens, err := h.db.ListAPITokens(ctx, userID)
+	if err != nil {
+		log.Printf("ListAPITokens error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	vm := TokensViewModel{NewToken: created}
+	for _, t := range tokens {
+		item := TokenItem{APIToken: t, Created: t.CreatedAt.Format("Jan 2, 2006"), LastUsed: "Never used"}
+		if t.LastUsedAt != nil {
+			item.LastUsed = "Last used " + t.LastUsedAt.Local().Format("Jan 2, 2006 15:04")
+		}
+		vm.Tokens = append(vm.Tokens, item)
+	}
+	h.render(w, r, "tokens.html", vm)
+}
+
+// issueAPIToken generates a token for the user and stores its hash.
+func (h *Handlers) issueAPIToken(ctx context.Context, userID int64, name string, scope models.TokenScope) (*APINewToken, error) {
+	token, err := auth.GenerateAPIToken()
+	if err != nil {
+		return nil, err
+	}
+	created, err := h.db.CreateAPIToken(ctx, userID, name, scope, auth.HashAPIToken(token))
+	if err != nil {
+		return nil, err
+	}
+	return &APINewToken{APIToken: *created, Token: token}, nil
+}
+
+func parseTokenRequest(name, scope string) (string, models.TokenScope, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", errors.New("name is required")
+	}
+	s, err := models.ParseTokenScope(scope)
+	if err != nil {
+		return "", "", err
+	}
+	return name, s, nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"expense-tracker/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func (s *ExpenseHandlerTestSuite) TestAPITokens_CreateListRevoke() {
+	h := NewHandlers(s.db, s.templateDir, false)
+
+	form := url.Values{"name": {"Backup script"}, "scope": {"read"}}
+	req := httptest.NewRequest("POST", "/settings/tokens", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	req = s.addUserContext(req)
+	w := httptest.NewRecorder()
+	h.CreateAPIToken(w, req)
+	s.Require().Equal(http.StatusOK, w.Code)
+
+	// The token is shown once and then only its name is listed
+	body := w.Body.String()
+	s.Regexp(regexp.MustCompile(`<code class="token-value">et_[A-Za-z0-9_=-]+</code>`), body)
+	s.Contains(body, "Backup script · read-only")
+	s.Contains(body, "Never used")
+
+	tokens, err := s.db.ListAPITokens(s.ctx, s.user.ID)
+	s.Require().NoError(err)
+	s.Require().Len(tokens, 1)
+	s.Equal(models.ScopeRead, tokens[0].Scope)
+
+	req = httptest.NewRequest("GET", "/settings/tokens", http.NoBody)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.APITokens(w, req)
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "Backup script")
+	s.NotContains(w.Body.String(), "token-value")
+
+	id := strconv.FormatInt(tokens[0].ID, 10)
+	req = httptest.NewRequest("DELETE", "/settings/tokens/"+id, http.NoBody)
+	req.SetPathValue("id", id)
+	req = s.addUserContext(req)
+	w = httptest.NewRecorder()
+	h.RevokeAPIToken(w, req)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(`{"path":"/settings/tokens", "target":"#content"}`, w.Header().Get("HX-Location"))
+
+	tokens, err = s.db.ListAPITokens(s.ctx, s.user.ID)
+	s.Require().NoError(err)
+	s.Empty(tokens)
+
+	w = httptest.NewRecorder()
+	h.RevokeAPIToken(w, req)
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func (s *ExpenseHandlerTestSuite) TestCreateAPIToken_Validation() {
+	h := NewHandlers(s.db, s.templateDir, false)
+	for _, form := range []url.Values{
+		{"name": {"  "}, "scope": {"read"}},
+		{"name": {"Script"}, "scope": {"admin"}},
+	} {
+		req := httptest.NewRequest("POST", "/settings/tokens", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = s.addUserContext(req)
+		w := httptest.NewRecorder()
+		h.CreateAPIToken(w, req)
+		s.Equal(http.StatusBadRequest, w.Code, form.Encode())
+	}
+}
@@ -0,0 +1,239 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrNotCamt is returned when a file holds no camt.053 statement.
+var ErrNotCamt = errors.New("not a camt.053 statement")
+
+// camtEntry is an Ntry element of a camt.053 statement. Element names are
+// matched without their namespace, so every version of the schema is read.
+type camtEntry struct {
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	Reversal    bool       `xml:"RvslInd"`
+	Status      camtStatus `xml:"Sts"`
+	BookingDate camtDate   `xml:"BookgDt"`
+	ValueDate   camtDate   `xml:"ValDt"`
+	ServicerRef string     `xml:"AcctSvcrRef"`
+	Info        string     `xml:"AddtlNtryInf"`
+	Details     []camtTx   `xml:"NtryDtls>TxDtls"`
+}
+
+// camtStatus holds the entry status, which versions 8 and later wrap in Cd.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+// camtAmount is an amount with its currency.
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// camtTx is a transaction booked in an entry. Batch entries hold several.
+type camtTx struct {
+	Amount       camtAmount `xml:"Amt"`
+	LegacyAmount camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	CreditDebit  string     `xml:"CdtDbtInd"`
+	ServicerRef  string     `xml:"Refs>AcctSvcrRef"`
+	Creditor     string     `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty  string     `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Debtor       string     `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPty    string     `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Remittance   []string   `xml:"RmtInf>Ustrd"`
+	Info         string     `xml:"AddtlTxInf"`
+}
+
+// ParseCamt reads the booked entries of an ISO 20022 camt.053 bank to
+// customer statement. Debits become expenses and credits income, or refunds
+// when they reverse a debit. The counterparty and the remittance information
+// make up the description. The bank's reference for an entry, qualified by
+// the account, becomes the ImportRef. Batch entries that list the amount of
+// each transaction are split into one row per transaction; pending entries
+// are left out. Amounts are in the currency given with each of them.
+func ParseCamt(r io.Reader) ([]Row, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+
+	var (
+		rows       []Row
+		account    string
+		statements int
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "Stmt":
+			statements++
+			account = ""
+		case "Acct":
+			var acct struct {
+				IBAN  string `xml:"Id>IBAN"`
+				Other string `xml:"Id>Othr>Id"`
+			}
+			if err := dec.DecodeElement(&acct, &start); err != nil {
+				return nil, err
+			}
+			account = strings.TrimSpace(acct.IBAN + acct.Other)
+		case "Ntry":
+			line, _ := dec.InputPos()
+			var entry camtEntry
+			if err := dec.DecodeElement(&entry, &start); err != nil {
+				return nil, err
+			}
+			rows = append(rows, camtRows(entry, account, line)...)
+		}
+	}
+	if statements == 0 {
+		return nil, ErrNotCamt
+	}
+	return rows, nil
+}
+
+func camtRows(entry camtEntry, account string, line int) []Row {
+	status := strings.TrimSpace(entry.Status.Text + entry.Status.Code)
+	if status == "PDNG" || status == "INFO" {
+		return nil
+	}
+
+	date, err := camtBookingDate(entry)
+	if err != nil {
+		return []Row{{Line: line, Error: err.Error()}}
+	}
+	ref := ""
+	if entry.ServicerRef != "" {
+		ref = "camt:" + account + ":" + strings.TrimSpace(entry.ServicerRef)
+	}
+
+	// A single transaction, or a batch without amounts per transaction,
+	// is imported as the entry itself
+	split := len(entry.Details) > 1
+	for _, tx := range entry.Details {
+		if tx.Amount.Value == "" && tx.LegacyAmount.Value == "" {
+			split = false
+		}
+	}
+	if !split {
+		var tx camtTx
+		if len(entry.Details) == 1 {
+			tx = entry.Details[0]
+		}
+		tx.Amount, tx.LegacyAmount, tx.CreditDebit = entry.Amount, camtAmount{}, entry.CreditDebit
+		if ref == "" && tx.ServicerRef != "" {
+			ref = "camt:" + account + ":" + strings.TrimSpace(tx.ServicerRef)
+		}
+		if tx.Info == "" {
+			tx.Info = entry.Info
+		}
+		return []Row{camtRow(tx, entry.Reversal, date, ref, line)}
+	}
+
+	rows := make([]Row, 0, len(entry.Details))
+	for i, tx := range entry.Details {
+		if tx.CreditDebit == "" {
+			tx.CreditDebit = entry.CreditDebit
+		}
+		txRef := ""
+		switch {
+		case tx.ServicerRef != "":
+			txRef = "camt:" + account + ":" + strings.TrimSpace(tx.ServicerRef)
+		case ref != "":
+			txRef = fmt.Sprintf("%s:%d", ref, i+1)
+		}
+		rows = append(rows, camtRow(tx, entry.Reversal, date, txRef, line))
+	}
+	return rows
+}
+
+func camtRow(tx camtTx, reversal bool, date time.Time, ref string, line int) Row {
+	row := Row{Line: line}
+	e := &row.Expense
+	e.Date = date
+	e.ImportRef = ref
+
+	amount := tx.Amount
+	if amount.Value == "" {
+		amount = tx.LegacyAmount
+	}
+	m, err := parseAmount(amount.Value, ".")
+	switch {
+	case err != nil:
+		row.Error = err.Error()
+		return row
+	case m == 0:
+		row.Error = "amount is zero"
+		return row
+	}
+	e.Amount = m.Abs()
+	if amount.Currency != "" {
+		if e.Currency, err = models.ParseCurrency(amount.Currency); err != nil {
+			row.Error = err.Error()
+			return row
+		}
+	}
+
+	// The counterparty is the creditor of a debit and the debtor of a credit
+	var counterparty string
+	switch strings.TrimSpace(tx.CreditDebit) {
+	case "DBIT":
+		e.Kind = models.KindExpense
+		counterparty = tx.Creditor + tx.CreditorPty
+	case "CRDT":
+		e.Kind = models.KindIncome
+		if reversal {
+			e.Kind = models.KindRefund
+		}
+		counterparty = tx.Debtor + tx.DebtorPty
+	default:
+		row.Error = fmt.Sprintf("invalid credit/debit indicator %q", tx.CreditDebit)
+		return row
+	}
+
+	remittance := strings.Join(tx.Remittance, " ")
+	if remittance == "" {
+		remittance = tx.Info
+	}
+	e.Description = joinDescription(counterparty, remittance)
+	return row
+}
+
+func camtBookingDate(entry camtEntry) (time.Time, error) {
+	d := entry.BookingDate
+	if d.Date == "" && d.DateTime == "" {
+		d = entry.ValueDate
+	}
+	s := strings.TrimSpace(d.Date + d.DateTime)
+	if len(s) < 10 {
+		return time.Time{}, fmt.Errorf("invalid booking date %q", s)
+	}
+	t, err := time.Parse("2006-01-02", s[:10])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid booking date %q", s)
+	}
+	return bookingDate(t), nil
+}
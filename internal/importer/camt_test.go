@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG1</MsgId><CreDtTm>2026-02-01T08:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT1</Id>
+      <Acct><Id><IBAN>DE02120300000000202051</IBAN></Id><Ccy>EUR</Ccy></Acct>
+      <Ntry>
+        <Amt Ccy="EUR">23.40</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-01-05</Dt></BookgDt>
+        <ValDt><Dt>2026-01-06</Dt></ValDt>
+        <AcctSvcrRef>REF-001</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties><Cdtr><Nm>REWE  Markt GmbH</Nm></Cdtr></RltdPties>
+          <RmtInf><Ustrd>Card payment</Ustrd><Ustrd>05.01. 18:03</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">2500.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><DtTm>2026-01-28T09:30:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls>
+          <Refs><AcctSvcrRef>REF-002</AcctSvcrRef></Refs>
+          <RltdPties><Dbtr><Nm>ACME Corp</Nm></Dbtr></RltdPties>
+          <RmtInf><Ustrd>Salary January</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">60.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-01-29</Dt></BookgDt>
+        <AcctSvcrRef>REF-003</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">40.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Cdtr><Nm>Electricity Ltd</Nm></Cdtr></RltdPties>
+          </TxDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="EUR">20.00</Amt></TxAmt></AmtDtls>
+            <RltdPties><Cdtr><Nm>Water Works</Nm></Cdtr></RltdPties>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">5.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <RvslInd>true</RvslInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2026-01-30</Dt></BookgDt>
+        <AddtlNtryInf>Chargeback</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">1.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2026-01-31</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">1.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+func TestParseCamt(t *testing.T) {
+	rows, err := ParseCamt(strings.NewReader(camt053))
+	require.NoError(t, err)
+	require.Len(t, rows, 6, "pending entries are left out")
+
+	date := func(day int) time.Time { return time.Date(2026, 1, day, 12, 0, 0, 0, time.UTC) }
+	assert.Equal(t, 8, rows[0].Line)
+	assert.Equal(t, models.Expense{Kind: models.KindExpense, Amount: 2340, Currency: "EUR", Description: "REWE Markt GmbH - Card payment 05.01. 18:03", Date: date(5), ImportRef: "camt:DE02120300000000202051:REF-001"}, rows[0].Expense)
+	assert.Equal(t, models.Expense{Kind: models.KindIncome, Amount: 250000, Currency: "EUR", Description: "ACME Corp - Salary January", Date: date(28), ImportRef: "camt:DE02120300000000202051:REF-002"}, rows[1].Expense)
+
+	// A batch is split into its transactions
+	assert.Equal(t, models.Expense{Kind: models.KindExpense, Amount: 4000, Currency: "EUR", Description: "Electricity Ltd", Date: date(29), ImportRef: "camt:DE02120300000000202051:REF-003:1"}, rows[2].Expense)
+	assert.Equal(t, models.Expense{Kind: models.KindExpense, Amount: 2000, Currency: "EUR", Description: "Water Works", Date: date(29), ImportRef: "camt:DE02120300000000202051:REF-003:2"}, rows[3].Expense)
+
+	assert.Equal(t, models.Expense{Kind: models.KindRefund, Amount: 500, Currency: "EUR", Description: "Chargeback", Date: date(30)}, rows[4].Expense)
+	assert.Equal(t, `invalid booking date ""`, rows[5].Error)
+}
+
+func TestParseCamt_Currency(t *testing.T) {
+	const statement = `<Document><BkToCstmrStmt><Stmt>
+  <Acct><Id><IBAN>CH9300762011623852957</IBAN></Id><Ccy>CHF</Ccy></Acct>
+  <Ntry><Amt Ccy="CHF">12.50</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2026-01-05</Dt></BookgDt></Ntry>
+  <Ntry><Amt Ccy="Franken">1.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2026-01-05</Dt></BookgDt></Ntry>
+</Stmt></BkToCstmrStmt></Document>`
+	rows, err := ParseCamt(strings.NewReader(statement))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "CHF", rows[0].Expense.Currency)
+	assert.Equal(t, models.Money(1250), rows[0].Expense.Amount)
+	assert.Contains(t, rows[1].Error, "invalid currency")
+}
+
+func TestParseCamt_NotCamt(t *testing.T) {
+	_, err := ParseCamt(strings.NewReader(`<?xml version="1.0"?><Document><Other/></Document>`))
+	assert.ErrorIs(t, err, ErrNotCamt)
+
+	_, err = ParseCamt(strings.NewReader("Date,Amount\n"))
+	assert.ErrorIs(t, err, ErrNotCamt)
+}
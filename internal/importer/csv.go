@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ParseCSV reads a CSV bank statement laid out as described by the profile.
+// Lines that cannot be read are returned as rows with an Error rather than
+// failing the whole statement. Categories are taken from the category column
+// as is; see Categorize.
+func ParseCSV(r io.Reader, p models.ImportProfile) ([]Row, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	layout, err := models.DateLayout(p.DateFormat, false)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.Comma = rune(p.Delimiter[0])
+	if p.Delimiter == "tab" {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows []Row
+	for n := 0; ; n++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n < p.SkipRows || blank(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line}
+		if err := parseRecord(record, p, layout, &row.Expense); err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseRecord(record []string, p models.ImportProfile, layout string, e *models.Expense) error {
+	field := func(column int) string {
+		if column < 1 || column > len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[column-1])
+	}
+	if columns := max(p.DateColumn, p.AmountColumn, p.DescriptionColumn, p.CreditColumn, p.CategoryColumn); len(record) < columns {
+		return fmt.Errorf("expected at least %d columns, found %d", columns, len(record))
+	}
+
+	date, err := parseDate(field(p.DateColumn), layout)
+	if err != nil {
+		return err
+	}
+	e.Date = bookingDate(date)
+	e.Description = cleanDescription(field(p.DescriptionColumn))
+	e.Category = field(p.CategoryColumn)
+
+	if p.SignConvention == models.SignColumns {
+		debit, err := parseAmount(field(p.AmountColumn), p.DecimalSeparator)
+		if err != nil {
+			return err
+		}
+		credit, err := parseAmount(field(p.CreditColumn), p.DecimalSeparator)
+		if err != nil {
+			return err
+		}
+		switch {
+		case debit != 0 && credit != 0:
+			return errors.New("both debit and credit are set")
+		case debit != 0:
+			e.Kind, e.Amount = models.KindExpense, debit.Abs()
+		case credit != 0:
+			e.Kind, e.Amount = models.KindIncome, credit.Abs()
+		default:
+			return errors.New("amount is zero")
+		}
+		return nil
+	}
+
+	amount, err := parseAmount(field(p.AmountColumn), p.DecimalSeparator)
+	if err != nil {
+		return err
+	}
+	if amount == 0 {
+		return errors.New("amount is zero")
+	}
+	outgoing := amount < 0
+	if p.SignConvention == models.SignPositiveOut {
+		outgoing = amount > 0
+	}
+	switch {
+	case outgoing:
+		e.Kind = models.KindExpense
+	case p.SignConvention == models.SignPositiveOut:
+		e.Kind = models.KindRefund
+	default:
+		e.Kind = models.KindIncome
+	}
+	e.Amount = amount.Abs()
+	return nil
+}
+
+func parseDate(s, layout string) (time.Time, error) {
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// parseAmount reads an amount as banks print it: with thousands separators,
+// currency symbols, a leading or trailing minus sign or in parentheses when
+// negative. An empty string is zero.
+func parseAmount(s, decimalSeparator string) (models.Money, error) {
+	original := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case string(r) == decimalSeparator:
+			digits.WriteByte('.')
+		case r == '-' || r == '\u2212':
+			negative = !negative
+		case r == '.' || r == ',' || r == '\'' || r == '+' || r == ' ' || r == '\u00a0' || r == '\u202f':
+			// Thousands separators and explicit plus signs
+		case r > 127 || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z':
+			// Currency symbols and codes
+		default:
+			return 0, fmt.Errorf("invalid amount %q", original)
+		}
+	}
+	m, err := models.ParseMoney(digits.String())
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", original)
+	}
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+func blank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
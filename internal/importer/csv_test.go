@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input   string
+		decimal string
+		want    models.Money
+	}{
+		{"12.34", ".", 1234},
+		{"-1,234.56", ".", -123456},
+		{"1.234,56", ",", 123456},
+		{"1 234,56 €", ",", 123456},
+		{"€ -7,05", ",", -705},
+		{"7.05-", ".", -705},
+		{"(12.00)", ".", -1200},
+		{"+3", ".", 300},
+		{"EUR 1'000.10", ".", 100010},
+		{"", ".", 0},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.input, tt.decimal)
+		require.NoError(t, err, "input %q", tt.input)
+		assert.Equal(t, tt.want, got, "input %q", tt.input)
+	}
+
+	_, err := parseAmount("12.3.4", ".")
+	assert.Error(t, err)
+	_, err = parseAmount("12#", ".")
+	assert.Error(t, err)
+}
+
+func TestParseCSV_CheckingAccount(t *testing.T) {
+	statement := "\ufeffAccount;DE00 1234\n" +
+		"Booking date;Text;Amount;Category\n" +
+		"05.01.2026;\"REWE  Markt\";-23,40;groceries\n" +
+		"6.1.2026;Salary;2.500,00;\n" +
+		"\n" +
+		"7.1.2026;Broken;abc;\n" +
+		"32.01.2026;Bad date;-1,00;\n"
+	p := models.ImportProfile{
+		Delimiter: ";", SkipRows: 2, DateColumn: 1, DescriptionColumn: 2, AmountColumn: 3, CategoryColumn: 4,
+		DateFormat: "DD.MM.YYYY", DecimalSeparator: ",", SignConvention: models.SignNegativeOut,
+	}
+
+	rows, err := ParseCSV(strings.NewReader(statement), p)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	assert.True(t, rows[0].Valid())
+	assert.Equal(t, 3, rows[0].Line)
+	assert.Equal(t, models.Expense{Kind: models.KindExpense, Amount: 2340, Description: "REWE Markt", Category: "groceries", Date: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)}, rows[0].Expense)
+
+	assert.Equal(t, models.KindIncome, rows[1].Expense.Kind)
+	assert.Equal(t, models.Money(250000), rows[1].Expense.Amount)
+
+	assert.Equal(t, 6, rows[2].Line)
+	assert.Contains(t, rows[2].Error, "invalid amount")
+	assert.Contains(t, rows[3].Error, "invalid date")
+}
+
+func TestParseCSV_SignConventions(t *testing.T) {
+	card := "2026-01-05,Coffee,4.50\n2026-01-06,Return,-20.00\n"
+	p := models.ImportProfile{
+		Delimiter: ",", DateColumn: 1, DescriptionColumn: 2, AmountColumn: 3,
+		DateFormat: "YYYY-MM-DD", DecimalSeparator: ".", SignConvention: models.SignPositiveOut,
+	}
+	rows, err := ParseCSV(strings.NewReader(card), p)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, models.KindExpense, rows[0].Expense.Kind)
+	assert.Equal(t, models.KindRefund, rows[1].Expense.Kind)
+	assert.Equal(t, models.Money(2000), rows[1].Expense.Amount)
+
+	columns := "01/05/2026\tRent\t950.00\t\n01/06/2026\tRefund\t\t12.00\n01/07/2026\tBoth\t1.00\t1.00\n"
+	p = models.ImportProfile{
+		Delimiter: "tab", DateColumn: 1, DescriptionColumn: 2, AmountColumn: 3, CreditColumn: 4,
+		DateFormat: "MM/DD/YYYY", DecimalSeparator: ".", SignConvention: models.SignColumns,
+	}
+	rows, err = ParseCSV(strings.NewReader(columns), p)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, models.KindExpense, rows[0].Expense.Kind)
+	assert.Equal(t, time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC), rows[0].Expense.Date)
+	assert.Equal(t, models.KindIncome, rows[1].Expense.Kind)
+	assert.Equal(t, models.Money(1200), rows[1].Expense.Amount)
+	assert.Equal(t, "both debit and credit are set", rows[2].Error)
+}
+
+func TestParseCSV_InvalidProfile(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader(""), models.ImportProfile{Delimiter: ",", DecimalSeparator: ".", SignConvention: models.SignColumns, DateColumn: 1, AmountColumn: 2, DescriptionColumn: 3, DateFormat: "YYYY-MM-DD"})
+	assert.ErrorContains(t, err, "credit column")
+}
+
+func TestCategorize(t *testing.T) {
+	rows := []Row{
+		{Expense: models.Expense{Description: "Bread", Category: "groceries"}},
+		{Expense: models.Expense{Category: "Unknown"}},
+	}
+	Categorize(rows, []models.Category{{Name: "Groceries"}, {Name: "Other"}}, "Other")
+	assert.Equal(t, "Groceries", rows[0].Expense.Category)
+	assert.Equal(t, "Other", rows[1].Expense.Category)
+	assert.Equal(t, "Other", rows[1].Expense.Description)
+}
@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrNoRates is returned when a file holds no exchange rates.
+var ErrNoRates = errors.New("no exchange rates found")
+
+// ecbBase is the currency the ECB quotes its reference rates against.
+const ecbBase = "EUR"
+
+// ecbDateFormats are the date formats of the ECB's files: the historical
+// files use ISO dates and the daily CSV file spells out the month.
+var ecbDateFormats = []string{"2006-01-02", "02 January 2006", "2 January 2006"}
+
+// ParseECBRates reads the euro foreign exchange reference rates of the
+// European Central Bank, as published in eurofxref-daily.xml,
+// eurofxref-hist.xml and their CSV counterparts. The format is told from
+// the content. Every rate has EUR as its base; missing rates (N/A) are
+// skipped.
+func ParseECBRates(r io.Reader) ([]models.ExchangeRate, error) {
+	br := bufio.NewReader(r)
+	start, err := br.Peek(64)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var rates []models.ExchangeRate
+	if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(start, []byte("\xef\xbb\xbf"))), []byte("<")) {
+		rates, err = parseECBXML(br)
+	} else {
+		rates, err = parseECBCSV(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rates) == 0 {
+		return nil, ErrNoRates
+	}
+	return rates, nil
+}
+
+// ecbCube is a Cube element of the ECB's XML: the outer one wraps the days,
+// which hold one Cube per currency.
+type ecbCube struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+func parseECBXML(r io.Reader) ([]models.ExchangeRate, error) {
+	var envelope struct {
+		Cube ecbCube `xml:"Cube"`
+	}
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("read ECB XML: %w", err)
+	}
+
+	var rates []models.ExchangeRate
+	for _, day := range envelope.Cube.Days {
+		date, err := parseECBDate(day.Time)
+		if err != nil {
+			return nil, err
+		}
+		for _, cube := range day.Rates {
+			rate, err := newECBRate(cube.Currency, date, cube.Rate)
+			if err != nil {
+				return nil, err
+			}
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+func parseECBCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read ECB CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, ErrNoRates
+	}
+
+	header := records[0]
+	if len(header) == 0 || !strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(header[0]), "\ufeff"), "Date") {
+		return nil, errors.New("read ECB CSV: first column must be Date")
+	}
+	var rates []models.ExchangeRate
+	for line, record := range records[1:] {
+		date, err := parseECBDate(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line+2, err)
+		}
+		for i := 1; i < len(record) && i < len(header); i++ {
+			value := strings.TrimSpace(record[i])
+			if value == "" || value == "N/A" || strings.TrimSpace(header[i]) == "" {
+				continue
+			}
+			rate, err := newECBRate(header[i], date, value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line+2, err)
+			}
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+func parseECBDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range ecbDateFormats {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func newECBRate(currency string, date time.Time, value string) (models.ExchangeRate, error) {
+	code, err := models.ParseCurrency(currency)
+	if err != nil {
+		return models.ExchangeRate{}, err
+	}
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || rate <= 0 {
+		return models.ExchangeRate{}, fmt.Errorf("invalid %s rate %q", code, value)
+	}
+	return models.ExchangeRate{Base: ecbBase, Currency: code, Date: date, Rate: rate}, nil
+}
@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ecbXML = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender><gesmes:name>European Central Bank</gesmes:name></gesmes:Sender>
+	<Cube>
+		<Cube time="2024-01-05">
+			<Cube currency="USD" rate="1.0921"/>
+			<Cube currency="JPY" rate="158.39"/>
+		</Cube>
+		<Cube time="2024-01-04">
+			<Cube currency="USD" rate="1.0953"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestParseECBRates_XML(t *testing.T) {
+	rates, err := ParseECBRates(strings.NewReader(ecbXML))
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExchangeRate{
+		{Base: "EUR", Currency: "USD", Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Rate: 1.0921},
+		{Base: "EUR", Currency: "JPY", Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Rate: 158.39},
+		{Base: "EUR", Currency: "USD", Date: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), Rate: 1.0953},
+	}, rates)
+}
+
+func TestParseECBRates_HistoricalCSV(t *testing.T) {
+	csv := "Date,USD,JPY,BGN,\n2024-01-05,1.0921,158.39,N/A,\n2024-01-04,1.0953,,1.9558,\n"
+	rates, err := ParseECBRates(strings.NewReader(csv))
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExchangeRate{
+		{Base: "EUR", Currency: "USD", Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Rate: 1.0921},
+		{Base: "EUR", Currency: "JPY", Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Rate: 158.39},
+		{Base: "EUR", Currency: "USD", Date: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), Rate: 1.0953},
+		{Base: "EUR", Currency: "BGN", Date: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), Rate: 1.9558},
+	}, rates)
+}
+
+func TestParseECBRates_DailyCSV(t *testing.T) {
+	csv := "Date, USD, JPY, \n05 January 2024, 1.0921, 158.39, \n"
+	rates, err := ParseECBRates(strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	assert.Equal(t, "JPY", rates[1].Currency)
+	assert.True(t, rates[1].Date.Equal(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestParseECBRates_Invalid(t *testing.T) {
+	_, err := ParseECBRates(strings.NewReader(""))
+	assert.ErrorIs(t, err, ErrNoRates)
+
+	_, err = ParseECBRates(strings.NewReader("Date,USD\n"))
+	assert.ErrorIs(t, err, ErrNoRates)
+
+	_, err = ParseECBRates(strings.NewReader("Booking,Amount\n2024-01-05,12.00\n"))
+	assert.ErrorContains(t, err, "first column must be Date")
+
+	_, err = ParseECBRates(strings.NewReader("Date,USD\n2024-01-05,abc\n"))
+	assert.ErrorContains(t, err, "line 2: invalid USD rate")
+
+	_, err = ParseECBRates(strings.NewReader("<Envelope><Cube><Cube time=\"yesterday\"/></Cube></Envelope>"))
+	assert.ErrorContains(t, err, "invalid date")
+}
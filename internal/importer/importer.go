@@ -0,0 +1,87 @@
+// Package importer reads bank statements into transactions that can be
+// previewed and then stored with storage.DB.ImportExpenses.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// Row is a transaction read from a statement, or the reason a line of the
+// statement could not be read.
+type Row struct {
+	Line    int            // Line of the statement the row starts on
+	Expense models.Expense // Kind, Amount, Description, Date and, when known, Category
+	Error   string         // Why the line was not read; empty for valid rows
+}
+
+// Valid reports whether the row holds a transaction.
+func (r Row) Valid() bool {
+	return r.Error == ""
+}
+
+// Parse reads a statement in one of the formats that need no column
+// mapping: "ofx" for OFX and QFX, "camt" for camt.053 XML and "mt940" for
+// SWIFT MT940.
+func Parse(format string, r io.Reader) ([]Row, error) {
+	switch format {
+	case "ofx":
+		return ParseOFX(r)
+	case "camt":
+		return ParseCamt(r)
+	case "mt940":
+		return ParseMT940(r)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Categorize assigns a household category to every valid row. A category
+// named in the statement is kept when the household has one of that name,
+// ignoring case; other rows get fallback. Rows without a description are
+// described by their category.
+func Categorize(rows []Row, categories []models.Category, fallback string) {
+	byName := make(map[string]string, len(categories))
+	for _, c := range categories {
+		byName[strings.ToLower(c.Name)] = c.Name
+	}
+	for i := range rows {
+		e := &rows[i].Expense
+		if name, ok := byName[strings.ToLower(strings.TrimSpace(e.Category))]; ok {
+			e.Category = name
+		} else {
+			e.Category = fallback
+		}
+		if e.Description == "" {
+			e.Description = e.Category
+		}
+	}
+}
+
+// bookingDate returns the calendar day of t at noon UTC. Imported
+// transactions carry no time of day, and a fixed time lets the unique index
+// on date, amount and description recognize a statement imported twice.
+func bookingDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, time.UTC)
+}
+
+// cleanDescription collapses the runs of whitespace banks pad descriptions with.
+func cleanDescription(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// joinDescription describes a transaction by its counterparty and the
+// purpose given for it, leaving out whichever is missing.
+func joinDescription(counterparty, purpose string) string {
+	counterparty, purpose = cleanDescription(counterparty), cleanDescription(purpose)
+	switch {
+	case counterparty == "":
+		return purpose
+	case purpose == "" || strings.EqualFold(counterparty, purpose):
+		return counterparty
+	}
+	return counterparty + " - " + purpose
+}
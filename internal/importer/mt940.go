@@ -0,0 +1,256 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrNotMT940 is returned when a file holds no MT940 statement line.
+var ErrNotMT940 = errors.New("not an MT940 statement")
+
+var (
+	mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+	// Value date, entry date, debit/credit mark, funds code, amount,
+	// transaction type and references of a :61: statement line
+	mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})(.*)$`)
+	// Subfields of structured :86: information, e.g. ?20 or ?32
+	mt940Subfield = regexp.MustCompile(`\?(\d{2})`)
+	// Qualifiers SEPA transfers prefix the remittance information with
+	sepaQualifier = regexp.MustCompile(`(EREF|KREF|MREF|CRED|DEBT|COAS|OAMT|SVWZ|ABWA|ABWE|IBAN|BIC)\+`)
+)
+
+// mt940Field is a tagged field of an MT940 message with its continuation lines.
+type mt940Field struct {
+	tag   string
+	value string
+	line  int
+}
+
+// ParseMT940 reads the statement lines of a SWIFT MT940 file, which may hold
+// several statements. Debits become expenses and credits income; reversed
+// debits become refunds. The counterparty and remittance information of the
+// :86: field, structured as German banks do or free text, make up the
+// description. The bank reference of a statement line, qualified by the
+// account, becomes the ImportRef. Amounts are in the currency of the
+// statement's opening balance.
+func ParseMT940(r io.Reader) ([]Row, error) {
+	fields, err := readMT940(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rows     []Row
+		account  string
+		currency string
+		pending  *mt940Field // The :61: line awaiting its :86: information
+		found    bool
+	)
+	flush := func(info string) {
+		if pending != nil {
+			rows = append(rows, mt940Row(*pending, info, account, currency))
+			pending = nil
+		}
+	}
+	for i := range fields {
+		f := &fields[i]
+		switch f.tag {
+		case "25":
+			flush("")
+			account = strings.TrimSpace(f.value)
+		case "60F", "60M":
+			// Debit/credit mark, date, currency and amount, as in C251230EUR1000,00
+			flush("")
+			currency = ""
+			if v := strings.TrimSpace(f.value); len(v) >= 10 {
+				currency = v[7:10]
+			}
+		case "61":
+			flush("")
+			pending = f
+			found = true
+		case "86":
+			flush(f.value)
+		default:
+			flush("")
+		}
+	}
+	flush("")
+	if !found {
+		return nil, ErrNotMT940
+	}
+	return rows, nil
+}
+
+// readMT940 splits a file into its fields. Lines outside fields, such as the
+// SWIFT block headers, and the "-" ending a statement are dropped.
+func readMT940(r io.Reader) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if m := mt940Tag.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: line[len(m[0]):], line: n})
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); trimmed == "-" || trimmed == "-}" || len(fields) == 0 {
+			continue
+		}
+		last := &fields[len(fields)-1]
+		last.value += "\n" + line
+	}
+	return fields, scanner.Err()
+}
+
+func mt940Row(f mt940Field, info, account, currency string) Row {
+	row := Row{Line: f.line}
+	e := &row.Expense
+
+	main, supplementary, _ := strings.Cut(f.value, "\n")
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(main))
+	if m == nil {
+		row.Error = fmt.Sprintf("invalid statement line %q", main)
+		return row
+	}
+	valueDate, entryDate, mark, amount, refs := m[1], m[2], m[3], m[5], m[7]
+
+	date, err := mt940Date(valueDate, entryDate)
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+	e.Date = bookingDate(date)
+
+	// Whole amounts end in the decimal comma, as in 100,
+	if e.Amount, err = parseAmount(strings.TrimSuffix(amount, ","), ","); err != nil {
+		row.Error = err.Error()
+		return row
+	}
+	if e.Amount == 0 {
+		row.Error = "amount is zero"
+		return row
+	}
+	if currency != "" {
+		if e.Currency, err = models.ParseCurrency(currency); err != nil {
+			row.Error = err.Error()
+			return row
+		}
+	}
+	switch mark {
+	case "D", "RC":
+		e.Kind = models.KindExpense
+	case "C":
+		e.Kind = models.KindIncome
+	case "RD":
+		e.Kind = models.KindRefund
+	}
+
+	if _, bankRef, ok := strings.Cut(refs, "//"); ok {
+		if bankRef = strings.TrimSpace(bankRef); bankRef != "" && bankRef != "NONREF" {
+			e.ImportRef = "mt940:" + account + ":" + bankRef
+		}
+	}
+
+	if info == "" {
+		info = supplementary
+	}
+	e.Description = joinDescription(mt940Details(info))
+	return row
+}
+
+// mt940Date returns the entry date of a statement line, or its value date
+// when the entry date is missing. The entry date has no year of its own; it
+// is taken from the value date, which may fall in the next or previous year.
+func mt940Date(valueDate, entryDate string) (time.Time, error) {
+	value, err := time.Parse("060102", valueDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid value date %q", valueDate)
+	}
+	if entryDate == "" {
+		return value, nil
+	}
+	entry, err := time.Parse("0102", entryDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid entry date %q", entryDate)
+	}
+	entry = time.Date(value.Year(), entry.Month(), entry.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case entry.Sub(value) > 180*24*time.Hour:
+		entry = entry.AddDate(-1, 0, 0)
+	case value.Sub(entry) > 180*24*time.Hour:
+		entry = entry.AddDate(1, 0, 0)
+	}
+	return entry, nil
+}
+
+// mt940Details returns the counterparty and the purpose of a transaction from
+// its :86: information. Structured information starts with a three digit
+// transaction code and holds subfields: ?00 the posting text, ?20 to ?29 and
+// ?60 to ?63 the purpose, and ?32 and ?33 the counterparty's name. Anything
+// else is taken as the purpose.
+func mt940Details(info string) (counterparty, purpose string) {
+	info = strings.ReplaceAll(info, "\n", "")
+	if len(info) < 4 || info[3] != '?' || strings.Trim(info[:3], "0123456789") != "" {
+		return "", info
+	}
+
+	var postingText string
+	var name, text strings.Builder
+	cut := false // Whether the last purpose subfield was cut mid-word
+	indexes := mt940Subfield.FindAllStringSubmatchIndex(info, -1)
+	for i, idx := range indexes {
+		end := len(info)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+		code, value := info[idx[2]:idx[3]], info[idx[1]:end]
+		switch {
+		case code == "00":
+			postingText = value
+		case code == "32" || code == "33":
+			name.WriteString(value)
+		case code >= "20" && code <= "29" || code >= "60" && code <= "63":
+			// Subfields hold 27 characters; a full one was cut mid-word
+			if text.Len() > 0 && !cut {
+				text.WriteByte(' ')
+			}
+			text.WriteString(value)
+			cut = len(value) == 27
+		}
+	}
+	purpose = sepaPurpose(text.String())
+	if purpose == "" {
+		purpose = postingText
+	}
+	return name.String(), purpose
+}
+
+// sepaPurpose returns the remittance information of SEPA transfer text such
+// as "EREF+123 SVWZ+Rent January", or the text itself when it has none.
+func sepaPurpose(text string) string {
+	indexes := sepaQualifier.FindAllStringSubmatchIndex(text, -1)
+	if len(indexes) == 0 {
+		return text
+	}
+	for i, idx := range indexes {
+		if text[idx[2]:idx[3]] != "SVWZ" {
+			continue
+		}
+		end := len(text)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+		return text[idx[1]:end]
+	}
+	return ""
+}
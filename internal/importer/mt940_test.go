@@ -0,0 +1,87 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mt940 = `{1:F01BANKDEFFXXXX0000000000}{2:O9400000000000BANKDEFFXXXX00000000000000000000N}{4:
+:20:STARTUMS
+:25:10020030/1234567
+:28C:1/1
+:60F:C251230EUR1000,00
+:61:2512311231D23,40NMSCNONREF//BANK-0001
+:86:106?00KARTENZAHLUNG?20EREF+4711?21SVWZ+REWE SAGT DANKE 123456?22789?32REWE Markt GmbH
+:61:2601020105CR2500,00NTRFNONREF//BANK-0002
+:86:166?00GUTSCHRIFT?20SVWZ+Gehalt Januar?32ACME?33 Corp
+:61:2601070107RD5,00NMSCNONREF
+:86:Chargeback of card
+ payment
+:61:2601080108D0,NMSCNONREF
+:61:260109D1,00
+:62F:C260131EUR3481,60
+-}
+`
+
+func TestParseMT940(t *testing.T) {
+	rows, err := ParseMT940(strings.NewReader(mt940))
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+
+	date := func(year, month, day int) time.Time {
+		return time.Date(year, time.Month(month), day, 12, 0, 0, 0, time.UTC)
+	}
+	assert.Equal(t, 6, rows[0].Line)
+	assert.Equal(t, models.Expense{Kind: models.KindExpense, Amount: 2340, Currency: "EUR", Description: "REWE Markt GmbH - REWE SAGT DANKE 123456789", Date: date(2025, 12, 31), ImportRef: "mt940:10020030/1234567:BANK-0001"}, rows[0].Expense)
+	// The entry date falls in the year after the value date
+	assert.Equal(t, models.Expense{Kind: models.KindIncome, Amount: 250000, Currency: "EUR", Description: "ACME Corp - Gehalt Januar", Date: date(2026, 1, 5), ImportRef: "mt940:10020030/1234567:BANK-0002"}, rows[1].Expense)
+	assert.Equal(t, models.Expense{Kind: models.KindRefund, Amount: 500, Currency: "EUR", Description: "Chargeback of card payment", Date: date(2026, 1, 7)}, rows[2].Expense)
+	assert.Equal(t, "amount is zero", rows[3].Error)
+	assert.Contains(t, rows[4].Error, "invalid statement line")
+}
+
+func TestParseMT940_Currency(t *testing.T) {
+	// Each statement states its currency in the opening balance
+	const statements = `:20:STMT1
+:25:10020030/1234567
+:60F:C251230EUR1000,00
+:61:2512311231D23,40NMSCNONREF
+:62F:C251231EUR976,60
+-
+:20:STMT2
+:25:10020030/7654321
+:60F:C251230USD500,00
+:61:2512311231D10,00NMSCNONREF
+:62F:C251231USD490,00
+-
+`
+	rows, err := ParseMT940(strings.NewReader(statements))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "EUR", rows[0].Expense.Currency)
+	assert.Equal(t, "USD", rows[1].Expense.Currency)
+	assert.Equal(t, models.Money(1000), rows[1].Expense.Amount)
+}
+
+func TestMT940Details(t *testing.T) {
+	// A full subfield continues in the next without a space
+	name, purpose := mt940Details("177?00SEPA-UEBERWEISUNG?20Miete Januar, Wohnung 123 O?21G links?32Hausverwaltung")
+	assert.Equal(t, "Hausverwaltung", name)
+	assert.Equal(t, "Miete Januar, Wohnung 123 OG links", purpose)
+
+	// Without remittance information the posting text is used
+	name, purpose = mt940Details("105?00LASTSCHRIFT?20EREF+123?32Telco")
+	assert.Equal(t, "Telco", name)
+	assert.Equal(t, "LASTSCHRIFT", purpose)
+}
+
+func TestParseMT940_NotMT940(t *testing.T) {
+	_, err := ParseMT940(strings.NewReader("Date,Amount\n"))
+	assert.ErrorIs(t, err, ErrNotMT940)
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrNotOFX is returned when a file has no OFX element.
+var ErrNotOFX = errors.New("not an OFX file")
+
+// ParseOFX reads the STMTTRN records of an OFX or QFX statement. Both OFX 1.x,
+// which is SGML with unclosed elements, and OFX 2.x, which is XML, are read.
+// Negative amounts become expenses; positive amounts become income, or
+// refunds on credit card statements. The FITID of a record, qualified by the
+// account, becomes the ImportRef, so importing the file again adds nothing.
+// Amounts are in the statement's CURDEF currency.
+func ParseOFX(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start < 0 {
+		return nil, ErrNotOFX
+	}
+
+	var (
+		rows       []Row
+		account    string
+		currency   string
+		creditCard bool
+		trn        map[string]string // Elements of the current STMTTRN
+		trnLine    int
+	)
+	for pos := start; ; {
+		open := bytes.IndexByte(data[pos:], '<')
+		if open < 0 {
+			break
+		}
+		open += pos
+		end := bytes.IndexByte(data[open:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated tag on line %d", lineAt(data, open))
+		}
+		end += open
+		tag := strings.ToUpper(strings.TrimSpace(string(data[open+1 : end])))
+		pos = end + 1
+
+		// The text up to the next tag is the value of an element
+		next := bytes.IndexByte(data[pos:], '<')
+		if next < 0 {
+			next = len(data) - pos
+		}
+		value := html.UnescapeString(strings.TrimSpace(string(data[pos : pos+next])))
+
+		switch {
+		case tag == "STMTTRN":
+			trn = make(map[string]string)
+			trnLine = lineAt(data, open)
+		case tag == "/STMTTRN":
+			if trn != nil {
+				rows = append(rows, ofxRow(trn, account, currency, creditCard, trnLine))
+				trn = nil
+			}
+		case tag == "CCSTMTRS":
+			creditCard = true
+		case tag == "STMTRS":
+			creditCard = false
+		case tag == "ACCTID" && trn == nil:
+			account = value
+		case tag == "CURDEF" && trn == nil:
+			currency = value
+		case trn != nil && value != "" && !strings.HasPrefix(tag, "/"):
+			// PAYEE aggregates hold a NAME of their own; the first NAME wins
+			if _, ok := trn[tag]; !ok {
+				trn[tag] = value
+			}
+		}
+	}
+	return rows, nil
+}
+
+func ofxRow(trn map[string]string, account, currency string, creditCard bool, line int) Row {
+	row := Row{Line: line}
+	e := &row.Expense
+
+	date, err := parseOFXDate(trn["DTPOSTED"])
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+	e.Date = bookingDate(date)
+
+	decimal := "."
+	if amount := trn["TRNAMT"]; strings.Contains(amount, ",") && !strings.Contains(amount, ".") {
+		decimal = ","
+	}
+	amount, err := parseAmount(trn["TRNAMT"], decimal)
+	switch {
+	case err != nil:
+		row.Error = err.Error()
+		return row
+	case amount == 0:
+		row.Error = "amount is zero"
+		return row
+	case amount < 0:
+		e.Kind = models.KindExpense
+	case creditCard:
+		e.Kind = models.KindRefund
+	default:
+		e.Kind = models.KindIncome
+	}
+	e.Amount = amount.Abs()
+	if currency != "" {
+		if e.Currency, err = models.ParseCurrency(currency); err != nil {
+			row.Error = err.Error()
+			return row
+		}
+	}
+
+	e.Description = cleanDescription(trn["NAME"])
+	if e.Description == "" {
+		e.Description = cleanDescription(trn["MEMO"])
+	}
+	if fitID := trn["FITID"]; fitID != "" {
+		e.ImportRef = "ofx:" + account + ":" + fitID
+	}
+	return row
+}
+
+// parseOFXDate reads the date of an OFX datetime such as 20260105,
+// 20260105120000 or 20260105120000.000[-5:EST].
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	t, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+func lineAt(data []byte, offset int) int {
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
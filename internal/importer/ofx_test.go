@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ofx1 = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20260201</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>1<STMTRS>
+<CURDEF>EUR
+<BANKACCTFROM><BANKID>12345678<ACCTID>DE001234<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST><DTSTART>20260101<DTEND>20260131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20260105120000.000[-5:EST]
+<TRNAMT>-23.40
+<FITID>2026010501
+<NAME>REWE  Markt
+<MEMO>Card payment
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20260106
+<TRNAMT>2500.00
+<FITID>2026010601
+<MEMO>Salary &amp; bonus
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>OTHER
+<DTPOSTED>2026
+<TRNAMT>1.00
+<FITID>2026010602
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const ofx2 = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <TRNUID>1</TRNUID>
+      <CCSTMTRS>
+        <CURDEF>USD</CURDEF>
+        <CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20260110</DTPOSTED>
+            <TRNAMT>-4.50</TRNAMT>
+            <FITID>T1</FITID>
+            <PAYEE><NAME>Coffee Shop</NAME></PAYEE>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>CREDIT</TRNTYPE>
+            <DTPOSTED>20260112</DTPOSTED>
+            <TRNAMT>20.00</TRNAMT>
+            <FITID>T2</FITID>
+            <NAME>Returned shoes</NAME>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX_SGML(t *testing.T) {
+	rows, err := ParseOFX(strings.NewReader(ofx1))
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, 13, rows[0].Line)
+	assert.Equal(t, models.Expense{
+		Kind: models.KindExpense, Amount: 2340, Currency: "EUR", Description: "REWE Markt",
+		Date: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC), ImportRef: "ofx:DE001234:2026010501",
+	}, rows[0].Expense)
+
+	assert.Equal(t, models.KindIncome, rows[1].Expense.Kind)
+	assert.Equal(t, models.Money(250000), rows[1].Expense.Amount)
+	assert.Equal(t, "Salary & bonus", rows[1].Expense.Description, "MEMO describes records without NAME")
+
+	assert.False(t, rows[2].Valid())
+	assert.Contains(t, rows[2].Error, "invalid date")
+}
+
+func TestParseOFX_XMLCreditCard(t *testing.T) {
+	rows, err := ParseOFX(strings.NewReader(ofx2))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, models.KindExpense, rows[0].Expense.Kind)
+	assert.Equal(t, "Coffee Shop", rows[0].Expense.Description)
+	assert.Equal(t, "ofx:4111:T1", rows[0].Expense.ImportRef)
+	assert.Equal(t, "USD", rows[0].Expense.Currency, "amounts are in the statement's CURDEF")
+
+	assert.Equal(t, models.KindRefund, rows[1].Expense.Kind, "credits on a card are refunds")
+	assert.Equal(t, models.Money(2000), rows[1].Expense.Amount)
+}
+
+func TestParseOFX_NotOFX(t *testing.T) {
+	_, err := ParseOFX(strings.NewReader("Date,Amount\n2026-01-01,1.00\n"))
+	assert.ErrorIs(t, err, ErrNotOFX)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// AccountType tells what kind of money an account holds.
+type AccountType string
+
+const (
+	// AccountCash is money in a wallet.
+	AccountCash AccountType = "cash"
+	// AccountChecking is a current bank account.
+	AccountChecking AccountType = "checking"
+	// AccountCard is a credit card; its balance is usually negative.
+	AccountCard AccountType = "card"
+	// AccountSavings is a savings account.
+	AccountSavings AccountType = "savings"
+)
+
+// AccountTypes lists the account types in display order.
+var AccountTypes = []AccountType{AccountCash, AccountChecking, AccountCard, AccountSavings}
+
+// ParseAccountType validates an account type name. An empty string means
+// AccountChecking.
+func ParseAccountType(s string) (AccountType, error) {
+	switch t := AccountType(s); t {
+	case "":
+		return AccountChecking, nil
+	case AccountCash, AccountChecking, AccountCard, AccountSavings:
+		return t, nil
+	default:
+		return "", fmt.Errorf("invalid account type %q", s)
+	}
+}
+
+// Account is a source of money a household pays from, such as a joint card
+// or a wallet. Its balance is kept in its own currency, starting from
+// OpeningBalance.
+type Account struct {
+	ID             int64       `json:"id"`
+	HouseholdID    int64       `json:"household_id"`
+	Name           string      `json:"name"`
+	Type           AccountType `json:"type"`
+	Currency       string      `json:"currency"`
+	OpeningBalance Money       `json:"opening_balance"`
+	Archived       bool        `json:"archived"`
+}
+
+// Transfer moves money between two accounts of a household. It is neither
+// spending nor income. Amount leaves FromAccountID in its currency and
+// ToAmount arrives at ToAccountID in its currency; both are equal when the
+// accounts share a currency.
+type Transfer struct {
+	ID            int64     `json:"id"`
+	HouseholdID   int64     `json:"household_id"`
+	UserID        *int64    `json:"user_id,omitempty"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        Money     `json:"amount"`
+	ToAmount      Money     `json:"to_amount"`
+	Description   string    `json:"description"`
+	Date          time.Time `json:"date"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the base currency of households that have not chosen one.
+const DefaultCurrency = "EUR"
+
+// ParseCurrency validates an ISO 4217 currency code such as "usd" and returns
+// it in upper case.
+func ParseCurrency(s string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(s))
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency %q", s)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency %q", s)
+		}
+	}
+	return code, nil
+}
+
+// currencySymbols holds the symbols of common currencies. Others are shown
+// by their code.
+var currencySymbols = map[string]string{
+	"EUR": "€",
+	"USD": "$",
+	"GBP": "£",
+	"JPY": "¥",
+	"CNY": "¥",
+	"INR": "₹",
+	"KRW": "₩",
+	"ILS": "₪",
+	"TRY": "₺",
+	"UAH": "₴",
+	"PLN": "zł",
+	"THB": "฿",
+	"VND": "₫",
+	"PHP": "₱",
+}
+
+// CurrencySymbol returns the symbol amounts in the currency are shown with,
+// or the code followed by a space if it has no well-known symbol.
+func CurrencySymbol(code string) string {
+	if symbol, ok := currencySymbols[code]; ok {
+		return symbol
+	}
+	return code + " "
+}
+
+// ExchangeRate is the price of one unit of Base in Currency on a day, e.g.
+// Base EUR, Currency USD and Rate 1.08.
+type ExchangeRate struct {
+	Base     string    `json:"base"`
+	Currency string    `json:"currency"`
+	Date     time.Time `json:"date"` // Midnight UTC of the day
+	Rate     float64   `json:"rate"`
+}
+
+// Day returns midnight UTC of the calendar day of t, the Date of exchange
+// rates for that day.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Rates looks up exchange rates between currencies on given days.
+type Rates struct {
+	pairs map[[2]string][]ExchangeRate // By base and currency, oldest first
+	bases []string
+}
+
+// NewRates indexes rates for lookups.
+func NewRates(rates []ExchangeRate) *Rates {
+	r := &Rates{pairs: make(map[[2]string][]ExchangeRate)}
+	for _, rate := range rates {
+		key := [2]string{rate.Base, rate.Currency}
+		if _, ok := r.pairs[key]; !ok && !containsString(r.bases, rate.Base) {
+			r.bases = append(r.bases, rate.Base)
+		}
+		r.pairs[key] = append(r.pairs[key], rate)
+	}
+	for _, list := range r.pairs {
+		sort.Slice(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+	}
+	sort.Strings(r.bases)
+	return r
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Rate returns how many units of to one unit of from is worth on the day of
+// date. It uses the latest rate on or before that day, or the earliest one
+// after it when there is none. A pair is found as stored, inverted, or
+// crossed through a common base such as EUR for ECB rates. ok is false when
+// no rate connects the currencies.
+func (r *Rates) Rate(from, to string, date time.Time) (rate float64, ok bool) {
+	if from == to {
+		return 1, true
+	}
+	day := Day(date)
+	if rate, ok := r.find(from, to, day); ok {
+		return rate, true
+	}
+	if rate, ok := r.find(to, from, day); ok {
+		return 1 / rate, true
+	}
+	for _, base := range r.bases {
+		fromRate, okFrom := r.find(base, from, day)
+		toRate, okTo := r.find(base, to, day)
+		if okFrom && okTo {
+			return toRate / fromRate, true
+		}
+	}
+	return 0, false
+}
+
+func (r *Rates) find(base, currency string, day time.Time) (float64, bool) {
+	list := r.pairs[[2]string{base, currency}]
+	if len(list) == 0 {
+		return 0, false
+	}
+	// Index of the first rate after day
+	i := sort.Search(len(list), func(i int) bool { return list[i].Date.After(day) })
+	if i == 0 {
+		return list[0].Rate, true
+	}
+	return list[i-1].Rate, true
+}
+
+// Convert converts amount between currencies at the rate of the day of date,
+// rounding to whole cents. Without a rate the amount is returned unchanged
+// and ok is false.
+func (r *Rates) Convert(amount Money, from, to string, date time.Time) (converted Money, ok bool) {
+	rate, ok := r.Rate(from, to, date)
+	if !ok {
+		return amount, false
+	}
+	return Money(math.Round(float64(amount) * rate)), true
+}
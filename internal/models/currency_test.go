@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurrency(t *testing.T) {
+	for input, want := range map[string]string{"EUR": "EUR", "usd": "USD", " gbp ": "GBP"} {
+		got, err := ParseCurrency(input)
+		require.NoError(t, err, "input %q", input)
+		assert.Equal(t, want, got, "input %q", input)
+	}
+	for _, input := range []string{"", "EU", "EURO", "E1R", "€"} {
+		_, err := ParseCurrency(input)
+		assert.Error(t, err, "input %q", input)
+	}
+}
+
+func TestCurrencySymbol(t *testing.T) {
+	assert.Equal(t, "€", CurrencySymbol("EUR"))
+	assert.Equal(t, "$", CurrencySymbol("USD"))
+	assert.Equal(t, "CHF ", CurrencySymbol("CHF"))
+}
+
+func TestRates_Rate(t *testing.T) {
+	rates := NewRates([]ExchangeRate{
+		{Base: "EUR", Currency: "USD", Date: date("2024-01-10 00:00"), Rate: 1.1},
+		{Base: "EUR", Currency: "USD", Date: date("2024-01-05 00:00"), Rate: 1.2},
+		{Base: "EUR", Currency: "GBP", Date: date("2024-01-05 00:00"), Rate: 0.8},
+		{Base: "USD", Currency: "JPY", Date: date("2024-01-05 00:00"), Rate: 150},
+	})
+
+	tests := []struct {
+		from, to string
+		on       string
+		want     float64
+	}{
+		{"EUR", "EUR", "2024-01-07", 1},
+		{"EUR", "USD", "2024-01-07", 1.2},       // Latest on or before the day
+		{"EUR", "USD", "2024-01-10", 1.1},       // Same day
+		{"EUR", "USD", "2024-02-01", 1.1},       // After the last rate
+		{"EUR", "USD", "2023-12-01", 1.2},       // Before the first rate
+		{"USD", "EUR", "2024-01-07", 1 / 1.2},   // Inverted
+		{"GBP", "USD", "2024-01-07", 1.2 / 0.8}, // Crossed through EUR
+	}
+	for _, tt := range tests {
+		got, ok := rates.Rate(tt.from, tt.to, date(tt.on+" 00:00"))
+		require.True(t, ok, "%s to %s on %s", tt.from, tt.to, tt.on)
+		assert.InDelta(t, tt.want, got, 1e-9, "%s to %s on %s", tt.from, tt.to, tt.on)
+	}
+
+	_, ok := rates.Rate("EUR", "CHF", date("2024-01-07 00:00"))
+	assert.False(t, ok)
+}
+
+func TestRates_Convert(t *testing.T) {
+	rates := NewRates([]ExchangeRate{{Base: "EUR", Currency: "USD", Date: date("2024-01-05 00:00"), Rate: 1.0837}})
+
+	got, ok := rates.Convert(1000, "USD", "EUR", date("2024-01-05 00:00"))
+	assert.True(t, ok)
+	assert.Equal(t, Money(923), got) // 9.2276 rounded to the cent
+
+	got, ok = rates.Convert(1000, "CHF", "EUR", date("2024-01-05 00:00"))
+	assert.False(t, ok)
+	assert.Equal(t, Money(1000), got)
+}
@@ -1,15 +1,76 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
-// Expense represents a financial expense record.
+// ExpenseKind tells whether a transaction is money going out or coming in.
+type ExpenseKind string
+
+const (
+	// KindExpense is regular spending.
+	KindExpense ExpenseKind = "expense"
+	// KindIncome is money received, such as salary.
+	KindIncome ExpenseKind = "income"
+	// KindRefund is money returned for earlier spending; it reduces spending.
+	KindRefund ExpenseKind = "refund"
+)
+
+// ParseExpenseKind validates a kind name. An empty string means KindExpense.
+func ParseExpenseKind(s string) (ExpenseKind, error) {
+	switch k := ExpenseKind(s); k {
+	case "":
+		return KindExpense, nil
+	case KindExpense, KindIncome, KindRefund:
+		return k, nil
+	default:
+		return "", fmt.Errorf("invalid transaction kind %q", s)
+	}
+}
+
+// Expense represents a financial transaction record. Despite the name it also
+// holds income and refunds, told apart by Kind. Amount is always positive and
+// in the household's base currency; OriginalAmount is the amount as paid, in
+// Currency. A split expense spreads its amount over several categories; its
+// Category is that of the first part.
 type Expense struct {
-	ID          int64     `json:"id"`
-	Amount      float64   `json:"amount"`
-	Description string    `json:"description"`
-	Category    string    `json:"category"`
-	Date        time.Time `json:"date"`
-	UserID      *int64    `json:"user_id,omitempty"`
+	ID             int64       `json:"id"`
+	Kind           ExpenseKind `json:"kind"`
+	Amount         Money       `json:"amount"`
+	Currency       string      `json:"currency"`
+	OriginalAmount Money       `json:"original_amount"`
+	Description    string      `json:"description"`
+	Category       string      `json:"category"`
+	Date           time.Time   `json:"date"`
+	UserID         *int64      `json:"user_id,omitempty"`
+	HouseholdID    int64       `json:"household_id"`
+	AccountID      *int64      `json:"account_id,omitempty"`   // Account paid from or into
+	RecurringID    *int64      `json:"recurring_id,omitempty"` // Template the transaction was created from
+	ImportRef      string      `json:"import_ref,omitempty"`   // Bank's reference of an imported transaction, unique per household
+	Splits         []Split     `json:"splits,omitempty"`       // Parts by category, adding up to OriginalAmount
+	Sharing        *Sharing    `json:"sharing,omitempty"`      // Members the expense is shared with
+	Tags           []string    `json:"tags,omitempty"`         // Normalized as by NormalizeTag
+}
+
+// Split is the part of a split expense that belongs to one category. Amount
+// is in the expense's Currency.
+type Split struct {
+	Category string `json:"category"`
+	Amount   Money  `json:"amount"`
+}
+
+// Spending returns how much the transaction adds to spending: the amount for
+// expenses, minus the amount for refunds and zero for income.
+func (e Expense) Spending() Money {
+	switch e.Kind {
+	case KindIncome:
+		return 0
+	case KindRefund:
+		return -e.Amount
+	default:
+		return e.Amount
+	}
 }
 
 // User represents a user account.
@@ -20,6 +81,79 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// Household is a shared ledger. Expenses belong to a household and are
+// visible to all of its members.
+type Household struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Currency  string    `json:"currency"` // Base currency totals are shown in
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Category is a household's label for transactions. Expenses refer to their
+// category by name. A category may have a parent, one level deep.
+type Category struct {
+	ID          int64  `json:"id"`
+	HouseholdID int64  `json:"household_id"`
+	ParentID    *int64 `json:"parent_id,omitempty"`
+	Name        string `json:"name"`
+	Icon        string `json:"icon"`
+	Color       string `json:"color"`
+	Position    int    `json:"position"`
+	Archived    bool   `json:"archived"`
+	Account     string `json:"account,omitempty"` // Account in accounting exports, e.g. Expenses:Groceries
+}
+
+// Budget is the amount a household plans to spend in a category per month.
+// It applies from Year/Month until a later budget for the same category
+// replaces it; an Amount of zero ends it. With Rollover, the unspent part of
+// a month is added to the next month's budget.
+type Budget struct {
+	ID          int64 `json:"id"`
+	HouseholdID int64 `json:"household_id"`
+	CategoryID  int64 `json:"category_id"`
+	Year        int   `json:"year"`
+	Month       int   `json:"month"`
+	Amount      Money `json:"amount"`
+	Rollover    bool  `json:"rollover"`
+}
+
+// TokenScope limits what an API token may do.
+type TokenScope string
+
+const (
+	// ScopeRead allows reading data only.
+	ScopeRead TokenScope = "read"
+	// ScopeReadWrite allows reading and changing data.
+	ScopeReadWrite TokenScope = "read-write"
+)
+
+// ParseTokenScope validates a token scope name.
+func ParseTokenScope(s string) (TokenScope, error) {
+	switch scope := TokenScope(s); scope {
+	case ScopeRead, ScopeReadWrite:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("invalid token scope %q", s)
+	}
+}
+
+// APIToken is a credential for the JSON API. Only a hash of the token is
+// stored; the token itself is shown once when it is created.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Scope      TokenScope `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CanWrite reports whether the token may change data.
+func (t APIToken) CanWrite() bool {
+	return t.Scope == ScopeReadWrite
+}
+
 // Session represents a user session.
 type Session struct {
 	Token     string    `json:"token"`
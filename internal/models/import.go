@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SignConvention tells how a bank statement marks money going out and coming in.
+type SignConvention string
+
+const (
+	// SignNegativeOut is the usual checking account statement: negative
+	// amounts are expenses and positive amounts income.
+	SignNegativeOut SignConvention = "negative"
+	// SignPositiveOut is the usual credit card statement: positive amounts
+	// are expenses and negative amounts refunds.
+	SignPositiveOut SignConvention = "positive"
+	// SignColumns has separate debit and credit columns; debits are expenses
+	// and credits income.
+	SignColumns SignConvention = "columns"
+)
+
+// ImportProfile describes the layout of a bank's CSV statements so they can be
+// imported again without mapping the columns each time. Column numbers start
+// at 1; 0 means the column is absent.
+type ImportProfile struct {
+	ID                int64          `json:"id"`
+	HouseholdID       int64          `json:"household_id"`
+	Name              string         `json:"name"`
+	Delimiter         string         `json:"delimiter"` // ",", ";", "|" or "tab"
+	SkipRows          int            `json:"skip_rows"` // Lines before the first transaction, header included
+	DateColumn        int            `json:"date_column"`
+	AmountColumn      int            `json:"amount_column"` // The debit column with SignColumns
+	CreditColumn      int            `json:"credit_column"` // SignColumns only
+	DescriptionColumn int            `json:"description_column"`
+	CategoryColumn    int            `json:"category_column"`
+	DateFormat        string         `json:"date_format"`       // Pattern made of YYYY, YY, MM and DD
+	DecimalSeparator  string         `json:"decimal_separator"` // "." or ","
+	SignConvention    SignConvention `json:"sign_convention"`
+	DefaultCategory   string         `json:"default_category"` // For rows without a known category
+}
+
+// Validate checks that the profile describes a usable layout.
+func (p ImportProfile) Validate() error {
+	switch p.Delimiter {
+	case ",", ";", "|", "tab":
+	default:
+		return fmt.Errorf("invalid delimiter %q", p.Delimiter)
+	}
+	switch p.DecimalSeparator {
+	case ".", ",":
+	default:
+		return fmt.Errorf("invalid decimal separator %q", p.DecimalSeparator)
+	}
+	switch p.SignConvention {
+	case SignNegativeOut, SignPositiveOut:
+	case SignColumns:
+		if p.CreditColumn < 1 {
+			return errors.New("the credit column is required with separate debit and credit columns")
+		}
+	default:
+		return fmt.Errorf("invalid sign convention %q", p.SignConvention)
+	}
+	if p.DateColumn < 1 || p.AmountColumn < 1 || p.DescriptionColumn < 1 {
+		return errors.New("the date, amount and description columns are required")
+	}
+	if p.SkipRows < 0 || p.CategoryColumn < 0 || p.CreditColumn < 0 {
+		return errors.New("row and column numbers must not be negative")
+	}
+	if _, err := DateLayout(p.DateFormat, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DateLayout converts a date pattern made of YYYY, YY, MM and DD, separated
+// by "-", ".", "/" or spaces, to a time layout. With padded, days and months
+// are formatted with two digits; without, they are parsed with one or two
+// unless the pattern has no separators.
+func DateLayout(pattern string, padded bool) (string, error) {
+	day, month := "2", "1"
+	if padded || !strings.ContainsAny(pattern, "-./ ") {
+		day, month = "02", "01"
+	}
+	var layout strings.Builder
+	for rest := strings.ToUpper(pattern); rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, "YYYY"):
+			layout.WriteString("2006")
+			rest = rest[4:]
+		case strings.HasPrefix(rest, "YY"):
+			layout.WriteString("06")
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "MM"):
+			layout.WriteString(month)
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "DD"):
+			layout.WriteString(day)
+			rest = rest[2:]
+		case strings.ContainsRune("-./ ", rune(rest[0])):
+			layout.WriteByte(rest[0])
+			rest = rest[1:]
+		default:
+			return "", fmt.Errorf("invalid date format %q: use YYYY, YY, MM, DD and - . / or space", pattern)
+		}
+	}
+	return layout.String(), nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in minor currency units (cents).
+type Money int64
+
+// ErrInvalidMoney is returned when a string is not a valid decimal amount.
+var ErrInvalidMoney = errors.New("invalid amount")
+
+// moneyScale is the number of minor units in one major unit.
+const moneyScale = 100
+
+// ParseMoney parses a decimal amount such as "12", "12.3", "-12.34" or "12,34"
+// without going through floating point. At most two fraction digits are allowed.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if hasFrac && (frac == "" || len(frac) > 2) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > math.MaxInt64/moneyScale-1 {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidMoney, s)
+	}
+	cents := int64(0)
+	if frac != "" {
+		frac += strings.Repeat("0", 2-len(frac))
+		cents, _ = strconv.ParseInt(frac, 10, 64)
+	}
+
+	m := Money(units*moneyScale + cents)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount with two decimals, e.g. "-12.30".
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/moneyScale, v%moneyScale)
+}
+
+// Rounded returns the amount rounded to whole major units.
+func (m Money) Rounded() int64 {
+	return int64(math.Round(m.Float64()))
+}
+
+// Float64 returns the amount in major units. Use it only for ratios and
+// display scaling, never for arithmetic that is stored again.
+func (m Money) Float64() float64 {
+	return float64(m) / moneyScale
+}
+
+// Abs returns the absolute value of m.
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// Div divides m by n, rounding half away from zero.
+func (m Money) Div(n int) Money {
+	if n == 0 {
+		return 0
+	}
+	return Money(math.Round(float64(m) / float64(n)))
+}
+
+// MarshalJSON encodes the amount as a JSON number with two decimals.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or string and parses it exactly.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
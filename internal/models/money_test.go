@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+	}{
+		{"0", 0},
+		{"12", 1200},
+		{"12.3", 1230},
+		{"12.34", 1234},
+		{"0.1", 10},
+		{".5", 50},
+		{"12,34", 1234},
+		{"-7.05", -705},
+		{"+3", 300},
+		{" 19.99 ", 1999},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.input)
+		require.NoError(t, err, "input %q", tt.input)
+		assert.Equal(t, tt.want, got, "input %q", tt.input)
+	}
+}
+
+func TestParseMoney_Invalid(t *testing.T) {
+	for _, input := range []string{"", "-", "abc", "1.234", "1.", "1.2.3", "1e3", "12a", "99999999999999999999"} {
+		_, err := ParseMoney(input)
+		assert.ErrorIs(t, err, ErrInvalidMoney, "input %q", input)
+	}
+}
+
+func TestParseMoney_NoFloatDrift(t *testing.T) {
+	// 0.1 + 0.2 != 0.3 in float64, but must be exact here
+	a, _ := ParseMoney("0.1")
+	b, _ := ParseMoney("0.2")
+	c, _ := ParseMoney("0.3")
+	assert.Equal(t, c, a+b)
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "0.00", Money(0).String())
+	assert.Equal(t, "12.30", Money(1230).String())
+	assert.Equal(t, "-0.05", Money(-5).String())
+	assert.Equal(t, "-1234.56", Money(-123456).String())
+}
+
+func TestMoney_RoundedAndDiv(t *testing.T) {
+	assert.Equal(t, int64(13), Money(1250).Rounded())
+	assert.Equal(t, int64(12), Money(1249).Rounded())
+	assert.Equal(t, Money(333), Money(1000).Div(3))
+	assert.Equal(t, Money(0), Money(1000).Div(0))
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(Expense{Amount: 1999})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"amount":19.99`)
+
+	var e Expense
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":0.3}`), &e))
+	assert.Equal(t, Money(30), e.Amount)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"12.50"}`), &e))
+	assert.Equal(t, Money(1250), e.Amount)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":1.005}`), &e))
+}
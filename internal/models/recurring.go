@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Frequency is the unit in which a recurring transaction repeats.
+type Frequency string
+
+const (
+	// FrequencyDaily repeats every Interval days.
+	FrequencyDaily Frequency = "daily"
+	// FrequencyWeekly repeats every Interval weeks on the start date's weekday.
+	FrequencyWeekly Frequency = "weekly"
+	// FrequencyMonthly repeats every Interval months on Day.
+	FrequencyMonthly Frequency = "monthly"
+	// FrequencyYearly repeats every Interval years on the start date's day.
+	FrequencyYearly Frequency = "yearly"
+)
+
+// ParseFrequency validates a frequency name.
+func ParseFrequency(s string) (Frequency, error) {
+	switch f := Frequency(s); f {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid frequency %q", s)
+	}
+}
+
+// Recurring is a template for a transaction that repeats, such as rent or a
+// subscription. Its occurrences become expenses created on behalf of UserID.
+type Recurring struct {
+	ID          int64       `json:"id"`
+	HouseholdID int64       `json:"household_id"`
+	UserID      int64       `json:"user_id"`
+	Kind        ExpenseKind `json:"kind"`
+	Amount      Money       `json:"amount"`
+	Description string      `json:"description"`
+	Category    string      `json:"category"`
+	Frequency   Frequency   `json:"frequency"`
+	Interval    int         `json:"interval"` // Repeat every Interval units, at least 1
+	Day         int         `json:"day"`      // Day of the month for monthly templates; 0 means the start date's day
+	StartDate   time.Time   `json:"start_date"`
+	EndDate     *time.Time  `json:"end_date,omitempty"` // Last day on which occurrences may fall
+	NextDate    time.Time   `json:"next_date"`          // Next occurrence not yet created
+}
+
+// First returns the first occurrence on or after the start date.
+func (r Recurring) First() time.Time {
+	if r.Frequency != FrequencyMonthly {
+		return r.StartDate
+	}
+	first := r.inMonth(r.StartDate.Year(), r.StartDate.Month())
+	if first.Before(r.StartDate) {
+		first = r.inMonth(r.StartDate.Year(), r.StartDate.Month()+1)
+	}
+	return first
+}
+
+// After returns the occurrence following the occurrence t.
+func (r Recurring) After(t time.Time) time.Time {
+	n := max(r.Interval, 1)
+	switch r.Frequency {
+	case FrequencyWeekly:
+		return t.AddDate(0, 0, 7*n)
+	case FrequencyMonthly:
+		return r.inMonth(t.Year(), t.Month()+time.Month(n))
+	case FrequencyYearly:
+		year := t.Year() + n
+		return clampDay(year, r.StartDate.Month(), r.StartDate.Day(), r.StartDate)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// Ended reports whether the occurrence t falls after the end date.
+func (r Recurring) Ended(t time.Time) bool {
+	if r.EndDate == nil {
+		return false
+	}
+	end := time.Date(r.EndDate.Year(), r.EndDate.Month(), r.EndDate.Day()+1, 0, 0, 0, 0, t.Location())
+	return !t.Before(end)
+}
+
+// Occurrences returns the occurrences from the next one up to and including
+// until, stopping at the end date.
+func (r Recurring) Occurrences(until time.Time) []time.Time {
+	var dates []time.Time
+	for t := r.NextDate; !t.After(until) && !r.Ended(t); t = r.After(t) {
+		dates = append(dates, t)
+	}
+	return dates
+}
+
+// Expense returns the transaction for the occurrence on date.
+func (r Recurring) Expense(date time.Time) Expense {
+	id := r.ID
+	return Expense{
+		Kind:        r.Kind,
+		Amount:      r.Amount,
+		Description: r.Description,
+		Category:    r.Category,
+		Date:        date,
+		RecurringID: &id,
+	}
+}
+
+// inMonth returns the monthly occurrence in the given month. Days past the
+// end of the month fall on its last day.
+func (r Recurring) inMonth(year int, month time.Month) time.Time {
+	day := r.Day
+	if day == 0 {
+		day = r.StartDate.Day()
+	}
+	return clampDay(year, month, day, r.StartDate)
+}
+
+// clampDay returns the date with the time of day of clock, moving days past
+// the end of the month to its last day. Month may be out of range.
+func clampDay(year int, month time.Month, day int, clock time.Time) time.Time {
+	first := time.Date(year, month, 1, clock.Hour(), clock.Minute(), clock.Second(), 0, clock.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, last)-1)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRecurring_Occurrences(t *testing.T) {
+	tests := []struct {
+		name  string
+		r     Recurring
+		until string
+		want  []string
+	}{
+		{
+			name:  "monthly on day 31 falls on the last day of short months",
+			r:     Recurring{Frequency: FrequencyMonthly, Day: 31, StartDate: date("2026-01-15 09:00")},
+			until: "2026-04-30 23:59",
+			want:  []string{"2026-01-31 09:00", "2026-02-28 09:00", "2026-03-31 09:00", "2026-04-30 09:00"},
+		},
+		{
+			name:  "monthly day before the start date begins next month",
+			r:     Recurring{Frequency: FrequencyMonthly, Day: 1, StartDate: date("2026-01-15 09:00")},
+			until: "2026-03-01 09:00",
+			want:  []string{"2026-02-01 09:00", "2026-03-01 09:00"},
+		},
+		{
+			name:  "weekly",
+			r:     Recurring{Frequency: FrequencyWeekly, StartDate: date("2026-01-05 08:00")},
+			until: "2026-01-20 00:00",
+			want:  []string{"2026-01-05 08:00", "2026-01-12 08:00", "2026-01-19 08:00"},
+		},
+		{
+			name:  "every 10 days",
+			r:     Recurring{Frequency: FrequencyDaily, Interval: 10, StartDate: date("2026-01-01 12:00")},
+			until: "2026-01-31 12:00",
+			want:  []string{"2026-01-01 12:00", "2026-01-11 12:00", "2026-01-21 12:00", "2026-01-31 12:00"},
+		},
+		{
+			name:  "yearly on 29 February",
+			r:     Recurring{Frequency: FrequencyYearly, StartDate: date("2024-02-29 10:00")},
+			until: "2028-12-31 00:00",
+			want:  []string{"2024-02-29 10:00", "2025-02-28 10:00", "2026-02-28 10:00", "2027-02-28 10:00", "2028-02-29 10:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.r.NextDate = tt.r.First()
+			var got []string
+			for _, d := range tt.r.Occurrences(date(tt.until)) {
+				got = append(got, d.Format("2006-01-02 15:04"))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRecurring_EndDate(t *testing.T) {
+	end := date("2026-03-10 00:00")
+	r := Recurring{Frequency: FrequencyMonthly, Day: 10, StartDate: date("2026-01-10 18:00"), EndDate: &end}
+	r.NextDate = r.First()
+
+	got := r.Occurrences(date("2026-12-31 00:00"))
+	assert.Len(t, got, 3, "an occurrence on the end date is included")
+	assert.True(t, r.Ended(date("2026-04-10 18:00")))
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ShareMethod tells how a shared expense is divided between members.
+type ShareMethod string
+
+const (
+	// ShareEqual divides the expense equally.
+	ShareEqual ShareMethod = "equal"
+	// SharePercent gives each member a percentage of the expense.
+	SharePercent ShareMethod = "percent"
+	// ShareExact gives each member an exact amount.
+	ShareExact ShareMethod = "exact"
+)
+
+// ParseShareMethod validates a share method name. An empty string means
+// ShareEqual.
+func ParseShareMethod(s string) (ShareMethod, error) {
+	switch m := ShareMethod(s); m {
+	case "":
+		return ShareEqual, nil
+	case ShareEqual, SharePercent, ShareExact:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid share method %q", s)
+	}
+}
+
+// Sharing marks an expense as shared between members of its household. The
+// member who recorded the expense paid it, and every member in Shares owes
+// their part of it, the payer included when listed.
+type Sharing struct {
+	Method ShareMethod `json:"method"`
+	Shares []Share     `json:"shares"`
+}
+
+// Share is a member's part of a shared expense. Percent is set for
+// SharePercent and Amount, in the expense's Currency, for ShareExact.
+type Share struct {
+	UserID  int64   `json:"user_id"`
+	Percent float64 `json:"percent,omitempty"`
+	Amount  Money   `json:"amount,omitempty"`
+}
+
+// Weight returns the weight of the share when dividing an expense with the
+// given method: one for ShareEqual, the percentage in hundredths for
+// SharePercent and the amount for ShareExact.
+func (s Share) Weight(method ShareMethod) int64 {
+	switch method {
+	case SharePercent:
+		return int64(math.Round(s.Percent * 100))
+	case ShareExact:
+		return int64(s.Amount)
+	default:
+		return 1
+	}
+}
+
+// Allocate divides total in proportion to weights, which must be positive.
+// The parts add up to total exactly: the cents left over by rounding down go
+// to the parts with the largest remainders, earlier ones first.
+func Allocate(total Money, weights []int64) []Money {
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	parts := make([]Money, len(weights))
+	if sum == 0 {
+		return parts
+	}
+
+	remainders := make([]int64, len(weights))
+	left := int64(total)
+	for i, w := range weights {
+		parts[i] = Money(int64(total) * w / sum)
+		remainders[i] = int64(total) * w % sum
+		left -= int64(parts[i])
+	}
+	for ; left > 0; left-- {
+		largest := 0
+		for i := range remainders {
+			if remainders[i] > remainders[largest] {
+				largest = i
+			}
+		}
+		parts[largest]++
+		remainders[largest] = -1
+	}
+	return parts
+}
+
+// Settlement is a payment from one member of a household to another that
+// evens out what they owe each other.
+type Settlement struct {
+	ID          int64     `json:"id"`
+	HouseholdID int64     `json:"household_id"`
+	FromUserID  int64     `json:"from_user_id"`
+	ToUserID    int64     `json:"to_user_id"`
+	Amount      Money     `json:"amount"`
+	Date        time.Time `json:"date"`
+}
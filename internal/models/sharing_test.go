@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		total   Money
+		weights []int64
+		want    []Money
+	}{
+		{1000, []int64{1, 1}, []Money{500, 500}},
+		{1000, []int64{1, 1, 1}, []Money{334, 333, 333}},
+		{1001, []int64{1, 1}, []Money{501, 500}},
+		{1000, []int64{6000, 4000}, []Money{600, 400}},
+		{999, []int64{3333, 3333, 3334}, []Money{333, 333, 333}},
+		{1250, []int64{250, 1000}, []Money{250, 1000}},
+		{800, []int64{250, 1000}, []Money{160, 640}},
+		{5, []int64{1, 2, 2}, []Money{1, 2, 2}},
+		{0, []int64{1, 1}, []Money{0, 0}},
+	}
+
+	for _, tt := range tests {
+		got := Allocate(tt.total, tt.weights)
+		assert.Equal(t, tt.want, got, "%s by %v", tt.total, tt.weights)
+		var sum Money
+		for _, part := range got {
+			sum += part
+		}
+		assert.Equal(t, tt.total, sum, "%s by %v", tt.total, tt.weights)
+	}
+}
+
+func TestShareWeight(t *testing.T) {
+	share := Share{UserID: 1, Percent: 33.33, Amount: 1250}
+	assert.Equal(t, int64(1), share.Weight(ShareEqual))
+	assert.Equal(t, int64(3333), share.Weight(SharePercent))
+	assert.Equal(t, int64(1250), share.Weight(ShareExact))
+}
+
+func TestParseShareMethod(t *testing.T) {
+	method, err := ParseShareMethod("")
+	require.NoError(t, err)
+	assert.Equal(t, ShareEqual, method)
+
+	method, err = ParseShareMethod("percent")
+	require.NoError(t, err)
+	assert.Equal(t, SharePercent, method)
+
+	_, err = ParseShareMethod("shares")
+	assert.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTagLength is the longest tag allowed, in characters.
+const MaxTagLength = 40
+
+// NormalizeTag returns a tag in lower case and without a leading #. Tags are
+// made of letters, digits, '-' and '_', e.g. vacation-2026, and hold at least
+// one letter so that numbers such as #123 are not taken for tags.
+func NormalizeTag(s string) (string, error) {
+	tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if tag == "" {
+		return "", fmt.Errorf("empty tag")
+	}
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+	}
+	letters := false
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", fmt.Errorf("invalid tag %q: use letters, digits, - and _", tag)
+		}
+		letters = letters || unicode.IsLetter(r)
+	}
+	if !letters {
+		return "", fmt.Errorf("invalid tag %q: use at least one letter", tag)
+	}
+	return tag, nil
+}
+
+// ParseTags takes the #tags out of a description. It returns them normalized
+// and without repeats, in order, and the rest of the description with single
+// spaces between words. Words starting with # that are not valid tags, such
+// as #123, stay in the description.
+func ParseTags(description string) (tags []string, rest string) {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(description) {
+		if len(word) > 1 && word[0] == '#' {
+			if tag, err := NormalizeTag(word); err == nil {
+				if !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+				}
+				continue
+			}
+		}
+		words = append(words, word)
+	}
+	return tags, strings.Join(words, " ")
+}
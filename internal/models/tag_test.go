@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	for input, want := range map[string]string{
+		"vacation-2026": "vacation-2026",
+		"#Business":     "business",
+		" reimbursable": "reimbursable",
+		"Çà_va":         "çà_va",
+	} {
+		tag, err := NormalizeTag(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, tag)
+	}
+
+	for _, input := range []string{"", "#", "two words", "a,b", "#a#b", "123", "#2026-01", strings.Repeat("x", MaxTagLength+1)} {
+		_, err := NormalizeTag(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags, rest := ParseTags("Hotel  #vacation-2026 in Rome #Business #vacation-2026")
+	assert.Equal(t, []string{"vacation-2026", "business"}, tags)
+	assert.Equal(t, "Hotel in Rome", rest)
+
+	tags, rest = ParseTags("Room #101, floor # 2")
+	assert.Empty(t, tags)
+	assert.Equal(t, "Room #101, floor # 2", rest)
+
+	tags, rest = ParseTags("Invoice #123 #business")
+	assert.Equal(t, []string{"business"}, tags)
+	assert.Equal(t, "Invoice #123", rest, "numbers are not tags")
+
+	tags, rest = ParseTags("#reimbursable")
+	assert.Equal(t, []string{"reimbursable"}, tags)
+	assert.Empty(t, rest)
+}
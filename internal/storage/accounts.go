@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrAccountExists is returned when a household already has an account with the same name.
+var ErrAccountExists = errors.New("account already exists")
+
+// ErrInvalidAccount is returned when an expense or transfer refers to an
+// account that does not belong to its household, or a transfer has the same
+// account on both sides.
+var ErrInvalidAccount = errors.New("invalid account")
+
+const accountColumns = "id, household_id, name, type, currency, opening_balance, archived"
+
+func scanAccount(row interface{ Scan(...any) error }, a *models.Account) error {
+	return row.Scan(&a.ID, &a.HouseholdID, &a.Name, &a.Type, &a.Currency, &a.OpeningBalance, &a.Archived)
+}
+
+// AccountBalance is an account with its current balance, in the account's
+// currency.
+type AccountBalance struct {
+	models.Account
+	Balance models.Money
+}
+
+// LedgerEntry is a transaction or transfer as it affects an account. Amount
+// is in the account's currency: positive for money coming in and negative
+// for money going out. Balance is the account's balance after the entry.
+type LedgerEntry struct {
+	Date        time.Time
+	Description string
+	Category    string // Empty for transfers
+	ExpenseID   int64  // Zero for transfers
+	TransferID  int64  // Zero for transactions
+	Counterpart string // Other account of a transfer
+	Amount      models.Money
+	Balance     models.Money
+}
+
+// checkAccount returns ErrInvalidAccount unless accountID is nil or an
+// account of the household.
+func checkAccount(ctx context.Context, q querier, householdID int64, accountID *int64) error {
+	if accountID == nil {
+		return nil
+	}
+	var count int
+	if err := q.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM accounts WHERE id = ? AND household_id = ?",
+		*accountID, householdID,
+	).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrInvalidAccount
+	}
+	return nil
+}
+
+// ListAccounts returns the accounts of a household by name. Archived accounts
+// are only included if includeArchived is set.
+func (db *DB) ListAccounts(ctx context.Context, householdID int64, includeArchived bool) ([]models.Account, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.listAccounts(ctx, householdID, includeArchived)
+}
+
+func (db *DB) listAccounts(ctx context.Context, householdID int64, includeArchived bool) ([]models.Account, error) {
+	query := "SELECT " + accountColumns + " FROM accounts WHERE household_id = ?"
+	if !includeArchived {
+		query += " AND NOT archived"
+	}
+	rows, err := db.read.QueryContext(ctx, query+" ORDER BY name", householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []models.Account
+	for rows.Next() {
+		var a models.Account
+		if err := scanAccount(rows, &a); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// GetAccount retrieves an account of a household by ID.
+// It returns ErrNotFound if the account does not exist in that household.
+func (db *DB) GetAccount(ctx context.Context, householdID, id int64) (*models.Account, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var a models.Account
+	err := scanAccount(db.read.QueryRowContext(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE id = ? AND household_id = ?",
+		id, householdID,
+	), &a)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateAccount adds an account to a household and sets a.ID. An empty Type
+// is stored as a checking account and an empty Currency as the household's
+// base currency. It returns ErrAccountExists if the name is taken.
+func (db *DB) CreateAccount(ctx context.Context, a *models.Account) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if a.Type == "" {
+		a.Type = models.AccountChecking
+	}
+	if a.Currency == "" {
+		base, err := householdCurrency(ctx, db.read, a.HouseholdID)
+		if err != nil {
+			return err
+		}
+		a.Currency = base
+	}
+	err := db.conn.QueryRowContext(ctx,
+		"INSERT INTO accounts (household_id, name, type, currency, opening_balance, archived) VALUES (?, ?, ?, ?, ?, ?) RETURNING id",
+		a.HouseholdID, a.Name, a.Type, a.Currency, a.OpeningBalance, a.Archived,
+	).Scan(&a.ID)
+	if isUniqueViolation(err) {
+		return ErrAccountExists
+	}
+	return err
+}
+
+// SetAccountArchived archives or restores an account. Archived accounts are
+// hidden from the pickers and the balance list but keep their transactions.
+func (db *DB) SetAccountArchived(ctx context.Context, householdID, id int64, archived bool) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.conn.ExecContext(ctx,
+		"UPDATE accounts SET archived = ? WHERE id = ? AND household_id = ?",
+		archived, id, householdID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// CreateTransfer records a transfer between two accounts of the user's
+// default household. A zero Date is stored as now. When ToAmount is zero it
+// is Amount converted to the currency of the receiving account at the rate
+// of the day. On success the ID, UserID, HouseholdID and ToAmount fields of t
+// are filled in. It returns ErrInvalidAccount if an account belongs to
+// another household or both are the same, and ErrNoExchangeRate if ToAmount
+// is needed and the household has no rate between the currencies.
+func (db *DB) CreateTransfer(ctx context.Context, userID int64, t *models.Transfer) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if t.Date.IsZero() {
+		t.Date = time.Now()
+	}
+	if t.FromAccountID == t.ToAccountID {
+		return ErrInvalidAccount
+	}
+	householdID, err := db.DefaultHouseholdID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	currencies := make(map[int64]string, 2)
+	rows, err := db.read.QueryContext(ctx,
+		"SELECT id, currency FROM accounts WHERE household_id = ? AND id IN (?, ?)",
+		householdID, t.FromAccountID, t.ToAccountID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var currency string
+		if err := rows.Scan(&id, &currency); err != nil {
+			return err
+		}
+		currencies[id] = currency
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if len(currencies) != 2 {
+		return ErrInvalidAccount
+	}
+
+	if t.ToAmount == 0 {
+		from, to := currencies[t.FromAccountID], currencies[t.ToAccountID]
+		t.ToAmount = t.Amount
+		if from != to {
+			rates, err := loadRates(ctx, db.read, householdID, from, to)
+			if err != nil {
+				return err
+			}
+			amount, ok := rates.Convert(t.Amount, from, to, t.Date)
+			if !ok {
+				return fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, from, to)
+			}
+			t.ToAmount = amount
+		}
+	}
+
+	if err := db.conn.QueryRowContext(ctx,
+		"INSERT INTO transfers (household_id, user_id, from_account_id, to_account_id, amount, to_amount, description, date) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id",
+		householdID, userID, t.FromAccountID, t.ToAccountID, t.Amount, t.ToAmount, t.Description, t.Date,
+	).Scan(&t.ID); err != nil {
+		return err
+	}
+	t.UserID = &userID
+	t.HouseholdID = householdID
+	return nil
+}
+
+// DeleteTransfer removes a transfer of a household.
+func (db *DB) DeleteTransfer(ctx context.Context, householdID, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.conn.ExecContext(ctx, "DELETE FROM transfers WHERE id = ? AND household_id = ?", id, householdID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// ListAccountBalances returns the household's accounts that are not archived
+// with their current balances: the opening balance, plus income and refunds,
+// minus expenses, plus or minus transfers. It returns ErrNoExchangeRate if a
+// transaction cannot be converted to its account's currency.
+func (db *DB) ListAccountBalances(ctx context.Context, householdID int64) ([]AccountBalance, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	accounts, err := db.listAccounts(ctx, householdID, false)
+	if err != nil {
+		return nil, err
+	}
+	ledgers, err := db.ledgers(ctx, householdID, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]AccountBalance, len(accounts))
+	for i, a := range accounts {
+		balances[i] = AccountBalance{Account: a, Balance: a.OpeningBalance}
+		if entries := ledgers[a.ID]; len(entries) > 0 {
+			balances[i].Balance = entries[len(entries)-1].Balance
+		}
+	}
+	return balances, nil
+}
+
+// GetAccountLedger returns up to limit of the latest entries of an account,
+// newest first, with the running balance after each. It returns ErrNotFound
+// if the account does not exist in the household and ErrNoExchangeRate if a
+// transaction of the household's accounts cannot be converted to its
+// account's currency.
+func (db *DB) GetAccountLedger(ctx context.Context, householdID, accountID int64, limit int) ([]LedgerEntry, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	// Transfers name the other account, so all of them are needed
+	accounts, err := db.listAccounts(ctx, householdID, true)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, a := range accounts {
+		found = found || a.ID == accountID
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	ledgers, err := db.ledgers(ctx, householdID, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := ledgers[accountID]
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	newest := make([]LedgerEntry, len(entries))
+	for i, entry := range entries {
+		newest[len(entries)-1-i] = entry
+	}
+	return newest, nil
+}
+
+// ledgers returns the entries of the household's accounts, oldest first,
+// with running balances. Transactions paid in another currency than their
+// account's are converted at the rate of their date; it returns
+// ErrNoExchangeRate if the household has no rate for one.
+func (db *DB) ledgers(ctx context.Context, householdID int64, accounts []models.Account) (map[int64][]LedgerEntry, error) {
+	ledgers := make(map[int64][]LedgerEntry, len(accounts))
+	if len(accounts) == 0 {
+		return ledgers, nil
+	}
+	byID := make(map[int64]models.Account, len(accounts))
+	for _, a := range accounts {
+		byID[a.ID] = a
+	}
+
+	base, err := householdCurrency(ctx, db.read, householdID)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := db.queryExpenses(ctx,
+		"SELECT "+expenseColumns+" FROM expenses e WHERE e.household_id = ? AND e.account_id IS NOT NULL ORDER BY e.date, e.id",
+		householdID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := db.queryTransfers(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := []string{base}
+	for _, a := range accounts {
+		currencies = append(currencies, a.Currency)
+	}
+	for _, e := range expenses {
+		currencies = append(currencies, e.Currency)
+	}
+	rates, err := loadRates(ctx, db.read, householdID, currencies...)
+	if err != nil {
+		return nil, err
+	}
+
+	add := func(accountID int64, entry LedgerEntry) {
+		if _, ok := byID[accountID]; ok {
+			ledgers[accountID] = append(ledgers[accountID], entry)
+		}
+	}
+	for _, e := range expenses {
+		a, ok := byID[*e.AccountID]
+		if !ok {
+			continue
+		}
+		amount := e.OriginalAmount
+		switch {
+		case e.Currency == a.Currency:
+		case a.Currency == base:
+			amount = e.Amount
+		default:
+			if amount, ok = rates.Convert(e.OriginalAmount, e.Currency, a.Currency, e.Date); !ok {
+				return nil, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, e.Currency, a.Currency)
+			}
+		}
+		if e.Kind == models.KindExpense {
+			amount = -amount
+		}
+		add(a.ID, LedgerEntry{Date: e.Date, Description: e.Description, Category: e.Category, ExpenseID: e.ID, Amount: amount})
+	}
+	for _, t := range transfers {
+		add(t.FromAccountID, LedgerEntry{Date: t.Date, Description: t.Description, TransferID: t.ID, Counterpart: byID[t.ToAccountID].Name, Amount: -t.Amount})
+		add(t.ToAccountID, LedgerEntry{Date: t.Date, Description: t.Description, TransferID: t.ID, Counterpart: byID[t.FromAccountID].Name, Amount: t.ToAmount})
+	}
+
+	for id, entries := range ledgers {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+		balance := byID[id].OpeningBalance
+		for i := range entries {
+			balance += entries[i].Amount
+			entries[i].Balance = balance
+		}
+	}
+	return ledgers, nil
+}
+
+// queryTransfers returns the transfers of a household, oldest first.
+func (db *DB) queryTransfers(ctx context.Context, householdID int64) ([]models.Transfer, error) {
+	rows, err := db.read.QueryContext(ctx,
+		`SELECT id, household_id, user_id, from_account_id, to_account_id, amount, to_amount, description, date
+		 FROM transfers WHERE household_id = ? ORDER BY date, id`,
+		householdID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []models.Transfer
+	for rows.Next() {
+		var t models.Transfer
+		if err := rows.Scan(&t.ID, &t.HouseholdID, &t.UserID, &t.FromAccountID, &t.ToAccountID, &t.Amount, &t.ToAmount, &t.Description, &t.Date); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
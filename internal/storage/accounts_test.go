@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// AccountTestSuite provides a test suite for accounts, transfers and balances
+type AccountTestSuite struct {
+	suite.Suite
+	dsn       string // Database under test, see runOnBackends
+	ctx       context.Context
+	db        *DB
+	user      *models.User
+	household *models.Household
+	card      *models.Account
+	cash      *models.Account
+}
+
+// SetupTest runs before each test
+func (s *AccountTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	s.household, err = s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+
+	s.card = &models.Account{HouseholdID: s.household.ID, Name: "Joint card", Type: models.AccountCard, OpeningBalance: 10000}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, s.card))
+	s.cash = &models.Account{HouseholdID: s.household.ID, Name: "Wallet", Type: models.AccountCash}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, s.cash))
+}
+
+// TearDownTest runs after each test
+func (s *AccountTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *AccountTestSuite) createExpense(kind models.ExpenseKind, amount models.Money, account *models.Account, day int) *models.Expense {
+	e := &models.Expense{
+		Kind:        kind,
+		Amount:      amount,
+		Description: "Test",
+		Category:    "Groceries",
+		Date:        time.Date(2026, 1, day, 12, 0, 0, 0, time.UTC),
+	}
+	if account != nil {
+		e.AccountID = &account.ID
+	}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+	return e
+}
+
+func (s *AccountTestSuite) balances() map[string]models.Money {
+	balances, err := s.db.ListAccountBalances(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	byName := make(map[string]models.Money, len(balances))
+	for _, b := range balances {
+		byName[b.Name] = b.Balance
+	}
+	return byName
+}
+
+func (s *AccountTestSuite) TestCreateAccount_Defaults() {
+	s.Equal("EUR", s.cash.Currency)
+
+	a := &models.Account{HouseholdID: s.household.ID, Name: "Savings"}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, a))
+	s.Equal(models.AccountChecking, a.Type)
+
+	err := s.db.CreateAccount(s.ctx, &models.Account{HouseholdID: s.household.ID, Name: "Wallet"})
+	s.ErrorIs(err, ErrAccountExists)
+}
+
+func (s *AccountTestSuite) TestListAccounts_Archived() {
+	s.Require().NoError(s.db.SetAccountArchived(s.ctx, s.household.ID, s.cash.ID, true))
+
+	accounts, err := s.db.ListAccounts(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	s.Require().Len(accounts, 1)
+	s.Equal("Joint card", accounts[0].Name)
+
+	accounts, err = s.db.ListAccounts(s.ctx, s.household.ID, true)
+	s.Require().NoError(err)
+	s.Len(accounts, 2)
+
+	s.ErrorIs(s.db.SetAccountArchived(s.ctx, s.household.ID, 12345, true), ErrNotFound)
+}
+
+func (s *AccountTestSuite) TestBalances() {
+	s.createExpense(models.KindExpense, 2500, s.card, 2)
+	s.createExpense(models.KindRefund, 500, s.card, 3)
+	s.createExpense(models.KindIncome, 3000, s.cash, 4)
+	s.createExpense(models.KindExpense, 999, nil, 4)
+
+	s.Equal(map[string]models.Money{"Joint card": 8000, "Wallet": 3000}, s.balances())
+}
+
+func (s *AccountTestSuite) TestTransfersAreNotSpending() {
+	s.createExpense(models.KindExpense, 1000, s.card, 2)
+	t := &models.Transfer{FromAccountID: s.card.ID, ToAccountID: s.cash.ID, Amount: 4000, Description: "ATM", Date: time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateTransfer(s.ctx, s.user.ID, t))
+	s.Equal(models.Money(4000), t.ToAmount)
+	s.Equal(s.household.ID, t.HouseholdID)
+
+	s.Equal(map[string]models.Money{"Joint card": 5000, "Wallet": 4000}, s.balances())
+	totals, err := s.db.GetTotalForPeriod(s.ctx, UserScope(s.user.ID), 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(PeriodTotals{Spending: 1000}, totals)
+
+	s.Require().NoError(s.db.DeleteTransfer(s.ctx, s.household.ID, t.ID))
+	s.Equal(map[string]models.Money{"Joint card": 9000, "Wallet": 0}, s.balances())
+	s.ErrorIs(s.db.DeleteTransfer(s.ctx, s.household.ID, t.ID), ErrNotFound)
+}
+
+func (s *AccountTestSuite) TestCreateTransfer_ConvertsToAccountCurrency() {
+	dollars := &models.Account{HouseholdID: s.household.ID, Name: "Dollars", Type: models.AccountCash, Currency: "USD"}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, dollars))
+	_, err := s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: "EUR", Currency: "USD", Date: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Rate: 1.25}})
+	s.Require().NoError(err)
+
+	t := &models.Transfer{FromAccountID: s.card.ID, ToAccountID: dollars.ID, Amount: 2000, Date: time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateTransfer(s.ctx, s.user.ID, t))
+	s.Equal(models.Money(2500), t.ToAmount)
+
+	// An expense paid in euros from the dollar account is converted
+	s.createExpense(models.KindExpense, 800, dollars, 6)
+	s.Equal(models.Money(1500), s.balances()["Dollars"])
+}
+
+func (s *AccountTestSuite) TestCreateTransfer_WithoutRate() {
+	yen := &models.Account{HouseholdID: s.household.ID, Name: "Yen", Type: models.AccountCash, Currency: "JPY"}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, yen))
+
+	t := &models.Transfer{FromAccountID: s.card.ID, ToAccountID: yen.ID, Amount: 2000}
+	s.ErrorIs(s.db.CreateTransfer(s.ctx, s.user.ID, t), ErrNoExchangeRate)
+
+	// The amount received needs no rate
+	t.ToAmount = 330000
+	s.Require().NoError(s.db.CreateTransfer(s.ctx, s.user.ID, t))
+}
+
+func (s *AccountTestSuite) TestBalances_WithoutRate() {
+	date := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	_, err := s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: "EUR", Currency: "USD", Date: date, Rate: 1.25}})
+	s.Require().NoError(err)
+	yen := &models.Account{HouseholdID: s.household.ID, Name: "Yen", Type: models.AccountCash, Currency: "JPY"}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, yen))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1000, Currency: "USD", Description: "Souvenir", Category: "Gifts", Date: date, AccountID: &yen.ID}))
+
+	// A dollar expense paid from a yen account is not counted at face value
+	_, err = s.db.ListAccountBalances(s.ctx, s.household.ID)
+	s.ErrorIs(err, ErrNoExchangeRate)
+	_, err = s.db.GetAccountLedger(s.ctx, s.household.ID, yen.ID, 10)
+	s.ErrorIs(err, ErrNoExchangeRate)
+
+	_, err = s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: "EUR", Currency: "JPY", Date: date, Rate: 160}})
+	s.Require().NoError(err)
+	s.Equal(models.Money(-128000), s.balances()["Yen"])
+}
+
+func (s *AccountTestSuite) TestCreateTransfer_InvalidAccounts() {
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	foreign := &models.Account{HouseholdID: other.ID, Name: "Theirs"}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, foreign))
+
+	for _, t := range []models.Transfer{
+		{FromAccountID: s.card.ID, ToAccountID: s.card.ID, Amount: 100},
+		{FromAccountID: s.card.ID, ToAccountID: foreign.ID, Amount: 100},
+		{FromAccountID: s.card.ID, ToAccountID: 12345, Amount: 100},
+	} {
+		s.ErrorIs(s.db.CreateTransfer(s.ctx, s.user.ID, &t), ErrInvalidAccount)
+	}
+}
+
+func (s *AccountTestSuite) TestExpenseAccountMustBelongToHousehold() {
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	foreign := &models.Account{HouseholdID: other.ID, Name: "Theirs"}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, foreign))
+
+	e := &models.Expense{Amount: 100, Description: "x", Category: "Other", AccountID: &foreign.ID}
+	s.ErrorIs(s.db.CreateExpense(s.ctx, s.user.ID, e), ErrInvalidAccount)
+
+	e = s.createExpense(models.KindExpense, 100, s.card, 2)
+	e.AccountID = &foreign.ID
+	s.ErrorIs(s.db.UpdateExpense(s.ctx, UserScope(s.user.ID), e), ErrInvalidAccount)
+	e.AccountID = &s.cash.ID
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, UserScope(s.user.ID), e))
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal(s.cash.ID, *got.AccountID)
+
+	_, err = s.db.ImportExpenses(s.ctx, s.user.ID, []models.Expense{{Amount: 100, Description: "y", Category: "Other", Date: time.Now(), AccountID: &foreign.ID}})
+	s.ErrorIs(err, ErrInvalidAccount)
+}
+
+func (s *AccountTestSuite) TestAccountScope() {
+	s.createExpense(models.KindExpense, 1000, s.card, 2)
+	s.createExpense(models.KindExpense, 300, s.cash, 3)
+	s.createExpense(models.KindExpense, 50, nil, 3)
+
+	scope := UserScope(s.user.ID).WithAccount(s.cash.ID)
+	expenses, err := s.db.ListExpenses(s.ctx, scope, 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal(models.Money(300), expenses[0].Amount)
+
+	totals, err := s.db.GetTotalForPeriod(s.ctx, scope, 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(300), totals.Spending)
+	categories, err := s.db.GetCategoryTotalsByMonth(s.ctx, scope, 2026, 1)
+	s.Require().NoError(err)
+	s.Require().Len(categories, 1)
+	s.Equal(models.Money(300), categories[0].Total)
+
+	totals, err = s.db.GetTotalForPeriod(s.ctx, UserScope(s.user.ID), 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(1350), totals.Spending)
+}
+
+func (s *AccountTestSuite) TestGetAccountLedger() {
+	s.createExpense(models.KindExpense, 2500, s.card, 2)
+	s.Require().NoError(s.db.CreateTransfer(s.ctx, s.user.ID, &models.Transfer{
+		FromAccountID: s.card.ID, ToAccountID: s.cash.ID, Amount: 1000, Description: "ATM", Date: time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC),
+	}))
+	s.createExpense(models.KindRefund, 500, s.card, 4)
+
+	ledger, err := s.db.GetAccountLedger(s.ctx, s.household.ID, s.card.ID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(ledger, 3)
+	s.Equal(models.Money(500), ledger[0].Amount)
+	s.Equal(models.Money(7000), ledger[0].Balance)
+	s.Equal("Wallet", ledger[1].Counterpart)
+	s.Equal(models.Money(-1000), ledger[1].Amount)
+	s.Equal(models.Money(6500), ledger[1].Balance)
+	s.Equal(models.Money(-2500), ledger[2].Amount)
+	s.Equal(models.Money(7500), ledger[2].Balance)
+
+	ledger, err = s.db.GetAccountLedger(s.ctx, s.household.ID, s.card.ID, 1)
+	s.Require().NoError(err)
+	s.Require().Len(ledger, 1)
+	s.Equal(models.Money(7000), ledger[0].Balance)
+
+	_, err = s.db.GetAccountLedger(s.ctx, s.household.ID, 12345, 10)
+	s.ErrorIs(err, ErrNotFound)
+}
+
+// TestAccountSuite runs the account test suite
+func TestAccountSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &AccountTestSuite{dsn: dsn})
+	})
+}
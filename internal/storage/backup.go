@@ -0,0 +1,500 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ArchiveVersion is the version of the archive format written by Backup.
+// Restore reads archives up to this version. Version 2 added currencies and
+// exchange rates, version 3 accounts and transfers, version 4 split expenses,
+// version 5 shared expenses and settlements, version 6 tags and version 7
+// exchange rates per household.
+const ArchiveVersion = 7
+
+// ErrNotEmpty is returned when restoring into a database that already has
+// users, households or expenses.
+var ErrNotEmpty = errors.New("database is not empty")
+
+// Archive is a copy of the data of a database that does not depend on its
+// schema. IDs are those of the source database; Restore assigns new ones and
+// rewrites the references between records. Sessions are not included.
+type Archive struct {
+	Version        int                    `json:"version"`
+	CreatedAt      time.Time              `json:"created_at"`
+	Users          []ArchiveUser          `json:"users"`
+	Households     []models.Household     `json:"households"`
+	Members        []ArchiveMember        `json:"members"`
+	Categories     []models.Category      `json:"categories"`
+	Budgets        []models.Budget        `json:"budgets"`
+	Recurring      []models.Recurring     `json:"recurring"`
+	ImportProfiles []models.ImportProfile `json:"import_profiles"`
+	APITokens      []ArchiveAPIToken      `json:"api_tokens"`
+	Accounts       []models.Account       `json:"accounts"`
+	Transfers      []models.Transfer      `json:"transfers"`
+	Expenses       []models.Expense       `json:"expenses"`
+	Settlements    []models.Settlement    `json:"settlements"`
+	ExchangeRates  []ArchiveExchangeRate  `json:"exchange_rates"`
+}
+
+// ArchiveUser is a user with the password hash, which models.User leaves out
+// of JSON.
+type ArchiveUser struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ArchiveMember is the membership of a user in a household.
+type ArchiveMember struct {
+	HouseholdID int64 `json:"household_id"`
+	UserID      int64 `json:"user_id"`
+}
+
+// ArchiveExchangeRate is an exchange rate of a household. Archives before
+// version 7 have no household; their rates apply to all households.
+type ArchiveExchangeRate struct {
+	HouseholdID int64 `json:"household_id,omitempty"`
+	models.ExchangeRate
+}
+
+// ArchiveAPIToken is an API token with its hash.
+type ArchiveAPIToken struct {
+	models.APIToken
+	TokenHash string `json:"token_hash"`
+}
+
+// dumpTable runs a query and scans every row with scan.
+func dumpTable[T any](ctx context.Context, tx *sql.Tx, query string, scan func(interface{ Scan(...any) error }, *T) error) ([]T, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []T
+	for rows.Next() {
+		var item T
+		if err := scan(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Backup reads all users, households, categories, budgets, recurring
+// templates, import profiles, API tokens, accounts, transfers, expenses,
+// settlements and exchange rates in one transaction, so the archive is consistent while the database is in use.
+func (db *DB) Backup(ctx context.Context) (*Archive, error) {
+	a := &Archive{Version: ArchiveVersion, CreatedAt: time.Now().UTC()}
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if a.Users, err = dumpTable(ctx, tx, "SELECT id, username, password_hash, created_at FROM users ORDER BY id",
+			func(row interface{ Scan(...any) error }, u *ArchiveUser) error {
+				return row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt)
+			}); err != nil {
+			return err
+		}
+		if a.Households, err = dumpTable(ctx, tx, "SELECT id, name, currency, created_at FROM households ORDER BY id",
+			func(row interface{ Scan(...any) error }, hh *models.Household) error {
+				return row.Scan(&hh.ID, &hh.Name, &hh.Currency, &hh.CreatedAt)
+			}); err != nil {
+			return err
+		}
+		if a.Members, err = dumpTable(ctx, tx, "SELECT household_id, user_id FROM household_members ORDER BY household_id, user_id",
+			func(row interface{ Scan(...any) error }, m *ArchiveMember) error {
+				return row.Scan(&m.HouseholdID, &m.UserID)
+			}); err != nil {
+			return err
+		}
+		if a.Categories, err = dumpTable(ctx, tx, "SELECT "+categoryColumns+" FROM categories ORDER BY id", scanCategory); err != nil {
+			return err
+		}
+		if a.Budgets, err = dumpTable(ctx, tx, "SELECT "+budgetColumns+" FROM budgets ORDER BY id",
+			func(row interface{ Scan(...any) error }, b *models.Budget) error {
+				return row.Scan(&b.ID, &b.HouseholdID, &b.CategoryID, &b.Year, &b.Month, &b.Amount, &b.Rollover)
+			}); err != nil {
+			return err
+		}
+		if a.Recurring, err = dumpTable(ctx, tx, "SELECT "+recurringColumns+" FROM recurring ORDER BY id",
+			func(row interface{ Scan(...any) error }, r *models.Recurring) error {
+				return row.Scan(
+					&r.ID, &r.HouseholdID, &r.UserID, &r.Kind, &r.Amount, &r.Description, &r.Category,
+					&r.Frequency, &r.Interval, &r.Day, &r.StartDate, &r.EndDate, &r.NextDate,
+				)
+			}); err != nil {
+			return err
+		}
+		if a.ImportProfiles, err = dumpTable(ctx, tx, "SELECT "+importProfileColumns+" FROM import_profiles ORDER BY id", scanImportProfile); err != nil {
+			return err
+		}
+		if a.APITokens, err = dumpTable(ctx, tx, "SELECT "+apiTokenColumns+", token_hash FROM api_tokens ORDER BY id",
+			func(row interface{ Scan(...any) error }, t *ArchiveAPIToken) error {
+				return row.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.CreatedAt, &t.LastUsedAt, &t.TokenHash)
+			}); err != nil {
+			return err
+		}
+		if a.Accounts, err = dumpTable(ctx, tx, "SELECT "+accountColumns+" FROM accounts ORDER BY id", scanAccount); err != nil {
+			return err
+		}
+		if a.Transfers, err = dumpTable(ctx, tx,
+			"SELECT id, household_id, user_id, from_account_id, to_account_id, amount, to_amount, description, date FROM transfers ORDER BY id",
+			func(row interface{ Scan(...any) error }, t *models.Transfer) error {
+				return row.Scan(&t.ID, &t.HouseholdID, &t.UserID, &t.FromAccountID, &t.ToAccountID, &t.Amount, &t.ToAmount, &t.Description, &t.Date)
+			}); err != nil {
+			return err
+		}
+		if a.Expenses, err = dumpTable(ctx, tx, "SELECT "+expenseColumns+" FROM expenses e ORDER BY e.id", scanExpense); err != nil {
+			return err
+		}
+		if err := loadExpenseDetails(ctx, tx, a.Expenses); err != nil {
+			return err
+		}
+		if a.Settlements, err = dumpTable(ctx, tx,
+			"SELECT id, household_id, from_user_id, to_user_id, amount, date FROM settlements ORDER BY id",
+			func(row interface{ Scan(...any) error }, s *models.Settlement) error {
+				return row.Scan(&s.ID, &s.HouseholdID, &s.FromUserID, &s.ToUserID, &s.Amount, &s.Date)
+			}); err != nil {
+			return err
+		}
+		a.ExchangeRates, err = dumpTable(ctx, tx,
+			"SELECT household_id, base, currency, date, rate FROM exchange_rates ORDER BY household_id, base, currency, date",
+			func(row interface{ Scan(...any) error }, r *ArchiveExchangeRate) error {
+				return row.Scan(&r.HouseholdID, &r.Base, &r.Currency, &r.Date, &r.Rate)
+			})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Restore writes an archive into an empty database in one transaction. Every
+// record gets a new ID and references between records are rewritten to
+// match. It returns ErrNotEmpty if the database has users, households or
+// expenses, and an error if the archive is of a newer version or refers to
+// records it does not hold.
+func (db *DB) Restore(ctx context.Context, a *Archive) error {
+	if a.Version < 1 || a.Version > ArchiveVersion {
+		return fmt.Errorf("unsupported archive version %d", a.Version)
+	}
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRowContext(ctx,
+			"SELECT (SELECT COUNT(*) FROM users) + (SELECT COUNT(*) FROM households) + (SELECT COUNT(*) FROM expenses)",
+		).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrNotEmpty
+		}
+
+		insert := func(query string, args ...any) (int64, error) {
+			var id int64
+			err := tx.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+			return id, err
+		}
+		users := make(idMap)
+		for _, u := range a.Users {
+			id, err := insert("INSERT INTO users (username, password_hash, created_at) VALUES (?, ?, ?)", u.Username, u.PasswordHash, u.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("user %s: %w", u.Username, err)
+			}
+			users[u.ID] = id
+		}
+		households := make(idMap)
+		baseCurrencies := make(map[int64]string) // By new household ID
+		for _, hh := range a.Households {
+			// Archives before version 2 have no currencies
+			if hh.Currency == "" {
+				hh.Currency = models.DefaultCurrency
+			}
+			id, err := insert("INSERT INTO households (name, currency, created_at) VALUES (?, ?, ?)", hh.Name, hh.Currency, hh.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("household %s: %w", hh.Name, err)
+			}
+			households[hh.ID] = id
+			baseCurrencies[id] = hh.Currency
+		}
+		for _, m := range a.Members {
+			householdID, err := households.get("household", m.HouseholdID)
+			if err != nil {
+				return err
+			}
+			userID, err := users.get("user", m.UserID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "INSERT INTO household_members (household_id, user_id) VALUES (?, ?)", householdID, userID); err != nil {
+				return err
+			}
+		}
+
+		// Parents may come after their subcategories, so they are linked once
+		// all categories exist
+		categories := make(idMap)
+		for _, c := range a.Categories {
+			householdID, err := households.get("household", c.HouseholdID)
+			if err != nil {
+				return err
+			}
+			id, err := insert(
+				"INSERT INTO categories (household_id, name, icon, color, position, archived, account) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				householdID, c.Name, c.Icon, c.Color, c.Position, c.Archived, c.Account,
+			)
+			if err != nil {
+				return fmt.Errorf("category %s: %w", c.Name, err)
+			}
+			categories[c.ID] = id
+		}
+		for _, c := range a.Categories {
+			if c.ParentID == nil {
+				continue
+			}
+			parentID, err := categories.get("category", *c.ParentID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE categories SET parent_id = ? WHERE id = ?", parentID, categories[c.ID]); err != nil {
+				return err
+			}
+		}
+
+		for _, b := range a.Budgets {
+			householdID, err := households.get("household", b.HouseholdID)
+			if err != nil {
+				return err
+			}
+			categoryID, err := categories.get("category", b.CategoryID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO budgets (household_id, category_id, year, month, amount, rollover) VALUES (?, ?, ?, ?, ?, ?)",
+				householdID, categoryID, b.Year, b.Month, b.Amount, b.Rollover,
+			); err != nil {
+				return err
+			}
+		}
+
+		recurring := make(idMap)
+		for _, r := range a.Recurring {
+			householdID, err := households.get("household", r.HouseholdID)
+			if err != nil {
+				return err
+			}
+			userID, err := users.get("user", r.UserID)
+			if err != nil {
+				return err
+			}
+			id, err := insert(
+				`INSERT INTO recurring (household_id, user_id, kind, amount, description, category, frequency, interval, day, start_date, end_date, next_date)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				householdID, userID, r.Kind, r.Amount, r.Description, r.Category,
+				r.Frequency, r.Interval, r.Day, r.StartDate, r.EndDate, r.NextDate,
+			)
+			if err != nil {
+				return err
+			}
+			recurring[r.ID] = id
+		}
+
+		for _, p := range a.ImportProfiles {
+			householdID, err := households.get("household", p.HouseholdID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO import_profiles (household_id, name, delimiter, skip_rows, date_column, amount_column, credit_column,
+					description_column, category_column, date_format, decimal_separator, sign_convention, default_category)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				householdID, p.Name, p.Delimiter, p.SkipRows, p.DateColumn, p.AmountColumn, p.CreditColumn,
+				p.DescriptionColumn, p.CategoryColumn, p.DateFormat, p.DecimalSeparator, p.SignConvention, p.DefaultCategory,
+			); err != nil {
+				return fmt.Errorf("import profile %s: %w", p.Name, err)
+			}
+		}
+
+		for _, t := range a.APITokens {
+			userID, err := users.get("user", t.UserID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO api_tokens (user_id, name, scope, token_hash, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?)",
+				userID, t.Name, t.Scope, t.TokenHash, t.CreatedAt, t.LastUsedAt,
+			); err != nil {
+				return err
+			}
+		}
+
+		accounts := make(idMap)
+		for _, acc := range a.Accounts {
+			householdID, err := households.get("household", acc.HouseholdID)
+			if err != nil {
+				return err
+			}
+			id, err := insert(
+				"INSERT INTO accounts (household_id, name, type, currency, opening_balance, archived) VALUES (?, ?, ?, ?, ?, ?)",
+				householdID, acc.Name, acc.Type, acc.Currency, acc.OpeningBalance, acc.Archived,
+			)
+			if err != nil {
+				return fmt.Errorf("account %s: %w", acc.Name, err)
+			}
+			accounts[acc.ID] = id
+		}
+		for _, t := range a.Transfers {
+			householdID, err := households.get("household", t.HouseholdID)
+			if err != nil {
+				return err
+			}
+			fromID, err := accounts.get("account", t.FromAccountID)
+			if err != nil {
+				return err
+			}
+			toID, err := accounts.get("account", t.ToAccountID)
+			if err != nil {
+				return err
+			}
+			var userID *int64
+			if t.UserID != nil {
+				id, err := users.get("user", *t.UserID)
+				if err != nil {
+					return err
+				}
+				userID = &id
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO transfers (household_id, user_id, from_account_id, to_account_id, amount, to_amount, description, date) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+				householdID, userID, fromID, toID, t.Amount, t.ToAmount, t.Description, t.Date,
+			); err != nil {
+				return fmt.Errorf("transfer %d: %w", t.ID, err)
+			}
+		}
+
+		for _, r := range a.ExchangeRates {
+			var householdIDs []int64
+			if r.HouseholdID == 0 {
+				for id := range baseCurrencies {
+					householdIDs = append(householdIDs, id)
+				}
+			} else {
+				id, err := households.get("household", r.HouseholdID)
+				if err != nil {
+					return err
+				}
+				householdIDs = append(householdIDs, id)
+			}
+			for _, householdID := range householdIDs {
+				if _, err := tx.ExecContext(ctx,
+					"INSERT INTO exchange_rates (household_id, base, currency, date, rate) VALUES (?, ?, ?, ?, ?)",
+					householdID, r.Base, r.Currency, r.Date, r.Rate,
+				); err != nil {
+					return fmt.Errorf("exchange rate %s/%s: %w", r.Base, r.Currency, err)
+				}
+			}
+		}
+
+		for _, e := range a.Expenses {
+			householdID, err := households.get("household", e.HouseholdID)
+			if err != nil {
+				return err
+			}
+			if e.Currency == "" {
+				e.Currency = baseCurrencies[householdID]
+			}
+			if e.OriginalAmount == 0 {
+				e.OriginalAmount = e.Amount
+			}
+			var userID, accountID, recurringID *int64
+			if e.UserID != nil {
+				id, err := users.get("user", *e.UserID)
+				if err != nil {
+					return err
+				}
+				userID = &id
+			}
+			if e.AccountID != nil {
+				id, err := accounts.get("account", *e.AccountID)
+				if err != nil {
+					return err
+				}
+				accountID = &id
+			}
+			// Templates may have been deleted since the expense was created
+			if e.RecurringID != nil {
+				if id, ok := recurring[*e.RecurringID]; ok {
+					recurringID = &id
+				}
+			}
+			id, err := insert(
+				`INSERT INTO expenses (kind, amount, currency, original_amount, description, category, date, user_id, household_id, account_id, recurring_id, import_ref)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))`,
+				e.Kind, e.Amount, e.Currency, e.OriginalAmount, e.Description, e.Category, e.Date, userID, householdID, accountID, recurringID, e.ImportRef,
+			)
+			if err != nil {
+				return fmt.Errorf("expense %d: %w", e.ID, err)
+			}
+			if err := saveSplits(ctx, tx, id, e.Splits); err != nil {
+				return fmt.Errorf("expense %d: %w", e.ID, err)
+			}
+			if e.Sharing != nil {
+				sharing := models.Sharing{Method: e.Sharing.Method, Shares: make([]models.Share, len(e.Sharing.Shares))}
+				for i, share := range e.Sharing.Shares {
+					if share.UserID, err = users.get("user", share.UserID); err != nil {
+						return err
+					}
+					sharing.Shares[i] = share
+				}
+				if err := saveSharing(ctx, tx, id, &sharing); err != nil {
+					return fmt.Errorf("expense %d: %w", e.ID, err)
+				}
+			}
+			if err := saveTags(ctx, tx, householdID, id, e.Tags); err != nil {
+				return fmt.Errorf("expense %d: %w", e.ID, err)
+			}
+		}
+
+		for _, s := range a.Settlements {
+			householdID, err := households.get("household", s.HouseholdID)
+			if err != nil {
+				return err
+			}
+			fromID, err := users.get("user", s.FromUserID)
+			if err != nil {
+				return err
+			}
+			toID, err := users.get("user", s.ToUserID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO settlements (household_id, from_user_id, to_user_id, amount, date) VALUES (?, ?, ?, ?, ?)",
+				householdID, fromID, toID, s.Amount, s.Date,
+			); err != nil {
+				return fmt.Errorf("settlement %d: %w", s.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// idMap maps the IDs of an archive to those assigned on restore.
+type idMap map[int64]int64
+
+func (m idMap) get(kind string, id int64) (int64, error) {
+	newID, ok := m[id]
+	if !ok {
+		return 0, fmt.Errorf("archive refers to unknown %s %d", kind, id)
+	}
+	return newID, nil
+}
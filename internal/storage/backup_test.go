@@ -0,0 +1,337 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// BackupTestSuite provides a test suite for archives of a whole database
+type BackupTestSuite struct {
+	suite.Suite
+	dsn string // Database under test, see runOnBackends
+	ctx context.Context
+	db  *DB
+}
+
+// SetupTest runs before each test
+func (s *BackupTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+}
+
+// TearDownTest runs after each test
+func (s *BackupTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+// roundTrip backs up the suite's database and restores the archive, passed
+// through JSON, into a new one.
+func (s *BackupTestSuite) roundTrip() *DB {
+	archive, err := s.db.Backup(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(ArchiveVersion, archive.Version)
+	data, err := json.Marshal(archive)
+	s.Require().NoError(err)
+
+	var restored Archive
+	s.Require().NoError(json.Unmarshal(data, &restored))
+	target, err := NewDB(":memory:")
+	s.Require().NoError(err)
+	s.Require().NoError(target.Restore(s.ctx, &restored))
+	return target
+}
+
+func (s *BackupTestSuite) TestBackupAndRestore() {
+	// Leave gaps in the IDs so that restoring has to remap them
+	scratch, err := s.db.CreateHousehold(s.ctx, "Scratch")
+	s.Require().NoError(err)
+	_, err = s.db.conn.Exec("DELETE FROM categories WHERE household_id = ?", scratch.ID)
+	s.Require().NoError(err)
+	_, err = s.db.conn.Exec("DELETE FROM households WHERE id = ?", scratch.ID)
+	s.Require().NoError(err)
+
+	alice, err := s.db.CreateUser(s.ctx, "alice", "alice-hash")
+	s.Require().NoError(err)
+	bob, err := s.db.CreateUser(s.ctx, "bob", "bob-hash")
+	s.Require().NoError(err)
+	home, err := s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, home.ID, alice.ID))
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, home.ID, bob.ID))
+
+	categories, err := s.db.ListCategories(s.ctx, home.ID, false)
+	s.Require().NoError(err)
+	groceries := categories[0]
+	groceries.Account = "Expenses:Food"
+	s.Require().NoError(s.db.UpdateCategory(s.ctx, &groceries))
+	bakery := models.Category{HouseholdID: home.ID, ParentID: &groceries.ID, Name: "Bakery", Icon: "🥐", Color: "#fbbf24"}
+	s.Require().NoError(s.db.CreateCategory(s.ctx, &bakery))
+	s.Require().NoError(s.db.SetBudget(s.ctx, &models.Budget{HouseholdID: home.ID, CategoryID: bakery.ID, Year: 2026, Month: 1, Amount: 5000, Rollover: true}))
+
+	rent := models.Recurring{Amount: 90000, Description: "Rent", Category: "Housing", Frequency: models.FrequencyMonthly, Interval: 1, Day: 1, StartDate: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateRecurring(s.ctx, bob.ID, &rent))
+	s.Require().NoError(s.db.SaveImportProfile(s.ctx, &models.ImportProfile{
+		HouseholdID: home.ID, Name: "Bank", Delimiter: ";", DateColumn: 1, AmountColumn: 2, DescriptionColumn: 3,
+		DateFormat: "DD.MM.YYYY", DecimalSeparator: ",", SignConvention: models.SignNegativeOut,
+	}))
+	_, err = s.db.CreateAPIToken(s.ctx, bob.ID, "Script", models.ScopeRead, "token-hash")
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.db.CreateExpense(s.ctx, alice.ID, &models.Expense{Amount: 350, Description: "Croissants", Category: "Bakery", Date: time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, bob.ID, &models.Expense{Amount: 90000, Description: "Rent", Category: "Housing", Date: rent.StartDate, RecurringID: &rent.ID}))
+	_, err = s.db.ImportExpenses(s.ctx, bob.ID, []models.Expense{{Kind: models.KindIncome, Amount: 250000, Description: "Salary", Category: "Other", Date: time.Date(2026, 1, 28, 12, 0, 0, 0, time.UTC), ImportRef: "ofx:1:A"}})
+	s.Require().NoError(err)
+
+	target := s.roundTrip()
+	defer target.Close()
+
+	user, err := target.GetUserByUsername(s.ctx, "bob")
+	s.Require().NoError(err)
+	s.Equal("bob-hash", user.PasswordHash)
+	householdID, err := target.DefaultHouseholdID(s.ctx, user.ID)
+	s.Require().NoError(err)
+	s.NotEqual(home.ID, householdID, "IDs are assigned anew")
+	members, err := target.ListHouseholdMembers(s.ctx, householdID)
+	s.Require().NoError(err)
+	s.Len(members, 2)
+
+	restoredCategories, err := target.ListCategories(s.ctx, householdID, true)
+	s.Require().NoError(err)
+	s.Require().Len(restoredCategories, len(categories)+1)
+	s.Equal("Expenses:Food", restoredCategories[0].Account)
+	restoredBakery := restoredCategories[len(restoredCategories)-1]
+	s.Equal("Bakery", restoredBakery.Name)
+	s.Require().NotNil(restoredBakery.ParentID)
+	s.Equal(restoredCategories[0].ID, *restoredBakery.ParentID)
+
+	budgets, err := target.ListBudgets(s.ctx, householdID)
+	s.Require().NoError(err)
+	s.Require().Len(budgets, 1)
+	s.Equal(restoredBakery.ID, budgets[0].CategoryID)
+	s.True(budgets[0].Rollover)
+
+	templates, err := target.ListRecurring(s.ctx, householdID)
+	s.Require().NoError(err)
+	s.Require().Len(templates, 1)
+	s.Equal(user.ID, templates[0].UserID)
+
+	profiles, err := target.ListImportProfiles(s.ctx, householdID)
+	s.Require().NoError(err)
+	s.Require().Len(profiles, 1)
+	s.Equal("DD.MM.YYYY", profiles[0].DateFormat)
+
+	tokenUser, token, err := target.UseAPIToken(s.ctx, "token-hash", time.Now())
+	s.Require().NoError(err)
+	s.Equal(user.ID, tokenUser.ID)
+	s.Equal(models.ScopeRead, token.Scope)
+
+	expenses, err := target.ListExpenses(s.ctx, HouseholdScope(householdID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 3)
+	s.Equal("ofx:1:A", expenses[0].ImportRef)
+	s.Equal(models.Money(350), expenses[1].Amount)
+	s.True(expenses[1].Date.Equal(time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)))
+	s.Require().NotNil(expenses[2].RecurringID)
+	s.Equal(templates[0].ID, *expenses[2].RecurringID)
+}
+
+func (s *BackupTestSuite) TestBackupAndRestore_Currencies() {
+	alice, err := s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	home, err := s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, home.ID, alice.ID))
+	s.Require().NoError(s.db.SetHouseholdCurrency(s.ctx, home.ID, "GBP"))
+	_, err = s.db.SaveExchangeRates(s.ctx, home.ID, []models.ExchangeRate{{Base: "GBP", Currency: "USD", Date: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Rate: 1.25}})
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, alice.ID, &models.Expense{OriginalAmount: 1000, Currency: "USD", Description: "Taxi", Category: "Transport", Date: time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)}))
+
+	target := s.roundTrip()
+	defer target.Close()
+
+	hh, err := target.GetHouseholdByName(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Equal("GBP", hh.Currency)
+	rates, err := target.ListExchangeRates(s.ctx, hh.ID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(rates, 1)
+	s.InDelta(1.25, rates[0].Rate, 1e-9)
+	expenses, err := target.ListExpenses(s.ctx, HouseholdScope(hh.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal("USD", expenses[0].Currency)
+	s.Equal(models.Money(1000), expenses[0].OriginalAmount)
+	s.Equal(models.Money(800), expenses[0].Amount)
+}
+
+func (s *BackupTestSuite) TestBackupAndRestore_Accounts() {
+	alice, err := s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	home, err := s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, home.ID, alice.ID))
+	card := &models.Account{HouseholdID: home.ID, Name: "Joint card", Type: models.AccountCard}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, card))
+	cash := &models.Account{HouseholdID: home.ID, Name: "Wallet", Type: models.AccountCash, OpeningBalance: 5000}
+	s.Require().NoError(s.db.CreateAccount(s.ctx, cash))
+	date := time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, alice.ID, &models.Expense{Amount: 1200, Description: "Lunch", Category: "Eating Out", Date: date, AccountID: &cash.ID}))
+	s.Require().NoError(s.db.CreateTransfer(s.ctx, alice.ID, &models.Transfer{FromAccountID: card.ID, ToAccountID: cash.ID, Amount: 2000, Date: date}))
+
+	target := s.roundTrip()
+	defer target.Close()
+
+	hh, err := target.GetHouseholdByName(s.ctx, "Home")
+	s.Require().NoError(err)
+	balances, err := target.ListAccountBalances(s.ctx, hh.ID)
+	s.Require().NoError(err)
+	s.Require().Len(balances, 2)
+	s.Equal("Joint card", balances[0].Name)
+	s.Equal(models.Money(-2000), balances[0].Balance)
+	s.Equal("Wallet", balances[1].Name)
+	s.Equal(models.Money(5800), balances[1].Balance)
+	expenses, err := target.ListExpenses(s.ctx, HouseholdScope(hh.ID).WithAccount(balances[1].ID), 10, 0)
+	s.Require().NoError(err)
+	s.Len(expenses, 1)
+}
+
+func (s *BackupTestSuite) TestBackupAndRestore_Splits() {
+	alice, err := s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	home, err := s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, home.ID, alice.ID))
+	splits := []models.Split{{Category: "Groceries", Amount: 3000}, {Category: "Housing", Amount: 1500}}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, alice.ID, &models.Expense{Amount: 4500, Description: "Supermarket", Date: time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC), Splits: splits}))
+
+	target := s.roundTrip()
+	defer target.Close()
+
+	hh, err := target.GetHouseholdByName(s.ctx, "Home")
+	s.Require().NoError(err)
+	expenses, err := target.ListExpenses(s.ctx, HouseholdScope(hh.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal(splits, expenses[0].Splits)
+	s.Equal("Groceries", expenses[0].Category)
+}
+
+func (s *BackupTestSuite) TestBackupAndRestore_Sharing() {
+	// IDs in the target differ from the source, so the shares must be remapped
+	_, err := s.db.CreateUser(s.ctx, "ghost", "hash")
+	s.Require().NoError(err)
+	alice, err := s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	bob, err := s.db.CreateUser(s.ctx, "bob", "hash")
+	s.Require().NoError(err)
+	home, err := s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, home.ID, alice.ID))
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, home.ID, bob.ID))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, alice.ID, &models.Expense{
+		Amount: 3000, Description: "Dinner", Date: time.Date(2026, 1, 3, 19, 0, 0, 0, time.UTC),
+		Sharing: &models.Sharing{Method: models.ShareExact, Shares: []models.Share{{UserID: alice.ID, Amount: 1000}, {UserID: bob.ID, Amount: 2000}}},
+	}))
+	s.Require().NoError(s.db.RecordSettlements(s.ctx, home.ID, []models.Settlement{{FromUserID: bob.ID, ToUserID: alice.ID, Amount: 500}}))
+	_, err = s.db.conn.ExecContext(s.ctx, "DELETE FROM users WHERE username = 'ghost'")
+	s.Require().NoError(err)
+
+	target := s.roundTrip()
+	defer target.Close()
+
+	hh, err := target.GetHouseholdByName(s.ctx, "Home")
+	s.Require().NoError(err)
+	balances, err := target.GetBalances(s.ctx, hh.ID)
+	s.Require().NoError(err)
+	s.Require().Len(balances, 2)
+	s.Equal("alice", balances[0].Username)
+	s.Equal(models.Money(1500), balances[0].Balance)
+	settlements, err := target.ListSettlements(s.ctx, hh.ID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(settlements, 1)
+	s.Equal("bob", settlements[0].FromUsername)
+}
+
+func (s *BackupTestSuite) TestBackupAndRestore_Tags() {
+	alice, err := s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	home, err := s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, home.ID, alice.ID))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, alice.ID, &models.Expense{
+		Amount: 12000, Description: "Hotel", Date: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+		Tags: []string{"vacation", "rome"},
+	}))
+
+	target := s.roundTrip()
+	defer target.Close()
+
+	hh, err := target.GetHouseholdByName(s.ctx, "Home")
+	s.Require().NoError(err)
+	expenses, err := target.ListExpenses(s.ctx, HouseholdScope(hh.ID).WithTag("rome"), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal([]string{"rome", "vacation"}, expenses[0].Tags)
+}
+
+func (s *BackupTestSuite) TestRestore_Version1() {
+	archive := &Archive{
+		Version:    1,
+		Users:      []ArchiveUser{{ID: 1, Username: "alice", PasswordHash: "hash"}},
+		Households: []models.Household{{ID: 1, Name: "Home"}},
+		Members:    []ArchiveMember{{HouseholdID: 1, UserID: 1}},
+		Expenses:   []models.Expense{{ID: 1, Kind: models.KindExpense, Amount: 350, Description: "Croissants", Category: "Groceries", Date: time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC), HouseholdID: 1}},
+	}
+	s.Require().NoError(s.db.Restore(s.ctx, archive))
+
+	hh, err := s.db.GetHouseholdByName(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Equal(models.DefaultCurrency, hh.Currency)
+	expenses, err := s.db.ListExpenses(s.ctx, HouseholdScope(hh.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal(models.DefaultCurrency, expenses[0].Currency)
+	s.Equal(models.Money(350), expenses[0].OriginalAmount)
+}
+
+func (s *BackupTestSuite) TestRestore_Errors() {
+	user, err := s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	archive, err := s.db.Backup(s.ctx)
+	s.Require().NoError(err)
+
+	s.ErrorIs(s.db.Restore(s.ctx, archive), ErrNotEmpty)
+
+	target, err := NewDB(":memory:")
+	s.Require().NoError(err)
+	defer target.Close()
+
+	archive.Version = ArchiveVersion + 1
+	s.ErrorContains(target.Restore(s.ctx, archive), "unsupported archive version")
+
+	archive.Version = ArchiveVersion
+	archive.Members = []ArchiveMember{{HouseholdID: 42, UserID: user.ID}}
+	s.ErrorContains(target.Restore(s.ctx, archive), "unknown household 42")
+
+	// A failed restore leaves the database empty
+	count, err := target.UserCount(s.ctx)
+	s.Require().NoError(err)
+	s.Zero(count)
+}
+
+// TestBackupSuite runs the backup test suite
+func TestBackupSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &BackupTestSuite{dsn: dsn})
+	})
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// BudgetStatus is the state of a category's budget in a month.
+type BudgetStatus struct {
+	Budget      models.Budget // The budget in effect for the month
+	Category    string
+	Subcategory bool         // The category has a parent
+	Carried     models.Money // Unspent amount rolled over from earlier months
+	Spent       models.Money // Spending in the category, including its subcategories
+}
+
+// Available returns the amount that may be spent in the month.
+func (bs BudgetStatus) Available() models.Money {
+	return bs.Budget.Amount + bs.Carried
+}
+
+// Remaining returns what is left of the available amount; negative when overspent.
+func (bs BudgetStatus) Remaining() models.Money {
+	return bs.Available() - bs.Spent
+}
+
+const budgetColumns = "id, household_id, category_id, year, month, amount, rollover"
+
+// SetBudget sets the budget of a category from the given month on. The
+// category must belong to the budget's household. On success b.ID is set.
+func (db *DB) SetBudget(ctx context.Context, b *models.Budget) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM categories WHERE id = ? AND household_id = ?",
+			b.CategoryID, b.HouseholdID,
+		).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO budgets (household_id, category_id, year, month, amount, rollover)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (category_id, year, month) DO UPDATE SET amount = excluded.amount, rollover = excluded.rollover
+			RETURNING id`,
+			b.HouseholdID, b.CategoryID, b.Year, b.Month, b.Amount, b.Rollover,
+		).Scan(&b.ID)
+	})
+}
+
+// ListBudgets returns all budgets of a household, oldest first.
+func (db *DB) ListBudgets(ctx context.Context, householdID int64) ([]models.Budget, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.queryBudgets(ctx,
+		"SELECT "+budgetColumns+" FROM budgets WHERE household_id = ? ORDER BY year, month, id",
+		householdID,
+	)
+}
+
+func (db *DB) queryBudgets(ctx context.Context, query string, args ...any) ([]models.Budget, error) {
+	rows, err := db.read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []models.Budget
+	for rows.Next() {
+		var b models.Budget
+		if err := rows.Scan(&b.ID, &b.HouseholdID, &b.CategoryID, &b.Year, &b.Month, &b.Amount, &b.Rollover); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+
+	return budgets, rows.Err()
+}
+
+// GetBudgetStatuses returns the budgets in effect in a household for a month,
+// with rolled over amounts and spending, in category order. Spending in a
+// subcategory counts against both its own budget and its parent's.
+func (db *DB) GetBudgetStatuses(ctx context.Context, householdID int64, year, month int) ([]BudgetStatus, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	budgets, err := db.queryBudgets(ctx,
+		"SELECT "+budgetColumns+" FROM budgets WHERE household_id = ? AND (year < ? OR (year = ? AND month <= ?)) ORDER BY year, month",
+		householdID, year, year, month,
+	)
+	if err != nil || len(budgets) == 0 {
+		return nil, err
+	}
+
+	categories, err := db.ListCategories(ctx, householdID, true)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	// Walk forward from the first budgeted month, applying budget changes
+	// and carrying unspent amounts into the following month.
+	target := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	current := time.Date(budgets[0].Year, time.Month(budgets[0].Month), 1, 0, 0, 0, 0, time.UTC)
+	effective := make(map[int64]models.Budget)
+	carried := make(map[int64]models.Money)
+	next := 0
+	for {
+		for next < len(budgets) && budgets[next].Year == current.Year() && budgets[next].Month == int(current.Month()) {
+			b := budgets[next]
+			if b.Amount == 0 {
+				delete(effective, b.CategoryID)
+			} else {
+				effective[b.CategoryID] = b
+			}
+			next++
+		}
+
+		spent, err := db.categorySpending(ctx, householdID, current)
+		if err != nil {
+			return nil, err
+		}
+
+		if !current.Before(target) {
+			var statuses []BudgetStatus
+			for _, c := range categories {
+				b, ok := effective[c.ID]
+				if !ok {
+					continue
+				}
+				statuses = append(statuses, BudgetStatus{
+					Budget:      b,
+					Category:    c.Name,
+					Subcategory: c.ParentID != nil,
+					Carried:     carried[c.ID],
+					Spent:       spent[c.Name],
+				})
+			}
+			return statuses, nil
+		}
+
+		for id := range carried {
+			if _, ok := effective[id]; !ok {
+				delete(carried, id)
+			}
+		}
+		for id, b := range effective {
+			left := b.Amount + carried[id] - spent[names[id]]
+			if !b.Rollover || left < 0 {
+				left = 0
+			}
+			carried[id] = left
+		}
+		current = current.AddDate(0, 1, 0)
+	}
+}
+
+// categorySpending returns a household's spending in a month by category
+// name. Parents include the spending of their subcategories.
+func (db *DB) categorySpending(ctx context.Context, householdID int64, month time.Time) (map[string]models.Money, error) {
+	totals, err := db.categoryTotals(ctx, HouseholdScope(householdID), month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make(map[string]models.Money)
+	for _, ct := range totals {
+		spent[ct.Category] = ct.Total
+		for _, child := range ct.Children {
+			if child.Category != ct.Category {
+				spent[child.Category] = child.Total
+			}
+		}
+	}
+	return spent, nil
+}
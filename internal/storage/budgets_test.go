@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// BudgetTestSuite provides a test suite for category budgets
+type BudgetTestSuite struct {
+	suite.Suite
+	dsn        string // Database under test, see runOnBackends
+	ctx        context.Context
+	db         *DB
+	user       *models.User
+	household  *models.Household
+	categories []models.Category
+}
+
+// SetupTest runs before each test
+func (s *BudgetTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "testuser", "hash")
+	s.Require().NoError(err)
+	s.household, err = s.db.CreateHousehold(s.ctx, "Test")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+	s.categories, err = s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+}
+
+// TearDownTest runs after each test
+func (s *BudgetTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *BudgetTestSuite) setBudget(category models.Category, year, month int, amount models.Money, rollover bool) *models.Budget {
+	b := &models.Budget{HouseholdID: s.household.ID, CategoryID: category.ID, Year: year, Month: month, Amount: amount, Rollover: rollover}
+	s.Require().NoError(s.db.SetBudget(s.ctx, b))
+	return b
+}
+
+func (s *BudgetTestSuite) spend(category string, amount models.Money, date time.Time) {
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: amount, Description: "Test", Category: category, Date: date}))
+}
+
+func (s *BudgetTestSuite) TestSetBudgetUpserts() {
+	groceries := s.categories[0]
+	first := s.setBudget(groceries, 2026, 1, 40000, false)
+	second := s.setBudget(groceries, 2026, 1, 45000, true)
+	s.Equal(first.ID, second.ID)
+
+	budgets, err := s.db.ListBudgets(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Require().Len(budgets, 1)
+	s.Equal(models.Money(45000), budgets[0].Amount)
+	s.True(budgets[0].Rollover)
+
+	// Categories of other households are not found
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	err = s.db.SetBudget(s.ctx, &models.Budget{HouseholdID: other.ID, CategoryID: groceries.ID, Year: 2026, Month: 1, Amount: 100})
+	s.ErrorIs(err, ErrNotFound)
+}
+
+func (s *BudgetTestSuite) TestBudgetAppliesUntilReplaced() {
+	groceries, transport := s.categories[0], s.categories[2]
+	s.setBudget(groceries, 2025, 11, 40000, false)
+	s.setBudget(transport, 2025, 11, 5000, false)
+	s.setBudget(groceries, 2026, 2, 50000, false)
+	s.setBudget(transport, 2026, 1, 0, false)
+
+	statuses, err := s.db.GetBudgetStatuses(s.ctx, s.household.ID, 2025, 10)
+	s.Require().NoError(err)
+	s.Empty(statuses, "no budget before the first month")
+
+	statuses, err = s.db.GetBudgetStatuses(s.ctx, s.household.ID, 2025, 12)
+	s.Require().NoError(err)
+	s.Require().Len(statuses, 2)
+	s.Equal("Groceries", statuses[0].Category)
+	s.Equal(models.Money(40000), statuses[0].Available())
+	s.Equal("Transport", statuses[1].Category)
+
+	statuses, err = s.db.GetBudgetStatuses(s.ctx, s.household.ID, 2026, 1)
+	s.Require().NoError(err)
+	s.Require().Len(statuses, 1, "a zero budget ends the category's budget")
+	s.Equal(models.Money(40000), statuses[0].Budget.Amount)
+
+	statuses, err = s.db.GetBudgetStatuses(s.ctx, s.household.ID, 2026, 6)
+	s.Require().NoError(err)
+	s.Require().Len(statuses, 1)
+	s.Equal(models.Money(50000), statuses[0].Budget.Amount)
+}
+
+func (s *BudgetTestSuite) TestSpentIncludesSubcategories() {
+	eatingOut := s.categories[1]
+	coffee := &models.Category{HouseholdID: s.household.ID, ParentID: &eatingOut.ID, Name: "Coffee", Icon: "☕", Color: "#60a5fa"}
+	s.Require().NoError(s.db.CreateCategory(s.ctx, coffee))
+	s.setBudget(eatingOut, 2026, 1, 10000, false)
+	s.setBudget(*coffee, 2026, 1, 2000, false)
+
+	date := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	s.spend("Eating Out", 4000, date)
+	s.spend("Coffee", 2500, date)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Kind: models.KindRefund, Amount: 500, Description: "Refund", Category: "Coffee", Date: date}))
+
+	statuses, err := s.db.GetBudgetStatuses(s.ctx, s.household.ID, 2026, 1)
+	s.Require().NoError(err)
+	s.Require().Len(statuses, 2)
+	s.Equal(models.Money(6000), statuses[0].Spent)
+	s.Equal(models.Money(4000), statuses[0].Remaining())
+	s.Equal("Coffee", statuses[1].Category)
+	s.Equal(models.Money(2000), statuses[1].Spent)
+	s.Equal(models.Money(0), statuses[1].Remaining())
+}
+
+func (s *BudgetTestSuite) TestRollover() {
+	groceries, sport := s.categories[0], s.categories[5]
+	s.setBudget(groceries, 2026, 1, 40000, true)
+	s.setBudget(sport, 2026, 1, 5000, false)
+
+	s.spend("Groceries", 30000, time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	s.spend("Sport", 1000, time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	s.spend("Groceries", 55000, time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC))
+
+	statuses, err := s.db.GetBudgetStatuses(s.ctx, s.household.ID, 2026, 2)
+	s.Require().NoError(err)
+	s.Require().Len(statuses, 2)
+	s.Equal(models.Money(10000), statuses[0].Carried)
+	s.Equal(models.Money(50000), statuses[0].Available())
+	s.Equal(models.Money(-5000), statuses[0].Remaining())
+	s.Equal(models.Money(0), statuses[1].Carried, "only rollover budgets carry over")
+
+	// Overspending is not carried into the next month
+	statuses, err = s.db.GetBudgetStatuses(s.ctx, s.household.ID, 2026, 3)
+	s.Require().NoError(err)
+	s.Equal(models.Money(0), statuses[0].Carried)
+	s.Equal(models.Money(40000), statuses[0].Available())
+}
+
+// TestBudgetSuite runs the budget test suite
+func TestBudgetSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &BudgetTestSuite{dsn: dsn})
+	})
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrCategoryExists is returned when a household already has a category with the same name.
+var ErrCategoryExists = errors.New("category already exists")
+
+// ErrInvalidParent is returned when a category cannot be placed under the requested parent:
+// the parent is missing, is itself a subcategory, or the category already has children.
+var ErrInvalidParent = errors.New("invalid parent category")
+
+// defaultCategories are given to every new household.
+var defaultCategories = []models.Category{
+	{Name: "Groceries", Icon: "🛒", Color: "#60a5fa"},
+	{Name: "Eating Out", Icon: "🍴", Color: "#60a5fa"},
+	{Name: "Transport", Icon: "🚌", Color: "#a78bfa"},
+	{Name: "Housing", Icon: "🏠", Color: "#818cf8"},
+	{Name: "Utilities", Icon: "💡", Color: "#fbbf24"},
+	{Name: "Sport", Icon: "🏋️‍♂️", Color: "#fbbf24"},
+	{Name: "Health", Icon: "🚑", Color: "#fbbf24"},
+	{Name: "Entertainment", Icon: "🎮", Color: "#f472b6"},
+	{Name: "Travel", Icon: "✈️", Color: "#f472b6"},
+	{Name: "Gifts", Icon: "🎁", Color: "#fb7185"},
+	{Name: "Other", Icon: "📦", Color: "#94a3b8"},
+}
+
+const categoryColumns = "id, household_id, parent_id, name, icon, color, position, archived, account"
+
+func scanCategory(row interface{ Scan(...any) error }, c *models.Category) error {
+	return row.Scan(&c.ID, &c.HouseholdID, &c.ParentID, &c.Name, &c.Icon, &c.Color, &c.Position, &c.Archived, &c.Account)
+}
+
+func seedCategories(ctx context.Context, tx *sql.Tx, householdID int64) error {
+	for i, c := range defaultCategories {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO categories (household_id, name, icon, color, position) VALUES (?, ?, ?, ?, ?)",
+			householdID, c.Name, c.Icon, c.Color, i+1,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListCategories returns the categories of a household in display order.
+// Archived categories are only included if includeArchived is set.
+func (db *DB) ListCategories(ctx context.Context, householdID int64, includeArchived bool) ([]models.Category, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + categoryColumns + " FROM categories WHERE household_id = ?"
+	if !includeArchived {
+		query += " AND NOT archived"
+	}
+	rows, err := db.read.QueryContext(ctx, query+" ORDER BY position, id", householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var c models.Category
+		if err := scanCategory(rows, &c); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+// GetCategory retrieves a category of a household by ID.
+// It returns ErrNotFound if the category does not exist in that household.
+func (db *DB) GetCategory(ctx context.Context, householdID, id int64) (*models.Category, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	row := db.read.QueryRowContext(ctx,
+		"SELECT "+categoryColumns+" FROM categories WHERE id = ? AND household_id = ?",
+		id, householdID,
+	)
+
+	var c models.Category
+	if err := scanCategory(row, &c); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCategory adds a category at the end of the household's list and sets c.ID and c.Position.
+func (db *DB) CreateCategory(ctx context.Context, c *models.Category) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := categoryNameFree(ctx, tx, c.HouseholdID, 0, c.Name); err != nil {
+			return err
+		}
+		if err := checkParent(ctx, tx, c); err != nil {
+			return err
+		}
+
+		var position int
+		if err := tx.QueryRowContext(ctx,
+			"SELECT COALESCE(MAX(position), 0) + 1 FROM categories WHERE household_id = ?",
+			c.HouseholdID,
+		).Scan(&position); err != nil {
+			return err
+		}
+
+		var id int64
+		if err := tx.QueryRowContext(ctx,
+			"INSERT INTO categories (household_id, parent_id, name, icon, color, position, archived, account) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id",
+			c.HouseholdID, c.ParentID, c.Name, c.Icon, c.Color, position, c.Archived, c.Account,
+		).Scan(&id); err != nil {
+			return err
+		}
+
+		c.ID = id
+		c.Position = position
+		return nil
+	})
+}
+
+// UpdateCategory changes the name, icon, color, parent and account of a category. When
+// the name changes, the household's expenses, split parts, recurring
+// templates and import profiles in the category are moved along.
+func (db *DB) UpdateCategory(ctx context.Context, c *models.Category) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		var oldName string
+		err := tx.QueryRowContext(ctx,
+			"SELECT name FROM categories WHERE id = ? AND household_id = ?",
+			c.ID, c.HouseholdID,
+		).Scan(&oldName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkParent(ctx, tx, c); err != nil {
+			return err
+		}
+
+		if c.Name != oldName {
+			if err := categoryNameFree(ctx, tx, c.HouseholdID, c.ID, c.Name); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE expenses SET category = ? WHERE household_id = ? AND category = ?",
+				c.Name, c.HouseholdID, oldName,
+			); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE expense_splits SET category = ? WHERE category = ? AND expense_id IN (SELECT id FROM expenses WHERE household_id = ?)",
+				c.Name, oldName, c.HouseholdID,
+			); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE recurring SET category = ? WHERE household_id = ? AND category = ?",
+				c.Name, c.HouseholdID, oldName,
+			); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE import_profiles SET default_category = ? WHERE household_id = ? AND default_category = ?",
+				c.Name, c.HouseholdID, oldName,
+			); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE categories SET parent_id = ?, name = ?, icon = ?, color = ?, account = ? WHERE id = ?",
+			c.ParentID, c.Name, c.Icon, c.Color, c.Account, c.ID,
+		)
+		return err
+	})
+}
+
+func categoryNameFree(ctx context.Context, tx *sql.Tx, householdID, exceptID int64, name string) error {
+	var count int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM categories WHERE household_id = ? AND name = ? AND id != ?",
+		householdID, name, exceptID,
+	).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCategoryExists
+	}
+	return nil
+}
+
+// checkParent ensures c.ParentID, if set, names a top-level category of the
+// same household and that c itself has no subcategories.
+func checkParent(ctx context.Context, tx *sql.Tx, c *models.Category) error {
+	if c.ParentID == nil {
+		return nil
+	}
+	if *c.ParentID == c.ID {
+		return ErrInvalidParent
+	}
+
+	var grandparent sql.NullInt64
+	err := tx.QueryRowContext(ctx,
+		"SELECT parent_id FROM categories WHERE id = ? AND household_id = ?",
+		*c.ParentID, c.HouseholdID,
+	).Scan(&grandparent)
+	if errors.Is(err, sql.ErrNoRows) || grandparent.Valid {
+		return ErrInvalidParent
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.ID != 0 {
+		var children int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM categories WHERE parent_id = ?", c.ID).Scan(&children); err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrInvalidParent
+		}
+	}
+	return nil
+}
+
+// SetCategoryArchived archives or restores a category. Archived categories
+// are hidden from the picker but keep styling existing expenses.
+func (db *DB) SetCategoryArchived(ctx context.Context, householdID, id int64, archived bool) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.conn.ExecContext(ctx,
+		"UPDATE categories SET archived = ? WHERE id = ? AND household_id = ?",
+		archived, id, householdID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// ReorderCategories sets the display order of a household's categories to the
+// order of ids. Categories not listed keep their relative order after them.
+func (db *DB) ReorderCategories(ctx context.Context, householdID int64, ids []int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE categories SET position = position + ? WHERE household_id = ?",
+			len(ids), householdID,
+		); err != nil {
+			return err
+		}
+		for i, id := range ids {
+			result, err := tx.ExecContext(ctx,
+				"UPDATE categories SET position = ? WHERE id = ? AND household_id = ?",
+				i+1, id, householdID,
+			)
+			if err != nil {
+				return err
+			}
+			if err := requireAffected(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// CategoryTestSuite provides a test suite for household categories
+type CategoryTestSuite struct {
+	suite.Suite
+	dsn       string // Database under test, see runOnBackends
+	ctx       context.Context
+	db        *DB
+	user      *models.User
+	household *models.Household
+}
+
+// SetupTest runs before each test
+func (s *CategoryTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "testuser", "hash")
+	s.Require().NoError(err)
+	s.household, err = s.db.CreateHousehold(s.ctx, "Test")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+}
+
+// TearDownTest runs after each test
+func (s *CategoryTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *CategoryTestSuite) TestNewHouseholdHasDefaultCategories() {
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	s.Require().Len(categories, len(defaultCategories))
+	s.Equal("Groceries", categories[0].Name)
+	s.Equal("🛒", categories[0].Icon)
+	s.Equal("Other", categories[len(categories)-1].Name)
+}
+
+func (s *CategoryTestSuite) TestCreateCategory() {
+	c := &models.Category{HouseholdID: s.household.ID, Name: "Pets", Icon: "🐶", Color: "#a3e635"}
+	s.Require().NoError(s.db.CreateCategory(s.ctx, c))
+	s.NotZero(c.ID)
+	s.Equal(len(defaultCategories)+1, c.Position, "new categories go last")
+
+	got, err := s.db.GetCategory(s.ctx, s.household.ID, c.ID)
+	s.Require().NoError(err)
+	s.Equal("Pets", got.Name)
+
+	err = s.db.CreateCategory(s.ctx, &models.Category{HouseholdID: s.household.ID, Name: "Pets", Icon: "🐱", Color: "#000"})
+	s.ErrorIs(err, ErrCategoryExists)
+}
+
+func (s *CategoryTestSuite) TestRenameRepointsExpenses() {
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1200, Description: "Pizza", Category: "Eating Out", Date: date}))
+
+	// Another household's expense in a category with the same name stays put
+	other, err := s.db.CreateUser(s.ctx, "other", "hash")
+	s.Require().NoError(err)
+	otherHousehold, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, otherHousehold.ID, other.ID))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, other.ID, &models.Expense{Amount: 900, Description: "Burger", Category: "Eating Out", Date: date}))
+
+	// Templates and import profiles refer to categories by name too
+	lunch := models.Recurring{Amount: 1000, Description: "Lunch", Category: "Eating Out", Frequency: models.FrequencyWeekly, StartDate: date}
+	s.Require().NoError(s.db.CreateRecurring(s.ctx, s.user.ID, &lunch))
+	otherLunch := lunch
+	s.Require().NoError(s.db.CreateRecurring(s.ctx, other.ID, &otherLunch))
+	profile := models.ImportProfile{
+		HouseholdID: s.household.ID, Name: "Card", Delimiter: ",", DateColumn: 1, AmountColumn: 2, DescriptionColumn: 3,
+		DateFormat: "YYYY-MM-DD", DecimalSeparator: ".", SignConvention: models.SignNegativeOut, DefaultCategory: "Eating Out",
+	}
+	s.Require().NoError(s.db.SaveImportProfile(s.ctx, &profile))
+	otherProfile := profile
+	otherProfile.ID, otherProfile.HouseholdID = 0, otherHousehold.ID
+	s.Require().NoError(s.db.SaveImportProfile(s.ctx, &otherProfile))
+
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	eatingOut := categories[1]
+	s.Require().Equal("Eating Out", eatingOut.Name)
+
+	eatingOut.Name = "Restaurants"
+	eatingOut.Icon = "🍽️"
+	s.Require().NoError(s.db.UpdateCategory(s.ctx, &eatingOut))
+
+	expenses, err := s.db.ListExpenses(s.ctx, UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal("Restaurants", expenses[0].Category)
+
+	expenses, err = s.db.ListExpenses(s.ctx, UserScope(other.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal("Eating Out", expenses[0].Category)
+
+	for _, c := range []struct {
+		householdID int64
+		want        string
+	}{{s.household.ID, "Restaurants"}, {otherHousehold.ID, "Eating Out"}} {
+		templates, err := s.db.ListRecurring(s.ctx, c.householdID)
+		s.Require().NoError(err)
+		s.Require().Len(templates, 1)
+		s.Equal(c.want, templates[0].Category)
+		profiles, err := s.db.ListImportProfiles(s.ctx, c.householdID)
+		s.Require().NoError(err)
+		s.Require().Len(profiles, 1)
+		s.Equal(c.want, profiles[0].DefaultCategory)
+	}
+
+	// Renaming onto an existing name is rejected
+	eatingOut.Name = "Groceries"
+	s.ErrorIs(s.db.UpdateCategory(s.ctx, &eatingOut), ErrCategoryExists)
+
+	// Categories of other households are not found
+	eatingOut.HouseholdID = otherHousehold.ID
+	eatingOut.Name = "Hijacked"
+	s.ErrorIs(s.db.UpdateCategory(s.ctx, &eatingOut), ErrNotFound)
+}
+
+func (s *CategoryTestSuite) TestArchiveCategory() {
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	sport := categories[5]
+	s.Require().Equal("Sport", sport.Name)
+
+	s.Require().NoError(s.db.SetCategoryArchived(s.ctx, s.household.ID, sport.ID, true))
+
+	active, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	s.Len(active, len(defaultCategories)-1)
+
+	all, err := s.db.ListCategories(s.ctx, s.household.ID, true)
+	s.Require().NoError(err)
+	s.Len(all, len(defaultCategories))
+	s.True(all[5].Archived)
+
+	s.ErrorIs(s.db.SetCategoryArchived(s.ctx, s.household.ID, 9999, true), ErrNotFound)
+}
+
+func (s *CategoryTestSuite) TestReorderCategories() {
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	other := categories[len(categories)-1]
+	groceries := categories[0]
+
+	s.Require().NoError(s.db.ReorderCategories(s.ctx, s.household.ID, []int64{other.ID, groceries.ID}))
+
+	categories, err = s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	s.Equal("Other", categories[0].Name)
+	s.Equal("Groceries", categories[1].Name)
+	s.Equal("Eating Out", categories[2].Name, "unlisted categories keep their order")
+}
+
+func (s *CategoryTestSuite) TestSubcategoryParentRules() {
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	eatingOut := categories[1]
+
+	coffee := &models.Category{HouseholdID: s.household.ID, ParentID: &eatingOut.ID, Name: "Coffee", Icon: "☕", Color: "#60a5fa"}
+	s.Require().NoError(s.db.CreateCategory(s.ctx, coffee))
+
+	got, err := s.db.GetCategory(s.ctx, s.household.ID, coffee.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(got.ParentID)
+	s.Equal(eatingOut.ID, *got.ParentID)
+
+	// Only one level deep
+	espresso := &models.Category{HouseholdID: s.household.ID, ParentID: &coffee.ID, Name: "Espresso", Icon: "☕", Color: "#60a5fa"}
+	s.ErrorIs(s.db.CreateCategory(s.ctx, espresso), ErrInvalidParent)
+
+	// A category with children cannot become a child
+	groceries := categories[0]
+	eatingOut.ParentID = &groceries.ID
+	s.ErrorIs(s.db.UpdateCategory(s.ctx, &eatingOut), ErrInvalidParent)
+
+	// Parent must be in the same household
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	otherCategories, err := s.db.ListCategories(s.ctx, other.ID, false)
+	s.Require().NoError(err)
+	coffee.ParentID = &otherCategories[0].ID
+	s.ErrorIs(s.db.UpdateCategory(s.ctx, coffee), ErrInvalidParent)
+
+	// Moving back to top level is allowed
+	coffee.ParentID = nil
+	s.Require().NoError(s.db.UpdateCategory(s.ctx, coffee))
+}
+
+func (s *CategoryTestSuite) TestCategoryTotalsRollUp() {
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	eatingOut := categories[1]
+	for _, name := range []string{"Coffee", "Lunch"} {
+		s.Require().NoError(s.db.CreateCategory(s.ctx, &models.Category{HouseholdID: s.household.ID, ParentID: &eatingOut.ID, Name: name, Icon: "☕", Color: "#60a5fa"}))
+	}
+
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	expenses := []models.Expense{
+		{Amount: 300, Description: "Latte", Category: "Coffee", Date: date},
+		{Amount: 350, Description: "Cappuccino", Category: "Coffee", Date: date.Add(time.Hour)},
+		{Amount: 1200, Description: "Sandwich", Category: "Lunch", Date: date.Add(2 * time.Hour)},
+		{Amount: 4000, Description: "Dinner", Category: "Eating Out", Date: date.Add(3 * time.Hour)},
+		{Amount: 5000, Description: "Supermarket", Category: "Groceries", Date: date.Add(4 * time.Hour)},
+	}
+	for i := range expenses {
+		s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &expenses[i]))
+	}
+
+	for _, totals := range [][]CategoryTotal{
+		s.mustTotals(s.db.GetCategoryTotalsByMonth(s.ctx, UserScope(s.user.ID), 2026, 1)),
+		s.mustTotals(s.db.GetCategoryTotalsByYear(s.ctx, UserScope(s.user.ID), 2026)),
+	} {
+		s.Require().Len(totals, 2)
+		s.Equal("Eating Out", totals[0].Category)
+		s.Equal(models.Money(5850), totals[0].Total)
+		s.Equal(4, totals[0].Count)
+		s.Require().Len(totals[0].Children, 3)
+		s.Equal("Eating Out", totals[0].Children[0].Category)
+		s.Equal("Lunch", totals[0].Children[1].Category)
+		s.Equal("Coffee", totals[0].Children[2].Category)
+		s.Equal(models.Money(650), totals[0].Children[2].Total)
+		s.Equal(2, totals[0].Children[2].Count)
+
+		s.Equal("Groceries", totals[1].Category)
+		s.Nil(totals[1].Children)
+	}
+}
+
+func (s *CategoryTestSuite) mustTotals(totals []CategoryTotal, err error) []CategoryTotal {
+	s.Require().NoError(err)
+	return totals
+}
+
+func (s *CategoryTestSuite) TestMigrationSeedsUsedCategories() {
+	if s.db.dialect.postgres() {
+		s.T().Skip("PostgreSQL databases start at the current schema")
+	}
+
+	db, err := Open(":memory:")
+	s.Require().NoError(err)
+	defer db.Close()
+
+	s.Require().NoError(db.ensureMigrationsTable(s.ctx))
+	for _, m := range migrations[:4] {
+		s.Require().NoError(db.applyMigration(s.ctx, m))
+	}
+	_, err = db.conn.Exec(`INSERT INTO households (name) VALUES ('Home');
+		INSERT INTO expenses (amount, description, category, date, household_id) VALUES
+		(100, 'Kibble', 'Pets', '2026-01-01 10:00:00', 1),
+		(200, 'Bread', 'Groceries', '2026-01-01 11:00:00', 1)`)
+	s.Require().NoError(err)
+
+	_, err = db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+
+	categories, err := db.ListCategories(s.ctx, 1, false)
+	s.Require().NoError(err)
+	s.Len(categories, len(defaultCategories)+1)
+	s.Equal("Pets", categories[len(categories)-1].Name)
+}
+
+// TestCategorySuite runs the category test suite
+func TestCategorySuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &CategoryTestSuite{dsn: dsn})
+	})
+}
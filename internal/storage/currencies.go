@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrNoExchangeRate is returned when an amount is in a currency the household
+// has no exchange rate for.
+var ErrNoExchangeRate = errors.New("no exchange rate")
+
+// querier runs queries on the database or within a transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// householdCurrency returns the base currency of a household.
+func householdCurrency(ctx context.Context, q querier, householdID int64) (string, error) {
+	var currency string
+	err := q.QueryRowContext(ctx, "SELECT currency FROM households WHERE id = ?", householdID).Scan(&currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return currency, err
+}
+
+// loadRates returns the exchange rates of a household that can connect the
+// currencies: those quoting one of them against any base.
+func loadRates(ctx context.Context, q querier, householdID int64, currencies ...string) (*models.Rates, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(currencies)), ", ")
+	args := make([]any, 0, len(currencies)+1)
+	args = append(args, householdID)
+	for _, c := range currencies {
+		args = append(args, c)
+	}
+	rows, err := q.QueryContext(ctx,
+		"SELECT base, currency, date, rate FROM exchange_rates WHERE household_id = ? AND currency IN ("+placeholders+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.ExchangeRate
+	for rows.Next() {
+		var r models.ExchangeRate
+		if err := rows.Scan(&r.Base, &r.Currency, &r.Date, &r.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return models.NewRates(rates), nil
+}
+
+// convertExpenses fills in the currency fields of expenses recorded in a
+// household with the given base currency. An empty Currency is the base
+// currency and a zero OriginalAmount is taken from Amount. Amount is then set
+// to OriginalAmount converted at the household's rate of the expense's date.
+// It returns ErrNoExchangeRate if the household has no rate for a currency.
+func convertExpenses(ctx context.Context, q querier, householdID int64, base string, expenses ...*models.Expense) error {
+	currencies := []string{base}
+	for _, e := range expenses {
+		if e.Currency == "" {
+			e.Currency = base
+		}
+		if e.OriginalAmount == 0 {
+			e.OriginalAmount = e.Amount
+		}
+		e.Amount = e.OriginalAmount
+		if e.Currency != base {
+			currencies = append(currencies, e.Currency)
+		}
+	}
+	if len(currencies) == 1 {
+		return nil
+	}
+
+	rates, err := loadRates(ctx, q, householdID, currencies...)
+	if err != nil {
+		return err
+	}
+	for _, e := range expenses {
+		amount, ok := rates.Convert(e.OriginalAmount, e.Currency, base, e.Date)
+		if !ok {
+			return fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, e.Currency, base)
+		}
+		e.Amount = amount
+	}
+	return nil
+}
+
+// reconvertExpenses recomputes Amount of the expenses of a household after
+// its base currency or its exchange rates changed. Expenses in a currency
+// without a rate are left as they are; it returns ErrNoExchangeRate, after
+// converting the others, if there are any.
+func reconvertExpenses(ctx context.Context, tx *sql.Tx, householdID int64) error {
+	base, err := householdCurrency(ctx, tx, householdID)
+	if err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, original_amount, currency, date FROM expenses WHERE household_id = ?",
+		householdID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var expenses []models.Expense
+	currencies := map[string]bool{base: true}
+	for rows.Next() {
+		var e models.Expense
+		if err := rows.Scan(&e.ID, &e.OriginalAmount, &e.Currency, &e.Date); err != nil {
+			return err
+		}
+		expenses = append(expenses, e)
+		currencies[e.Currency] = true
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if len(expenses) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(currencies))
+	for c := range currencies {
+		codes = append(codes, c)
+	}
+	rates, err := loadRates(ctx, tx, householdID, codes...)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE expenses SET amount = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var missing []string
+	for _, e := range expenses {
+		amount, ok := rates.Convert(e.OriginalAmount, e.Currency, base, e.Date)
+		if !ok {
+			if !slices.Contains(missing, e.Currency) {
+				missing = append(missing, e.Currency)
+			}
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, amount, e.ID); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, strings.Join(missing, ", "), base)
+	}
+	return nil
+}
+
+// SetHouseholdCurrency changes the base currency of a household and converts
+// its expenses to it. It returns ErrNoExchangeRate, changing nothing, if an
+// expense cannot be converted.
+func (db *DB) SetHouseholdCurrency(ctx context.Context, householdID int64, currency string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "UPDATE households SET currency = ? WHERE id = ?", currency, householdID)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(result); err != nil {
+			return err
+		}
+		return reconvertExpenses(ctx, tx, householdID)
+	})
+}
+
+// SaveExchangeRates stores exchange rates of a household, replacing those of
+// the same currencies and day, and converts its expenses at the new rates.
+// Expenses recorded before there was a rate for their currency, which were
+// kept at face value, stay so until one is saved. It returns the number of
+// rates saved.
+func (db *DB) SaveExchangeRates(ctx context.Context, householdID int64, rates []models.ExchangeRate) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO exchange_rates (household_id, base, currency, date, rate) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (household_id, base, currency, date) DO UPDATE SET rate = excluded.rate`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, r := range rates {
+			if _, err := stmt.ExecContext(ctx, householdID, r.Base, r.Currency, models.Day(r.Date), r.Rate); err != nil {
+				return err
+			}
+		}
+		if err := reconvertExpenses(ctx, tx, householdID); err != nil && !errors.Is(err, ErrNoExchangeRate) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+// ListExchangeRates returns up to limit exchange rates of a household, newest
+// first.
+func (db *DB) ListExchangeRates(ctx context.Context, householdID int64, limit int) ([]models.ExchangeRate, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.read.QueryContext(ctx,
+		"SELECT base, currency, date, rate FROM exchange_rates WHERE household_id = ? ORDER BY date DESC, base, currency LIMIT ?",
+		householdID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.ExchangeRate
+	for rows.Next() {
+		var r models.ExchangeRate
+		if err := rows.Scan(&r.Base, &r.Currency, &r.Date, &r.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// CurrencyTestSuite provides a test suite for base currencies and exchange rates
+type CurrencyTestSuite struct {
+	suite.Suite
+	dsn       string // Database under test, see runOnBackends
+	ctx       context.Context
+	db        *DB
+	user      *models.User
+	household *models.Household
+}
+
+// SetupTest runs before each test
+func (s *CurrencyTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	s.household, err = s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+}
+
+// TearDownTest runs after each test
+func (s *CurrencyTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *CurrencyTestSuite) saveRate(base, currency, day string, rate float64) {
+	date, err := time.Parse("2006-01-02", day)
+	s.Require().NoError(err)
+	_, err = s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: base, Currency: currency, Date: date, Rate: rate}})
+	s.Require().NoError(err)
+}
+
+func (s *CurrencyTestSuite) TestNewHouseholdUsesDefaultCurrency() {
+	s.Equal(models.DefaultCurrency, s.household.Currency)
+}
+
+func (s *CurrencyTestSuite) TestCreateExpense_BaseCurrency() {
+	e := &models.Expense{Amount: 1250, Description: "Lunch", Category: "Eating Out", Date: time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal("EUR", got.Currency)
+	s.Equal(models.Money(1250), got.Amount)
+	s.Equal(models.Money(1250), got.OriginalAmount)
+}
+
+func (s *CurrencyTestSuite) TestCreateExpense_ConvertsAtRateOfDate() {
+	s.saveRate("EUR", "USD", "2024-01-01", 1.25)
+	s.saveRate("EUR", "USD", "2024-02-01", 1.0)
+
+	e := &models.Expense{OriginalAmount: 1000, Currency: "USD", Description: "Taxi", Category: "Transport", Date: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+	s.Equal(models.Money(800), e.Amount)
+
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal("USD", got.Currency)
+	s.Equal(models.Money(1000), got.OriginalAmount)
+	s.Equal(models.Money(800), got.Amount)
+
+	totals, err := s.db.GetTotalForPeriod(s.ctx, UserScope(s.user.ID), 2024, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(800), totals.Spending)
+}
+
+func (s *CurrencyTestSuite) TestCreateExpense_WithoutRate() {
+	e := &models.Expense{Amount: 1000, Currency: "JPY", Description: "Coffee", Category: "Eating Out", Date: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)}
+	s.ErrorIs(s.db.CreateExpense(s.ctx, s.user.ID, e), ErrNoExchangeRate)
+	expenses, err := s.db.ListExpenses(s.ctx, UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Empty(expenses)
+
+	e = &models.Expense{Amount: 1000, Description: "Coffee", Category: "Eating Out", Date: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+	e.Currency = "JPY"
+	s.ErrorIs(s.db.UpdateExpense(s.ctx, UserScope(s.user.ID), e), ErrNoExchangeRate)
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal("EUR", got.Currency)
+
+	_, err = s.db.ImportExpenses(s.ctx, s.user.ID, []models.Expense{{Amount: 500, Currency: "JPY", Description: "Tea", Category: "Eating Out", Date: e.Date}})
+	s.ErrorIs(err, ErrNoExchangeRate)
+}
+
+func (s *CurrencyTestSuite) TestUpdateExpense_Converts() {
+	s.saveRate("EUR", "USD", "2024-01-01", 2)
+	e := &models.Expense{Amount: 1000, Description: "Taxi", Category: "Transport", Date: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+
+	e.Currency = "USD"
+	e.OriginalAmount = 3000
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, UserScope(s.user.ID), e))
+
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Money(1500), got.Amount)
+	s.Equal(models.Money(3000), got.OriginalAmount)
+}
+
+func (s *CurrencyTestSuite) TestUpdateExpense_OtherHousehold() {
+	err := s.db.UpdateExpense(s.ctx, UserScope(s.user.ID), &models.Expense{ID: 12345, Amount: 100, Description: "x", Category: "Other"})
+	s.ErrorIs(err, ErrNotFound)
+}
+
+func (s *CurrencyTestSuite) TestSaveExchangeRates_ReconvertsExpenses() {
+	s.saveRate("EUR", "USD", "2024-01-01", 1)
+	e := &models.Expense{OriginalAmount: 1000, Currency: "USD", Description: "Taxi", Category: "Transport", Date: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+	s.Equal(models.Money(1000), e.Amount)
+
+	s.saveRate("EUR", "USD", "2024-01-19", 1.25)
+
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Money(800), got.Amount)
+
+	// Replacing the rate of the same day
+	s.saveRate("EUR", "USD", "2024-01-19", 2)
+	got, err = s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Money(500), got.Amount)
+}
+
+func (s *CurrencyTestSuite) TestSaveExchangeRates_LeavesExpensesWithoutRate() {
+	// Recorded at face value before rates were required
+	_, err := s.db.conn.ExecContext(s.ctx,
+		"INSERT INTO expenses (kind, amount, currency, original_amount, description, category, date, household_id) VALUES ('expense', 1000, 'CHF', 1000, 'Coffee', 'Eating Out', ?, ?)",
+		time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC), s.household.ID,
+	)
+	s.Require().NoError(err)
+
+	s.saveRate("EUR", "USD", "2024-01-01", 2)
+	totals, err := s.db.GetTotalForPeriod(s.ctx, UserScope(s.user.ID), 2024, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(1000), totals.Spending)
+
+	s.saveRate("EUR", "CHF", "2024-01-01", 0.5)
+	totals, err = s.db.GetTotalForPeriod(s.ctx, UserScope(s.user.ID), 2024, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(2000), totals.Spending)
+}
+
+func (s *CurrencyTestSuite) TestSaveExchangeRates_OtherHouseholdUnchanged() {
+	bob, err := s.db.CreateUser(s.ctx, "bob", "hash")
+	s.Require().NoError(err)
+	flat, err := s.db.CreateHousehold(s.ctx, "Flat")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, flat.ID, bob.ID))
+	_, err = s.db.SaveExchangeRates(s.ctx, flat.ID, []models.ExchangeRate{{Base: "EUR", Currency: "USD", Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Rate: 1.25}})
+	s.Require().NoError(err)
+	e := &models.Expense{OriginalAmount: 1000, Currency: "USD", Description: "Taxi", Category: "Transport", Date: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, bob.ID, e))
+	s.Equal(models.Money(800), e.Amount)
+
+	// Another household's rate neither converts nor reconverts the expense
+	s.saveRate("EUR", "USD", "2024-01-19", 2)
+	got, err := s.db.GetExpense(s.ctx, UserScope(bob.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Money(800), got.Amount)
+
+	rates, err := s.db.ListExchangeRates(s.ctx, flat.ID, 10)
+	s.Require().NoError(err)
+	s.Len(rates, 1)
+}
+
+func (s *CurrencyTestSuite) TestSetHouseholdCurrency() {
+	s.saveRate("EUR", "USD", "2024-01-01", 1.5)
+	e := &models.Expense{Amount: 1000, Description: "Groceries", Category: "Groceries", Date: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC)}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+
+	s.Require().NoError(s.db.SetHouseholdCurrency(s.ctx, s.household.ID, "USD"))
+
+	hh, err := s.db.GetHousehold(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Equal("USD", hh.Currency)
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal("EUR", got.Currency)
+	s.Equal(models.Money(1500), got.Amount)
+
+	s.ErrorIs(s.db.SetHouseholdCurrency(s.ctx, 12345, "USD"), ErrNotFound)
+
+	// Without a rate to the new base nothing changes
+	s.ErrorIs(s.db.SetHouseholdCurrency(s.ctx, s.household.ID, "JPY"), ErrNoExchangeRate)
+	hh, err = s.db.GetHousehold(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Equal("USD", hh.Currency)
+	got, err = s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Money(1500), got.Amount)
+}
+
+func (s *CurrencyTestSuite) TestImportExpenses_Converts() {
+	s.saveRate("EUR", "GBP", "2024-01-01", 0.5)
+	date := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	imported, err := s.db.ImportExpenses(s.ctx, s.user.ID, []models.Expense{
+		{Amount: 1000, Currency: "GBP", Description: "Train", Category: "Transport", Date: date},
+		{Amount: 700, Description: "Bakery", Category: "Groceries", Date: date},
+	})
+	s.Require().NoError(err)
+	s.Equal(2, imported)
+
+	totals, err := s.db.GetTotalForPeriod(s.ctx, UserScope(s.user.ID), 2024, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(2700), totals.Spending)
+}
+
+func (s *CurrencyTestSuite) TestListExchangeRates() {
+	s.saveRate("EUR", "USD", "2024-01-01", 1.1)
+	s.saveRate("EUR", "USD", "2024-01-02", 1.2)
+	s.saveRate("EUR", "GBP", "2024-01-02", 0.9)
+
+	rates, err := s.db.ListExchangeRates(s.ctx, s.household.ID, 2)
+	s.Require().NoError(err)
+	s.Require().Len(rates, 2)
+	s.Equal("GBP", rates[0].Currency)
+	s.Equal("USD", rates[1].Currency)
+	s.InDelta(1.2, rates[1].Rate, 1e-9)
+	s.True(rates[1].Date.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+}
+
+// TestCurrencySuite runs the currency test suite
+func TestCurrencySuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &CurrencyTestSuite{dsn: dsn})
+	})
+}
@@ -1,78 +1,212 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
-	// Import sqlite driver
-	_ "modernc.org/sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
 )
 
-// DB wraps a sql.DB connection.
+// sqliteConstraintUnique is the extended result code of a UNIQUE constraint violation.
+const sqliteConstraintUnique = 2067
+
+// DB wraps the connections to a SQLite or PostgreSQL database. With SQLite
+// all writes go through a single connection, so they queue in Go instead of
+// failing with SQLITE_BUSY, while reads use a pool of their own and never
+// wait for a writer in WAL mode. PostgreSQL uses one pool for both.
 type DB struct {
-	conn *sql.DB
+	conn    *sql.DB // The single connection writes and transactions use
+	read    *sql.DB // Read-only pool for queries; conn itself for in-memory databases
+	dialect dialect
+
+	timeout time.Duration // Limit of a single storage call, see Options.QueryTimeout
 }
 
-// NewDB opens a database connection and runs migrations.
-func NewDB(path string) (*DB, error) {
-	conn, err := sql.Open("sqlite", path)
+// Options tunes the connections of a DB. Only ReadConns and QueryTimeout
+// apply to PostgreSQL, where the pool has ReadConns+1 connections.
+type Options struct {
+	JournalMode string        // journal_mode pragma, e.g. WAL; empty keeps the database's mode
+	Synchronous string        // synchronous pragma, e.g. NORMAL or FULL; empty keeps SQLite's default
+	BusyTimeout time.Duration // How long to wait for another process's lock before failing
+	ForeignKeys bool          // Enforce foreign key constraints
+	ReadConns   int           // Size of the read pool; 0 sends reads through the writer
+	ReadOnly    bool          // Refuse writes on every connection and leave the journal mode alone
+	// QueryTimeout limits how long a single storage call may take, including
+	// the wait for the writer connection; 0 leaves it to the caller's context.
+	// Maintenance such as backups and migrations is not limited.
+	QueryTimeout time.Duration
+}
+
+// DefaultOptions returns the options Open and NewDB use: WAL with synchronous
+// NORMAL, a five second busy timeout, foreign keys on, four readers and ten
+// seconds per query.
+func DefaultOptions() Options {
+	return Options{
+		JournalMode:  "WAL",
+		Synchronous:  "NORMAL",
+		BusyTimeout:  5 * time.Second,
+		ForeignKeys:  true,
+		ReadConns:    4,
+		QueryTimeout: 10 * time.Second,
+	}
+}
+
+func (o Options) validate() error {
+	switch strings.ToUpper(o.JournalMode) {
+	case "", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF":
+	default:
+		return fmt.Errorf("invalid journal mode %q", o.JournalMode)
+	}
+	switch strings.ToUpper(o.Synchronous) {
+	case "", "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		return fmt.Errorf("invalid synchronous setting %q", o.Synchronous)
+	}
+	if o.BusyTimeout < 0 || o.ReadConns < 0 || o.QueryTimeout < 0 {
+		return errors.New("timeouts and read connections must not be negative")
+	}
+	return nil
+}
+
+// dsn returns the data source name of path with the pragmas every new
+// connection runs.
+func (o Options) dsn(path string, readOnly bool) string {
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", o.BusyTimeout.Milliseconds()))
+	if o.ForeignKeys {
+		q.Add("_pragma", "foreign_keys(1)")
+	}
+	if o.Synchronous != "" {
+		q.Add("_pragma", "synchronous("+o.Synchronous+")")
+	}
+	if readOnly || o.ReadOnly {
+		q.Add("_pragma", "query_only(1)")
+	} else {
+		// Take the write lock when a transaction begins rather than on its
+		// first write, so the busy timeout applies instead of a deadlock error
+		q.Set("_txlock", "immediate")
+		if o.JournalMode != "" {
+			q.Add("_pragma", "journal_mode("+o.JournalMode+")")
+		}
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + q.Encode()
+}
+
+// isMemory reports whether path names an in-memory database, which each
+// connection would otherwise get a separate copy of.
+func isMemory(path string) bool {
+	return path == "" || strings.HasPrefix(path, ":memory:") || strings.Contains(path, "mode=memory")
+}
+
+// Open opens a database connection without touching the schema.
+// Use it for tooling that manages migrations explicitly. A path starting with
+// postgres:// or postgresql:// connects to PostgreSQL instead of SQLite.
+func Open(path string) (*DB, error) {
+	return OpenWithOptions(path, DefaultOptions())
+}
+
+// OpenWithOptions is like Open but tunes the connections with opts.
+func OpenWithOptions(path string, opts Options) (*DB, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if isPostgres(path) {
+		return openPostgres(path, opts)
+	}
+
+	conn, err := sql.Open("sqlite", opts.dsn(path, false))
 	if err != nil {
 		return nil, err
 	}
+	conn.SetMaxOpenConns(1)
+	conn.SetConnMaxLifetime(0)
+	conn.SetConnMaxIdleTime(0)
 
 	if err := conn.Ping(); err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	db := &DB{conn: conn}
-	if err := db.migrate(); err != nil {
-		return nil, err
+	db := &DB{conn: conn, read: conn, dialect: sqliteDialect, timeout: opts.QueryTimeout}
+	if opts.ReadConns > 0 && !isMemory(path) {
+		read, err := sql.Open("sqlite", opts.dsn(path, true))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		read.SetMaxOpenConns(opts.ReadConns)
+		read.SetMaxIdleConns(opts.ReadConns)
+		db.read = read
 	}
 
 	return db, nil
 }
 
-func (db *DB) migrate() error {
-	migrations := []string{
-		`CREATE TABLE IF NOT EXISTS expenses (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			amount REAL NOT NULL,
-			description TEXT NOT NULL,
-			category TEXT NOT NULL,
-			date DATETIME NOT NULL
-		)`,
-		`CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			username TEXT UNIQUE NOT NULL,
-			password_hash TEXT NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS sessions (
-			token TEXT PRIMARY KEY,
-			user_id INTEGER NOT NULL,
-			expires_at DATETIME NOT NULL,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		)`,
-	}
-
-	for _, m := range migrations {
-		if _, err := db.conn.Exec(m); err != nil {
-			return err
-		}
+// NewDB opens a database connection and runs migrations.
+func NewDB(path string) (*DB, error) {
+	return NewDBWithOptions(path, DefaultOptions())
+}
+
+// NewDBWithOptions is like NewDB but tunes the connections with opts.
+func NewDBWithOptions(path string, opts Options) (*DB, error) {
+	db, err := OpenWithOptions(path, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add user_id column to expenses if it doesn't exist (for backwards compatibility)
-	// We ignore the error here because the column might already exist
-	_, _ = db.conn.Exec(`ALTER TABLE expenses ADD COLUMN user_id INTEGER REFERENCES users(id)`)
+	if _, err := db.MigrateUp(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
 
-	// Add last_activity column to sessions for rolling sessions
-	_, _ = db.conn.Exec(`ALTER TABLE sessions ADD COLUMN last_activity DATETIME DEFAULT CURRENT_TIMESTAMP`)
+	return db, nil
+}
 
-	// Add unique constraint on date, amount, description for expenses
-	_, _ = db.conn.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS expenses_date_amount_description_uindex ON expenses (date, amount, description)`)
-	return nil
+// withTimeout bounds a single storage call by the query timeout, on top of
+// any deadline or cancellation ctx already carries.
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.timeout)
 }
 
-// Close closes the database connection.
+// inTx runs fn inside a transaction, committing on success and rolling back on error.
+func (db *DB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// isUniqueViolation reports whether err is a UNIQUE constraint violation.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	var pgErr *pgconn.PgError
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqliteConstraintUnique ||
+		errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation
+}
+
+// Close closes the database connections.
 func (db *DB) Close() error {
+	if db.read != db.conn {
+		db.read.Close()
+	}
 	return db.conn.Close()
 }
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// DBTestSuite provides a test suite for connection setup
+type DBTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	db  *DB
+}
+
+// SetupTest runs before each test
+func (s *DBTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := NewDB(filepath.Join(s.T().TempDir(), "expenses.db"))
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+}
+
+// TearDownTest runs after each test
+func (s *DBTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *DBTestSuite) pragma(conn interface {
+	QueryRow(string, ...any) *sql.Row
+}, name string) string {
+	var value string
+	s.Require().NoError(conn.QueryRow("PRAGMA " + name).Scan(&value))
+	return value
+}
+
+func (s *DBTestSuite) TestDefaultOptions() {
+	s.Equal("wal", s.pragma(s.db.conn, "journal_mode"))
+	s.Equal("1", s.pragma(s.db.conn, "synchronous"), "NORMAL")
+	s.Equal("5000", s.pragma(s.db.conn, "busy_timeout"))
+	s.Equal("1", s.pragma(s.db.conn, "foreign_keys"))
+
+	s.NotSame(s.db.conn, s.db.read)
+	s.Equal("1", s.pragma(s.db.read, "query_only"))
+	s.Equal("5000", s.pragma(s.db.read, "busy_timeout"))
+}
+
+func (s *DBTestSuite) TestOpenWithOptions() {
+	path := filepath.Join(s.T().TempDir(), "tuned.db")
+	db, err := OpenWithOptions(path, Options{JournalMode: "delete", Synchronous: "full", BusyTimeout: time.Second})
+	s.Require().NoError(err)
+	defer db.Close()
+
+	s.Equal("delete", s.pragma(db.conn, "journal_mode"))
+	s.Equal("2", s.pragma(db.conn, "synchronous"), "FULL")
+	s.Equal("1000", s.pragma(db.conn, "busy_timeout"))
+	s.Equal("0", s.pragma(db.conn, "foreign_keys"))
+	s.Same(db.conn, db.read, "no read pool without read connections")
+
+	readOnly, err := OpenWithOptions(path, Options{JournalMode: "wal", ReadOnly: true})
+	s.Require().NoError(err)
+	defer readOnly.Close()
+	s.Equal("1", s.pragma(readOnly.conn, "query_only"))
+	s.Equal("delete", s.pragma(readOnly.conn, "journal_mode"), "read-only connections keep the journal mode")
+
+	_, err = OpenWithOptions(path, Options{JournalMode: "wal; DROP TABLE users"})
+	s.Error(err)
+	_, err = OpenWithOptions(path, Options{Synchronous: "sometimes"})
+	s.Error(err)
+}
+
+func (s *DBTestSuite) TestForeignKeysCascade() {
+	user, err := s.db.CreateUser(s.ctx, "testuser", "hash")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.CreateSession(s.ctx, "token", user.ID, time.Now().Add(time.Hour)))
+
+	_, err = s.db.conn.Exec("DELETE FROM users WHERE id = ?", user.ID)
+	s.Require().NoError(err)
+
+	var sessions int
+	s.Require().NoError(s.db.conn.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&sessions))
+	s.Zero(sessions, "sessions are deleted with their user")
+}
+
+func (s *DBTestSuite) TestReadsDoNotWaitForWriter() {
+	user, err := s.db.CreateUser(s.ctx, "testuser", "hash")
+	s.Require().NoError(err)
+	household, err := s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, household.ID, user.ID))
+
+	// Hold the only writer connection in an open transaction
+	tx, err := s.db.conn.Begin()
+	s.Require().NoError(err)
+	defer tx.Rollback()
+	_, err = tx.Exec(
+		"INSERT INTO expenses (household_id, user_id, kind, amount, description, category, date) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		household.ID, user.ID, models.KindExpense, 1250, "Lunch", "Food", time.Now(),
+	)
+	s.Require().NoError(err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.db.ListExpenses(s.ctx, UserScope(user.ID), 10, 0)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		s.NoError(err)
+	case <-time.After(2 * time.Second):
+		s.Fail("reading blocked on the open write transaction")
+	}
+}
+
+func (s *DBTestSuite) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	_, err := s.db.UserCount(ctx)
+	s.ErrorIs(err, context.Canceled)
+}
+
+func (s *DBTestSuite) TestQueryTimeout() {
+	opts := DefaultOptions()
+	opts.QueryTimeout = 50 * time.Millisecond
+	db, err := NewDBWithOptions(filepath.Join(s.T().TempDir(), "timeout.db"), opts)
+	s.Require().NoError(err)
+	defer db.Close()
+
+	// A write waiting for the writer connection gives up after the timeout
+	tx, err := db.conn.BeginTx(s.ctx, nil)
+	s.Require().NoError(err)
+	defer tx.Rollback()
+
+	start := time.Now()
+	_, err = db.CreateUser(s.ctx, "testuser", "hash")
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Less(time.Since(start), 2*time.Second)
+}
+
+// TestDBSuite runs the connection test suite
+func TestDBSuite(t *testing.T) {
+	suite.Run(t, new(DBTestSuite))
+}
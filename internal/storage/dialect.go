@@ -0,0 +1,38 @@
+package storage
+
+// dialect holds the parts of the SQL that differ between SQLite and
+// PostgreSQL. Queries are otherwise written once, with ? placeholders.
+type dialect struct {
+	name      string
+	timestamp string // Column type of points in time
+	month     string // Month of e.date as an integer
+	day       string // Day of the month of e.date as an integer
+}
+
+var sqliteDialect = dialect{
+	name:      "sqlite",
+	timestamp: "DATETIME",
+	// Dates are stored as text starting with YYYY-MM-DD
+	month: "CAST(SUBSTR(e.date, 6, 2) AS INTEGER)",
+	day:   "CAST(SUBSTR(e.date, 9, 2) AS INTEGER)",
+}
+
+var postgresDialect = dialect{
+	name:      "postgres",
+	timestamp: "TIMESTAMPTZ",
+	month:     "CAST(EXTRACT(MONTH FROM e.date) AS INTEGER)",
+	day:       "CAST(EXTRACT(DAY FROM e.date) AS INTEGER)",
+}
+
+// postgres reports whether the dialect is PostgreSQL's.
+func (d dialect) postgres() bool {
+	return d.name == postgresDialect.name
+}
+
+// migrations returns the schema changes of the dialect in order.
+func (d dialect) migrations() []migration {
+	if d.postgres() {
+		return postgresMigrations
+	}
+	return migrations
+}
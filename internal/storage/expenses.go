@@ -1,141 +1,587 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"expense-tracker/internal/models"
 )
 
-// CreateExpense inserts a new expense into the database.
-func (db *DB) CreateExpense(amount float64, description, category string, date time.Time, userID int64) error {
-	if date.IsZero() {
-		date = time.Now()
+// ErrDuplicateExpense is returned when the household already has a transaction
+// with the same date, amount and description, or the same occurrence of a
+// recurring template.
+var ErrDuplicateExpense = errors.New("expense already exists")
+
+const expenseColumns = "e.id, e.kind, e.amount, e.currency, e.original_amount, e.description, e.category, e.date, e.user_id, e.household_id, e.account_id, e.recurring_id, COALESCE(e.import_ref, '')"
+
+// spendingAmount is the contribution of a transaction to spending: expenses
+// add, refunds subtract and income does not count.
+const spendingAmount = "CASE e.kind WHEN 'expense' THEN e.amount WHEN 'refund' THEN -e.amount ELSE 0 END"
+
+// incomeAmount is the contribution of a transaction to income.
+const incomeAmount = "CASE e.kind WHEN 'income' THEN e.amount ELSE 0 END"
+
+func scanExpense(row interface{ Scan(...any) error }, e *models.Expense) error {
+	return row.Scan(&e.ID, &e.Kind, &e.Amount, &e.Currency, &e.OriginalAmount, &e.Description, &e.Category, &e.Date, &e.UserID, &e.HouseholdID, &e.AccountID, &e.RecurringID, &e.ImportRef)
+}
+
+func (db *DB) queryExpenses(ctx context.Context, query string, args ...any) ([]models.Expense, error) {
+	rows, err := db.read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
-	_, err := db.conn.Exec(
-		"INSERT INTO expenses (amount, description, category, date, user_id) VALUES (?, ?, ?, ?, ?)",
-		amount, description, category, date, userID,
-	)
-	return err
+	defer rows.Close()
+
+	var expenses []models.Expense
+	for rows.Next() {
+		var e models.Expense
+		if err := scanExpense(rows, &e); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadExpenseDetails(ctx, db.read, expenses); err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+// expenseBatch is how many expenses loadByExpense looks up per query, well
+// below the placeholder limits of both backends.
+const expenseBatch = 500
+
+// loadExpenseDetails fills in the split parts, sharing and tags of expenses,
+// which are kept in tables of their own.
+func loadExpenseDetails(ctx context.Context, q querier, expenses []models.Expense) error {
+	if err := loadSplits(ctx, q, expenses); err != nil {
+		return err
+	}
+	if err := loadSharing(ctx, q, expenses); err != nil {
+		return err
+	}
+	return loadTags(ctx, q, expenses)
+}
+
+// loadByExpense runs query for batches of expenses, with placeholders for
+// their IDs in place of %s, and calls scan for every row with the expenses by
+// ID.
+func loadByExpense(ctx context.Context, q querier, expenses []models.Expense, query string, scan func(rows *sql.Rows, byID map[int64]*models.Expense) error) error {
+	byID := make(map[int64]*models.Expense, len(expenses))
+	ids := make([]any, len(expenses))
+	for i := range expenses {
+		byID[expenses[i].ID] = &expenses[i]
+		ids[i] = expenses[i].ID
+	}
+
+	for start := 0; start < len(ids); start += expenseBatch {
+		batch := ids[start:min(start+expenseBatch, len(ids))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		rows, err := q.QueryContext(ctx, fmt.Sprintf(query, placeholders), batch...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			if err := scan(rows, byID); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateExpense inserts a new transaction into the user's default household.
+// An empty Kind is stored as an expense and a zero Date as now. The amount is
+// converted to the household's base currency as described at
+// convertExpenses. On success the ID, UserID, HouseholdID and currency fields
+// of e are filled in. It returns ErrDuplicateExpense if the transaction is
+// already recorded, ErrInvalidAccount if AccountID is not an account of the
+// household, ErrNoExchangeRate if the household has no rate for the currency,
+// ErrInvalidSplit if the parts of a split expense do not add up,
+// ErrInvalidShare if the shares of a shared expense are not valid and
+// ErrInvalidTag if a tag is not valid.
+func (db *DB) CreateExpense(ctx context.Context, userID int64, e *models.Expense) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	householdID, err := db.DefaultHouseholdID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return db.createExpense(ctx, householdID, userID, e)
+}
+
+// CreateHouseholdExpense inserts a new transaction recorded by a user into
+// the given household, like CreateExpense does into the user's default one.
+// The caller is responsible for the user's access to the household.
+func (db *DB) CreateHouseholdExpense(ctx context.Context, householdID, userID int64, e *models.Expense) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.createExpense(ctx, householdID, userID, e)
+}
+
+func (db *DB) createExpense(ctx context.Context, householdID, userID int64, e *models.Expense) error {
+	if e.Date.IsZero() {
+		e.Date = time.Now()
+	}
+	if e.Kind == "" {
+		e.Kind = models.KindExpense
+	}
+	if err := checkAccount(ctx, db.read, householdID, e.AccountID); err != nil {
+		return err
+	}
+	base, err := householdCurrency(ctx, db.read, householdID)
+	if err != nil {
+		return err
+	}
+	if err := convertExpenses(ctx, db.read, householdID, base, e); err != nil {
+		return err
+	}
+	if err := prepareSplits(e); err != nil {
+		return err
+	}
+	if err := prepareSharing(ctx, db.read, householdID, e); err != nil {
+		return err
+	}
+	if err := prepareTags(e); err != nil {
+		return err
+	}
+	var id int64
+	err = db.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			"INSERT INTO expenses (kind, amount, currency, original_amount, description, category, date, user_id, household_id, account_id, recurring_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id",
+			e.Kind, e.Amount, e.Currency, e.OriginalAmount, e.Description, e.Category, e.Date, userID, householdID, e.AccountID, e.RecurringID,
+		).Scan(&id)
+		if isUniqueViolation(err) {
+			return ErrDuplicateExpense
+		}
+		if err != nil {
+			return err
+		}
+		if err := saveSplits(ctx, tx, id, e.Splits); err != nil {
+			return err
+		}
+		if err := saveSharing(ctx, tx, id, e.Sharing); err != nil {
+			return err
+		}
+		return saveTags(ctx, tx, householdID, id, e.Tags)
+	})
+	if err != nil {
+		return err
+	}
+
+	e.ID = id
+	e.UserID = &userID
+	e.HouseholdID = householdID
+	return nil
 }
 
-// GetExpense retrieves a single expense by ID.
-func (db *DB) GetExpense(id int64) (*models.Expense, error) {
-	row := db.conn.QueryRow(
-		"SELECT id, amount, description, category, date, user_id FROM expenses WHERE id = ?",
-		id,
+// GetExpense retrieves a single expense by ID within the scope.
+// It returns ErrNotFound if the expense does not exist or belongs to another household.
+func (db *DB) GetExpense(ctx context.Context, scope Scope, id int64) (*models.Expense, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	cond, args := scope.clause()
+	row := db.read.QueryRowContext(ctx,
+		"SELECT "+expenseColumns+" FROM expenses e WHERE e.id = ? AND "+cond,
+		append([]any{id}, args...)...,
 	)
 
 	var e models.Expense
-	if err := row.Scan(&e.ID, &e.Amount, &e.Description, &e.Category, &e.Date, &e.UserID); err != nil {
+	if err := scanExpense(row, &e); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	expenses := []models.Expense{e}
+	if err := loadExpenseDetails(ctx, db.read, expenses); err != nil {
 		return nil, err
 	}
-	return &e, nil
+	return &expenses[0], nil
+}
+
+// UpdateExpense updates an existing expense within the scope, converting its
+// amount like CreateExpense. It returns ErrNotFound if the expense does not
+// exist or belongs to another household, ErrDuplicateExpense if the change
+// makes it a duplicate, ErrInvalidAccount if AccountID is not an account of
+// the expense's household, ErrNoExchangeRate if the household has no rate
+// for the currency, ErrInvalidSplit if the parts of a split expense do
+// not add up, ErrInvalidShare if the shares are not valid and ErrInvalidTag if
+// a tag is not valid. The parts, shares and tags are replaced by e.Splits,
+// e.Sharing and e.Tags.
+func (db *DB) UpdateExpense(ctx context.Context, scope Scope, e *models.Expense) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if e.Kind == "" {
+		e.Kind = models.KindExpense
+	}
+	cond, args := scope.clause()
+	var householdID int64
+	var base string
+	err := db.read.QueryRowContext(ctx,
+		"SELECT h.id, h.currency FROM expenses e JOIN households h ON h.id = e.household_id WHERE e.id = ? AND "+cond,
+		append([]any{e.ID}, args...)...,
+	).Scan(&householdID, &base)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkAccount(ctx, db.read, householdID, e.AccountID); err != nil {
+		return err
+	}
+	if err := convertExpenses(ctx, db.read, householdID, base, e); err != nil {
+		return err
+	}
+	if err := prepareSplits(e); err != nil {
+		return err
+	}
+	if err := prepareSharing(ctx, db.read, householdID, e); err != nil {
+		return err
+	}
+	if err := prepareTags(e); err != nil {
+		return err
+	}
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE expenses AS e SET kind = ?, amount = ?, currency = ?, original_amount = ?, description = ?, category = ?, date = ?, account_id = ? WHERE e.id = ? AND "+cond,
+			append([]any{e.Kind, e.Amount, e.Currency, e.OriginalAmount, e.Description, e.Category, e.Date, e.AccountID, e.ID}, args...)...,
+		)
+		if isUniqueViolation(err) {
+			return ErrDuplicateExpense
+		}
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(result); err != nil {
+			return err
+		}
+		if err := saveSplits(ctx, tx, e.ID, e.Splits); err != nil {
+			return err
+		}
+		if err := saveSharing(ctx, tx, e.ID, e.Sharing); err != nil {
+			return err
+		}
+		return saveTags(ctx, tx, householdID, e.ID, e.Tags)
+	})
 }
 
-// UpdateExpense updates an existing expense in the database.
-func (db *DB) UpdateExpense(e *models.Expense) error {
-	_, err := db.conn.Exec(
-		"UPDATE expenses SET amount = ?, description = ?, category = ?, date = ? WHERE id = ?",
-		e.Amount, e.Description, e.Category, e.Date, e.ID,
+// DeleteExpense removes an expense by ID within the scope.
+// It returns ErrNotFound if the expense does not exist or belongs to another household.
+func (db *DB) DeleteExpense(ctx context.Context, scope Scope, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	cond, args := scope.clause()
+	result, err := db.conn.ExecContext(ctx,
+		"DELETE FROM expenses AS e WHERE e.id = ? AND "+cond,
+		append([]any{id}, args...)...,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
 }
 
-// DeleteExpense removes an expense from the database by ID.
-func (db *DB) DeleteExpense(id int64) error {
-	_, err := db.conn.Exec("DELETE FROM expenses WHERE id = ?", id)
-	return err
+func requireAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-// ListExpenses retrieves expenses from the database, ordered by date descending.
+// ListExpenses retrieves expenses visible in the scope, ordered by date descending.
 // Supports pagination with limit and offset parameters.
-func (db *DB) ListExpenses(limit, offset int) ([]models.Expense, error) {
-	rows, err := db.conn.Query(
-		"SELECT id, amount, description, category, date, user_id FROM expenses ORDER BY date DESC LIMIT ? OFFSET ?",
-		limit, offset,
+func (db *DB) ListExpenses(ctx context.Context, scope Scope, limit, offset int) ([]models.Expense, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	cond, args := scope.clause()
+	return db.queryExpenses(ctx,
+		"SELECT "+expenseColumns+" FROM expenses e WHERE "+cond+" ORDER BY e.date DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...,
+	)
+}
+
+// Cursor marks a position in the date-descending expense order: the date and
+// ID of the last expense of a page.
+type Cursor struct {
+	Date time.Time
+	ID   int64
+}
+
+// ExpenseFilter selects expenses for FilterExpenses and EachExpense. Zero fields do not filter.
+type ExpenseFilter struct {
+	From     time.Time // Inclusive
+	To       time.Time // Exclusive
+	Category string    // Includes the category's subcategories and split expenses with a part in them
+	Kind     models.ExpenseKind
+	UserID   int64   // Recorded by this user
+	Search   string  // Substring of the description, ignoring case
+	After    *Cursor // Only expenses after this position
+	Limit    int
+}
+
+// where returns the SQL conditions of the filter other than After and Limit,
+// and their arguments.
+func (f ExpenseFilter) where(scope Scope) ([]string, []any) {
+	cond, args := scope.clause()
+	conds := []string{cond}
+	if !f.From.IsZero() {
+		conds = append(conds, "e.date >= ?")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "e.date < ?")
+		args = append(args, f.To)
+	}
+	if f.Category != "" {
+		conds = append(conds, `(`+inCategory("e.category")+` OR EXISTS (
+			SELECT 1 FROM expense_splits s WHERE s.expense_id = e.id AND `+inCategory("s.category")+`))`)
+		args = append(args, f.Category, f.Category, f.Category, f.Category)
+	}
+	if f.Kind != "" {
+		conds = append(conds, "e.kind = ?")
+		args = append(args, f.Kind)
+	}
+	if f.UserID != 0 {
+		conds = append(conds, "e.user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.Search != "" {
+		conds = append(conds, "LOWER(e.description) LIKE LOWER(?) ESCAPE '\\'")
+		args = append(args, "%"+likeEscaper.Replace(f.Search)+"%")
+	}
+	return conds, args
+}
+
+// inCategory returns a condition that column names the category or one of its
+// subcategories. The category name is its argument, twice.
+func inCategory(column string) string {
+	return `(` + column + ` = ? OR ` + column + ` IN (
+		SELECT c.name FROM categories c JOIN categories p ON c.parent_id = p.id
+		WHERE p.household_id = e.household_id AND p.name = ?))`
+}
+
+// FilterExpenses retrieves expenses visible in the scope that match the filter,
+// newest first. Expenses with the same date are ordered by descending ID so
+// that a Cursor taken from the last result continues where the page ended.
+func (db *DB) FilterExpenses(ctx context.Context, scope Scope, f ExpenseFilter) ([]models.Expense, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conds, args := f.where(scope)
+	if f.After != nil {
+		conds = append(conds, "(e.date < ? OR (e.date = ? AND e.id < ?))")
+		args = append(args, f.After.Date, f.After.Date, f.After.ID)
+	}
+
+	return db.queryExpenses(ctx,
+		"SELECT "+expenseColumns+" FROM expenses e WHERE "+strings.Join(conds, " AND ")+" ORDER BY e.date DESC, e.id DESC LIMIT ?",
+		append(args, f.Limit)...,
+	)
+}
+
+// ExpenseRow is an expense together with the name of the user who recorded it
+// and its amount by category.
+type ExpenseRow struct {
+	models.Expense
+	Username string // Empty for expenses recorded before users existed
+	// Parts hold the expense itself, or one part per category of a split
+	// expense, as counted in the statistics. With a category filter only
+	// the parts in the category are included.
+	Parts []ExpensePart
+}
+
+// ExpensePart is the share of an expense's Amount, in the household's base
+// currency, that belongs to one category.
+type ExpensePart struct {
+	Category string
+	Amount   models.Money
+}
+
+// EachExpense calls fn for every expense visible in the scope that matches the
+// filter, oldest first, without loading them all into memory. Split parts are
+// loaded along. After and Limit are ignored. Iteration stops at the first
+// error returned by fn.
+func (db *DB) EachExpense(ctx context.Context, scope Scope, f ExpenseFilter, fn func(ExpenseRow) error) error {
+	conds, args := f.where(scope)
+	// Whether a part of a split expense is in the filtered category
+	match := "1"
+	if f.Category != "" {
+		match = "CASE WHEN s.expense_id IS NULL OR " + inCategory("s.category") + " THEN 1 ELSE 0 END"
+		args = append([]any{f.Category, f.Category}, args...)
+	}
+	rows, err := db.read.QueryContext(ctx,
+		"SELECT "+expenseColumns+", COALESCE(u.username, ''), COALESCE(s.category, ''), COALESCE(s.amount, 0), "+match+
+			" FROM expenses e LEFT JOIN users u ON u.id = e.user_id LEFT JOIN expense_splits s ON s.expense_id = e.id WHERE "+
+			strings.Join(conds, " AND ")+" ORDER BY e.date, e.id, s.position",
+		args...,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var expenses []models.Expense
+	// Rows come one per split part; an expense is passed on once all of its
+	// parts have been read
+	var (
+		row     ExpenseRow
+		pending bool
+		keep    []bool
+	)
+	flush := func() error {
+		if !pending {
+			return nil
+		}
+		row.Parts = expenseParts(row.Expense, keep)
+		return fn(row)
+	}
 	for rows.Next() {
-		var e models.Expense
-		if err := rows.Scan(&e.ID, &e.Amount, &e.Description, &e.Category, &e.Date, &e.UserID); err != nil {
-			return nil, err
+		var next ExpenseRow
+		var split models.Split
+		var matches bool
+		e := &next.Expense
+		if err := rows.Scan(&e.ID, &e.Kind, &e.Amount, &e.Currency, &e.OriginalAmount, &e.Description, &e.Category, &e.Date, &e.UserID, &e.HouseholdID, &e.AccountID, &e.RecurringID, &e.ImportRef, &next.Username, &split.Category, &split.Amount, &matches); err != nil {
+			return err
 		}
-		expenses = append(expenses, e)
+		if !pending || next.ID != row.ID {
+			if err := flush(); err != nil {
+				return err
+			}
+			row, keep, pending = next, nil, true
+		}
+		if split.Category != "" {
+			row.Splits = append(row.Splits, split)
+			keep = append(keep, matches)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
+	return flush()
+}
+
+// likeEscaper escapes the LIKE wildcards of a search term.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
 
-	return expenses, rows.Err()
+// PeriodTotals holds the income and spending of a period. Spending is net of refunds.
+type PeriodTotals struct {
+	Income   models.Money
+	Spending models.Money
 }
 
-// GetCurrentMonthTotal returns the total spent in the current month.
-func (db *DB) GetCurrentMonthTotal() (float64, error) {
+// Net returns the net cash flow: income minus spending.
+func (t PeriodTotals) Net() models.Money {
+	return t.Income - t.Spending
+}
+
+func (db *DB) periodTotals(ctx context.Context, scope Scope, start, end time.Time) (PeriodTotals, error) {
+	cond, args := scope.clause()
+	var totals PeriodTotals
+	err := db.read.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(`+incomeAmount+`), 0), COALESCE(SUM(`+spendingAmount+`), 0)
+		 FROM expenses e WHERE `+cond+` AND e.date >= ? AND e.date < ?`,
+		append(args, start, end)...,
+	).Scan(&totals.Income, &totals.Spending)
+
+	return totals, err
+}
+
+// GetCurrentMonthTotal returns the income and spending of the current month.
+func (db *DB) GetCurrentMonthTotal(ctx context.Context, scope Scope) (PeriodTotals, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	now := time.Now()
 	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 
-	var total float64
-	err := db.conn.QueryRow(
-		"SELECT COALESCE(SUM(amount), 0) FROM expenses WHERE date >= ?",
-		startOfMonth,
-	).Scan(&total)
-
-	return total, err
+	return db.periodTotals(ctx, scope, startOfMonth, startOfMonth.AddDate(0, 1, 0))
 }
 
 // ClearExpenses deletes all expenses from the database (used for testing).
-func (db *DB) ClearExpenses() error {
-	_, err := db.conn.Exec("DELETE FROM expenses")
+func (db *DB) ClearExpenses(ctx context.Context) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.conn.ExecContext(ctx, "DELETE FROM expenses")
 	return err
 }
 
 // GetExpensesByMonth retrieves expenses for a specific month.
-func (db *DB) GetExpensesByMonth(year, month int) ([]models.Expense, error) {
+func (db *DB) GetExpensesByMonth(ctx context.Context, scope Scope, year, month int) ([]models.Expense, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	startOfMonth := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 	endOfMonth := startOfMonth.AddDate(0, 1, 0)
 
-	rows, err := db.conn.Query(
-		"SELECT id, amount, description, category, date, user_id FROM expenses WHERE date >= ? AND date < ? ORDER BY date DESC",
-		startOfMonth, endOfMonth,
+	cond, args := scope.clause()
+	return db.queryExpenses(ctx,
+		"SELECT "+expenseColumns+" FROM expenses e WHERE "+cond+" AND e.date >= ? AND e.date < ? ORDER BY e.date DESC",
+		append(args, startOfMonth, endOfMonth)...,
 	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var expenses []models.Expense
-	for rows.Next() {
-		var e models.Expense
-		if err := rows.Scan(&e.ID, &e.Amount, &e.Description, &e.Category, &e.Date, &e.UserID); err != nil {
-			return nil, err
-		}
-		expenses = append(expenses, e)
-	}
-
-	return expenses, rows.Err()
 }
 
-// CategoryTotal represents spending total for a category.
+// CategoryTotal represents spending total for a category. Refunds reduce the
+// total and income is left out; Count includes both expenses and refunds.
+// The parts of split expenses count in their own categories.
+// Totals of subcategories are rolled up into their parent; when that happens
+// Children holds the breakdown, including the parent's own expenses.
 type CategoryTotal struct {
-	Category string
-	Total    float64
-	Count    int
+	Category string          `json:"category"`
+	Total    models.Money    `json:"total"`
+	Count    int             `json:"count"`
+	Children []CategoryTotal `json:"children,omitempty"`
 }
 
-// GetCategoryTotalsByMonth retrieves spending totals by category for a specific month.
-func (db *DB) GetCategoryTotalsByMonth(year, month int) ([]CategoryTotal, error) {
+// GetCategoryTotalsByMonth retrieves spending totals by top-level category for a specific month.
+func (db *DB) GetCategoryTotalsByMonth(ctx context.Context, scope Scope, year, month int) ([]CategoryTotal, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	startOfMonth := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 	endOfMonth := startOfMonth.AddDate(0, 1, 0)
 
-	rows, err := db.conn.Query(
-		`SELECT category, SUM(amount) as total, COUNT(*) as count 
-		 FROM expenses 
-		 WHERE date >= ? AND date < ? 
-		 GROUP BY category 
+	return db.categoryTotals(ctx, scope, startOfMonth, endOfMonth)
+}
+
+func (db *DB) categoryTotals(ctx context.Context, scope Scope, start, end time.Time) ([]CategoryTotal, error) {
+	// Categories are matched by name within the expense's household; expenses
+	// in unknown categories count as top-level.
+	cond, args := scope.clause()
+	rows, err := db.read.QueryContext(ctx,
+		`SELECT COALESCE(p.name, l.category) as parent, l.category, SUM(CASE l.kind WHEN 'refund' THEN -l.amount ELSE l.amount END) as total, COUNT(*) as count
+		 FROM (`+expenseLines(cond+` AND e.kind != 'income' AND e.date >= ? AND e.date < ?`)+`) l
+		 LEFT JOIN categories c ON c.household_id = l.household_id AND c.name = l.category
+		 LEFT JOIN categories p ON p.id = c.parent_id
+		 GROUP BY parent, l.category
 		 ORDER BY total DESC`,
-		startOfMonth, endOfMonth,
+		append(args, start, end)...,
 	)
 	if err != nil {
 		return nil, err
@@ -143,36 +589,61 @@ func (db *DB) GetCategoryTotalsByMonth(year, month int) ([]CategoryTotal, error)
 	defer rows.Close()
 
 	var totals []CategoryTotal
+	index := make(map[string]int)
 	for rows.Next() {
+		var parent string
 		var ct CategoryTotal
-		if err := rows.Scan(&ct.Category, &ct.Total, &ct.Count); err != nil {
+		if err := rows.Scan(&parent, &ct.Category, &ct.Total, &ct.Count); err != nil {
 			return nil, err
 		}
-		totals = append(totals, ct)
+
+		i, ok := index[parent]
+		if !ok {
+			i = len(totals)
+			index[parent] = i
+			totals = append(totals, CategoryTotal{Category: parent})
+		}
+		totals[i].Total += ct.Total
+		totals[i].Count += ct.Count
+		totals[i].Children = append(totals[i].Children, ct)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return totals, rows.Err()
+	for i := range totals {
+		// A category without subcategory spending needs no breakdown
+		if len(totals[i].Children) == 1 && totals[i].Children[0].Category == totals[i].Category {
+			totals[i].Children = nil
+		}
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].Total > totals[j].Total })
+
+	return totals, nil
 }
 
 // MonthlyTotal represents spending total for a month.
 type MonthlyTotal struct {
 	Month int
-	Total float64
+	Total models.Money
 }
 
 // GetMonthlyTotalsForYear retrieves spending totals by month for a specific year.
-func (db *DB) GetMonthlyTotalsForYear(year int) ([]MonthlyTotal, error) {
+func (db *DB) GetMonthlyTotalsForYear(ctx context.Context, scope Scope, year int) ([]MonthlyTotal, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	startOfYear := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
 	endOfYear := startOfYear.AddDate(1, 0, 0)
 
-	// Use SUBSTR to extract month from ISO 8601 format (YYYY-MM-DDTHH:MM:SSZ)
-	rows, err := db.conn.Query(
-		`SELECT CAST(SUBSTR(date, 6, 2) AS INTEGER) as month, SUM(amount) as total 
-		 FROM expenses 
-		 WHERE date >= ? AND date < ? 
-		 GROUP BY SUBSTR(date, 6, 2) 
+	cond, args := scope.clause()
+	rows, err := db.read.QueryContext(ctx,
+		`SELECT `+db.dialect.month+` as month, SUM(`+spendingAmount+`) as total 
+		 FROM expenses e 
+		 WHERE `+cond+` AND e.kind != 'income' AND e.date >= ? AND e.date < ? 
+		 GROUP BY month 
 		 ORDER BY month`,
-		startOfYear, endOfYear,
+		append(args, startOfYear, endOfYear)...,
 	)
 	if err != nil {
 		return nil, err
@@ -194,22 +665,25 @@ func (db *DB) GetMonthlyTotalsForYear(year int) ([]MonthlyTotal, error) {
 // DailyTotal represents spending total for a day.
 type DailyTotal struct {
 	Day   int
-	Total float64
+	Total models.Money
 }
 
 // GetDailyTotalsForMonth retrieves spending totals by day for a specific month.
-func (db *DB) GetDailyTotalsForMonth(year, month int) ([]DailyTotal, error) {
+func (db *DB) GetDailyTotalsForMonth(ctx context.Context, scope Scope, year, month int) ([]DailyTotal, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	startOfMonth := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 	endOfMonth := startOfMonth.AddDate(0, 1, 0)
 
-	// Use SUBSTR to extract day from ISO 8601 format (YYYY-MM-DDTHH:MM:SSZ)
-	rows, err := db.conn.Query(
-		`SELECT CAST(SUBSTR(date, 9, 2) AS INTEGER) as day, SUM(amount) as total 
-		 FROM expenses 
-		 WHERE date >= ? AND date < ? 
-		 GROUP BY SUBSTR(date, 9, 2) 
+	cond, args := scope.clause()
+	rows, err := db.read.QueryContext(ctx,
+		`SELECT `+db.dialect.day+` as day, SUM(`+spendingAmount+`) as total 
+		 FROM expenses e 
+		 WHERE `+cond+` AND e.kind != 'income' AND e.date >= ? AND e.date < ? 
+		 GROUP BY day 
 		 ORDER BY day`,
-		startOfMonth, endOfMonth,
+		append(args, startOfMonth, endOfMonth)...,
 	)
 	if err != nil {
 		return nil, err
@@ -228,10 +702,13 @@ func (db *DB) GetDailyTotalsForMonth(year, month int) ([]DailyTotal, error) {
 	return totals, rows.Err()
 }
 
-// GetTotalForPeriod retrieves the total spending for a period.
-// If month is 0, it returns the total for the entire year.
-// Otherwise, it returns the total for the specific month.
-func (db *DB) GetTotalForPeriod(year, month int) (float64, error) {
+// GetTotalForPeriod retrieves the income and spending for a period.
+// If month is 0, it returns the totals for the entire year.
+// Otherwise, it returns the totals for the specific month.
+func (db *DB) GetTotalForPeriod(ctx context.Context, scope Scope, year, month int) (PeriodTotals, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	var startDate, endDate time.Time
 
 	if month == 0 {
@@ -244,67 +721,31 @@ func (db *DB) GetTotalForPeriod(year, month int) (float64, error) {
 		endDate = startDate.AddDate(0, 1, 0)
 	}
 
-	var total float64
-	err := db.conn.QueryRow(
-		`SELECT COALESCE(SUM(amount), 0) FROM expenses WHERE date >= ? AND date < ?`,
-		startDate, endDate,
-	).Scan(&total)
-
-	return total, err
+	return db.periodTotals(ctx, scope, startDate, endDate)
 }
 
 // GetExpensesByYear retrieves all expenses for a specific year.
-func (db *DB) GetExpensesByYear(year int) ([]models.Expense, error) {
+func (db *DB) GetExpensesByYear(ctx context.Context, scope Scope, year int) ([]models.Expense, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	startOfYear := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
 	endOfYear := startOfYear.AddDate(1, 0, 0)
 
-	rows, err := db.conn.Query(
-		"SELECT id, amount, description, category, date, user_id FROM expenses WHERE date >= ? AND date < ? ORDER BY date DESC",
-		startOfYear, endOfYear,
+	cond, args := scope.clause()
+	return db.queryExpenses(ctx,
+		"SELECT "+expenseColumns+" FROM expenses e WHERE "+cond+" AND e.date >= ? AND e.date < ? ORDER BY e.date DESC",
+		append(args, startOfYear, endOfYear)...,
 	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var expenses []models.Expense
-	for rows.Next() {
-		var e models.Expense
-		if err := rows.Scan(&e.ID, &e.Amount, &e.Description, &e.Category, &e.Date, &e.UserID); err != nil {
-			return nil, err
-		}
-		expenses = append(expenses, e)
-	}
-
-	return expenses, rows.Err()
 }
 
-// GetCategoryTotalsByYear retrieves spending totals by category for a specific year.
-func (db *DB) GetCategoryTotalsByYear(year int) ([]CategoryTotal, error) {
+// GetCategoryTotalsByYear retrieves spending totals by top-level category for a specific year.
+func (db *DB) GetCategoryTotalsByYear(ctx context.Context, scope Scope, year int) ([]CategoryTotal, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	startOfYear := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
 	endOfYear := startOfYear.AddDate(1, 0, 0)
 
-	rows, err := db.conn.Query(
-		`SELECT category, SUM(amount) as total, COUNT(*) as count 
-		 FROM expenses 
-		 WHERE date >= ? AND date < ? 
-		 GROUP BY category 
-		 ORDER BY total DESC`,
-		startOfYear, endOfYear,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var totals []CategoryTotal
-	for rows.Next() {
-		var ct CategoryTotal
-		if err := rows.Scan(&ct.Category, &ct.Total, &ct.Count); err != nil {
-			return nil, err
-		}
-		totals = append(totals, ct)
-	}
-
-	return totals, rows.Err()
+	return db.categoryTotals(ctx, scope, startOfYear, endOfYear)
 }
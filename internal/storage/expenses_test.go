@@ -1,23 +1,40 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"expense-tracker/internal/models"
+
 	"github.com/stretchr/testify/suite"
 )
 
 // ExpenseTestSuite provides a test suite for expense operations
 type ExpenseTestSuite struct {
 	suite.Suite
-	db *DB
+	dsn   string // Database under test, see runOnBackends
+	ctx   context.Context
+	db    *DB
+	user  *models.User
+	scope Scope
 }
 
 // SetupTest runs before each test
 func (s *ExpenseTestSuite) SetupTest() {
-	db, err := NewDB(":memory:")
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
 	s.Require().NoError(err, "failed to create test database")
 	s.db = db
+
+	// Create a test user in its own household
+	s.user, err = s.db.CreateUser(s.ctx, "testuser", "hash")
+	s.Require().NoError(err, "failed to create test user")
+	household, err := s.db.CreateHousehold(s.ctx, "Test")
+	s.Require().NoError(err, "failed to create test household")
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, household.ID, s.user.ID))
+	s.scope = UserScope(s.user.ID)
 }
 
 // TearDownTest runs after each test
@@ -28,50 +45,50 @@ func (s *ExpenseTestSuite) TearDownTest() {
 }
 
 func (s *ExpenseTestSuite) TestCreateExpense() {
-	err := s.db.CreateExpense(10.50, "Lunch", "food", time.Now(), 1)
+	err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1050, Description: "Lunch", Category: "food", Date: time.Now()})
 	s.NoError(err)
 }
 
 func (s *ExpenseTestSuite) TestDeleteExpense() {
 	// Create an expense
-	err := s.db.CreateExpense(25.00, "Dinner", "food", time.Now(), 1)
+	err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 2500, Description: "Dinner", Category: "food", Date: time.Now()})
 	s.Require().NoError(err)
 
 	// Get the expense to find its ID
-	expenses, err := s.db.ListExpenses(100, 0)
+	expenses, err := s.db.ListExpenses(s.ctx, s.scope, 100, 0)
 	s.Require().NoError(err)
 	s.Require().Len(expenses, 1)
 	expenseID := expenses[0].ID
 
 	// Delete the expense
-	err = s.db.DeleteExpense(expenseID)
+	err = s.db.DeleteExpense(s.ctx, s.scope, expenseID)
 	s.Require().NoError(err)
 
 	// Verify it's gone
-	expenses, err = s.db.ListExpenses(100, 0)
+	expenses, err = s.db.ListExpenses(s.ctx, s.scope, 100, 0)
 	s.Require().NoError(err)
 	s.Empty(expenses, "expected no expenses after deletion")
 }
 
 func (s *ExpenseTestSuite) TestDeleteExpense_NonExistent() {
-	// Deleting a non-existent expense should not error (no-op)
-	err := s.db.DeleteExpense(99999)
-	s.NoError(err, "deleting non-existent expense should not error")
+	// Deleting a non-existent expense reports it as not found
+	err := s.db.DeleteExpense(s.ctx, s.scope, 99999)
+	s.ErrorIs(err, ErrNotFound)
 }
 
 func (s *ExpenseTestSuite) TestDeleteExpense_OnlyDeletesTarget() {
 	baseTime := time.Now()
 
 	// Create multiple expenses
-	err := s.db.CreateExpense(10.00, "Coffee", "food", baseTime, 1)
+	err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1000, Description: "Coffee", Category: "food", Date: baseTime})
 	s.Require().NoError(err)
-	err = s.db.CreateExpense(20.00, "Lunch", "food", baseTime.Add(time.Minute), 1)
+	err = s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 2000, Description: "Lunch", Category: "food", Date: baseTime.Add(time.Minute)})
 	s.Require().NoError(err)
-	err = s.db.CreateExpense(30.00, "Dinner", "food", baseTime.Add(2*time.Minute), 1)
+	err = s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 3000, Description: "Dinner", Category: "food", Date: baseTime.Add(2 * time.Minute)})
 	s.Require().NoError(err)
 
 	// Get all expenses
-	expenses, err := s.db.ListExpenses(100, 0)
+	expenses, err := s.db.ListExpenses(s.ctx, s.scope, 100, 0)
 	s.Require().NoError(err)
 	s.Require().Len(expenses, 3)
 
@@ -85,11 +102,11 @@ func (s *ExpenseTestSuite) TestDeleteExpense_OnlyDeletesTarget() {
 	}
 	s.Require().NotZero(lunchID, "could not find Lunch expense")
 
-	err = s.db.DeleteExpense(lunchID)
+	err = s.db.DeleteExpense(s.ctx, s.scope, lunchID)
 	s.Require().NoError(err)
 
 	// Verify only 2 remain and Lunch is gone
-	expenses, err = s.db.ListExpenses(100, 0)
+	expenses, err = s.db.ListExpenses(s.ctx, s.scope, 100, 0)
 	s.Require().NoError(err)
 	s.Len(expenses, 2, "expected 2 expenses after deletion")
 
@@ -103,28 +120,28 @@ func (s *ExpenseTestSuite) TestListExpenses() {
 
 	// Create test expenses
 	expenses := []struct {
-		amount      float64
+		amount      models.Money
 		description string
 		category    string
 		offset      time.Duration
 	}{
-		{20.00, "Bus", "transport", time.Minute},
-		{5.00, "Coffee", "food", 2 * time.Minute},
-		{15.00, "Snack", "food", 3 * time.Minute},
+		{2000, "Bus", "transport", time.Minute},
+		{500, "Coffee", "food", 2 * time.Minute},
+		{1500, "Snack", "food", 3 * time.Minute},
 	}
 
 	for _, exp := range expenses {
-		err := s.db.CreateExpense(exp.amount, exp.description, exp.category, baseTime.Add(exp.offset), 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: exp.amount, Description: exp.description, Category: exp.category, Date: baseTime.Add(exp.offset)})
 		s.Require().NoError(err, "failed to create expense: %s", exp.description)
 	}
 
-	result, err := s.db.ListExpenses(100, 0)
+	result, err := s.db.ListExpenses(s.ctx, s.scope, 100, 0)
 	s.Require().NoError(err)
 	s.Len(result, 3, "expected 3 expenses")
 
 	// Check order (latest first). Snack was added last with latest timestamp
 	if len(result) > 0 {
-		s.InDelta(15.00, result[0].Amount, 0.001, "expected first expense to be Snack")
+		s.Equal(models.Money(1500), result[0].Amount, "expected first expense to be Snack")
 		s.Equal("Snack", result[0].Description)
 	}
 }
@@ -137,33 +154,33 @@ func (s *ExpenseTestSuite) TestListExpensesCurrentMonth() {
 
 	// Create expenses in different months
 	testExpenses := []struct {
-		amount      float64
+		amount      models.Money
 		description string
 		category    string
 		date        time.Time
 	}{
-		{100.00, "Current Month 1", "food", currentMonth},
-		{150.00, "Current Month 2", "transport", currentMonth.Add(24 * time.Hour)},
-		{200.00, "Last Month", "food", lastMonth},
-		{300.00, "Two Months Ago", "utilities", twoMonthsAgo},
+		{10000, "Current Month 1", "food", currentMonth},
+		{15000, "Current Month 2", "transport", currentMonth.Add(24 * time.Hour)},
+		{20000, "Last Month", "food", lastMonth},
+		{30000, "Two Months Ago", "utilities", twoMonthsAgo},
 	}
 
 	for _, exp := range testExpenses {
-		err := s.db.CreateExpense(exp.amount, exp.description, exp.category, exp.date, 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: exp.amount, Description: exp.description, Category: exp.category, Date: exp.date})
 		s.Require().NoError(err, "failed to create expense: %s", exp.description)
 	}
 
 	// List expenses should return all expenses (no longer filtered by month)
-	expenses, err := s.db.ListExpenses(100, 0)
+	expenses, err := s.db.ListExpenses(s.ctx, s.scope, 100, 0)
 	s.Require().NoError(err)
 	s.Len(expenses, 4, "expected all expenses")
 
 	// Verify the expenses are ordered by date DESC
 	if s.Len(expenses, 4) {
 		s.Equal("Current Month 2", expenses[0].Description)
-		s.InDelta(150.00, expenses[0].Amount, 0.001)
+		s.Equal(models.Money(15000), expenses[0].Amount)
 		s.Equal("Current Month 1", expenses[1].Description)
-		s.InDelta(100.00, expenses[1].Amount, 0.001)
+		s.Equal(models.Money(10000), expenses[1].Amount)
 	}
 }
 
@@ -171,22 +188,22 @@ func (s *ExpenseTestSuite) TestListExpensesPagination() {
 	// Create 5 expenses
 	baseTime := time.Now()
 	for i := 1; i <= 5; i++ {
-		err := s.db.CreateExpense(float64(i*10), "Expense "+string(rune('0'+i)), "food", baseTime.Add(time.Duration(i)*time.Minute), 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: models.Money(i * 1000), Description: "Expense " + string(rune('0'+i)), Category: "food", Date: baseTime.Add(time.Duration(i) * time.Minute)})
 		s.Require().NoError(err)
 	}
 
 	// Test limit
-	expenses, err := s.db.ListExpenses(2, 0)
+	expenses, err := s.db.ListExpenses(s.ctx, s.scope, 2, 0)
 	s.Require().NoError(err)
 	s.Len(expenses, 2, "expected 2 expenses with limit=2")
 
 	// Test offset
-	expenses, err = s.db.ListExpenses(2, 2)
+	expenses, err = s.db.ListExpenses(s.ctx, s.scope, 2, 2)
 	s.Require().NoError(err)
 	s.Len(expenses, 2, "expected 2 expenses with limit=2, offset=2")
 
 	// Test offset beyond data
-	expenses, err = s.db.ListExpenses(10, 10)
+	expenses, err = s.db.ListExpenses(s.ctx, s.scope, 10, 10)
 	s.Require().NoError(err)
 	s.Empty(expenses, "expected 0 expenses with offset beyond data")
 }
@@ -198,55 +215,55 @@ func (s *ExpenseTestSuite) TestGetExpensesByMonth() {
 	dec2025 := time.Date(2025, 12, 15, 12, 0, 0, 0, time.UTC)
 
 	testExpenses := []struct {
-		amount      float64
+		amount      models.Money
 		description string
 		category    string
 		date        time.Time
 	}{
-		{100.00, "January Expense 1", "groceries", jan2026},
-		{150.00, "January Expense 2", "transport", jan2026.Add(24 * time.Hour)},
-		{200.00, "February Expense", "eating out", feb2026},
-		{300.00, "December Expense", "utilities", dec2025},
+		{10000, "January Expense 1", "groceries", jan2026},
+		{15000, "January Expense 2", "transport", jan2026.Add(24 * time.Hour)},
+		{20000, "February Expense", "eating out", feb2026},
+		{30000, "December Expense", "utilities", dec2025},
 	}
 
 	for _, exp := range testExpenses {
-		err := s.db.CreateExpense(exp.amount, exp.description, exp.category, exp.date, 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: exp.amount, Description: exp.description, Category: exp.category, Date: exp.date})
 		s.Require().NoError(err, "failed to create expense: %s", exp.description)
 	}
 
 	// Test getting January 2026 expenses
-	janExpenses, err := s.db.GetExpensesByMonth(2026, 1)
+	janExpenses, err := s.db.GetExpensesByMonth(s.ctx, s.scope, 2026, 1)
 	s.Require().NoError(err)
 	s.Len(janExpenses, 2, "expected 2 expenses in January 2026")
 
 	// Verify expenses are ordered by date DESC
 	if s.Len(janExpenses, 2) {
 		s.Equal("January Expense 2", janExpenses[0].Description)
-		s.InDelta(150.00, janExpenses[0].Amount, 0.001)
+		s.Equal(models.Money(15000), janExpenses[0].Amount)
 		s.Equal("January Expense 1", janExpenses[1].Description)
-		s.InDelta(100.00, janExpenses[1].Amount, 0.001)
+		s.Equal(models.Money(10000), janExpenses[1].Amount)
 	}
 
 	// Test getting February 2026 expenses
-	febExpenses, err := s.db.GetExpensesByMonth(2026, 2)
+	febExpenses, err := s.db.GetExpensesByMonth(s.ctx, s.scope, 2026, 2)
 	s.Require().NoError(err)
 	s.Len(febExpenses, 1, "expected 1 expense in February 2026")
 	if s.Len(febExpenses, 1) {
 		s.Equal("February Expense", febExpenses[0].Description)
-		s.InDelta(200.00, febExpenses[0].Amount, 0.001)
+		s.Equal(models.Money(20000), febExpenses[0].Amount)
 	}
 
 	// Test getting December 2025 expenses
-	decExpenses, err := s.db.GetExpensesByMonth(2025, 12)
+	decExpenses, err := s.db.GetExpensesByMonth(s.ctx, s.scope, 2025, 12)
 	s.Require().NoError(err)
 	s.Len(decExpenses, 1, "expected 1 expense in December 2025")
 	if s.Len(decExpenses, 1) {
 		s.Equal("December Expense", decExpenses[0].Description)
-		s.InDelta(300.00, decExpenses[0].Amount, 0.001)
+		s.Equal(models.Money(30000), decExpenses[0].Amount)
 	}
 
 	// Test getting a month with no expenses
-	novExpenses, err := s.db.GetExpensesByMonth(2025, 11)
+	novExpenses, err := s.db.GetExpensesByMonth(s.ctx, s.scope, 2025, 11)
 	s.Require().NoError(err)
 	s.Empty(novExpenses, "expected 0 expenses in November 2025")
 }
@@ -256,27 +273,27 @@ func (s *ExpenseTestSuite) TestGetCategoryTotalsByMonth() {
 	jan2026 := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
 
 	testExpenses := []struct {
-		amount      float64
+		amount      models.Money
 		description string
 		category    string
 		date        time.Time
 	}{
-		{100.00, "Groceries 1", "groceries", jan2026},
-		{150.00, "Groceries 2", "groceries", jan2026.Add(time.Hour)},
-		{200.00, "Bus", "transport", jan2026.Add(2 * time.Hour)},
-		{50.00, "Taxi", "transport", jan2026.Add(3 * time.Hour)},
-		{75.00, "Restaurant", "eating out", jan2026.Add(4 * time.Hour)},
+		{10000, "Groceries 1", "groceries", jan2026},
+		{15000, "Groceries 2", "groceries", jan2026.Add(time.Hour)},
+		{20000, "Bus", "transport", jan2026.Add(2 * time.Hour)},
+		{5000, "Taxi", "transport", jan2026.Add(3 * time.Hour)},
+		{7500, "Restaurant", "eating out", jan2026.Add(4 * time.Hour)},
 		// February expenses (should not be included)
-		{300.00, "Feb Groceries", "groceries", time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{30000, "Feb Groceries", "groceries", time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)},
 	}
 
 	for _, exp := range testExpenses {
-		err := s.db.CreateExpense(exp.amount, exp.description, exp.category, exp.date, 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: exp.amount, Description: exp.description, Category: exp.category, Date: exp.date})
 		s.Require().NoError(err, "failed to create expense: %s", exp.description)
 	}
 
 	// Test getting category totals for January 2026
-	totals, err := s.db.GetCategoryTotalsByMonth(2026, 1)
+	totals, err := s.db.GetCategoryTotalsByMonth(s.ctx, s.scope, 2026, 1)
 	s.Require().NoError(err)
 	s.Len(totals, 3, "expected 3 categories in January 2026")
 
@@ -288,34 +305,34 @@ func (s *ExpenseTestSuite) TestGetCategoryTotalsByMonth() {
 
 	// Verify groceries: 100 + 150 = 250
 	s.Contains(categoryMap, "groceries")
-	s.InDelta(250.00, categoryMap["groceries"].Total, 0.001)
+	s.Equal(models.Money(25000), categoryMap["groceries"].Total)
 	s.Equal(2, categoryMap["groceries"].Count)
 
 	// Verify transport: 200 + 50 = 250
 	s.Contains(categoryMap, "transport")
-	s.InDelta(250.00, categoryMap["transport"].Total, 0.001)
+	s.Equal(models.Money(25000), categoryMap["transport"].Total)
 	s.Equal(2, categoryMap["transport"].Count)
 
 	// Verify eating out: 75 (should be last since it has lowest total)
 	s.Contains(categoryMap, "eating out")
-	s.InDelta(75.00, categoryMap["eating out"].Total, 0.001)
+	s.Equal(models.Money(7500), categoryMap["eating out"].Total)
 	s.Equal(1, categoryMap["eating out"].Count)
 
 	// The last item should be eating out (lowest total)
 	s.Equal("eating out", totals[2].Category)
 
 	// Test getting category totals for February 2026
-	febTotals, err := s.db.GetCategoryTotalsByMonth(2026, 2)
+	febTotals, err := s.db.GetCategoryTotalsByMonth(s.ctx, s.scope, 2026, 2)
 	s.Require().NoError(err)
 	s.Len(febTotals, 1, "expected 1 category in February 2026")
 	if s.Len(febTotals, 1) {
 		s.Equal("groceries", febTotals[0].Category)
-		s.InDelta(300.00, febTotals[0].Total, 0.001)
+		s.Equal(models.Money(30000), febTotals[0].Total)
 		s.Equal(1, febTotals[0].Count)
 	}
 
 	// Test getting category totals for a month with no expenses
-	novTotals, err := s.db.GetCategoryTotalsByMonth(2025, 11)
+	novTotals, err := s.db.GetCategoryTotalsByMonth(s.ctx, s.scope, 2025, 11)
 	s.Require().NoError(err)
 	s.Empty(novTotals, "expected 0 categories in November 2025")
 }
@@ -325,30 +342,161 @@ func (s *ExpenseTestSuite) TestGetCategoryTotalsByMonth_SingleCategory() {
 	jan2026 := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
 
 	expenses := []struct {
-		amount float64
+		amount models.Money
 		desc   string
 	}{
-		{10.00, "Coffee"},
-		{20.00, "Lunch"},
-		{30.00, "Dinner"},
+		{1000, "Coffee"},
+		{2000, "Lunch"},
+		{3000, "Dinner"},
 	}
 
 	for _, exp := range expenses {
-		err := s.db.CreateExpense(exp.amount, exp.desc, "eating out", jan2026.Add(time.Hour), 1)
+		err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: exp.amount, Description: exp.desc, Category: "eating out", Date: jan2026.Add(time.Hour)})
 		jan2026 = jan2026.Add(time.Hour)
 		s.Require().NoError(err)
 	}
 
-	totals, err := s.db.GetCategoryTotalsByMonth(2026, 1)
+	totals, err := s.db.GetCategoryTotalsByMonth(s.ctx, s.scope, 2026, 1)
 	s.Require().NoError(err)
 	s.Len(totals, 1, "expected 1 category")
 	if s.Len(totals, 1) {
 		s.Equal("eating out", totals[0].Category)
-		s.InDelta(60.00, totals[0].Total, 0.001)
+		s.Equal(models.Money(6000), totals[0].Total)
 		s.Equal(3, totals[0].Count)
 	}
 }
 
+func (s *ExpenseTestSuite) TestTransactionKinds() {
+	jan2026 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	transactions := []models.Expense{
+		{Kind: models.KindIncome, Amount: 300000, Description: "Salary", Category: "Other", Date: jan2026},
+		{Kind: models.KindExpense, Amount: 8000, Description: "Shoes", Category: "Other", Date: jan2026.Add(time.Hour)},
+		{Kind: models.KindRefund, Amount: 3000, Description: "Shoes returned", Category: "Other", Date: jan2026.Add(2 * time.Hour)},
+		{Amount: 1500, Description: "Lunch", Category: "Eating Out", Date: jan2026.Add(3 * time.Hour)},
+	}
+	for i := range transactions {
+		s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &transactions[i]))
+		s.NotZero(transactions[i].ID)
+	}
+
+	e, err := s.db.GetExpense(s.ctx, s.scope, transactions[3].ID)
+	s.Require().NoError(err)
+	s.Equal(models.KindExpense, e.Kind, "empty kind is stored as expense")
+
+	totals, err := s.db.GetTotalForPeriod(s.ctx, s.scope, 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(300000), totals.Income)
+	s.Equal(models.Money(6500), totals.Spending)
+	s.Equal(models.Money(293500), totals.Net())
+
+	categories, err := s.db.GetCategoryTotalsByMonth(s.ctx, s.scope, 2026, 1)
+	s.Require().NoError(err)
+	s.Require().Len(categories, 2)
+	s.Equal("Other", categories[0].Category)
+	s.Equal(models.Money(5000), categories[0].Total, "refund reduces and income is excluded")
+	s.Equal(2, categories[0].Count)
+
+	daily, err := s.db.GetDailyTotalsForMonth(s.ctx, s.scope, 2026, 1)
+	s.Require().NoError(err)
+	s.Require().Len(daily, 1)
+	s.Equal(models.Money(6500), daily[0].Total)
+
+	e.Kind = models.KindIncome
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, s.scope, e))
+	totals, err = s.db.GetTotalForPeriod(s.ctx, s.scope, 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(301500), totals.Income)
+	s.Equal(models.Money(5000), totals.Spending)
+}
+
+func (s *ExpenseTestSuite) TestFilterExpenses() {
+	household, err := s.db.DefaultHouseholdID(s.ctx, s.user.ID)
+	s.Require().NoError(err)
+	categories, err := s.db.ListCategories(s.ctx, household, false)
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.CreateCategory(s.ctx, &models.Category{HouseholdID: household, ParentID: &categories[1].ID, Name: "Coffee", Icon: "☕", Color: "#60a5fa"}))
+
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	expenses := []models.Expense{
+		{Amount: 300, Description: "Latte", Category: "Coffee", Date: date},
+		{Amount: 4000, Description: "Dinner", Category: "Eating Out", Date: date},
+		{Amount: 5000, Description: "100% juice", Category: "Groceries", Date: date.Add(time.Hour)},
+		{Kind: models.KindIncome, Amount: 300000, Description: "Salary", Category: "Other", Date: date.AddDate(0, 1, 0)},
+	}
+	for i := range expenses {
+		s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &expenses[i]))
+	}
+
+	got, err := s.db.FilterExpenses(s.ctx, s.scope, ExpenseFilter{Category: "Eating Out", Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(got, 2, "subcategories are included")
+
+	got, err = s.db.FilterExpenses(s.ctx, s.scope, ExpenseFilter{Kind: models.KindIncome, Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(got, 1)
+
+	got, err = s.db.FilterExpenses(s.ctx, s.scope, ExpenseFilter{From: date, To: date.AddDate(0, 0, 1), Limit: 10})
+	s.Require().NoError(err)
+	s.Len(got, 3)
+
+	got, err = s.db.FilterExpenses(s.ctx, s.scope, ExpenseFilter{Search: "0%", Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(got, 1, "LIKE wildcards in the search are literal")
+	s.Equal("100% juice", got[0].Description)
+
+	// Paging with a cursor visits every expense once, even with equal dates
+	var seen []string
+	filter := ExpenseFilter{Limit: 1}
+	for {
+		page, err := s.db.FilterExpenses(s.ctx, s.scope, filter)
+		s.Require().NoError(err)
+		if len(page) == 0 {
+			break
+		}
+		seen = append(seen, page[0].Description)
+		filter.After = &Cursor{Date: page[0].Date, ID: page[0].ID}
+	}
+	s.Equal([]string{"Salary", "100% juice", "Dinner", "Latte"}, seen)
+}
+
+func (s *ExpenseTestSuite) TestEachExpense() {
+	household, err := s.db.DefaultHouseholdID(s.ctx, s.user.ID)
+	s.Require().NoError(err)
+	partner, err := s.db.CreateUser(s.ctx, "partner", "hash")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, household, partner.ID))
+
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 300, Description: "Latte", Category: "Eating Out", Date: date.Add(time.Hour)}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, partner.ID, &models.Expense{Amount: 4000, Description: "Dinner", Category: "Eating Out", Date: date}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, partner.ID, &models.Expense{Amount: 5000, Description: "Bread", Category: "Groceries", Date: date.AddDate(0, 1, 0)}))
+
+	var rows []ExpenseRow
+	collect := func(row ExpenseRow) error {
+		rows = append(rows, row)
+		return nil
+	}
+	s.Require().NoError(s.db.EachExpense(s.ctx, s.scope, ExpenseFilter{}, collect))
+	s.Require().Len(rows, 3)
+	s.Equal("Dinner", rows[0].Description, "oldest first")
+	s.Equal("partner", rows[0].Username)
+	s.Equal("testuser", rows[1].Username)
+
+	rows = nil
+	s.Require().NoError(s.db.EachExpense(s.ctx, s.scope, ExpenseFilter{UserID: partner.ID, To: date.AddDate(0, 0, 1)}, collect))
+	s.Require().Len(rows, 1)
+	s.Equal("Dinner", rows[0].Description)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = s.db.EachExpense(s.ctx, s.scope, ExpenseFilter{}, func(ExpenseRow) error {
+		calls++
+		return stop
+	})
+	s.ErrorIs(err, stop)
+	s.Equal(1, calls)
+}
+
 func (s *ExpenseTestSuite) TestGetExpensesByMonth_EdgeCases() {
 	// Test month boundaries
 	// Last day of January
@@ -356,13 +504,13 @@ func (s *ExpenseTestSuite) TestGetExpensesByMonth_EdgeCases() {
 	// First day of February
 	feb1 := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
 
-	err := s.db.CreateExpense(100.00, "End of January", "groceries", jan31, 1)
+	err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 10000, Description: "End of January", Category: "groceries", Date: jan31})
 	s.Require().NoError(err)
-	err = s.db.CreateExpense(200.00, "Start of February", "groceries", feb1, 1)
+	err = s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 20000, Description: "Start of February", Category: "groceries", Date: feb1})
 	s.Require().NoError(err)
 
 	// Get January expenses
-	janExpenses, err := s.db.GetExpensesByMonth(2026, 1)
+	janExpenses, err := s.db.GetExpensesByMonth(s.ctx, s.scope, 2026, 1)
 	s.Require().NoError(err)
 	s.Len(janExpenses, 1, "expected 1 expense in January")
 	if s.Len(janExpenses, 1) {
@@ -370,7 +518,7 @@ func (s *ExpenseTestSuite) TestGetExpensesByMonth_EdgeCases() {
 	}
 
 	// Get February expenses
-	febExpenses, err := s.db.GetExpensesByMonth(2026, 2)
+	febExpenses, err := s.db.GetExpensesByMonth(s.ctx, s.scope, 2026, 2)
 	s.Require().NoError(err)
 	s.Len(febExpenses, 1, "expected 1 expense in February")
 	if s.Len(febExpenses, 1) {
@@ -380,5 +528,7 @@ func (s *ExpenseTestSuite) TestGetExpensesByMonth_EdgeCases() {
 
 // Test suite runner
 func TestExpenseSuite(t *testing.T) {
-	suite.Run(t, new(ExpenseTestSuite))
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &ExpenseTestSuite{dsn: dsn})
+	})
 }
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrNotFound is returned when a record does not exist or is not visible in the given scope.
+var ErrNotFound = errors.New("not found")
+
+// ErrNoHousehold is returned when a user does not belong to any household.
+var ErrNoHousehold = errors.New("user does not belong to a household")
+
+// Scope restricts queries to the households a user is a member of, or to a
+// single household. When both are set, both restrictions apply. The zero
+// Scope matches nothing. AccountID further limits expenses to those paid
+// from or into one account, and Tag to those with a tag.
+type Scope struct {
+	UserID      int64
+	HouseholdID int64
+	AccountID   int64
+	Tag         string
+}
+
+// UserScope returns the scope for the given user.
+func UserScope(userID int64) Scope {
+	return Scope{UserID: userID}
+}
+
+// HouseholdScope returns the scope for a single household. Callers are
+// responsible for checking that the household may be accessed.
+func HouseholdScope(householdID int64) Scope {
+	return Scope{HouseholdID: householdID}
+}
+
+// WithAccount returns the scope limited to the expenses of an account; zero
+// removes the limit.
+func (sc Scope) WithAccount(accountID int64) Scope {
+	sc.AccountID = accountID
+	return sc
+}
+
+// WithTag returns the scope limited to the expenses with a tag, normalized
+// as by models.NormalizeTag; an empty tag removes the limit.
+func (sc Scope) WithTag(tag string) Scope {
+	sc.Tag = tag
+	return sc
+}
+
+// clause returns a SQL condition on the expenses table aliased as e, and its arguments.
+func (sc Scope) clause() (string, []any) {
+	var conds []string
+	var args []any
+	if sc.UserID != 0 {
+		conds = append(conds, "e.household_id IN (SELECT household_id FROM household_members WHERE user_id = ?)")
+		args = append(args, sc.UserID)
+	}
+	if sc.HouseholdID != 0 {
+		conds = append(conds, "e.household_id = ?")
+		args = append(args, sc.HouseholdID)
+	}
+	if len(conds) == 0 {
+		return "1 = 0", nil
+	}
+	if sc.AccountID != 0 {
+		conds = append(conds, "e.account_id = ?")
+		args = append(args, sc.AccountID)
+	}
+	if sc.Tag != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = e.id AND t.name = ?)")
+		args = append(args, sc.Tag)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// CreateHousehold creates a new household without members and with the default categories.
+func (db *DB) CreateHousehold(ctx context.Context, name string) (*models.Household, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, "INSERT INTO households (name) VALUES (?) RETURNING id", name).Scan(&id); err != nil {
+			return err
+		}
+		return seedCategories(ctx, tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetHousehold(ctx, id)
+}
+
+// GetHousehold retrieves a household by ID. It returns ErrNotFound if the
+// household does not exist.
+func (db *DB) GetHousehold(ctx context.Context, id int64) (*models.Household, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	row := db.read.QueryRowContext(ctx, "SELECT id, name, currency, created_at FROM households WHERE id = ?", id)
+
+	var hh models.Household
+	if err := row.Scan(&hh.ID, &hh.Name, &hh.Currency, &hh.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &hh, nil
+}
+
+// GetHouseholdByName retrieves a household by its unique name.
+func (db *DB) GetHouseholdByName(ctx context.Context, name string) (*models.Household, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	row := db.read.QueryRowContext(ctx, "SELECT id, name, currency, created_at FROM households WHERE name = ?", name)
+
+	var hh models.Household
+	if err := row.Scan(&hh.ID, &hh.Name, &hh.Currency, &hh.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &hh, nil
+}
+
+// AddHouseholdMember adds a user to a household. Adding an existing member is a no-op.
+func (db *DB) AddHouseholdMember(ctx context.Context, householdID, userID int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.conn.ExecContext(ctx,
+		"INSERT INTO household_members (household_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		householdID, userID,
+	)
+	return err
+}
+
+// RemoveHouseholdMember removes a user from a household.
+func (db *DB) RemoveHouseholdMember(ctx context.Context, householdID, userID int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.conn.ExecContext(ctx,
+		"DELETE FROM household_members WHERE household_id = ? AND user_id = ?",
+		householdID, userID,
+	)
+	return err
+}
+
+// ListUserHouseholds returns the households a user belongs to, oldest membership first.
+func (db *DB) ListUserHouseholds(ctx context.Context, userID int64) ([]models.Household, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.read.QueryContext(ctx, `
+		SELECT h.id, h.name, h.currency, h.created_at
+		FROM households h
+		JOIN household_members m ON m.household_id = h.id
+		WHERE m.user_id = ?
+		ORDER BY m.joined_at, h.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var households []models.Household
+	for rows.Next() {
+		var hh models.Household
+		if err := rows.Scan(&hh.ID, &hh.Name, &hh.Currency, &hh.CreatedAt); err != nil {
+			return nil, err
+		}
+		households = append(households, hh)
+	}
+
+	return households, rows.Err()
+}
+
+// ListHouseholdMembers returns the users that belong to a household.
+func (db *DB) ListHouseholdMembers(ctx context.Context, householdID int64) ([]models.User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.read.QueryContext(ctx, `
+		SELECT u.id, u.username, u.password_hash, u.created_at
+		FROM users u
+		JOIN household_members m ON m.user_id = u.id
+		WHERE m.household_id = ?
+		ORDER BY u.username`,
+		householdID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// DefaultHouseholdID returns the household new expenses of a user are recorded in:
+// the one the user joined first.
+func (db *DB) DefaultHouseholdID(ctx context.Context, userID int64) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := db.read.QueryRowContext(ctx,
+		"SELECT household_id FROM household_members WHERE user_id = ? ORDER BY joined_at, household_id LIMIT 1",
+		userID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNoHousehold
+	}
+	return id, err
+}
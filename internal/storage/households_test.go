@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// HouseholdTestSuite provides a test suite for household membership and data isolation
+type HouseholdTestSuite struct {
+	suite.Suite
+	dsn   string // Database under test, see runOnBackends
+	ctx   context.Context
+	db    *DB
+	alice *models.User
+	bob   *models.User
+	home  *models.Household
+	flat  *models.Household
+}
+
+// SetupTest runs before each test
+func (s *HouseholdTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.alice, err = s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	s.bob, err = s.db.CreateUser(s.ctx, "bob", "hash")
+	s.Require().NoError(err)
+
+	s.home, err = s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.flat, err = s.db.CreateHousehold(s.ctx, "Flat")
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.home.ID, s.alice.ID))
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.flat.ID, s.bob.ID))
+}
+
+// TearDownTest runs after each test
+func (s *HouseholdTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *HouseholdTestSuite) TestExpensesAreIsolatedBetweenHouseholds() {
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.alice.ID, &models.Expense{Amount: 1000, Description: "Alice lunch", Category: "Eating Out", Date: date}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.bob.ID, &models.Expense{Amount: 2500, Description: "Bob rent", Category: "Housing", Date: date.Add(time.Hour)}))
+
+	aliceExpenses, err := s.db.ListExpenses(s.ctx, UserScope(s.alice.ID), 100, 0)
+	s.Require().NoError(err)
+	s.Require().Len(aliceExpenses, 1)
+	s.Equal("Alice lunch", aliceExpenses[0].Description)
+	s.Equal(s.home.ID, aliceExpenses[0].HouseholdID)
+
+	total, err := s.db.GetTotalForPeriod(s.ctx, UserScope(s.bob.ID), 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(2500), total.Spending)
+
+	totals, err := s.db.GetCategoryTotalsByYear(s.ctx, UserScope(s.alice.ID), 2026)
+	s.Require().NoError(err)
+	s.Require().Len(totals, 1)
+	s.Equal("Eating Out", totals[0].Category)
+}
+
+func (s *HouseholdTestSuite) TestOtherHouseholdExpenseIsNotFound() {
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.bob.ID, &models.Expense{Amount: 2500, Description: "Bob rent", Category: "Housing", Date: date}))
+	bobExpenses, err := s.db.ListExpenses(s.ctx, UserScope(s.bob.ID), 1, 0)
+	s.Require().NoError(err)
+	s.Require().Len(bobExpenses, 1)
+	id := bobExpenses[0].ID
+
+	aliceScope := UserScope(s.alice.ID)
+	_, err = s.db.GetExpense(s.ctx, aliceScope, id)
+	s.ErrorIs(err, ErrNotFound)
+
+	err = s.db.UpdateExpense(s.ctx, aliceScope, &models.Expense{ID: id, Amount: 1, Description: "Hijacked", Category: "Other", Date: date})
+	s.ErrorIs(err, ErrNotFound)
+
+	err = s.db.DeleteExpense(s.ctx, aliceScope, id)
+	s.ErrorIs(err, ErrNotFound)
+
+	// Bob's expense is untouched
+	e, err := s.db.GetExpense(s.ctx, UserScope(s.bob.ID), id)
+	s.Require().NoError(err)
+	s.Equal("Bob rent", e.Description)
+	s.Equal(models.Money(2500), e.Amount)
+}
+
+func (s *HouseholdTestSuite) TestMemberOfSeveralHouseholdsSeesAll() {
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.alice.ID, &models.Expense{Amount: 1000, Description: "Alice lunch", Category: "Eating Out", Date: date}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.bob.ID, &models.Expense{Amount: 2500, Description: "Bob rent", Category: "Housing", Date: date.Add(time.Hour)}))
+
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.flat.ID, s.alice.ID))
+
+	expenses, err := s.db.ListExpenses(s.ctx, UserScope(s.alice.ID), 100, 0)
+	s.Require().NoError(err)
+	s.Len(expenses, 2)
+
+	households, err := s.db.ListUserHouseholds(s.ctx, s.alice.ID)
+	s.Require().NoError(err)
+	s.Require().Len(households, 2)
+	s.Equal("Home", households[0].Name, "first joined household comes first")
+
+	// New expenses still go to the household joined first
+	defaultID, err := s.db.DefaultHouseholdID(s.ctx, s.alice.ID)
+	s.Require().NoError(err)
+	s.Equal(s.home.ID, defaultID)
+
+	s.Require().NoError(s.db.RemoveHouseholdMember(s.ctx, s.flat.ID, s.alice.ID))
+	expenses, err = s.db.ListExpenses(s.ctx, UserScope(s.alice.ID), 100, 0)
+	s.Require().NoError(err)
+	s.Len(expenses, 1)
+}
+
+func (s *HouseholdTestSuite) TestSameExpenseInTwoHouseholds() {
+	date := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	expense := models.Expense{Amount: 4500, Description: "Netflix", Category: "Entertainment", Date: date}
+
+	first := expense
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.alice.ID, &first))
+	second := expense
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.bob.ID, &second), "another household may record the same transaction")
+	again := expense
+	s.ErrorIs(s.db.CreateExpense(s.ctx, s.bob.ID, &again), ErrDuplicateExpense)
+
+	carol, err := s.db.CreateUser(s.ctx, "carol", "hash")
+	s.Require().NoError(err)
+	shed, err := s.db.CreateHousehold(s.ctx, "Shed")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, shed.ID, carol.ID))
+
+	imported, err := s.db.ImportExpenses(s.ctx, carol.ID, []models.Expense{expense})
+	s.Require().NoError(err)
+	s.Equal(1, imported)
+}
+
+func (s *HouseholdTestSuite) TestCreateExpenseWithoutHousehold() {
+	carol, err := s.db.CreateUser(s.ctx, "carol", "hash")
+	s.Require().NoError(err)
+
+	err = s.db.CreateExpense(s.ctx, carol.ID, &models.Expense{Amount: 1000, Description: "Orphan", Category: "Other", Date: time.Now()})
+	s.ErrorIs(err, ErrNoHousehold)
+}
+
+func (s *HouseholdTestSuite) TestGetHousehold() {
+	hh, err := s.db.GetHousehold(s.ctx, s.home.ID)
+	s.Require().NoError(err)
+	s.Equal(s.home.Name, hh.Name)
+
+	_, err = s.db.GetHousehold(s.ctx, s.home.ID+1000)
+	s.ErrorIs(err, ErrNotFound)
+}
+
+func (s *HouseholdTestSuite) TestListHouseholdMembers() {
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.home.ID, s.bob.ID))
+	// Adding twice is a no-op
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.home.ID, s.bob.ID))
+
+	members, err := s.db.ListHouseholdMembers(s.ctx, s.home.ID)
+	s.Require().NoError(err)
+	s.Require().Len(members, 2)
+	s.Equal("alice", members[0].Username)
+	s.Equal("bob", members[1].Username)
+}
+
+// TestHouseholdSuite runs the household test suite
+func TestHouseholdSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &HouseholdTestSuite{dsn: dsn})
+	})
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"expense-tracker/internal/models"
+)
+
+const importProfileColumns = `id, household_id, name, delimiter, skip_rows, date_column, amount_column, credit_column,
+	description_column, category_column, date_format, decimal_separator, sign_convention, default_category`
+
+func scanImportProfile(row interface{ Scan(...any) error }, p *models.ImportProfile) error {
+	return row.Scan(
+		&p.ID, &p.HouseholdID, &p.Name, &p.Delimiter, &p.SkipRows, &p.DateColumn, &p.AmountColumn, &p.CreditColumn,
+		&p.DescriptionColumn, &p.CategoryColumn, &p.DateFormat, &p.DecimalSeparator, &p.SignConvention, &p.DefaultCategory,
+	)
+}
+
+// SaveImportProfile stores a CSV import profile, replacing the household's
+// profile of the same name. On success the ID field of p is filled in.
+func (db *DB) SaveImportProfile(ctx context.Context, p *models.ImportProfile) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.conn.QueryRowContext(ctx, `
+		INSERT INTO import_profiles (household_id, name, delimiter, skip_rows, date_column, amount_column, credit_column,
+			description_column, category_column, date_format, decimal_separator, sign_convention, default_category)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (household_id, name) DO UPDATE SET
+			delimiter = excluded.delimiter, skip_rows = excluded.skip_rows, date_column = excluded.date_column,
+			amount_column = excluded.amount_column, credit_column = excluded.credit_column,
+			description_column = excluded.description_column, category_column = excluded.category_column,
+			date_format = excluded.date_format, decimal_separator = excluded.decimal_separator,
+			sign_convention = excluded.sign_convention, default_category = excluded.default_category
+		RETURNING id`,
+		p.HouseholdID, p.Name, p.Delimiter, p.SkipRows, p.DateColumn, p.AmountColumn, p.CreditColumn,
+		p.DescriptionColumn, p.CategoryColumn, p.DateFormat, p.DecimalSeparator, p.SignConvention, p.DefaultCategory,
+	).Scan(&p.ID)
+}
+
+// ListImportProfiles returns the CSV import profiles of a household by name.
+func (db *DB) ListImportProfiles(ctx context.Context, householdID int64) ([]models.ImportProfile, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.read.QueryContext(ctx,
+		"SELECT "+importProfileColumns+" FROM import_profiles WHERE household_id = ? ORDER BY name",
+		householdID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []models.ImportProfile
+	for rows.Next() {
+		var p models.ImportProfile
+		if err := scanImportProfile(rows, &p); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// GetImportProfile returns a CSV import profile of a household. It returns
+// ErrNotFound if the household has no such profile.
+func (db *DB) GetImportProfile(ctx context.Context, householdID, id int64) (*models.ImportProfile, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var p models.ImportProfile
+	err := scanImportProfile(db.read.QueryRowContext(ctx,
+		"SELECT "+importProfileColumns+" FROM import_profiles WHERE id = ? AND household_id = ?",
+		id, householdID,
+	), &p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DeleteImportProfile removes a CSV import profile of a household.
+func (db *DB) DeleteImportProfile(ctx context.Context, householdID, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.conn.ExecContext(ctx, "DELETE FROM import_profiles WHERE id = ? AND household_id = ?", id, householdID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// FindDuplicates reports for each expense whether it is already stored: the
+// household has an expense with the same date, amount and description, the
+// combination the unique index
+// expenses_household_date_amount_description_uindex allows only once, or one
+// with the same ImportRef. Amounts are compared once converted to the
+// household's base currency like in ImportExpenses, so it returns
+// ErrNoExchangeRate if the household has no rate for the currency of one.
+func (db *DB) FindDuplicates(ctx context.Context, householdID int64, expenses []models.Expense) ([]bool, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	base, err := householdCurrency(ctx, db.read, householdID)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]*models.Expense, len(expenses))
+	for i := range expenses {
+		e := expenses[i]
+		converted[i] = &e
+	}
+	if err := convertExpenses(ctx, db.read, householdID, base, converted...); err != nil {
+		return nil, err
+	}
+
+	stmt, err := db.read.PrepareContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM expenses WHERE household_id = ? AND date = ? AND amount = ? AND description = ?)
+			OR EXISTS (SELECT 1 FROM expenses WHERE household_id = ? AND import_ref = ?)`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	duplicates := make([]bool, len(converted))
+	for i, e := range converted {
+		if err := stmt.QueryRowContext(ctx, householdID, e.Date, e.Amount, e.Description, householdID, e.ImportRef).Scan(&duplicates[i]); err != nil {
+			return nil, err
+		}
+	}
+	return duplicates, nil
+}
+
+// ImportExpenses stores transactions in the user's default household in a
+// single transaction. Transactions that are already stored, by date, amount
+// and description or by ImportRef, are skipped. Amounts are converted to the
+// household's base currency like in CreateExpense. It returns the number of
+// transactions added, ErrInvalidAccount if one refers to an account of
+// another household or ErrNoExchangeRate if the household has no rate for the
+// currency of one.
+func (db *DB) ImportExpenses(ctx context.Context, userID int64, expenses []models.Expense) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	householdID, err := db.DefaultHouseholdID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	base, err := householdCurrency(ctx, db.read, householdID)
+	if err != nil {
+		return 0, err
+	}
+	converted := make([]*models.Expense, len(expenses))
+	for i := range expenses {
+		e := expenses[i]
+		if err := checkAccount(ctx, db.read, householdID, e.AccountID); err != nil {
+			return 0, err
+		}
+		converted[i] = &e
+	}
+	if err := convertExpenses(ctx, db.read, householdID, base, converted...); err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	err = db.inTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO expenses (kind, amount, currency, original_amount, description, category, date, user_id, household_id, account_id, import_ref)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))
+			ON CONFLICT DO NOTHING`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, e := range converted {
+			if e.Kind == "" {
+				e.Kind = models.KindExpense
+			}
+			result, err := stmt.ExecContext(ctx, e.Kind, e.Amount, e.Currency, e.OriginalAmount, e.Description, e.Category, e.Date, userID, householdID, e.AccountID, e.ImportRef)
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			imported += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
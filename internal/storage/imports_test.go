@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// ImportTestSuite provides a test suite for statement imports
+type ImportTestSuite struct {
+	suite.Suite
+	dsn       string // Database under test, see runOnBackends
+	ctx       context.Context
+	db        *DB
+	user      *models.User
+	household *models.Household
+}
+
+// SetupTest runs before each test
+func (s *ImportTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "testuser", "hash")
+	s.Require().NoError(err)
+	s.household, err = s.db.CreateHousehold(s.ctx, "Test")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+}
+
+// TearDownTest runs after each test
+func (s *ImportTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *ImportTestSuite) TestSaveImportProfile() {
+	p := models.ImportProfile{
+		HouseholdID: s.household.ID, Name: "Sparkasse", Delimiter: ";", SkipRows: 1,
+		DateColumn: 1, AmountColumn: 3, DescriptionColumn: 2, DateFormat: "DD.MM.YYYY",
+		DecimalSeparator: ",", SignConvention: models.SignNegativeOut, DefaultCategory: "Other",
+	}
+	s.Require().NoError(s.db.SaveImportProfile(s.ctx, &p))
+	s.NotZero(p.ID)
+
+	// Saving under the same name updates the profile
+	updated := p
+	updated.ID = 0
+	updated.SkipRows = 5
+	s.Require().NoError(s.db.SaveImportProfile(s.ctx, &updated))
+	s.Equal(p.ID, updated.ID)
+
+	profiles, err := s.db.ListImportProfiles(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Require().Len(profiles, 1)
+	s.Equal(updated, profiles[0])
+
+	got, err := s.db.GetImportProfile(s.ctx, s.household.ID, p.ID)
+	s.Require().NoError(err)
+	s.Equal(5, got.SkipRows)
+
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	_, err = s.db.GetImportProfile(s.ctx, other.ID, p.ID)
+	s.ErrorIs(err, ErrNotFound)
+	s.ErrorIs(s.db.DeleteImportProfile(s.ctx, other.ID, p.ID), ErrNotFound)
+
+	s.Require().NoError(s.db.DeleteImportProfile(s.ctx, s.household.ID, p.ID))
+	profiles, err = s.db.ListImportProfiles(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Empty(profiles)
+}
+
+func (s *ImportTestSuite) TestImportExpenses() {
+	date := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 2340, Description: "REWE", Category: "Groceries", Date: date}))
+
+	rows := []models.Expense{
+		{Kind: models.KindExpense, Amount: 2340, Description: "REWE", Category: "Groceries", Date: date},
+		{Kind: models.KindIncome, Amount: 250000, Description: "Salary", Category: "Other", Date: date},
+		{Kind: models.KindExpense, Amount: 2340, Description: "REWE", Category: "Groceries", Date: date.AddDate(0, 0, 1)},
+	}
+	duplicates, err := s.db.FindDuplicates(s.ctx, s.household.ID, rows)
+	s.Require().NoError(err)
+	s.Equal([]bool{true, false, false}, duplicates)
+
+	imported, err := s.db.ImportExpenses(s.ctx, s.user.ID, rows)
+	s.Require().NoError(err)
+	s.Equal(2, imported, "the duplicate is skipped")
+
+	expenses, err := s.db.GetExpensesByMonth(s.ctx, UserScope(s.user.ID), 2026, 1)
+	s.Require().NoError(err)
+	s.Len(expenses, 3)
+
+	// Importing the same statement again adds nothing
+	imported, err = s.db.ImportExpenses(s.ctx, s.user.ID, rows)
+	s.Require().NoError(err)
+	s.Zero(imported)
+}
+
+func (s *ImportTestSuite) TestFindDuplicates_HouseholdAndCurrency() {
+	date := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	_, err := s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: "EUR", Currency: "USD", Date: date, Rate: 1.25}})
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1000, Currency: "USD", Description: "Hotel", Category: "Travel", Date: date}))
+
+	// Another household has the same transaction
+	bob, err := s.db.CreateUser(s.ctx, "bob", "hash")
+	s.Require().NoError(err)
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, other.ID, bob.ID))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, bob.ID, &models.Expense{Amount: 4500, Description: "Netflix", Category: "Entertainment", Date: date}))
+
+	rows := []models.Expense{
+		{Kind: models.KindExpense, Amount: 1000, Currency: "USD", Description: "Hotel", Category: "Travel", Date: date},
+		{Kind: models.KindExpense, Amount: 4500, Description: "Netflix", Category: "Entertainment", Date: date},
+	}
+	duplicates, err := s.db.FindDuplicates(s.ctx, s.household.ID, rows)
+	s.Require().NoError(err)
+	s.Equal([]bool{true, false}, duplicates)
+
+	_, err = s.db.FindDuplicates(s.ctx, s.household.ID, []models.Expense{{Amount: 500, Currency: "JPY", Description: "Tea", Category: "Eating Out", Date: date}})
+	s.ErrorIs(err, ErrNoExchangeRate)
+}
+
+func (s *ImportTestSuite) TestImportExpenses_RollsBackOnError() {
+	date := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	rows := []models.Expense{
+		{Kind: models.KindExpense, Amount: 100, Description: "Valid", Category: "Other", Date: date},
+		{Kind: "gift", Amount: 100, Description: "Invalid kind", Category: "Other", Date: date},
+	}
+	_, err := s.db.ImportExpenses(s.ctx, s.user.ID, rows)
+	s.Require().Error(err)
+
+	expenses, err := s.db.GetExpensesByMonth(s.ctx, UserScope(s.user.ID), 2026, 1)
+	s.Require().NoError(err)
+	s.Empty(expenses, "nothing is imported when a row fails")
+}
+
+func (s *ImportTestSuite) TestImportExpenses_ByReference() {
+	date := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	first := []models.Expense{{Kind: models.KindExpense, Amount: 500, Description: "Card payment", Category: "Other", Date: date, ImportRef: "ofx:123:A1"}}
+	imported, err := s.db.ImportExpenses(s.ctx, s.user.ID, first)
+	s.Require().NoError(err)
+	s.Equal(1, imported)
+
+	// The bank changed the description, but the reference identifies the transaction
+	again := []models.Expense{
+		{Kind: models.KindExpense, Amount: 500, Description: "CARD PAYMENT 05/01", Category: "Other", Date: date, ImportRef: "ofx:123:A1"},
+		{Kind: models.KindExpense, Amount: 500, Description: "Card payment", Category: "Other", Date: date.AddDate(0, 0, 1), ImportRef: "ofx:123:A2"},
+	}
+	duplicates, err := s.db.FindDuplicates(s.ctx, s.household.ID, again)
+	s.Require().NoError(err)
+	s.Equal([]bool{true, false}, duplicates)
+
+	imported, err = s.db.ImportExpenses(s.ctx, s.user.ID, again)
+	s.Require().NoError(err)
+	s.Equal(1, imported)
+
+	expenses, err := s.db.GetExpensesByMonth(s.ctx, UserScope(s.user.ID), 2026, 1)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 2)
+	s.Equal("ofx:123:A2", expenses[0].ImportRef)
+}
+
+// TestImportSuite runs the import test suite
+func TestImportSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &ImportTestSuite{dsn: dsn})
+	})
+}
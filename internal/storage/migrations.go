@@ -0,0 +1,834 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// migration is a single numbered schema change.
+// Up and Down may contain several statements separated by semicolons.
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// checksum identifies the exact Up script that was applied, so edits to an
+// already-applied migration are detected instead of silently ignored.
+func (m migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// migrations is the ordered list of schema changes. Never edit or reorder an
+// entry once it has been released; add a new one instead.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: `
+			CREATE TABLE expenses (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				amount REAL NOT NULL,
+				description TEXT NOT NULL,
+				category TEXT NOT NULL,
+				date DATETIME NOT NULL,
+				user_id INTEGER REFERENCES users(id)
+			);
+			CREATE TABLE users (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				username TEXT UNIQUE NOT NULL,
+				password_hash TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE sessions (
+				token TEXT PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				expires_at DATETIME NOT NULL,
+				last_activity DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+			);
+			CREATE UNIQUE INDEX expenses_date_amount_description_uindex ON expenses (date, amount, description);
+		`,
+		Down: `
+			DROP TABLE sessions;
+			DROP TABLE expenses;
+			DROP TABLE users;
+		`,
+	},
+	{
+		Version: 2,
+		Name:    "store amounts as integer cents",
+		Up: `
+			CREATE TABLE expenses_new (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				amount INTEGER NOT NULL,
+				description TEXT NOT NULL,
+				category TEXT NOT NULL,
+				date DATETIME NOT NULL,
+				user_id INTEGER REFERENCES users(id)
+			);
+			INSERT INTO expenses_new (id, amount, description, category, date, user_id)
+				SELECT id, CAST(ROUND(amount * 100) AS INTEGER), description, category, date, user_id FROM expenses;
+			DROP TABLE expenses;
+			ALTER TABLE expenses_new RENAME TO expenses;
+			CREATE UNIQUE INDEX expenses_date_amount_description_uindex ON expenses (date, amount, description);
+		`,
+		Down: `
+			CREATE TABLE expenses_old (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				amount REAL NOT NULL,
+				description TEXT NOT NULL,
+				category TEXT NOT NULL,
+				date DATETIME NOT NULL,
+				user_id INTEGER REFERENCES users(id)
+			);
+			INSERT INTO expenses_old (id, amount, description, category, date, user_id)
+				SELECT id, amount / 100.0, description, category, date, user_id FROM expenses;
+			DROP TABLE expenses;
+			ALTER TABLE expenses_old RENAME TO expenses;
+			CREATE UNIQUE INDEX expenses_date_amount_description_uindex ON expenses (date, amount, description);
+		`,
+	},
+	{
+		Version: 3,
+		Name:    "households",
+		Up: `
+			CREATE TABLE households (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT UNIQUE NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE household_members (
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (household_id, user_id)
+			);
+			CREATE INDEX household_members_user_index ON household_members (user_id);
+			ALTER TABLE expenses ADD COLUMN household_id INTEGER REFERENCES households(id);
+
+			-- Before households existed every user saw every expense, so existing
+			-- data becomes one shared household.
+			INSERT INTO households (name)
+				SELECT 'Household' WHERE EXISTS (SELECT 1 FROM users) OR EXISTS (SELECT 1 FROM expenses);
+			INSERT INTO household_members (household_id, user_id)
+				SELECT h.id, u.id FROM households h CROSS JOIN users u;
+			UPDATE expenses SET household_id = (SELECT MIN(id) FROM households);
+
+			CREATE INDEX expenses_household_date_index ON expenses (household_id, date);
+		`,
+		Down: `
+			DROP INDEX expenses_household_date_index;
+			CREATE TABLE expenses_old (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				amount INTEGER NOT NULL,
+				description TEXT NOT NULL,
+				category TEXT NOT NULL,
+				date DATETIME NOT NULL,
+				user_id INTEGER REFERENCES users(id)
+			);
+			INSERT INTO expenses_old (id, amount, description, category, date, user_id)
+				SELECT id, amount, description, category, date, user_id FROM expenses;
+			DROP TABLE expenses;
+			ALTER TABLE expenses_old RENAME TO expenses;
+			CREATE UNIQUE INDEX expenses_date_amount_description_uindex ON expenses (date, amount, description);
+			DROP TABLE household_members;
+			DROP TABLE households;
+		`,
+	},
+	{
+		Version: 4,
+		Name:    "transaction kind",
+		Up: `
+			ALTER TABLE expenses ADD COLUMN kind TEXT NOT NULL DEFAULT 'expense'
+				CHECK (kind IN ('expense', 'income', 'refund'));
+
+			-- Income used to be marked with an "[Income]" tag in the description
+			UPDATE expenses
+				SET kind = 'income', description = TRIM(REPLACE(description, '[Income]', ''))
+				WHERE description LIKE '%[Income]%';
+			UPDATE expenses SET description = category WHERE description = '';
+		`,
+		Down: `
+			UPDATE expenses SET description = '[Income] ' || description WHERE kind = 'income';
+			UPDATE expenses SET amount = -amount WHERE kind = 'refund';
+			ALTER TABLE expenses DROP COLUMN kind;
+		`,
+	},
+	{
+		Version: 5,
+		Name:    "categories",
+		Up: `
+			CREATE TABLE categories (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				icon TEXT NOT NULL,
+				color TEXT NOT NULL,
+				position INTEGER NOT NULL DEFAULT 0,
+				archived INTEGER NOT NULL DEFAULT 0,
+				UNIQUE (household_id, name)
+			);
+
+			INSERT INTO categories (household_id, name, icon, color, position)
+				SELECT h.id, d.column1, d.column2, d.column3, d.column4
+				FROM households h, (VALUES
+					('Groceries', '🛒', '#60a5fa', 1),
+					('Eating Out', '🍴', '#60a5fa', 2),
+					('Transport', '🚌', '#a78bfa', 3),
+					('Housing', '🏠', '#818cf8', 4),
+					('Utilities', '💡', '#fbbf24', 5),
+					('Sport', '🏋️‍♂️', '#fbbf24', 6),
+					('Health', '🚑', '#fbbf24', 7),
+					('Entertainment', '🎮', '#f472b6', 8),
+					('Travel', '✈️', '#f472b6', 9),
+					('Gifts', '🎁', '#fb7185', 10),
+					('Other', '📦', '#94a3b8', 11)
+				) d;
+
+			-- Keep categories that expenses use but the default list does not have
+			INSERT OR IGNORE INTO categories (household_id, name, icon, color, position)
+				SELECT DISTINCT household_id, category, '📦', '#94a3b8', 100
+				FROM expenses WHERE household_id IS NOT NULL;
+		`,
+		Down: `
+			DROP TABLE categories;
+		`,
+	},
+	{
+		Version: 6,
+		Name:    "subcategories",
+		Up: `
+			ALTER TABLE categories ADD COLUMN parent_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+			CREATE INDEX categories_parent_index ON categories (parent_id);
+		`,
+		Down: `
+			CREATE TABLE categories_old (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				icon TEXT NOT NULL,
+				color TEXT NOT NULL,
+				position INTEGER NOT NULL DEFAULT 0,
+				archived INTEGER NOT NULL DEFAULT 0,
+				UNIQUE (household_id, name)
+			);
+			INSERT INTO categories_old (id, household_id, name, icon, color, position, archived)
+				SELECT id, household_id, name, icon, color, position, archived FROM categories;
+			DROP TABLE categories;
+			ALTER TABLE categories_old RENAME TO categories;
+		`,
+	},
+	{
+		Version: 7,
+		Name:    "budgets",
+		Up: `
+			CREATE TABLE budgets (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+				year INTEGER NOT NULL,
+				month INTEGER NOT NULL CHECK (month BETWEEN 1 AND 12),
+				amount INTEGER NOT NULL CHECK (amount >= 0),
+				rollover INTEGER NOT NULL DEFAULT 0,
+				UNIQUE (category_id, year, month)
+			);
+			CREATE INDEX budgets_household_index ON budgets (household_id, year, month);
+		`,
+		Down: `
+			DROP TABLE budgets;
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "recurring",
+		// expenses.recurring_id has no foreign key so that deleting a
+		// template keeps the expenses already created from it.
+		Up: `
+			CREATE TABLE recurring (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				kind TEXT NOT NULL DEFAULT 'expense' CHECK (kind IN ('expense', 'income', 'refund')),
+				amount INTEGER NOT NULL,
+				description TEXT NOT NULL,
+				category TEXT NOT NULL,
+				frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+				interval INTEGER NOT NULL DEFAULT 1 CHECK (interval >= 1),
+				day INTEGER NOT NULL DEFAULT 0 CHECK (day BETWEEN 0 AND 31),
+				start_date DATETIME NOT NULL,
+				end_date DATETIME,
+				next_date DATETIME NOT NULL
+			);
+			CREATE INDEX recurring_next_date_index ON recurring (next_date);
+			ALTER TABLE expenses ADD COLUMN recurring_id INTEGER;
+			CREATE UNIQUE INDEX expenses_recurring_uindex ON expenses (recurring_id, date);
+		`,
+		Down: `
+			DROP INDEX expenses_recurring_uindex;
+			ALTER TABLE expenses DROP COLUMN recurring_id;
+			DROP TABLE recurring;
+		`,
+	},
+	{
+		Version: 9,
+		Name:    "api tokens",
+		Up: `
+			CREATE TABLE api_tokens (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				token_hash TEXT UNIQUE NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX api_tokens_user_index ON api_tokens (user_id);
+		`,
+		Down: `
+			DROP TABLE api_tokens;
+		`,
+	},
+	{
+		Version: 10,
+		Name:    "api token scopes",
+		Up: `
+			ALTER TABLE api_tokens ADD COLUMN scope TEXT NOT NULL DEFAULT 'read-write';
+			ALTER TABLE api_tokens ADD COLUMN last_used_at DATETIME;
+		`,
+		Down: `
+			ALTER TABLE api_tokens DROP COLUMN last_used_at;
+			ALTER TABLE api_tokens DROP COLUMN scope;
+		`,
+	},
+	{
+		Version: 11,
+		Name:    "import profiles",
+		Up: `
+			CREATE TABLE import_profiles (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				delimiter TEXT NOT NULL,
+				skip_rows INTEGER NOT NULL DEFAULT 0,
+				date_column INTEGER NOT NULL,
+				amount_column INTEGER NOT NULL,
+				credit_column INTEGER NOT NULL DEFAULT 0,
+				description_column INTEGER NOT NULL,
+				category_column INTEGER NOT NULL DEFAULT 0,
+				date_format TEXT NOT NULL,
+				decimal_separator TEXT NOT NULL,
+				sign_convention TEXT NOT NULL,
+				default_category TEXT NOT NULL DEFAULT '',
+				UNIQUE (household_id, name)
+			);
+		`,
+		Down: `
+			DROP TABLE import_profiles;
+		`,
+	},
+	{
+		Version: 12,
+		Name:    "import references",
+		Up: `
+			ALTER TABLE expenses ADD COLUMN import_ref TEXT;
+			CREATE UNIQUE INDEX expenses_import_ref_uindex ON expenses (household_id, import_ref);
+		`,
+		Down: `
+			DROP INDEX expenses_import_ref_uindex;
+			ALTER TABLE expenses DROP COLUMN import_ref;
+		`,
+	},
+	{
+		Version: 13,
+		Name:    "category accounts",
+		Up: `
+			ALTER TABLE categories ADD COLUMN account TEXT NOT NULL DEFAULT '';
+		`,
+		Down: `
+			ALTER TABLE categories DROP COLUMN account;
+		`,
+	},
+	{
+		Version: 14,
+		Name:    "currencies",
+		Up: `
+			ALTER TABLE households ADD COLUMN currency TEXT NOT NULL DEFAULT 'EUR';
+			ALTER TABLE expenses ADD COLUMN currency TEXT NOT NULL DEFAULT 'EUR';
+			ALTER TABLE expenses ADD COLUMN original_amount INTEGER NOT NULL DEFAULT 0;
+			UPDATE expenses SET original_amount = amount;
+
+			-- One unit of base is worth rate units of currency on date.
+			CREATE TABLE exchange_rates (
+				base TEXT NOT NULL,
+				currency TEXT NOT NULL,
+				date DATETIME NOT NULL,
+				rate REAL NOT NULL CHECK (rate > 0),
+				PRIMARY KEY (base, currency, date)
+			);
+		`,
+		Down: `
+			DROP TABLE exchange_rates;
+			ALTER TABLE expenses DROP COLUMN original_amount;
+			ALTER TABLE expenses DROP COLUMN currency;
+			ALTER TABLE households DROP COLUMN currency;
+		`,
+	},
+	{
+		Version: 15,
+		Name:    "accounts",
+		// expenses.account_id has no foreign key: accounts are archived
+		// rather than deleted, and SQLite cannot drop a column that has one.
+		Up: `
+			CREATE TABLE accounts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				type TEXT NOT NULL CHECK (type IN ('cash', 'checking', 'card', 'savings')),
+				currency TEXT NOT NULL,
+				opening_balance INTEGER NOT NULL DEFAULT 0,
+				archived INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (household_id, name)
+			);
+			ALTER TABLE expenses ADD COLUMN account_id INTEGER;
+			CREATE INDEX expenses_account_index ON expenses (account_id);
+
+			-- amount leaves from_account_id in its currency, to_amount arrives
+			-- at to_account_id in its currency.
+			CREATE TABLE transfers (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				from_account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				to_account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				amount INTEGER NOT NULL CHECK (amount > 0),
+				to_amount INTEGER NOT NULL CHECK (to_amount > 0),
+				description TEXT NOT NULL DEFAULT '',
+				date DATETIME NOT NULL,
+				CHECK (from_account_id <> to_account_id)
+			);
+			CREATE INDEX transfers_household_index ON transfers (household_id, date);
+		`,
+		Down: `
+			DROP TABLE transfers;
+			DROP INDEX expenses_account_index;
+			ALTER TABLE expenses DROP COLUMN account_id;
+			DROP TABLE accounts;
+		`,
+	},
+	{
+		Version: 16,
+		Name:    "expense splits",
+		// The parts of a split expense, in its currency. expenses.category
+		// holds the category of the first part.
+		Up: `
+			CREATE TABLE expense_splits (
+				expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				category TEXT NOT NULL,
+				amount INTEGER NOT NULL CHECK (amount > 0),
+				PRIMARY KEY (expense_id, position)
+			);
+		`,
+		Down: `
+			DROP TABLE expense_splits;
+		`,
+	},
+	{
+		Version: 17,
+		Name:    "shared expenses",
+		// Every share of an expense has the same method. weight is 1 for
+		// equal shares, the percentage in hundredths or the amount in the
+		// expense's currency.
+		Up: `
+			CREATE TABLE expense_shares (
+				expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				method TEXT NOT NULL CHECK (method IN ('equal', 'percent', 'exact')),
+				weight INTEGER NOT NULL CHECK (weight > 0),
+				PRIMARY KEY (expense_id, user_id)
+			);
+
+			CREATE TABLE settlements (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				from_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				to_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				amount INTEGER NOT NULL CHECK (amount > 0),
+				date DATETIME NOT NULL,
+				CHECK (from_user_id <> to_user_id)
+			);
+			CREATE INDEX settlements_household_index ON settlements (household_id, date);
+		`,
+		Down: `
+			DROP TABLE settlements;
+			DROP TABLE expense_shares;
+		`,
+	},
+	{
+		Version: 18,
+		Name:    "expense tags",
+		// Tags are kept per household and lower case; tags no longer in use
+		// are left in place.
+		Up: `
+			CREATE TABLE tags (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				UNIQUE (household_id, name)
+			);
+
+			CREATE TABLE expense_tags (
+				expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+				tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+				PRIMARY KEY (expense_id, tag_id)
+			);
+			CREATE INDEX expense_tags_tag_index ON expense_tags (tag_id);
+		`,
+		Down: `
+			DROP TABLE expense_tags;
+			DROP TABLE tags;
+		`,
+	},
+	{
+		Version: 19,
+		Name:    "household exchange rates",
+		// Rates were shared by all households; each household gets a copy of
+		// them.
+		Up: `
+			CREATE TABLE household_exchange_rates (
+				household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				base TEXT NOT NULL,
+				currency TEXT NOT NULL,
+				date DATETIME NOT NULL,
+				rate REAL NOT NULL CHECK (rate > 0),
+				PRIMARY KEY (household_id, base, currency, date)
+			);
+			INSERT INTO household_exchange_rates (household_id, base, currency, date, rate)
+				SELECT h.id, r.base, r.currency, r.date, r.rate FROM households h CROSS JOIN exchange_rates r;
+			DROP TABLE exchange_rates;
+			ALTER TABLE household_exchange_rates RENAME TO exchange_rates;
+		`,
+		Down: `
+			CREATE TABLE shared_exchange_rates (
+				base TEXT NOT NULL,
+				currency TEXT NOT NULL,
+				date DATETIME NOT NULL,
+				rate REAL NOT NULL CHECK (rate > 0),
+				PRIMARY KEY (base, currency, date)
+			);
+			INSERT INTO shared_exchange_rates (base, currency, date, rate)
+				SELECT base, currency, date, MAX(rate) FROM exchange_rates GROUP BY base, currency, date;
+			DROP TABLE exchange_rates;
+			ALTER TABLE shared_exchange_rates RENAME TO exchange_rates;
+		`,
+	},
+	{
+		Version: 20,
+		Name:    "duplicate expenses per household",
+		// The same transaction may be recorded by several households.
+		Up: `
+			DROP INDEX IF EXISTS expenses_date_amount_description_uindex;
+			CREATE UNIQUE INDEX expenses_household_date_amount_description_uindex
+				ON expenses (household_id, date, amount, description);
+		`,
+		Down: `
+			DROP INDEX expenses_household_date_amount_description_uindex;
+			CREATE UNIQUE INDEX expenses_date_amount_description_uindex ON expenses (date, amount, description);
+		`,
+	},
+}
+
+// ErrChecksumMismatch is returned when an applied migration no longer matches
+// the script compiled into the binary.
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// MigrationStatus describes the state of a single migration.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// ChecksumOK is false when the applied script differs from the known one.
+	ChecksumOK bool
+}
+
+// appliedMigration is a row of the schema_migrations table.
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func (db *DB) ensureMigrationsTable(ctx context.Context) error {
+	_, err := db.conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at `+db.dialect.timestamp+` NOT NULL
+	)`)
+	return err
+}
+
+func (db *DB) appliedMigrations(ctx context.Context) (map[int]appliedMigration, error) {
+	rows, err := db.conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// loadMigrationState prepares the bookkeeping table, adopts databases created
+// before versioned migrations existed, and verifies recorded checksums.
+func (db *DB) loadMigrationState(ctx context.Context) (map[int]appliedMigration, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	if err := db.adoptLegacySchema(ctx); err != nil {
+		return nil, fmt.Errorf("adopt legacy schema: %w", err)
+	}
+
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	all := db.dialect.migrations()
+	known := make(map[int]migration, len(all))
+	for _, m := range all {
+		known[m.Version] = m
+	}
+	for version, a := range applied {
+		m, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("database has migration %d which is unknown to this binary", version)
+		}
+		if a.checksum != m.checksum() {
+			return nil, fmt.Errorf("%w: migration %d (%s)", ErrChecksumMismatch, m.Version, m.Name)
+		}
+	}
+	return applied, nil
+}
+
+// MigrationStatus reports every known migration and whether it has been applied.
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	all := db.dialect.migrations()
+	statuses := make([]MigrationStatus, 0, len(all))
+	for _, m := range all {
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = a.appliedAt
+			st.ChecksumOK = a.checksum == m.checksum()
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies all pending migrations in order and returns how many ran.
+// Each migration runs in its own transaction; the first failure stops the run.
+func (db *DB) MigrateUp(ctx context.Context) (int, error) {
+	count := 0
+	err := db.withoutForeignKeys(ctx, func() error {
+		applied, err := db.loadMigrationState(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range db.dialect.migrations() {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := db.applyMigration(ctx, m); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrateDown reverts up to steps of the most recently applied migrations and
+// returns how many were reverted.
+func (db *DB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := db.withoutForeignKeys(ctx, func() error {
+		applied, err := db.loadMigrationState(ctx)
+		if err != nil {
+			return err
+		}
+
+		all := db.dialect.migrations()
+		for i := len(all) - 1; i >= 0 && count < steps; i-- {
+			m := all[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if err := db.revertMigration(ctx, m); err != nil {
+				return fmt.Errorf("revert migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// withoutForeignKeys runs fn with foreign key enforcement switched off, as
+// SQLite requires for migrations that rebuild a table: dropping the old table
+// would otherwise cascade into every table referencing it. The pragma has no
+// effect inside a transaction, so it is set on the writer connection around
+// the migrations' transactions. PostgreSQL migrations never need this.
+func (db *DB) withoutForeignKeys(ctx context.Context, fn func() error) error {
+	if db.dialect.postgres() {
+		return fn()
+	}
+
+	var enabled bool
+	if err := db.conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled); err != nil {
+		return err
+	}
+	if !enabled {
+		return fn()
+	}
+
+	if _, err := db.conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	err := fn()
+	if _, onErr := db.conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err == nil {
+		err = onErr
+	}
+	return err
+}
+
+func (db *DB) applyMigration(ctx context.Context, m migration) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+			m.Version, m.Name, m.checksum(), time.Now().UTC(),
+		)
+		return err
+	})
+}
+
+func (db *DB) revertMigration(ctx context.Context, m migration) error {
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", m.Version)
+		return err
+	})
+}
+
+// adoptLegacySchema brings databases created by the old unversioned migrate()
+// up to the shape of migration 1 and records it as applied. Only SQLite
+// databases predate versioned migrations.
+func (db *DB) adoptLegacySchema(ctx context.Context) error {
+	if db.dialect.postgres() {
+		return nil
+	}
+
+	var recorded int
+	if err := db.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&recorded); err != nil {
+		return err
+	}
+	if recorded > 0 {
+		return nil
+	}
+
+	exists, err := db.tableExists(ctx, "expenses")
+	if err != nil || !exists {
+		return err
+	}
+
+	baseline := migrations[0]
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT UNIQUE NOT NULL,
+			password_hash TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS sessions (
+			token TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			expires_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`); err != nil {
+			return err
+		}
+		if err := addColumnIfMissing(ctx, tx, "expenses", "user_id", "INTEGER REFERENCES users(id)"); err != nil {
+			return err
+		}
+		// SQLite cannot add a column with a non-constant default, so backfill instead.
+		if err := addColumnIfMissing(ctx, tx, "sessions", "last_activity", "DATETIME"); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE sessions SET last_activity = CURRENT_TIMESTAMP WHERE last_activity IS NULL"); err != nil {
+			return err
+		}
+		// The index as of migration 1; migration 20 scopes it to the household.
+		if _, err := tx.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS expenses_date_amount_description_uindex ON expenses (date, amount, description)`); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+			baseline.Version, baseline.Name, baseline.checksum(), time.Now().UTC(),
+		)
+		return err
+	})
+}
+
+func (db *DB) tableExists(ctx context.Context, name string) (bool, error) {
+	var count int
+	err := db.conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table, column, definition string) error {
+	var count int
+	err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column,
+	).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
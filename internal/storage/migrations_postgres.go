@@ -0,0 +1,330 @@
+package storage
+
+// postgresMigrations is the ordered list of schema changes of PostgreSQL
+// databases. PostgreSQL support was added at version 13, so it starts with
+// the schema SQLite databases have at that point; later changes are added to
+// both lists under the same version.
+var postgresMigrations = []migration{
+	{
+		Version: 13,
+		Name:    "initial schema",
+		Up: `
+			CREATE TABLE users (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				username TEXT UNIQUE NOT NULL,
+				password_hash TEXT NOT NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE sessions (
+				token TEXT PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				expires_at TIMESTAMPTZ NOT NULL,
+				last_activity TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE households (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				name TEXT UNIQUE NOT NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE household_members (
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				joined_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (household_id, user_id)
+			);
+			CREATE INDEX household_members_user_index ON household_members (user_id);
+
+			CREATE TABLE categories (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				parent_id BIGINT REFERENCES categories(id) ON DELETE SET NULL,
+				name TEXT NOT NULL,
+				icon TEXT NOT NULL,
+				color TEXT NOT NULL,
+				position INTEGER NOT NULL DEFAULT 0,
+				archived BOOLEAN NOT NULL DEFAULT FALSE,
+				account TEXT NOT NULL DEFAULT '',
+				UNIQUE (household_id, name)
+			);
+			CREATE INDEX categories_parent_index ON categories (parent_id);
+
+			CREATE TABLE budgets (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+				year INTEGER NOT NULL,
+				month INTEGER NOT NULL CHECK (month BETWEEN 1 AND 12),
+				amount BIGINT NOT NULL CHECK (amount >= 0),
+				rollover BOOLEAN NOT NULL DEFAULT FALSE,
+				UNIQUE (category_id, year, month)
+			);
+			CREATE INDEX budgets_household_index ON budgets (household_id, year, month);
+
+			CREATE TABLE recurring (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				kind TEXT NOT NULL DEFAULT 'expense' CHECK (kind IN ('expense', 'income', 'refund')),
+				amount BIGINT NOT NULL,
+				description TEXT NOT NULL,
+				category TEXT NOT NULL,
+				frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+				interval INTEGER NOT NULL DEFAULT 1 CHECK (interval >= 1),
+				day INTEGER NOT NULL DEFAULT 0 CHECK (day BETWEEN 0 AND 31),
+				start_date TIMESTAMPTZ NOT NULL,
+				end_date TIMESTAMPTZ,
+				next_date TIMESTAMPTZ NOT NULL
+			);
+			CREATE INDEX recurring_next_date_index ON recurring (next_date);
+
+			-- recurring_id has no foreign key so that deleting a template keeps
+			-- the expenses already created from it.
+			CREATE TABLE expenses (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				kind TEXT NOT NULL DEFAULT 'expense' CHECK (kind IN ('expense', 'income', 'refund')),
+				amount BIGINT NOT NULL,
+				description TEXT NOT NULL,
+				category TEXT NOT NULL,
+				date TIMESTAMPTZ NOT NULL,
+				user_id BIGINT REFERENCES users(id),
+				household_id BIGINT REFERENCES households(id),
+				recurring_id BIGINT,
+				import_ref TEXT
+			);
+			CREATE UNIQUE INDEX expenses_date_amount_description_uindex ON expenses (date, amount, description);
+			CREATE UNIQUE INDEX expenses_recurring_uindex ON expenses (recurring_id, date);
+			CREATE UNIQUE INDEX expenses_import_ref_uindex ON expenses (household_id, import_ref);
+			CREATE INDEX expenses_household_date_index ON expenses (household_id, date);
+
+			CREATE TABLE api_tokens (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				token_hash TEXT UNIQUE NOT NULL,
+				scope TEXT NOT NULL DEFAULT 'read-write',
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				last_used_at TIMESTAMPTZ
+			);
+			CREATE INDEX api_tokens_user_index ON api_tokens (user_id);
+
+			CREATE TABLE import_profiles (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				delimiter TEXT NOT NULL,
+				skip_rows INTEGER NOT NULL DEFAULT 0,
+				date_column INTEGER NOT NULL,
+				amount_column INTEGER NOT NULL,
+				credit_column INTEGER NOT NULL DEFAULT 0,
+				description_column INTEGER NOT NULL,
+				category_column INTEGER NOT NULL DEFAULT 0,
+				date_format TEXT NOT NULL,
+				decimal_separator TEXT NOT NULL,
+				sign_convention TEXT NOT NULL,
+				default_category TEXT NOT NULL DEFAULT '',
+				UNIQUE (household_id, name)
+			);
+		`,
+		Down: `
+			DROP TABLE import_profiles;
+			DROP TABLE api_tokens;
+			DROP TABLE expenses;
+			DROP TABLE recurring;
+			DROP TABLE budgets;
+			DROP TABLE categories;
+			DROP TABLE household_members;
+			DROP TABLE households;
+			DROP TABLE sessions;
+			DROP TABLE users;
+		`,
+	},
+	{
+		Version: 14,
+		Name:    "currencies",
+		Up: `
+			ALTER TABLE households ADD COLUMN currency TEXT NOT NULL DEFAULT 'EUR';
+			ALTER TABLE expenses ADD COLUMN currency TEXT NOT NULL DEFAULT 'EUR';
+			ALTER TABLE expenses ADD COLUMN original_amount BIGINT NOT NULL DEFAULT 0;
+			UPDATE expenses SET original_amount = amount;
+
+			-- One unit of base is worth rate units of currency on date.
+			CREATE TABLE exchange_rates (
+				base TEXT NOT NULL,
+				currency TEXT NOT NULL,
+				date TIMESTAMPTZ NOT NULL,
+				rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+				PRIMARY KEY (base, currency, date)
+			);
+		`,
+		Down: `
+			DROP TABLE exchange_rates;
+			ALTER TABLE expenses DROP COLUMN original_amount;
+			ALTER TABLE expenses DROP COLUMN currency;
+			ALTER TABLE households DROP COLUMN currency;
+		`,
+	},
+	{
+		Version: 15,
+		Name:    "accounts",
+		// expenses.account_id has no foreign key: accounts are archived
+		// rather than deleted, and SQLite cannot drop a column that has one.
+		Up: `
+			CREATE TABLE accounts (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				type TEXT NOT NULL CHECK (type IN ('cash', 'checking', 'card', 'savings')),
+				currency TEXT NOT NULL,
+				opening_balance BIGINT NOT NULL DEFAULT 0,
+				archived BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (household_id, name)
+			);
+			ALTER TABLE expenses ADD COLUMN account_id BIGINT;
+			CREATE INDEX expenses_account_index ON expenses (account_id);
+
+			-- amount leaves from_account_id in its currency, to_amount arrives
+			-- at to_account_id in its currency.
+			CREATE TABLE transfers (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+				from_account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				to_account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				amount BIGINT NOT NULL CHECK (amount > 0),
+				to_amount BIGINT NOT NULL CHECK (to_amount > 0),
+				description TEXT NOT NULL DEFAULT '',
+				date TIMESTAMPTZ NOT NULL,
+				CHECK (from_account_id <> to_account_id)
+			);
+			CREATE INDEX transfers_household_index ON transfers (household_id, date);
+		`,
+		Down: `
+			DROP TABLE transfers;
+			DROP INDEX expenses_account_index;
+			ALTER TABLE expenses DROP COLUMN account_id;
+			DROP TABLE accounts;
+		`,
+	},
+	{
+		Version: 16,
+		Name:    "expense splits",
+		// The parts of a split expense, in its currency. expenses.category
+		// holds the category of the first part.
+		Up: `
+			CREATE TABLE expense_splits (
+				expense_id BIGINT NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				category TEXT NOT NULL,
+				amount BIGINT NOT NULL CHECK (amount > 0),
+				PRIMARY KEY (expense_id, position)
+			);
+		`,
+		Down: `
+			DROP TABLE expense_splits;
+		`,
+	},
+	{
+		Version: 17,
+		Name:    "shared expenses",
+		// Every share of an expense has the same method. weight is 1 for
+		// equal shares, the percentage in hundredths or the amount in the
+		// expense's currency.
+		Up: `
+			CREATE TABLE expense_shares (
+				expense_id BIGINT NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+				user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				method TEXT NOT NULL CHECK (method IN ('equal', 'percent', 'exact')),
+				weight BIGINT NOT NULL CHECK (weight > 0),
+				PRIMARY KEY (expense_id, user_id)
+			);
+
+			CREATE TABLE settlements (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				from_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				to_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				amount BIGINT NOT NULL CHECK (amount > 0),
+				date TIMESTAMPTZ NOT NULL,
+				CHECK (from_user_id <> to_user_id)
+			);
+			CREATE INDEX settlements_household_index ON settlements (household_id, date);
+		`,
+		Down: `
+			DROP TABLE settlements;
+			DROP TABLE expense_shares;
+		`,
+	},
+	{
+		Version: 18,
+		Name:    "expense tags",
+		// Tags are kept per household and lower case; tags no longer in use
+		// are left in place.
+		Up: `
+			CREATE TABLE tags (
+				id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				UNIQUE (household_id, name)
+			);
+
+			CREATE TABLE expense_tags (
+				expense_id BIGINT NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+				tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+				PRIMARY KEY (expense_id, tag_id)
+			);
+			CREATE INDEX expense_tags_tag_index ON expense_tags (tag_id);
+		`,
+		Down: `
+			DROP TABLE expense_tags;
+			DROP TABLE tags;
+		`,
+	},
+	{
+		Version: 19,
+		Name:    "household exchange rates",
+		// Rates were shared by all households; each household gets a copy of
+		// them.
+		Up: `
+			CREATE TABLE household_exchange_rates (
+				household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+				base TEXT NOT NULL,
+				currency TEXT NOT NULL,
+				date TIMESTAMPTZ NOT NULL,
+				rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+				PRIMARY KEY (household_id, base, currency, date)
+			);
+			INSERT INTO household_exchange_rates (household_id, base, currency, date, rate)
+				SELECT h.id, r.base, r.currency, r.date, r.rate FROM households h CROSS JOIN exchange_rates r;
+			DROP TABLE exchange_rates;
+			ALTER TABLE household_exchange_rates RENAME TO exchange_rates;
+		`,
+		Down: `
+			CREATE TABLE shared_exchange_rates (
+				base TEXT NOT NULL,
+				currency TEXT NOT NULL,
+				date TIMESTAMPTZ NOT NULL,
+				rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+				PRIMARY KEY (base, currency, date)
+			);
+			INSERT INTO shared_exchange_rates (base, currency, date, rate)
+				SELECT base, currency, date, MAX(rate) FROM exchange_rates GROUP BY base, currency, date;
+			DROP TABLE exchange_rates;
+			ALTER TABLE shared_exchange_rates RENAME TO exchange_rates;
+		`,
+	},
+	{
+		Version: 20,
+		Name:    "duplicate expenses per household",
+		// The same transaction may be recorded by several households.
+		Up: `
+			DROP INDEX IF EXISTS expenses_date_amount_description_uindex;
+			CREATE UNIQUE INDEX expenses_household_date_amount_description_uindex
+				ON expenses (household_id, date, amount, description);
+		`,
+		Down: `
+			DROP INDEX expenses_household_date_amount_description_uindex;
+			CREATE UNIQUE INDEX expenses_date_amount_description_uindex ON expenses (date, amount, description);
+		`,
+	},
+}
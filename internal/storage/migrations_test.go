@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MigrationTestSuite provides a test suite for schema migrations
+type MigrationTestSuite struct {
+	suite.Suite
+	ctx  context.Context
+	path string
+}
+
+// SetupTest runs before each test
+func (s *MigrationTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.path = filepath.Join(s.T().TempDir(), "migrations.db")
+}
+
+func (s *MigrationTestSuite) open() *DB {
+	db, err := Open(s.path)
+	s.Require().NoError(err, "failed to open test database")
+	s.T().Cleanup(func() { db.Close() })
+	return db
+}
+
+func (s *MigrationTestSuite) TestMigrateUp_FreshDatabase() {
+	db := s.open()
+
+	applied, err := db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(len(migrations), applied)
+
+	statuses, err := db.MigrationStatus(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Len(statuses, len(migrations))
+	for _, st := range statuses {
+		s.True(st.Applied, "migration %d should be applied", st.Version)
+		s.True(st.ChecksumOK, "migration %d should have a valid checksum", st.Version)
+	}
+
+	// Running again is a no-op
+	applied, err = db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+	s.Zero(applied)
+}
+
+func (s *MigrationTestSuite) TestMigrateDownAndUp() {
+	db := s.open()
+	_, err := db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+
+	reverted, err := db.MigrateDown(s.ctx, len(migrations))
+	s.Require().NoError(err)
+	s.Equal(len(migrations), reverted)
+
+	exists, err := db.tableExists(s.ctx, "expenses")
+	s.Require().NoError(err)
+	s.False(exists, "expenses table should be dropped")
+
+	applied, err := db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(len(migrations), applied)
+}
+
+func (s *MigrationTestSuite) TestMigrateUp_AdoptsLegacySchema() {
+	db := s.open()
+
+	// Schema as created by the old unversioned migrate(), before user_id and last_activity existed
+	_, err := db.conn.Exec(`
+		CREATE TABLE expenses (id INTEGER PRIMARY KEY AUTOINCREMENT, amount REAL NOT NULL, description TEXT NOT NULL, category TEXT NOT NULL, date DATETIME NOT NULL);
+		CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT UNIQUE NOT NULL, password_hash TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE sessions (token TEXT PRIMARY KEY, user_id INTEGER NOT NULL, expires_at DATETIME NOT NULL);
+		INSERT INTO users (username, password_hash) VALUES ('legacy', 'hash');
+		INSERT INTO sessions (token, user_id, expires_at) VALUES ('tok', 1, '2099-01-01 00:00:00');
+	`)
+	s.Require().NoError(err)
+
+	_, err = db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+
+	statuses, err := db.MigrationStatus(s.ctx)
+	s.Require().NoError(err)
+	s.True(statuses[0].Applied, "baseline should be recorded for legacy databases")
+
+	// Legacy sessions keep working now that last_activity exists
+	info, err := db.ValidateSessionWithInfo(s.ctx, "tok")
+	s.Require().NoError(err)
+	s.Equal("legacy", info.User.Username)
+	s.False(info.LastActivity.IsZero())
+
+	lunch := models.Expense{Amount: 1250, Description: "Lunch", Category: "Eating Out", Date: time.Now()}
+	s.NoError(db.CreateExpense(s.ctx, 1, &lunch))
+	s.ErrorIs(db.CreateExpense(s.ctx, 1, &lunch), ErrDuplicateExpense, "the household's unique index replaced the legacy one")
+}
+
+func (s *MigrationTestSuite) TestMigrateUp_ConvertsAmountsToCents() {
+	db := s.open()
+	s.Require().NoError(db.ensureMigrationsTable(s.ctx))
+	s.Require().NoError(db.applyMigration(s.ctx, migrations[0]))
+
+	// Float values that do not round-trip exactly through binary floating point
+	_, err := db.conn.Exec(`INSERT INTO users (username, password_hash) VALUES ('legacy', 'hash');
+		INSERT INTO expenses (amount, description, category, date) VALUES
+		(0.1, 'a', 'Other', '2026-01-01 10:00:00'),
+		(0.2, 'b', 'Other', '2026-01-01 11:00:00'),
+		(19.99, 'c', 'Other', '2026-01-01 12:00:00')`)
+	s.Require().NoError(err)
+
+	_, err = db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+
+	// Pre-existing users and expenses end up in one shared household
+	total, err := db.GetTotalForPeriod(s.ctx, UserScope(1), 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(2029), total.Spending)
+}
+
+func (s *MigrationTestSuite) TestMigrateUp_ConvertsIncomeMarker() {
+	db := s.open()
+	s.Require().NoError(db.ensureMigrationsTable(s.ctx))
+	for _, m := range migrations[:3] {
+		s.Require().NoError(db.applyMigration(s.ctx, m))
+	}
+
+	_, err := db.conn.Exec(`INSERT INTO households (name) VALUES ('Home');
+		INSERT INTO users (username, password_hash) VALUES ('legacy', 'hash');
+		INSERT INTO household_members (household_id, user_id) VALUES (1, 1);
+		INSERT INTO expenses (amount, description, category, date, household_id) VALUES
+		(300000, '[Income] Salary', 'Other', '2026-01-01 10:00:00', 1),
+		(5000, '[Income]', 'Gifts', '2026-01-02 10:00:00', 1),
+		(1999, 'Lunch', 'Eating Out', '2026-01-03 10:00:00', 1)`)
+	s.Require().NoError(err)
+
+	_, err = db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+
+	expenses, err := db.ListExpenses(s.ctx, UserScope(1), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 3)
+	s.Equal(models.KindExpense, expenses[0].Kind)
+	s.Equal(models.KindIncome, expenses[1].Kind)
+	s.Equal("Gifts", expenses[1].Description, "empty description falls back to the category")
+	s.Equal(models.KindIncome, expenses[2].Kind)
+	s.Equal("Salary", expenses[2].Description)
+
+	total, err := db.GetTotalForPeriod(s.ctx, UserScope(1), 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(305000), total.Income)
+	s.Equal(models.Money(1999), total.Spending)
+
+	// Reverting to version 3 restores the marker
+	_, err = db.MigrateDown(s.ctx, len(migrations)-3)
+	s.Require().NoError(err)
+	var description string
+	s.Require().NoError(db.conn.QueryRow("SELECT description FROM expenses WHERE amount = 300000").Scan(&description))
+	s.Equal("[Income] Salary", description)
+}
+
+func (s *MigrationTestSuite) TestMigrateUp_CopiesExchangeRatesToHouseholds() {
+	db := s.open()
+	s.Require().NoError(db.ensureMigrationsTable(s.ctx))
+	for _, m := range migrations {
+		if m.Version < 19 {
+			s.Require().NoError(db.applyMigration(s.ctx, m))
+		}
+	}
+
+	_, err := db.conn.Exec(`INSERT INTO households (name) VALUES ('Home'), ('Flat');
+		INSERT INTO exchange_rates (base, currency, date, rate) VALUES ('EUR', 'USD', '2026-01-01 00:00:00', 1.25)`)
+	s.Require().NoError(err)
+
+	_, err = db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+
+	for _, householdID := range []int64{1, 2} {
+		rates, err := db.ListExchangeRates(s.ctx, householdID, 10)
+		s.Require().NoError(err)
+		s.Require().Len(rates, 1, "household %d keeps the shared rate", householdID)
+		s.InDelta(1.25, rates[0].Rate, 1e-9)
+	}
+}
+
+func (s *MigrationTestSuite) TestMigrateUp_ChecksumMismatch() {
+	db := s.open()
+	_, err := db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+
+	_, err = db.conn.Exec("UPDATE schema_migrations SET checksum = 'tampered' WHERE version = 1")
+	s.Require().NoError(err)
+
+	_, err = db.MigrateUp(s.ctx)
+	s.Require().ErrorIs(err, ErrChecksumMismatch)
+
+	statuses, err := db.MigrationStatus(s.ctx)
+	s.Require().NoError(err)
+	s.False(statuses[0].ChecksumOK)
+}
+
+func (s *MigrationTestSuite) TestMigrateUp_StopsOnFirstError() {
+	original := migrations
+	s.T().Cleanup(func() { migrations = original })
+
+	migrations = append(append([]migration{}, original...),
+		migration{Version: 1000, Name: "broken", Up: `CREATE TABLE broken (id INTEGER); INSERT INTO missing VALUES (1);`},
+		migration{Version: 1001, Name: "after broken", Up: `CREATE TABLE after_broken (id INTEGER);`},
+	)
+
+	db := s.open()
+	applied, err := db.MigrateUp(s.ctx)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "migration 1000")
+	s.Equal(len(original), applied)
+
+	// The failing migration was rolled back as a whole and nothing after it ran
+	for _, table := range []string{"broken", "after_broken"} {
+		exists, err := db.tableExists(s.ctx, table)
+		s.Require().NoError(err)
+		s.False(exists, "table %s should not exist", table)
+	}
+}
+
+func (s *MigrationTestSuite) TestMigrateUp_RebuildKeepsReferencingRows() {
+	original := migrations
+	s.T().Cleanup(func() { migrations = original })
+
+	db := s.open()
+	_, err := db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+
+	household, err := db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	categories, err := db.ListCategories(s.ctx, household.ID, false)
+	s.Require().NoError(err)
+	s.Require().NotEmpty(categories)
+
+	// Rebuilding households drops the table categories reference with
+	// ON DELETE CASCADE
+	migrations = append(append([]migration{}, original...), migration{Version: 1000, Name: "rebuild households", Up: `
+		CREATE TABLE households_new (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		INSERT INTO households_new SELECT id, name, created_at FROM households;
+		DROP TABLE households;
+		ALTER TABLE households_new RENAME TO households;
+	`})
+	applied, err := db.MigrateUp(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(1, applied)
+
+	after, err := db.ListCategories(s.ctx, household.ID, false)
+	s.Require().NoError(err)
+	s.Len(after, len(categories))
+
+	var enabled bool
+	s.Require().NoError(db.conn.QueryRow("PRAGMA foreign_keys").Scan(&enabled))
+	s.True(enabled, "foreign keys should be enforced again")
+}
+
+// TestMigrationSuite runs the migration test suite
+func TestMigrationSuite(t *testing.T) {
+	suite.Run(t, new(MigrationTestSuite))
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// pgUniqueViolation is the SQLSTATE of a unique constraint violation.
+const pgUniqueViolation = "23505"
+
+// isPostgres reports whether path is a PostgreSQL connection URL rather than
+// the path of a SQLite database.
+func isPostgres(path string) bool {
+	return strings.HasPrefix(path, "postgres://") || strings.HasPrefix(path, "postgresql://")
+}
+
+// openPostgres connects to the PostgreSQL database of dsn. Writes and reads
+// share one pool, since PostgreSQL handles concurrent writers itself; the
+// SQLite pragmas of opts do not apply.
+func openPostgres(dsn string, opts Options) (*DB, error) {
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	// Dates are extracted from timestamps in the session's time zone, which
+	// must match the UTC boundaries statistics use
+	if _, ok := config.RuntimeParams["timezone"]; !ok {
+		config.RuntimeParams["timezone"] = "UTC"
+	}
+
+	conn := sql.OpenDB(pgConnector{stdlib.GetConnector(*config)})
+	conn.SetMaxOpenConns(opts.ReadConns + 1)
+	conn.SetMaxIdleConns(opts.ReadConns + 1)
+
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &DB{conn: conn, read: conn, dialect: postgresDialect, timeout: opts.QueryTimeout}, nil
+}
+
+// pgConnector hands out connections that accept the ? placeholders the
+// queries of this package are written with.
+type pgConnector struct {
+	driver.Connector
+}
+
+func (c pgConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pgConn{conn.(*stdlib.Conn)}, nil
+}
+
+// pgConn is a pgx connection that rewrites placeholders before a query is
+// prepared or run. All other driver interfaces are those of stdlib.Conn.
+type pgConn struct {
+	*stdlib.Conn
+}
+
+func (c *pgConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebind(query))
+}
+
+func (c *pgConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.PrepareContext(ctx, rebind(query))
+}
+
+func (c *pgConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.ExecContext(ctx, rebind(query), args)
+}
+
+func (c *pgConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.QueryContext(ctx, rebind(query), args)
+}
+
+// rebind replaces the ? placeholders of query with PostgreSQL's numbered $1,
+// $2 and so on. Question marks in quoted strings and identifiers are kept.
+func rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+const recurringColumns = "id, household_id, user_id, kind, amount, description, category, frequency, interval, day, start_date, end_date, next_date"
+
+func (db *DB) queryRecurring(ctx context.Context, query string, args ...any) ([]models.Recurring, error) {
+	rows, err := db.read.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []models.Recurring
+	for rows.Next() {
+		var r models.Recurring
+		if err := rows.Scan(
+			&r.ID, &r.HouseholdID, &r.UserID, &r.Kind, &r.Amount, &r.Description, &r.Category,
+			&r.Frequency, &r.Interval, &r.Day, &r.StartDate, &r.EndDate, &r.NextDate,
+		); err != nil {
+			return nil, err
+		}
+		templates = append(templates, r)
+	}
+
+	return templates, rows.Err()
+}
+
+// CreateRecurring stores a recurring template in the user's default household.
+// Its first occurrence is the next one to be created. On success the ID,
+// UserID, HouseholdID and NextDate fields of r are filled in.
+func (db *DB) CreateRecurring(ctx context.Context, userID int64, r *models.Recurring) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if r.Kind == "" {
+		r.Kind = models.KindExpense
+	}
+	if r.Interval < 1 {
+		r.Interval = 1
+	}
+	householdID, err := db.DefaultHouseholdID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	r.UserID = userID
+	r.HouseholdID = householdID
+	r.NextDate = r.First()
+
+	return db.conn.QueryRowContext(ctx,
+		`INSERT INTO recurring (household_id, user_id, kind, amount, description, category, frequency, interval, day, start_date, end_date, next_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		r.HouseholdID, r.UserID, r.Kind, r.Amount, r.Description, r.Category,
+		r.Frequency, r.Interval, r.Day, r.StartDate, r.EndDate, r.NextDate,
+	).Scan(&r.ID)
+}
+
+// ListRecurring returns the recurring templates of a household by next occurrence.
+func (db *DB) ListRecurring(ctx context.Context, householdID int64) ([]models.Recurring, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.queryRecurring(ctx,
+		"SELECT "+recurringColumns+" FROM recurring WHERE household_id = ? ORDER BY next_date, id",
+		householdID,
+	)
+}
+
+// DeleteRecurring removes a recurring template of a household. Expenses
+// already created from it are kept.
+func (db *DB) DeleteRecurring(ctx context.Context, householdID, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.conn.ExecContext(ctx, "DELETE FROM recurring WHERE id = ? AND household_id = ?", id, householdID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// DueRecurring returns the templates of all households with an occurrence at
+// or before now that has not been created yet. Templates past their end date
+// may be included; callers check models.Recurring.Ended and delete them.
+func (db *DB) DueRecurring(ctx context.Context, now time.Time) ([]models.Recurring, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.queryRecurring(ctx,
+		"SELECT "+recurringColumns+" FROM recurring WHERE next_date <= ? ORDER BY next_date, id",
+		now,
+	)
+}
+
+// SetRecurringNext records the next occurrence of a template to be created.
+func (db *DB) SetRecurringNext(ctx context.Context, id int64, next time.Time) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.conn.ExecContext(ctx, "UPDATE recurring SET next_date = ? WHERE id = ?", next, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
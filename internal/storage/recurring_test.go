@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// RecurringTestSuite provides a test suite for recurring templates
+type RecurringTestSuite struct {
+	suite.Suite
+	dsn       string // Database under test, see runOnBackends
+	ctx       context.Context
+	db        *DB
+	user      *models.User
+	household *models.Household
+}
+
+// SetupTest runs before each test
+func (s *RecurringTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "testuser", "hash")
+	s.Require().NoError(err)
+	s.household, err = s.db.CreateHousehold(s.ctx, "Test")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+}
+
+// TearDownTest runs after each test
+func (s *RecurringTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *RecurringTestSuite) TestCreateAndListRecurring() {
+	r := &models.Recurring{
+		Amount: 95000, Description: "Rent", Category: "Housing",
+		Frequency: models.FrequencyMonthly, Day: 1,
+		StartDate: time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC),
+	}
+	s.Require().NoError(s.db.CreateRecurring(s.ctx, s.user.ID, r))
+	s.NotZero(r.ID)
+	s.Equal(s.household.ID, r.HouseholdID)
+	s.Equal(models.KindExpense, r.Kind)
+	s.Equal(time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC), r.NextDate)
+
+	templates, err := s.db.ListRecurring(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Require().Len(templates, 1)
+	s.Equal("Rent", templates[0].Description)
+	s.True(templates[0].NextDate.Equal(r.NextDate))
+	s.Nil(templates[0].EndDate)
+
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	templates, err = s.db.ListRecurring(s.ctx, other.ID)
+	s.Require().NoError(err)
+	s.Empty(templates)
+}
+
+func (s *RecurringTestSuite) TestDueRecurring() {
+	r := &models.Recurring{
+		Amount: 1299, Description: "Streaming", Category: "Entertainment",
+		Frequency: models.FrequencyMonthly, StartDate: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC),
+	}
+	s.Require().NoError(s.db.CreateRecurring(s.ctx, s.user.ID, r))
+
+	due, err := s.db.DueRecurring(s.ctx, time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC))
+	s.Require().NoError(err)
+	s.Empty(due)
+
+	due, err = s.db.DueRecurring(s.ctx, time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	s.Require().NoError(err)
+	s.Len(due, 1)
+
+	s.Require().NoError(s.db.SetRecurringNext(s.ctx, r.ID, r.After(r.NextDate)))
+	due, err = s.db.DueRecurring(s.ctx, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	s.Require().NoError(err)
+	s.Empty(due)
+}
+
+func (s *RecurringTestSuite) TestOccurrenceIsStoredOnce() {
+	r := &models.Recurring{
+		Amount: 1299, Description: "Streaming", Category: "Entertainment",
+		Frequency: models.FrequencyMonthly, StartDate: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC),
+	}
+	s.Require().NoError(s.db.CreateRecurring(s.ctx, s.user.ID, r))
+
+	e := r.Expense(r.NextDate)
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &e))
+	s.Require().NotNil(e.RecurringID)
+
+	again := r.Expense(r.NextDate)
+	again.Description = "Streaming (edited template)"
+	s.ErrorIs(s.db.CreateExpense(s.ctx, s.user.ID, &again), ErrDuplicateExpense)
+
+	// Deleting the template keeps its expenses
+	s.Require().NoError(s.db.DeleteRecurring(s.ctx, s.household.ID, r.ID))
+	s.ErrorIs(s.db.DeleteRecurring(s.ctx, s.household.ID, r.ID), ErrNotFound)
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal(r.ID, *got.RecurringID)
+}
+
+// TestRecurringSuite runs the recurring test suite
+func TestRecurringSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &RecurringTestSuite{dsn: dsn})
+	})
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"time"
 
 	"expense-tracker/internal/models"
@@ -14,9 +15,12 @@ type SessionInfo struct {
 }
 
 // CreateSession creates a new session for a user.
-func (db *DB) CreateSession(token string, userID int64, expiresAt time.Time) error {
+func (db *DB) CreateSession(ctx context.Context, token string, userID int64, expiresAt time.Time) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	now := time.Now()
-	_, err := db.conn.Exec(
+	_, err := db.conn.ExecContext(ctx,
 		"INSERT INTO sessions (token, user_id, expires_at, last_activity) VALUES (?, ?, ?, ?)",
 		token, userID, expiresAt, now,
 	)
@@ -24,8 +28,11 @@ func (db *DB) CreateSession(token string, userID int64, expiresAt time.Time) err
 }
 
 // ValidateSession checks if a session token is valid and returns the associated user.
-func (db *DB) ValidateSession(token string) (*models.User, error) {
-	info, err := db.ValidateSessionWithInfo(token)
+func (db *DB) ValidateSession(ctx context.Context, token string) (*models.User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	info, err := db.ValidateSessionWithInfo(ctx, token)
 	if err != nil {
 		return nil, err
 	}
@@ -33,8 +40,11 @@ func (db *DB) ValidateSession(token string) (*models.User, error) {
 }
 
 // ValidateSessionWithInfo checks if a session token is valid and returns session details.
-func (db *DB) ValidateSessionWithInfo(token string) (*SessionInfo, error) {
-	row := db.conn.QueryRow(`
+func (db *DB) ValidateSessionWithInfo(ctx context.Context, token string) (*SessionInfo, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	row := db.read.QueryRowContext(ctx, `
 		SELECT u.id, u.username, u.password_hash, u.created_at, s.last_activity, s.expires_at
 		FROM sessions s
 		JOIN users u ON s.user_id = u.id
@@ -54,9 +64,12 @@ func (db *DB) ValidateSessionWithInfo(token string) (*SessionInfo, error) {
 }
 
 // RenewSession updates the last_activity and expires_at for a session.
-func (db *DB) RenewSession(token string, newExpiresAt time.Time) error {
+func (db *DB) RenewSession(ctx context.Context, token string, newExpiresAt time.Time) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	now := time.Now()
-	_, err := db.conn.Exec(
+	_, err := db.conn.ExecContext(ctx,
 		"UPDATE sessions SET last_activity = ?, expires_at = ? WHERE token = ?",
 		now, newExpiresAt, token,
 	)
@@ -64,13 +77,19 @@ func (db *DB) RenewSession(token string, newExpiresAt time.Time) error {
 }
 
 // DeleteSession removes a session by token.
-func (db *DB) DeleteSession(token string) error {
-	_, err := db.conn.Exec("DELETE FROM sessions WHERE token = ?", token)
+func (db *DB) DeleteSession(ctx context.Context, token string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.conn.ExecContext(ctx, "DELETE FROM sessions WHERE token = ?", token)
 	return err
 }
 
 // CleanExpiredSessions removes all expired sessions.
-func (db *DB) CleanExpiredSessions() error {
-	_, err := db.conn.Exec("DELETE FROM sessions WHERE expires_at <= CURRENT_TIMESTAMP")
+func (db *DB) CleanExpiredSessions(ctx context.Context) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.conn.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= CURRENT_TIMESTAMP")
 	return err
 }
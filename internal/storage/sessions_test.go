@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -13,13 +14,16 @@ import (
 // SessionTestSuite provides a test suite for session operations
 type SessionTestSuite struct {
 	suite.Suite
+	dsn  string // Database under test, see runOnBackends
+	ctx  context.Context
 	db   *DB
 	user *models.User
 }
 
 // SetupTest runs before each test
 func (s *SessionTestSuite) SetupTest() {
-	db, err := NewDB(":memory:")
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
 	s.Require().NoError(err, "failed to create test database")
 	s.db = db
 
@@ -27,7 +31,7 @@ func (s *SessionTestSuite) SetupTest() {
 	password, err := auth.HashPassword("testpass")
 	s.Require().NoError(err, "failed to hash password")
 
-	user, err := s.db.CreateUser("testuser", password)
+	user, err := s.db.CreateUser(s.ctx, "testuser", password)
 	s.Require().NoError(err, "failed to create test user")
 	s.user = user
 }
@@ -44,11 +48,11 @@ func (s *SessionTestSuite) TestCreateAndValidateSession() {
 	s.Require().NoError(err)
 
 	expiresAt := time.Now().Add(30 * 24 * time.Hour)
-	err = s.db.CreateSession(token, s.user.ID, expiresAt)
+	err = s.db.CreateSession(s.ctx, token, s.user.ID, expiresAt)
 	s.Require().NoError(err)
 
 	// Validate the session
-	sessionUser, err := s.db.ValidateSession(token)
+	sessionUser, err := s.db.ValidateSession(s.ctx, token)
 	s.Require().NoError(err)
 	s.Equal("testuser", sessionUser.Username)
 }
@@ -58,11 +62,11 @@ func (s *SessionTestSuite) TestValidateSessionWithInfo() {
 	s.Require().NoError(err)
 
 	expiresAt := time.Now().Add(30 * 24 * time.Hour)
-	err = s.db.CreateSession(token, s.user.ID, expiresAt)
+	err = s.db.CreateSession(s.ctx, token, s.user.ID, expiresAt)
 	s.Require().NoError(err)
 
 	// Get session info
-	info, err := s.db.ValidateSessionWithInfo(token)
+	info, err := s.db.ValidateSessionWithInfo(s.ctx, token)
 	s.Require().NoError(err)
 	s.Equal("testuser", info.User.Username)
 
@@ -76,23 +80,23 @@ func (s *SessionTestSuite) TestRenewSession() {
 	s.Require().NoError(err)
 
 	originalExpiry := time.Now().Add(30 * 24 * time.Hour)
-	err = s.db.CreateSession(token, s.user.ID, originalExpiry)
+	err = s.db.CreateSession(s.ctx, token, s.user.ID, originalExpiry)
 	s.Require().NoError(err)
 
 	// Wait a moment to ensure timestamps differ
 	time.Sleep(10 * time.Millisecond)
 
 	// Get original session info
-	originalInfo, err := s.db.ValidateSessionWithInfo(token)
+	originalInfo, err := s.db.ValidateSessionWithInfo(s.ctx, token)
 	s.Require().NoError(err)
 
 	// Renew the session
 	newExpiry := time.Now().Add(60 * 24 * time.Hour)
-	err = s.db.RenewSession(token, newExpiry)
+	err = s.db.RenewSession(s.ctx, token, newExpiry)
 	s.Require().NoError(err)
 
 	// Get updated session info
-	updatedInfo, err := s.db.ValidateSessionWithInfo(token)
+	updatedInfo, err := s.db.ValidateSessionWithInfo(s.ctx, token)
 	s.Require().NoError(err)
 
 	// Verify last_activity was updated
@@ -109,23 +113,25 @@ func (s *SessionTestSuite) TestDeleteSession() {
 	s.Require().NoError(err)
 
 	expiresAt := time.Now().Add(30 * 24 * time.Hour)
-	err = s.db.CreateSession(token, s.user.ID, expiresAt)
+	err = s.db.CreateSession(s.ctx, token, s.user.ID, expiresAt)
 	s.Require().NoError(err)
 
 	// Verify session exists
-	_, err = s.db.ValidateSession(token)
+	_, err = s.db.ValidateSession(s.ctx, token)
 	s.Require().NoError(err, "session should exist before deletion")
 
 	// Delete session
-	err = s.db.DeleteSession(token)
+	err = s.db.DeleteSession(s.ctx, token)
 	s.Require().NoError(err)
 
 	// Verify session is gone
-	_, err = s.db.ValidateSession(token)
+	_, err = s.db.ValidateSession(s.ctx, token)
 	s.Error(err, "expected error after deleting session")
 }
 
 // Test suite runner
 func TestSessionSuite(t *testing.T) {
-	suite.Run(t, new(SessionTestSuite))
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &SessionTestSuite{dsn: dsn})
+	})
 }
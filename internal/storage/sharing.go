@@ -0,0 +1,417 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrInvalidShare is returned when income is shared, when a share names a
+// user outside the expense's household or names one twice, or when the
+// percentages or amounts of the shares do not add up.
+var ErrInvalidShare = errors.New("invalid sharing")
+
+// ErrInvalidSettlement is returned when a settlement is not a positive
+// payment between two different members of the household.
+var ErrInvalidSettlement = errors.New("invalid settlement")
+
+// householdMembers returns the IDs of the members of a household.
+func householdMembers(ctx context.Context, q querier, householdID int64) (map[int64]bool, error) {
+	rows, err := q.QueryContext(ctx, "SELECT user_id FROM household_members WHERE household_id = ?", householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		members[id] = true
+	}
+	return members, rows.Err()
+}
+
+// prepareSharing validates the sharing of an expense recorded in a household
+// and clears the share fields its method does not use. Sharing without shares
+// is no sharing.
+func prepareSharing(ctx context.Context, q querier, householdID int64, e *models.Expense) error {
+	if e.Sharing == nil || len(e.Sharing.Shares) == 0 {
+		e.Sharing = nil
+		return nil
+	}
+	if e.Kind == models.KindIncome {
+		return fmt.Errorf("%w: income cannot be shared", ErrInvalidShare)
+	}
+	method, err := models.ParseShareMethod(string(e.Sharing.Method))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidShare, err)
+	}
+	members, err := householdMembers(ctx, q, householdID)
+	if err != nil {
+		return err
+	}
+
+	e.Sharing.Method = method
+	seen := make(map[int64]bool, len(e.Sharing.Shares))
+	var sum int64
+	for i, s := range e.Sharing.Shares {
+		switch {
+		case !members[s.UserID]:
+			return fmt.Errorf("%w: user %d is not a member of the household", ErrInvalidShare, s.UserID)
+		case seen[s.UserID]:
+			return fmt.Errorf("%w: user %d appears twice", ErrInvalidShare, s.UserID)
+		case s.Weight(method) <= 0:
+			return fmt.Errorf("%w: shares must be positive", ErrInvalidShare)
+		}
+		seen[s.UserID] = true
+		sum += s.Weight(method)
+		e.Sharing.Shares[i] = shareOf(method, s.UserID, s.Weight(method))
+	}
+
+	switch {
+	case method == models.SharePercent && sum != 10000:
+		return fmt.Errorf("%w: percentages add up to %g, not 100", ErrInvalidShare, float64(sum)/100)
+	case method == models.ShareExact && sum != int64(e.OriginalAmount):
+		return fmt.Errorf("%w: shares add up to %s, not %s", ErrInvalidShare, models.Money(sum), e.OriginalAmount)
+	}
+	return nil
+}
+
+// shareOf returns the share of a user with the given weight, the inverse of
+// models.Share.Weight.
+func shareOf(method models.ShareMethod, userID, weight int64) models.Share {
+	share := models.Share{UserID: userID}
+	switch method {
+	case models.SharePercent:
+		share.Percent = float64(weight) / 100
+	case models.ShareExact:
+		share.Amount = models.Money(weight)
+	}
+	return share
+}
+
+// saveSharing replaces the shares of an expense.
+func saveSharing(ctx context.Context, tx *sql.Tx, expenseID int64, sharing *models.Sharing) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM expense_shares WHERE expense_id = ?", expenseID); err != nil {
+		return err
+	}
+	if sharing == nil {
+		return nil
+	}
+	for _, s := range sharing.Shares {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO expense_shares (expense_id, user_id, method, weight) VALUES (?, ?, ?, ?)",
+			expenseID, s.UserID, sharing.Method, s.Weight(sharing.Method),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSharing fills in the sharing of the shared expenses among expenses.
+func loadSharing(ctx context.Context, q querier, expenses []models.Expense) error {
+	return loadByExpense(ctx, q, expenses,
+		"SELECT expense_id, user_id, method, weight FROM expense_shares WHERE expense_id IN (%s) ORDER BY expense_id, user_id",
+		func(rows *sql.Rows, byID map[int64]*models.Expense) error {
+			var id, userID, weight int64
+			var method models.ShareMethod
+			if err := rows.Scan(&id, &userID, &method, &weight); err != nil {
+				return err
+			}
+			e := byID[id]
+			if e.Sharing == nil {
+				e.Sharing = &models.Sharing{Method: method}
+			}
+			e.Sharing.Shares = append(e.Sharing.Shares, shareOf(method, userID, weight))
+			return nil
+		})
+}
+
+// MemberBalance is what a member of a household is owed by the others, in
+// the household's base currency. It is negative when the member owes them.
+type MemberBalance struct {
+	UserID   int64
+	Username string
+	Balance  models.Money
+}
+
+// SettlementRow is a settlement together with the names of the members.
+type SettlementRow struct {
+	models.Settlement
+	FromUsername string
+	ToUsername   string
+}
+
+// GetBalances returns the balances of the members of a household from its
+// shared expenses and settlements, ordered by username. The payer of a
+// shared expense is owed it and every member sharing it owes their part;
+// refunds work the other way round. Former members are included while their
+// balance is not zero. The balances add up to zero.
+func (db *DB) GetBalances(ctx context.Context, householdID int64) ([]MemberBalance, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	members, err := db.ListHouseholdMembers(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[int64]*MemberBalance)
+	add := func(userID int64, username string, amount models.Money) {
+		b, ok := balances[userID]
+		if !ok {
+			b = &MemberBalance{UserID: userID, Username: username}
+			balances[userID] = b
+		}
+		b.Balance += amount
+	}
+	isMember := make(map[int64]bool, len(members))
+	for _, m := range members {
+		add(m.ID, m.Username, 0)
+		isMember[m.ID] = true
+	}
+
+	if err := db.addShares(ctx, householdID, add); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.read.QueryContext(ctx, `
+		SELECT s.from_user_id, f.username, s.to_user_id, t.username, s.amount
+		FROM settlements s
+		JOIN users f ON f.id = s.from_user_id
+		JOIN users t ON t.id = s.to_user_id
+		WHERE s.household_id = ?`,
+		householdID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s SettlementRow
+		if err := rows.Scan(&s.FromUserID, &s.FromUsername, &s.ToUserID, &s.ToUsername, &s.Amount); err != nil {
+			return nil, err
+		}
+		add(s.FromUserID, s.FromUsername, s.Amount)
+		add(s.ToUserID, s.ToUsername, -s.Amount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]MemberBalance, 0, len(balances))
+	for _, b := range balances {
+		if isMember[b.UserID] || b.Balance != 0 {
+			result = append(result, *b)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Username < result[j].Username })
+	return result, nil
+}
+
+// addShares adds the shared expenses of a household to balances. Expenses
+// whose payer was deleted are left out.
+func (db *DB) addShares(ctx context.Context, householdID int64, add func(userID int64, username string, amount models.Money)) error {
+	rows, err := db.read.QueryContext(ctx, `
+		SELECT e.id, e.kind, e.amount, e.user_id, p.username, s.user_id, u.username, s.weight
+		FROM expenses e
+		JOIN expense_shares s ON s.expense_id = e.id
+		JOIN users p ON p.id = e.user_id
+		JOIN users u ON u.id = s.user_id
+		WHERE e.household_id = ? AND e.kind != 'income'
+		ORDER BY e.id, s.user_id`,
+		householdID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// The rows of an expense are collected until the next one starts
+	var current struct {
+		id        int64
+		spending  models.Money
+		payer     int64
+		payerName string
+		users     []int64
+		names     []string
+		weights   []int64
+	}
+	flush := func() {
+		if current.id == 0 {
+			return
+		}
+		add(current.payer, current.payerName, current.spending)
+		sign := models.Money(1)
+		if current.spending < 0 {
+			sign = -1
+		}
+		for i, part := range models.Allocate(current.spending*sign, current.weights) {
+			add(current.users[i], current.names[i], -part*sign)
+		}
+	}
+
+	for rows.Next() {
+		var e models.Expense
+		var payerName, username string
+		var userID, weight int64
+		if err := rows.Scan(&e.ID, &e.Kind, &e.Amount, &e.UserID, &payerName, &userID, &username, &weight); err != nil {
+			return err
+		}
+		if e.ID != current.id {
+			flush()
+			current.id, current.spending, current.payer, current.payerName = e.ID, e.Spending(), *e.UserID, payerName
+			current.users, current.names, current.weights = nil, nil, nil
+		}
+		current.users = append(current.users, userID)
+		current.names = append(current.names, username)
+		current.weights = append(current.weights, weight)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+// SettleUp returns payments that zero the balances. A debt equal to a credit
+// is paid off directly; the remaining debtors pay the largest creditors
+// first. That takes at most one payment fewer than there are members with a
+// balance.
+func SettleUp(balances []MemberBalance) []SettlementRow {
+	var debtors, creditors []MemberBalance
+	for _, b := range balances {
+		switch {
+		case b.Balance < 0:
+			b.Balance = -b.Balance
+			debtors = append(debtors, b)
+		case b.Balance > 0:
+			creditors = append(creditors, b)
+		}
+	}
+
+	var plan []SettlementRow
+	pay := func(from, to *MemberBalance, amount models.Money) {
+		plan = append(plan, SettlementRow{
+			Settlement:   models.Settlement{FromUserID: from.UserID, ToUserID: to.UserID, Amount: amount},
+			FromUsername: from.Username,
+			ToUsername:   to.Username,
+		})
+		from.Balance -= amount
+		to.Balance -= amount
+	}
+	for i := range debtors {
+		for j := range creditors {
+			if creditors[j].Balance == debtors[i].Balance {
+				pay(&debtors[i], &creditors[j], debtors[i].Balance)
+				break
+			}
+		}
+	}
+
+	byBalance := func(s []MemberBalance) {
+		sort.SliceStable(s, func(i, j int) bool { return s[i].Balance > s[j].Balance })
+	}
+	byBalance(debtors)
+	byBalance(creditors)
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		switch {
+		case debtors[i].Balance == 0:
+			i++
+		case creditors[j].Balance == 0:
+			j++
+		default:
+			pay(&debtors[i], &creditors[j], min(debtors[i].Balance, creditors[j].Balance))
+		}
+	}
+	return plan
+}
+
+// RecordSettlements records payments between members of a household in one
+// transaction. A zero Date is stored as now. On success the ID and
+// HouseholdID of each settlement are set. It returns ErrInvalidSettlement if
+// a payment is not positive or not between two different members.
+func (db *DB) RecordSettlements(ctx context.Context, householdID int64, settlements []models.Settlement) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	members, err := householdMembers(ctx, db.read, householdID)
+	if err != nil {
+		return err
+	}
+	for i := range settlements {
+		s := &settlements[i]
+		if s.Amount <= 0 || s.FromUserID == s.ToUserID || !members[s.FromUserID] || !members[s.ToUserID] {
+			return ErrInvalidSettlement
+		}
+		if s.Date.IsZero() {
+			s.Date = time.Now()
+		}
+	}
+
+	return db.inTx(ctx, func(tx *sql.Tx) error {
+		for i := range settlements {
+			s := &settlements[i]
+			if err := tx.QueryRowContext(ctx,
+				"INSERT INTO settlements (household_id, from_user_id, to_user_id, amount, date) VALUES (?, ?, ?, ?, ?) RETURNING id",
+				householdID, s.FromUserID, s.ToUserID, s.Amount, s.Date,
+			).Scan(&s.ID); err != nil {
+				return err
+			}
+			s.HouseholdID = householdID
+		}
+		return nil
+	})
+}
+
+// ListSettlements returns the latest settlements of a household, newest first.
+func (db *DB) ListSettlements(ctx context.Context, householdID int64, limit int) ([]SettlementRow, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.read.QueryContext(ctx, `
+		SELECT s.id, s.household_id, s.from_user_id, f.username, s.to_user_id, t.username, s.amount, s.date
+		FROM settlements s
+		JOIN users f ON f.id = s.from_user_id
+		JOIN users t ON t.id = s.to_user_id
+		WHERE s.household_id = ?
+		ORDER BY s.date DESC, s.id DESC
+		LIMIT ?`,
+		householdID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settlements []SettlementRow
+	for rows.Next() {
+		var s SettlementRow
+		if err := rows.Scan(&s.ID, &s.HouseholdID, &s.FromUserID, &s.FromUsername, &s.ToUserID, &s.ToUsername, &s.Amount, &s.Date); err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, s)
+	}
+	return settlements, rows.Err()
+}
+
+// DeleteSettlement removes a settlement of a household.
+// It returns ErrNotFound if the settlement does not exist or belongs to another household.
+func (db *DB) DeleteSettlement(ctx context.Context, householdID, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.conn.ExecContext(ctx, "DELETE FROM settlements WHERE id = ? AND household_id = ?", id, householdID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// SharingTestSuite provides a test suite for shared expenses and settlements
+type SharingTestSuite struct {
+	suite.Suite
+	dsn       string // Database under test, see runOnBackends
+	ctx       context.Context
+	db        *DB
+	alice     *models.User
+	bob       *models.User
+	carol     *models.User
+	household *models.Household
+}
+
+// SetupTest runs before each test
+func (s *SharingTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.household, err = s.db.CreateHousehold(s.ctx, "Flat")
+	s.Require().NoError(err)
+	s.alice = s.member("alice")
+	s.bob = s.member("bob")
+	s.carol = s.member("carol")
+}
+
+// TearDownTest runs after each test
+func (s *SharingTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *SharingTestSuite) member(username string) *models.User {
+	user, err := s.db.CreateUser(s.ctx, username, "hash")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, user.ID))
+	return user
+}
+
+// share records an expense paid by payer and shared between users.
+func (s *SharingTestSuite) share(payer *models.User, amount models.Money, sharing models.Sharing) *models.Expense {
+	e := &models.Expense{
+		Amount:      amount,
+		Description: "Paid by " + payer.Username,
+		Date:        time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC),
+		Sharing:     &sharing,
+	}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, payer.ID, e))
+	return e
+}
+
+func (s *SharingTestSuite) balances() map[string]models.Money {
+	balances, err := s.db.GetBalances(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	byName := make(map[string]models.Money, len(balances))
+	var sum models.Money
+	for _, b := range balances {
+		byName[b.Username] = b.Balance
+		sum += b.Balance
+	}
+	s.Zero(sum, "balances must add up to zero")
+	return byName
+}
+
+func (s *SharingTestSuite) TestCreateExpense_Sharing() {
+	e := s.share(s.alice, 3000, models.Sharing{
+		Method: models.SharePercent,
+		Shares: []models.Share{{UserID: s.bob.ID, Percent: 25.5, Amount: 99}, {UserID: s.alice.ID, Percent: 74.5}},
+	})
+
+	got, err := s.db.GetExpense(s.ctx, HouseholdScope(s.household.ID), e.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(got.Sharing)
+	s.Equal(models.SharePercent, got.Sharing.Method)
+	// Shares come back by user and without the unused amount
+	s.Equal([]models.Share{{UserID: s.alice.ID, Percent: 74.5}, {UserID: s.bob.ID, Percent: 25.5}}, got.Sharing.Shares)
+
+	expenses, err := s.db.ListExpenses(s.ctx, HouseholdScope(s.household.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal(got.Sharing, expenses[0].Sharing)
+}
+
+func (s *SharingTestSuite) TestGetBalances_Methods() {
+	// 10.00 between three: alice paid and is owed 6.66, the odd cent falls on her own part
+	s.share(s.alice, 1000, models.Sharing{Shares: []models.Share{{UserID: s.alice.ID}, {UserID: s.bob.ID}, {UserID: s.carol.ID}}})
+	s.Equal(map[string]models.Money{"alice": 666, "bob": -333, "carol": -333}, s.balances())
+
+	// bob paid 20.00 of which carol owes 15.00 and he owes 5.00
+	s.share(s.bob, 2000, models.Sharing{
+		Method: models.ShareExact,
+		Shares: []models.Share{{UserID: s.bob.ID, Amount: 500}, {UserID: s.carol.ID, Amount: 1500}},
+	})
+	s.Equal(map[string]models.Money{"alice": 666, "bob": 1167, "carol": -1833}, s.balances())
+
+	// carol paid 10.00 for alice alone
+	s.share(s.carol, 1000, models.Sharing{Method: models.SharePercent, Shares: []models.Share{{UserID: s.alice.ID, Percent: 100}}})
+	s.Equal(map[string]models.Money{"alice": -334, "bob": 1167, "carol": -833}, s.balances())
+}
+
+func (s *SharingTestSuite) TestGetBalances_Refund() {
+	s.share(s.alice, 1000, models.Sharing{Shares: []models.Share{{UserID: s.alice.ID}, {UserID: s.bob.ID}}})
+	refund := &models.Expense{
+		Kind:        models.KindRefund,
+		Amount:      400,
+		Description: "Returned",
+		Date:        time.Date(2026, 2, 2, 12, 0, 0, 0, time.UTC),
+		Sharing:     &models.Sharing{Shares: []models.Share{{UserID: s.alice.ID}, {UserID: s.bob.ID}}},
+	}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.alice.ID, refund))
+
+	s.Equal(map[string]models.Money{"alice": 300, "bob": -300, "carol": 0}, s.balances())
+}
+
+func (s *SharingTestSuite) TestGetBalances_ConvertsCurrency() {
+	_, err := s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{
+		Base: s.household.Currency, Currency: "USD", Date: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Rate: 1.25,
+	}})
+	s.Require().NoError(err)
+	e := &models.Expense{
+		Amount:      1000,
+		Currency:    "USD",
+		Description: "Abroad",
+		Date:        time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC),
+		Sharing:     &models.Sharing{Method: models.ShareExact, Shares: []models.Share{{UserID: s.bob.ID, Amount: 1000}}},
+	}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.alice.ID, e))
+
+	// Balances are in the base currency
+	s.Equal(models.Money(800), e.Amount)
+	s.Equal(map[string]models.Money{"alice": 800, "bob": -800, "carol": 0}, s.balances())
+}
+
+func (s *SharingTestSuite) TestSharing_Validation() {
+	stranger, err := s.db.CreateUser(s.ctx, "dave", "hash")
+	s.Require().NoError(err)
+
+	for name, sharing := range map[string]models.Sharing{
+		"not a member": {Shares: []models.Share{{UserID: stranger.ID}}},
+		"twice":        {Shares: []models.Share{{UserID: s.bob.ID}, {UserID: s.bob.ID}}},
+		"method":       {Method: "thirds", Shares: []models.Share{{UserID: s.bob.ID}}},
+		"percentages":  {Method: models.SharePercent, Shares: []models.Share{{UserID: s.bob.ID, Percent: 60}, {UserID: s.alice.ID, Percent: 30}}},
+		"zero percent": {Method: models.SharePercent, Shares: []models.Share{{UserID: s.bob.ID, Percent: 100}, {UserID: s.alice.ID}}},
+		"amounts":      {Method: models.ShareExact, Shares: []models.Share{{UserID: s.bob.ID, Amount: 500}, {UserID: s.alice.ID, Amount: 400}}},
+	} {
+		sharing := sharing
+		e := &models.Expense{Amount: 1000, Description: name, Sharing: &sharing}
+		s.ErrorIs(s.db.CreateExpense(s.ctx, s.alice.ID, e), ErrInvalidShare, name)
+	}
+
+	income := &models.Expense{Kind: models.KindIncome, Amount: 1000, Description: "Salary", Sharing: &models.Sharing{Shares: []models.Share{{UserID: s.bob.ID}}}}
+	s.ErrorIs(s.db.CreateExpense(s.ctx, s.alice.ID, income), ErrInvalidShare)
+
+	// Sharing without shares is no sharing
+	e := &models.Expense{Amount: 1000, Description: "Alone", Sharing: &models.Sharing{Method: models.ShareEqual}}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.alice.ID, e))
+	s.Nil(e.Sharing)
+}
+
+func (s *SharingTestSuite) TestUpdateExpense_ReplacesSharing() {
+	e := s.share(s.alice, 1000, models.Sharing{Shares: []models.Share{{UserID: s.alice.ID}, {UserID: s.bob.ID}}})
+
+	e.Sharing = &models.Sharing{Shares: []models.Share{{UserID: s.carol.ID}}}
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, HouseholdScope(s.household.ID), e))
+	s.Equal(map[string]models.Money{"alice": 1000, "bob": 0, "carol": -1000}, s.balances())
+
+	e.Sharing = nil
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, HouseholdScope(s.household.ID), e))
+	s.Equal(map[string]models.Money{"alice": 0, "bob": 0, "carol": 0}, s.balances())
+}
+
+func (s *SharingTestSuite) TestRecordSettlements_ZeroBalances() {
+	s.share(s.alice, 900, models.Sharing{Shares: []models.Share{{UserID: s.alice.ID}, {UserID: s.bob.ID}, {UserID: s.carol.ID}}})
+	s.share(s.bob, 600, models.Sharing{Shares: []models.Share{{UserID: s.carol.ID}}})
+
+	balances, err := s.db.GetBalances(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	plan := SettleUp(balances)
+	s.Require().Len(plan, 2)
+
+	settlements := make([]models.Settlement, len(plan))
+	for i, p := range plan {
+		settlements[i] = p.Settlement
+	}
+	s.Require().NoError(s.db.RecordSettlements(s.ctx, s.household.ID, settlements))
+	s.NotZero(settlements[0].ID)
+	s.Equal(map[string]models.Money{"alice": 0, "bob": 0, "carol": 0}, s.balances())
+
+	listed, err := s.db.ListSettlements(s.ctx, s.household.ID, 10)
+	s.Require().NoError(err)
+	s.Len(listed, 2)
+	s.Equal("carol", listed[0].FromUsername)
+
+	// Deleting a settlement brings its debt back
+	s.Require().NoError(s.db.DeleteSettlement(s.ctx, s.household.ID, listed[0].ID))
+	s.NotZero(s.balances()["carol"])
+	s.ErrorIs(s.db.DeleteSettlement(s.ctx, s.household.ID, listed[0].ID), ErrNotFound)
+}
+
+func (s *SharingTestSuite) TestRecordSettlements_Validation() {
+	stranger, err := s.db.CreateUser(s.ctx, "dave", "hash")
+	s.Require().NoError(err)
+
+	for _, settlement := range []models.Settlement{
+		{FromUserID: s.alice.ID, ToUserID: s.alice.ID, Amount: 100},
+		{FromUserID: s.alice.ID, ToUserID: s.bob.ID, Amount: 0},
+		{FromUserID: stranger.ID, ToUserID: s.bob.ID, Amount: 100},
+	} {
+		s.ErrorIs(s.db.RecordSettlements(s.ctx, s.household.ID, []models.Settlement{settlement}), ErrInvalidSettlement)
+	}
+	listed, err := s.db.ListSettlements(s.ctx, s.household.ID, 10)
+	s.Require().NoError(err)
+	s.Empty(listed)
+}
+
+func (s *SharingTestSuite) TestGetBalances_FormerMember() {
+	s.share(s.alice, 1000, models.Sharing{Shares: []models.Share{{UserID: s.carol.ID}}})
+	s.Require().NoError(s.db.RemoveHouseholdMember(s.ctx, s.household.ID, s.carol.ID))
+
+	// carol still owes alice, but bob is only listed as a member
+	s.Equal(map[string]models.Money{"alice": 1000, "bob": 0, "carol": -1000}, s.balances())
+	s.Require().NoError(s.db.RemoveHouseholdMember(s.ctx, s.household.ID, s.bob.ID))
+	s.NotContains(s.balances(), "bob")
+}
+
+// TestSharingSuite runs the shared expense test suite
+func TestSharingSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &SharingTestSuite{dsn: dsn})
+	})
+}
+
+func TestSettleUp(t *testing.T) {
+	balances := []MemberBalance{
+		{UserID: 1, Username: "alice", Balance: 5000},
+		{UserID: 2, Username: "bob", Balance: -2000},
+		{UserID: 3, Username: "carol", Balance: -1000},
+		{UserID: 4, Username: "dave", Balance: 1000},
+		{UserID: 5, Username: "erin", Balance: -3000},
+		{UserID: 6, Username: "frank", Balance: 0},
+	}
+	plan := SettleUp(balances)
+
+	// carol pays dave off directly; bob and erin pay alice
+	assert.Len(t, plan, 3)
+	after := make(map[int64]models.Money)
+	for _, b := range balances {
+		after[b.UserID] = b.Balance
+	}
+	for _, p := range plan {
+		assert.Positive(t, int64(p.Amount))
+		after[p.FromUserID] += p.Amount
+		after[p.ToUserID] -= p.Amount
+	}
+	for id, balance := range after {
+		assert.Zero(t, balance, "user %d", id)
+	}
+	assert.Equal(t, "carol", plan[0].FromUsername)
+	assert.Equal(t, "dave", plan[0].ToUsername)
+	assert.Equal(t, models.Money(5000), balances[0].Balance, "balances are left alone")
+
+	assert.Empty(t, SettleUp([]MemberBalance{{UserID: 1, Username: "alice"}}))
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errPostgresMaintenance is returned by the SQLite file operations on a
+// PostgreSQL database, which is backed up and checked with its own tools.
+var errPostgresMaintenance = fmt.Errorf("not available for PostgreSQL, use pg_dump and amcheck: %w", errors.ErrUnsupported)
+
+// Snapshot writes a consistent copy of the database to path with VACUUM INTO,
+// without blocking readers; writes wait until it is done. The file at path
+// must not exist yet. PostgreSQL databases are not supported.
+func (db *DB) Snapshot(ctx context.Context, path string) error {
+	if db.dialect.postgres() {
+		return errPostgresMaintenance
+	}
+	_, err := db.conn.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
+
+// IntegrityCheck runs PRAGMA integrity_check and returns an error listing the
+// problems found, if any. PostgreSQL databases are not supported.
+func (db *DB) IntegrityCheck(ctx context.Context) error {
+	if db.dialect.postgres() {
+		return errPostgresMaintenance
+	}
+	rows, err := db.read.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
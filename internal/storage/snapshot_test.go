@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// SnapshotTestSuite provides a test suite for online database copies
+type SnapshotTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	db  *DB
+}
+
+// SetupTest runs before each test
+func (s *SnapshotTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := NewDB(filepath.Join(s.T().TempDir(), "expenses.db"))
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+}
+
+// TearDownTest runs after each test
+func (s *SnapshotTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *SnapshotTestSuite) TestSnapshot() {
+	user, err := s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	household, err := s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, household.ID, user.ID))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, user.ID, &models.Expense{Amount: 100, Description: "Coffee", Category: "Eating Out", Date: time.Now()}))
+
+	path := filepath.Join(s.T().TempDir(), "snapshot.db")
+	s.Require().NoError(s.db.Snapshot(s.ctx, path))
+	s.Error(s.db.Snapshot(s.ctx, path), "an existing file is not overwritten")
+
+	snapshot, err := Open(path)
+	s.Require().NoError(err)
+	defer snapshot.Close()
+	s.NoError(snapshot.IntegrityCheck(s.ctx))
+	expenses, err := snapshot.ListExpenses(s.ctx, UserScope(user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Len(expenses, 1)
+}
+
+func (s *SnapshotTestSuite) TestIntegrityCheck_Corrupt() {
+	path := filepath.Join(s.T().TempDir(), "snapshot.db")
+	s.Require().NoError(s.db.Snapshot(s.ctx, path))
+
+	// Overwrite the second page, which holds a table
+	data, err := os.ReadFile(path)
+	s.Require().NoError(err)
+	s.Require().Greater(len(data), 8192)
+	for i := 4096; i < 8192; i++ {
+		data[i] = 0xff
+	}
+	s.Require().NoError(os.WriteFile(path, data, 0o600))
+
+	snapshot, err := Open(path)
+	s.Require().NoError(err)
+	defer snapshot.Close()
+	s.Error(snapshot.IntegrityCheck(s.ctx))
+}
+
+// TestSnapshotSuite runs the snapshot test suite
+func TestSnapshotSuite(t *testing.T) {
+	suite.Run(t, new(SnapshotTestSuite))
+}
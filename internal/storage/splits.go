@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrInvalidSplit is returned when the parts of a split expense lack a
+// category or a positive amount, repeat a category or do not add up to the
+// expense's amount.
+var ErrInvalidSplit = errors.New("invalid split")
+
+// prepareSplits validates the parts of a split expense against its
+// OriginalAmount and sets Category to the category of the first part. A
+// single part is no split: it only sets the category.
+func prepareSplits(e *models.Expense) error {
+	if len(e.Splits) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(e.Splits))
+	var sum models.Money
+	for _, s := range e.Splits {
+		switch {
+		case s.Category == "":
+			return fmt.Errorf("%w: every part needs a category", ErrInvalidSplit)
+		case s.Amount <= 0:
+			return fmt.Errorf("%w: amounts must be positive", ErrInvalidSplit)
+		case seen[s.Category]:
+			return fmt.Errorf("%w: %s appears twice", ErrInvalidSplit, s.Category)
+		}
+		seen[s.Category] = true
+		sum += s.Amount
+	}
+	if sum != e.OriginalAmount {
+		return fmt.Errorf("%w: parts add up to %s, not %s", ErrInvalidSplit, sum, e.OriginalAmount)
+	}
+
+	e.Category = e.Splits[0].Category
+	if len(e.Splits) == 1 {
+		e.Splits = nil
+	}
+	return nil
+}
+
+// saveSplits replaces the parts of an expense.
+func saveSplits(ctx context.Context, tx *sql.Tx, expenseID int64, splits []models.Split) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM expense_splits WHERE expense_id = ?", expenseID); err != nil {
+		return err
+	}
+	for i, s := range splits {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO expense_splits (expense_id, position, category, amount) VALUES (?, ?, ?, ?)",
+			expenseID, i, s.Category, s.Amount,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSplits fills in the parts of the split expenses among expenses.
+func loadSplits(ctx context.Context, q querier, expenses []models.Expense) error {
+	return loadByExpense(ctx, q, expenses,
+		"SELECT expense_id, category, amount FROM expense_splits WHERE expense_id IN (%s) ORDER BY expense_id, position",
+		func(rows *sql.Rows, byID map[int64]*models.Expense) error {
+			var id int64
+			var s models.Split
+			if err := rows.Scan(&id, &s.Category, &s.Amount); err != nil {
+				return err
+			}
+			e := byID[id]
+			e.Splits = append(e.Splits, s)
+			return nil
+		})
+}
+
+// expenseLines returns a query with a row per category of each expense
+// matching cond: the expense itself, or one per part of a split expense. The
+// parts share the converted amount in proportion to their amounts as paid;
+// taking the difference of the running sums makes them add up to it exactly.
+// The sums are cast because PostgreSQL sums BIGINT as NUMERIC, which would
+// not truncate the division.
+func expenseLines(cond string) string {
+	return `SELECT e.id, e.household_id, e.kind, e.date, COALESCE(s.category, e.category) AS category,
+			CASE WHEN s.expense_id IS NULL THEN e.amount
+			ELSE e.amount * CAST(SUM(s.amount) OVER parts AS BIGINT) / e.original_amount
+				- e.amount * CAST(SUM(s.amount) OVER parts - s.amount AS BIGINT) / e.original_amount END AS amount
+		FROM expenses e
+		LEFT JOIN expense_splits s ON s.expense_id = e.id
+		WHERE ` + cond + `
+		WINDOW parts AS (PARTITION BY e.id ORDER BY s.position)`
+}
+
+// expenseParts shares the amount of an expense among its split parts as
+// expenseLines does, keeping the parts for which keep is true. An expense that
+// is not split is a single part.
+func expenseParts(e models.Expense, keep []bool) []ExpensePart {
+	if len(e.Splits) == 0 {
+		return []ExpensePart{{Category: e.Category, Amount: e.Amount}}
+	}
+	var parts []ExpensePart
+	var sum models.Money
+	for i, s := range e.Splits {
+		before := sum
+		sum += s.Amount
+		if keep[i] {
+			parts = append(parts, ExpensePart{
+				Category: s.Category,
+				Amount:   e.Amount*sum/e.OriginalAmount - e.Amount*before/e.OriginalAmount,
+			})
+		}
+	}
+	return parts
+}
@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// SplitTestSuite provides a test suite for expenses split across categories
+type SplitTestSuite struct {
+	suite.Suite
+	dsn       string // Database under test, see runOnBackends
+	ctx       context.Context
+	db        *DB
+	user      *models.User
+	household *models.Household
+}
+
+// SetupTest runs before each test
+func (s *SplitTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	s.household, err = s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+}
+
+// TearDownTest runs after each test
+func (s *SplitTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+// supermarket records a receipt of 50.00 split over three categories.
+func (s *SplitTestSuite) supermarket() *models.Expense {
+	e := &models.Expense{
+		Amount:      5000,
+		Description: "Supermarket",
+		Date:        time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC),
+		Splits: []models.Split{
+			{Category: "Groceries", Amount: 3000},
+			{Category: "Housing", Amount: 1500},
+			{Category: "Gifts", Amount: 500},
+		},
+	}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+	return e
+}
+
+func (s *SplitTestSuite) totals(year, month int) map[string]CategoryTotal {
+	totals, err := s.db.GetCategoryTotalsByMonth(s.ctx, UserScope(s.user.ID), year, month)
+	s.Require().NoError(err)
+	byCategory := make(map[string]CategoryTotal, len(totals))
+	for _, ct := range totals {
+		byCategory[ct.Category] = ct
+	}
+	return byCategory
+}
+
+func (s *SplitTestSuite) TestCreateExpense_Splits() {
+	e := s.supermarket()
+	s.Equal("Groceries", e.Category)
+
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal("Groceries", got.Category)
+	s.Equal(e.Splits, got.Splits)
+
+	// The feed still has one row per payment
+	expenses, err := s.db.ListExpenses(s.ctx, UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Len(expenses[0].Splits, 3)
+}
+
+func (s *SplitTestSuite) TestCategoryTotals_AttributeParts() {
+	s.supermarket()
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 1000, Description: "Bread", Category: "Groceries", Date: time.Date(2026, 1, 11, 12, 0, 0, 0, time.UTC)}))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{
+		Kind: models.KindRefund, Amount: 200, Description: "Returned candles", Date: time.Date(2026, 1, 12, 12, 0, 0, 0, time.UTC),
+		Splits: []models.Split{{Category: "Housing", Amount: 150}, {Category: "Gifts", Amount: 50}},
+	}))
+
+	totals := s.totals(2026, 1)
+	s.Len(totals, 3)
+	s.Equal(models.Money(4000), totals["Groceries"].Total)
+	s.Equal(2, totals["Groceries"].Count)
+	s.Equal(models.Money(1350), totals["Housing"].Total)
+	s.Equal(models.Money(450), totals["Gifts"].Total)
+
+	yearly, err := s.db.GetCategoryTotalsByYear(s.ctx, UserScope(s.user.ID), 2026)
+	s.Require().NoError(err)
+	s.Len(yearly, 3)
+	s.Equal("Groceries", yearly[0].Category)
+	s.Equal(models.Money(4000), yearly[0].Total)
+
+	// Daily and period totals count the payment once
+	period, err := s.db.GetTotalForPeriod(s.ctx, UserScope(s.user.ID), 2026, 1)
+	s.Require().NoError(err)
+	s.Equal(models.Money(5800), period.Spending)
+}
+
+func (s *SplitTestSuite) TestCategoryTotals_ForeignCurrencyPartsAddUp() {
+	date := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	_, err := s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: "EUR", Currency: "USD", Date: date, Rate: 1.07}})
+	s.Require().NoError(err)
+	e := &models.Expense{
+		Amount:   1000,
+		Currency: "USD",
+		Date:     date,
+		Splits: []models.Split{
+			{Category: "Groceries", Amount: 334},
+			{Category: "Housing", Amount: 333},
+			{Category: "Gifts", Amount: 333},
+		},
+	}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+
+	var sum models.Money
+	for _, ct := range s.totals(2026, 1) {
+		s.InDelta(int64(e.Amount)/3, int64(ct.Total), 1, ct.Category)
+		sum += ct.Total
+	}
+	s.Equal(e.Amount, sum)
+}
+
+func (s *SplitTestSuite) TestSplits_Validation() {
+	date := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	for name, splits := range map[string][]models.Split{
+		"short":          {{Category: "Groceries", Amount: 3000}, {Category: "Housing", Amount: 1000}},
+		"too much":       {{Category: "Groceries", Amount: 3000}, {Category: "Housing", Amount: 3000}},
+		"repeated":       {{Category: "Groceries", Amount: 2500}, {Category: "Groceries", Amount: 2500}},
+		"no category":    {{Category: "Groceries", Amount: 2500}, {Amount: 2500}},
+		"negative":       {{Category: "Groceries", Amount: 6000}, {Category: "Housing", Amount: -1000}},
+		"single, short":  {{Category: "Groceries", Amount: 4000}},
+		"zero in a part": {{Category: "Groceries", Amount: 5000}, {Category: "Housing"}},
+	} {
+		e := &models.Expense{Amount: 5000, Description: name, Date: date, Splits: splits}
+		s.ErrorIs(s.db.CreateExpense(s.ctx, s.user.ID, e), ErrInvalidSplit, name)
+	}
+	expenses, err := s.db.ListExpenses(s.ctx, UserScope(s.user.ID), 10, 0)
+	s.Require().NoError(err)
+	s.Empty(expenses)
+
+	// A single part is just the category
+	e := &models.Expense{Amount: 5000, Description: "Rent", Date: date, Category: "Groceries", Splits: []models.Split{{Category: "Housing", Amount: 5000}}}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal("Housing", got.Category)
+	s.Empty(got.Splits)
+}
+
+func (s *SplitTestSuite) TestUpdateExpense_ReplacesSplits() {
+	e := s.supermarket()
+
+	e.Splits = []models.Split{{Category: "Housing", Amount: 2000}, {Category: "Groceries", Amount: 3000}}
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, UserScope(s.user.ID), e))
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal("Housing", got.Category)
+	s.Equal(e.Splits, got.Splits)
+	s.NotContains(s.totals(2026, 1), "Gifts")
+
+	e.Splits, e.Category = nil, "Groceries"
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, UserScope(s.user.ID), e))
+	got, err = s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Empty(got.Splits)
+	s.Equal(models.Money(5000), s.totals(2026, 1)["Groceries"].Total)
+
+	e.Amount, e.OriginalAmount = 6000, 0
+	e.Splits = []models.Split{{Category: "Housing", Amount: 2000}, {Category: "Groceries", Amount: 3000}}
+	s.ErrorIs(s.db.UpdateExpense(s.ctx, UserScope(s.user.ID), e), ErrInvalidSplit)
+}
+
+func (s *SplitTestSuite) TestFilterExpenses_MatchesParts() {
+	e := s.supermarket()
+
+	expenses, err := s.db.FilterExpenses(s.ctx, UserScope(s.user.ID), ExpenseFilter{Category: "Housing", Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal(e.ID, expenses[0].ID)
+
+	expenses, err = s.db.FilterExpenses(s.ctx, UserScope(s.user.ID), ExpenseFilter{Category: "Transport", Limit: 10})
+	s.Require().NoError(err)
+	s.Empty(expenses)
+}
+
+func (s *SplitTestSuite) TestEachExpense_Parts() {
+	date := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	_, err := s.db.SaveExchangeRates(s.ctx, s.household.ID, []models.ExchangeRate{{Base: "EUR", Currency: "USD", Date: date, Rate: 1.07}})
+	s.Require().NoError(err)
+	e := &models.Expense{
+		Amount:   1000,
+		Currency: "USD",
+		Date:     date,
+		Splits: []models.Split{
+			{Category: "Groceries", Amount: 334},
+			{Category: "Housing", Amount: 333},
+			{Category: "Gifts", Amount: 333},
+		},
+	}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{Amount: 250, Description: "Bus", Category: "Transport", Date: date.Add(time.Hour)}))
+
+	each := func(f ExpenseFilter) []ExpenseRow {
+		var rows []ExpenseRow
+		s.Require().NoError(s.db.EachExpense(s.ctx, UserScope(s.user.ID), f, func(row ExpenseRow) error {
+			rows = append(rows, row)
+			return nil
+		}))
+		return rows
+	}
+
+	// Parts share the converted amount as the statistics do
+	rows := each(ExpenseFilter{})
+	s.Require().Len(rows, 2)
+	s.Equal(e.Splits, rows[0].Splits)
+	s.Require().Len(rows[0].Parts, 3)
+	totals := s.totals(2026, 1)
+	var sum models.Money
+	for _, part := range rows[0].Parts {
+		s.Equal(totals[part.Category].Total, part.Amount, part.Category)
+		sum += part.Amount
+	}
+	s.Equal(e.Amount, sum)
+	s.Equal([]ExpensePart{{Category: "Transport", Amount: 250}}, rows[1].Parts)
+
+	// A category filter keeps only the parts in the category
+	rows = each(ExpenseFilter{Category: "Housing"})
+	s.Require().Len(rows, 1)
+	s.Equal([]ExpensePart{{Category: "Housing", Amount: totals["Housing"].Total}}, rows[0].Parts)
+}
+
+func (s *SplitTestSuite) TestUpdateCategory_RenamesParts() {
+	e := s.supermarket()
+	categories, err := s.db.ListCategories(s.ctx, s.household.ID, false)
+	s.Require().NoError(err)
+	var housing models.Category
+	for _, c := range categories {
+		if c.Name == "Housing" {
+			housing = c
+		}
+	}
+	s.Require().NotZero(housing.ID)
+
+	housing.Name = "Home"
+	s.Require().NoError(s.db.UpdateCategory(s.ctx, &housing))
+	got, err := s.db.GetExpense(s.ctx, UserScope(s.user.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal("Home", got.Splits[1].Category)
+	s.Equal(models.Money(1500), s.totals(2026, 1)["Home"].Total)
+}
+
+func (s *SplitTestSuite) TestDeleteExpense_RemovesParts() {
+	e := s.supermarket()
+	s.Require().NoError(s.db.DeleteExpense(s.ctx, UserScope(s.user.ID), e.ID))
+
+	var count int
+	s.Require().NoError(s.db.read.QueryRowContext(s.ctx, "SELECT COUNT(*) FROM expense_splits").Scan(&count))
+	s.Zero(count)
+}
+
+// TestSplitSuite runs the split expense test suite
+func TestSplitSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &SplitTestSuite{dsn: dsn})
+	})
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// Store is the storage the HTTP handlers and exports work with. DB implements
+// it for both SQLite and PostgreSQL; maintenance such as migrations, backups
+// and household administration stays on DB.
+type Store interface {
+	// Users and sessions
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	CreateSession(ctx context.Context, token string, userID int64, expiresAt time.Time) error
+	ValidateSession(ctx context.Context, token string) (*models.User, error)
+	ValidateSessionWithInfo(ctx context.Context, token string) (*SessionInfo, error)
+	RenewSession(ctx context.Context, token string, newExpiresAt time.Time) error
+	DeleteSession(ctx context.Context, token string) error
+	DefaultHouseholdID(ctx context.Context, userID int64) (int64, error)
+	GetHousehold(ctx context.Context, id int64) (*models.Household, error)
+	ListUserHouseholds(ctx context.Context, userID int64) ([]models.Household, error)
+
+	// API tokens
+	CreateAPIToken(ctx context.Context, userID int64, name string, scope models.TokenScope, tokenHash string) (*models.APIToken, error)
+	ListAPITokens(ctx context.Context, userID int64) ([]models.APIToken, error)
+	RevokeAPIToken(ctx context.Context, userID, id int64) error
+	UseAPIToken(ctx context.Context, tokenHash string, now time.Time) (*models.User, *models.APIToken, error)
+
+	// Expenses
+	CreateExpense(ctx context.Context, userID int64, e *models.Expense) error
+	GetExpense(ctx context.Context, scope Scope, id int64) (*models.Expense, error)
+	UpdateExpense(ctx context.Context, scope Scope, e *models.Expense) error
+	DeleteExpense(ctx context.Context, scope Scope, id int64) error
+	ListExpenses(ctx context.Context, scope Scope, limit, offset int) ([]models.Expense, error)
+	FilterExpenses(ctx context.Context, scope Scope, f ExpenseFilter) ([]models.Expense, error)
+	EachExpense(ctx context.Context, scope Scope, f ExpenseFilter, fn func(ExpenseRow) error) error
+	GetExpensesByMonth(ctx context.Context, scope Scope, year, month int) ([]models.Expense, error)
+	GetExpensesByYear(ctx context.Context, scope Scope, year int) ([]models.Expense, error)
+
+	// Statistics
+	GetCurrentMonthTotal(ctx context.Context, scope Scope) (PeriodTotals, error)
+	GetTotalForPeriod(ctx context.Context, scope Scope, year, month int) (PeriodTotals, error)
+	GetCategoryTotalsByMonth(ctx context.Context, scope Scope, year, month int) ([]CategoryTotal, error)
+	GetCategoryTotalsByYear(ctx context.Context, scope Scope, year int) ([]CategoryTotal, error)
+	GetMonthlyTotalsForYear(ctx context.Context, scope Scope, year int) ([]MonthlyTotal, error)
+	GetDailyTotalsForMonth(ctx context.Context, scope Scope, year, month int) ([]DailyTotal, error)
+
+	// Categories and budgets
+	ListCategories(ctx context.Context, householdID int64, includeArchived bool) ([]models.Category, error)
+	CreateCategory(ctx context.Context, c *models.Category) error
+	UpdateCategory(ctx context.Context, c *models.Category) error
+	SetCategoryArchived(ctx context.Context, householdID, id int64, archived bool) error
+	ReorderCategories(ctx context.Context, householdID int64, ids []int64) error
+	SetBudget(ctx context.Context, b *models.Budget) error
+	GetBudgetStatuses(ctx context.Context, householdID int64, year, month int) ([]BudgetStatus, error)
+
+	// Currencies
+	SetHouseholdCurrency(ctx context.Context, householdID int64, currency string) error
+	SaveExchangeRates(ctx context.Context, householdID int64, rates []models.ExchangeRate) (int, error)
+	ListExchangeRates(ctx context.Context, householdID int64, limit int) ([]models.ExchangeRate, error)
+
+	// Accounts
+	ListAccounts(ctx context.Context, householdID int64, includeArchived bool) ([]models.Account, error)
+	GetAccount(ctx context.Context, householdID, id int64) (*models.Account, error)
+	CreateAccount(ctx context.Context, a *models.Account) error
+	SetAccountArchived(ctx context.Context, householdID, id int64, archived bool) error
+	CreateTransfer(ctx context.Context, userID int64, t *models.Transfer) error
+	DeleteTransfer(ctx context.Context, householdID, id int64) error
+	ListAccountBalances(ctx context.Context, householdID int64) ([]AccountBalance, error)
+	GetAccountLedger(ctx context.Context, householdID, accountID int64, limit int) ([]LedgerEntry, error)
+
+	// Sharing
+	ListHouseholdMembers(ctx context.Context, householdID int64) ([]models.User, error)
+	GetBalances(ctx context.Context, householdID int64) ([]MemberBalance, error)
+	RecordSettlements(ctx context.Context, householdID int64, settlements []models.Settlement) error
+	ListSettlements(ctx context.Context, householdID int64, limit int) ([]SettlementRow, error)
+	DeleteSettlement(ctx context.Context, householdID, id int64) error
+
+	// Tags
+	ListTags(ctx context.Context, householdID int64) ([]string, error)
+	GetTagTotalsByMonth(ctx context.Context, scope Scope, year, month int) ([]TagTotal, error)
+	GetTagTotalsByYear(ctx context.Context, scope Scope, year int) ([]TagTotal, error)
+
+	// Recurring templates
+	CreateRecurring(ctx context.Context, userID int64, r *models.Recurring) error
+	ListRecurring(ctx context.Context, householdID int64) ([]models.Recurring, error)
+	DeleteRecurring(ctx context.Context, householdID, id int64) error
+
+	// Imports
+	SaveImportProfile(ctx context.Context, p *models.ImportProfile) error
+	ListImportProfiles(ctx context.Context, householdID int64) ([]models.ImportProfile, error)
+	DeleteImportProfile(ctx context.Context, householdID, id int64) error
+	FindDuplicates(ctx context.Context, householdID int64, expenses []models.Expense) ([]bool, error)
+	ImportExpenses(ctx context.Context, userID int64, expenses []models.Expense) (int, error)
+}
+
+var _ Store = (*DB)(nil)
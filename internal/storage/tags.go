@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+// ErrInvalidTag is returned when a tag of an expense is not valid as
+// described at models.NormalizeTag.
+var ErrInvalidTag = errors.New("invalid tag")
+
+// prepareTags normalizes the tags of an expense and drops repeats.
+func prepareTags(e *models.Expense) error {
+	if len(e.Tags) == 0 {
+		e.Tags = nil
+		return nil
+	}
+	tags := make([]string, 0, len(e.Tags))
+	seen := make(map[string]bool, len(e.Tags))
+	for _, s := range e.Tags {
+		tag, err := models.NormalizeTag(s)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTag, err)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	e.Tags = tags
+	return nil
+}
+
+// saveTags replaces the tags of an expense, adding new ones to the household.
+func saveTags(ctx context.Context, tx *sql.Tx, householdID, expenseID int64, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM expense_tags WHERE expense_id = ?", expenseID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO tags (household_id, name) VALUES (?, ?) ON CONFLICT (household_id, name) DO NOTHING",
+			householdID, tag,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO expense_tags (expense_id, tag_id) SELECT ?, id FROM tags WHERE household_id = ? AND name = ?",
+			expenseID, householdID, tag,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills in the tags of the tagged expenses among expenses, in
+// alphabetical order.
+func loadTags(ctx context.Context, q querier, expenses []models.Expense) error {
+	return loadByExpense(ctx, q, expenses,
+		"SELECT et.expense_id, t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id IN (%s) ORDER BY et.expense_id, t.name",
+		func(rows *sql.Rows, byID map[int64]*models.Expense) error {
+			var id int64
+			var tag string
+			if err := rows.Scan(&id, &tag); err != nil {
+				return err
+			}
+			e := byID[id]
+			e.Tags = append(e.Tags, tag)
+			return nil
+		})
+}
+
+// ListTags returns the tags in use in a household, the most used first.
+func (db *DB) ListTags(ctx context.Context, householdID int64) ([]string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.read.QueryContext(ctx, `
+		SELECT t.name FROM tags t
+		JOIN expense_tags et ON et.tag_id = t.id
+		WHERE t.household_id = ?
+		GROUP BY t.name
+		ORDER BY COUNT(*) DESC, t.name`,
+		householdID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// TagTotal is the spending of a period on expenses with a tag. An expense
+// with several tags counts towards each of them.
+type TagTotal struct {
+	Tag   string       `json:"tag"`
+	Total models.Money `json:"total"`
+	Count int          `json:"count"`
+}
+
+// GetTagTotalsByMonth retrieves spending totals by tag for a specific month,
+// the largest first.
+func (db *DB) GetTagTotalsByMonth(ctx context.Context, scope Scope, year, month int) ([]TagTotal, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	startOfMonth := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return db.tagTotals(ctx, scope, startOfMonth, startOfMonth.AddDate(0, 1, 0))
+}
+
+// GetTagTotalsByYear retrieves spending totals by tag for a year, the largest first.
+func (db *DB) GetTagTotalsByYear(ctx context.Context, scope Scope, year int) ([]TagTotal, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	startOfYear := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	return db.tagTotals(ctx, scope, startOfYear, startOfYear.AddDate(1, 0, 0))
+}
+
+func (db *DB) tagTotals(ctx context.Context, scope Scope, start, end time.Time) ([]TagTotal, error) {
+	cond, args := scope.clause()
+	rows, err := db.read.QueryContext(ctx,
+		`SELECT t.name, SUM(CASE e.kind WHEN 'refund' THEN -e.amount ELSE e.amount END) AS total, COUNT(*)
+		 FROM expenses e
+		 JOIN expense_tags et ON et.expense_id = e.id
+		 JOIN tags t ON t.id = et.tag_id
+		 WHERE `+cond+` AND e.kind != 'income' AND e.date >= ? AND e.date < ?
+		 GROUP BY t.name
+		 ORDER BY total DESC, t.name`,
+		append(args, start, end)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []TagTotal
+	for rows.Next() {
+		var tt TagTotal
+		if err := rows.Scan(&tt.Tag, &tt.Total, &tt.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, tt)
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TagTestSuite provides a test suite for expense tags
+type TagTestSuite struct {
+	suite.Suite
+	dsn       string // Database under test, see runOnBackends
+	ctx       context.Context
+	db        *DB
+	user      *models.User
+	household *models.Household
+}
+
+// SetupTest runs before each test
+func (s *TagTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "alice", "hash")
+	s.Require().NoError(err)
+	s.household, err = s.db.CreateHousehold(s.ctx, "Home")
+	s.Require().NoError(err)
+	s.Require().NoError(s.db.AddHouseholdMember(s.ctx, s.household.ID, s.user.ID))
+}
+
+// TearDownTest runs after each test
+func (s *TagTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *TagTestSuite) createExpense(description string, kind models.ExpenseKind, amount models.Money, tags ...string) *models.Expense {
+	e := &models.Expense{
+		Kind:        kind,
+		Amount:      amount,
+		Description: description,
+		Category:    "Travel",
+		Date:        time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+		Tags:        tags,
+	}
+	s.Require().NoError(s.db.CreateExpense(s.ctx, s.user.ID, e))
+	return e
+}
+
+func (s *TagTestSuite) TestCreateExpense_Tags() {
+	e := s.createExpense("Hotel", models.KindExpense, 12000, "Vacation", "#rome", "vacation")
+	s.Equal([]string{"vacation", "rome"}, e.Tags, "tags are normalized and repeats dropped")
+
+	got, err := s.db.GetExpense(s.ctx, HouseholdScope(s.household.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal([]string{"rome", "vacation"}, got.Tags)
+
+	untagged := s.createExpense("Bread", models.KindExpense, 300)
+	got, err = s.db.GetExpense(s.ctx, HouseholdScope(s.household.ID), untagged.ID)
+	s.Require().NoError(err)
+	s.Empty(got.Tags)
+}
+
+func (s *TagTestSuite) TestCreateExpense_InvalidTag() {
+	err := s.db.CreateExpense(s.ctx, s.user.ID, &models.Expense{
+		Amount: 100, Description: "Coffee", Date: time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC),
+		Tags: []string{"two words"},
+	})
+	s.ErrorIs(err, ErrInvalidTag)
+}
+
+func (s *TagTestSuite) TestUpdateExpense_ReplacesTags() {
+	e := s.createExpense("Hotel", models.KindExpense, 12000, "vacation", "rome")
+	e.Tags = []string{"work"}
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, HouseholdScope(s.household.ID), e))
+
+	got, err := s.db.GetExpense(s.ctx, HouseholdScope(s.household.ID), e.ID)
+	s.Require().NoError(err)
+	s.Equal([]string{"work"}, got.Tags)
+
+	e.Tags = nil
+	s.Require().NoError(s.db.UpdateExpense(s.ctx, HouseholdScope(s.household.ID), e))
+	got, err = s.db.GetExpense(s.ctx, HouseholdScope(s.household.ID), e.ID)
+	s.Require().NoError(err)
+	s.Empty(got.Tags)
+}
+
+func (s *TagTestSuite) TestScopeWithTag() {
+	s.createExpense("Hotel", models.KindExpense, 12000, "vacation", "rome")
+	s.createExpense("Flight", models.KindExpense, 20000, "vacation")
+	s.createExpense("Bread", models.KindExpense, 300)
+
+	scope := HouseholdScope(s.household.ID)
+	expenses, err := s.db.ListExpenses(s.ctx, scope.WithTag("vacation"), 10, 0)
+	s.Require().NoError(err)
+	s.Len(expenses, 2)
+	expenses, err = s.db.ListExpenses(s.ctx, scope.WithTag("rome"), 10, 0)
+	s.Require().NoError(err)
+	s.Require().Len(expenses, 1)
+	s.Equal("Hotel", expenses[0].Description)
+	expenses, err = s.db.ListExpenses(s.ctx, scope.WithTag("unknown"), 10, 0)
+	s.Require().NoError(err)
+	s.Empty(expenses)
+	expenses, err = s.db.ListExpenses(s.ctx, scope.WithTag(""), 10, 0)
+	s.Require().NoError(err)
+	s.Len(expenses, 3)
+
+	// Tags of another household are not visible
+	other, err := s.db.CreateHousehold(s.ctx, "Other")
+	s.Require().NoError(err)
+	expenses, err = s.db.ListExpenses(s.ctx, HouseholdScope(other.ID).WithTag("vacation"), 10, 0)
+	s.Require().NoError(err)
+	s.Empty(expenses)
+}
+
+func (s *TagTestSuite) TestGetTagTotals() {
+	s.createExpense("Hotel", models.KindExpense, 12000, "vacation", "rome")
+	s.createExpense("Flight", models.KindExpense, 20000, "vacation")
+	s.createExpense("Hotel refund", models.KindRefund, 2000, "vacation")
+	s.createExpense("Per diem", models.KindIncome, 5000, "vacation")
+	s.createExpense("Bread", models.KindExpense, 300)
+
+	scope := HouseholdScope(s.household.ID)
+	totals, err := s.db.GetTagTotalsByMonth(s.ctx, scope, 2026, 3)
+	s.Require().NoError(err)
+	s.Equal([]TagTotal{
+		{Tag: "vacation", Total: 30000, Count: 3},
+		{Tag: "rome", Total: 12000, Count: 1},
+	}, totals)
+
+	yearly, err := s.db.GetTagTotalsByYear(s.ctx, scope, 2026)
+	s.Require().NoError(err)
+	s.Equal(totals, yearly)
+
+	totals, err = s.db.GetTagTotalsByMonth(s.ctx, scope, 2026, 4)
+	s.Require().NoError(err)
+	s.Empty(totals)
+}
+
+func (s *TagTestSuite) TestListTags() {
+	tags, err := s.db.ListTags(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Empty(tags)
+
+	s.createExpense("Hotel", models.KindExpense, 12000, "vacation", "rome")
+	s.createExpense("Flight", models.KindExpense, 20000, "vacation")
+	e := s.createExpense("Laptop", models.KindExpense, 90000, "work")
+
+	tags, err = s.db.ListTags(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Equal([]string{"vacation", "rome", "work"}, tags)
+
+	// Tags no longer in use are not offered
+	s.Require().NoError(s.db.DeleteExpense(s.ctx, HouseholdScope(s.household.ID), e.ID))
+	tags, err = s.db.ListTags(s.ctx, s.household.ID)
+	s.Require().NoError(err)
+	s.Equal([]string{"vacation", "rome"}, tags)
+}
+
+// TestTagSuite runs the tag test suite
+func TestTagSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &TagTestSuite{dsn: dsn})
+	})
+}
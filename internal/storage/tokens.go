@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"expense-tracker/internal/models"
+)
+
+const apiTokenColumns = "id, user_id, name, scope, created_at, last_used_at"
+
+func scanAPIToken(row interface{ Scan(...any) error }, t *models.APIToken) error {
+	return row.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.CreatedAt, &t.LastUsedAt)
+}
+
+// CreateAPIToken stores the hash of a new API token for a user.
+func (db *DB) CreateAPIToken(ctx context.Context, userID int64, name string, scope models.TokenScope, tokenHash string) (*models.APIToken, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var t models.APIToken
+	err := scanAPIToken(db.conn.QueryRowContext(ctx,
+		"INSERT INTO api_tokens (user_id, name, scope, token_hash) VALUES (?, ?, ?, ?) RETURNING "+apiTokenColumns,
+		userID, name, scope, tokenHash,
+	), &t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListAPITokens returns a user's API tokens, newest first.
+func (db *DB) ListAPITokens(ctx context.Context, userID int64) ([]models.APIToken, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.read.QueryContext(ctx,
+		"SELECT "+apiTokenColumns+" FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		var t models.APIToken
+		if err := scanAPIToken(rows, &t); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAPIToken deletes one of a user's API tokens.
+func (db *DB) RevokeAPIToken(ctx context.Context, userID, id int64) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := db.conn.ExecContext(ctx, "DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// UseAPIToken looks up the API token with the given hash, records now as its
+// last use and returns it together with its owner. It returns ErrNotFound if
+// no such token exists.
+func (db *DB) UseAPIToken(ctx context.Context, tokenHash string, now time.Time) (*models.User, *models.APIToken, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var token models.APIToken
+	err := scanAPIToken(db.conn.QueryRowContext(ctx,
+		"UPDATE api_tokens SET last_used_at = ? WHERE token_hash = ? RETURNING "+apiTokenColumns,
+		now.UTC(), tokenHash,
+	), &token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := db.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, &token, nil
+}
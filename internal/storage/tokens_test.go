@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-tracker/internal/models"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TokenTestSuite provides a test suite for API tokens
+type TokenTestSuite struct {
+	suite.Suite
+	dsn  string // Database under test, see runOnBackends
+	ctx  context.Context
+	db   *DB
+	user *models.User
+}
+
+// SetupTest runs before each test
+func (s *TokenTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
+	s.Require().NoError(err, "failed to create test database")
+	s.db = db
+
+	s.user, err = s.db.CreateUser(s.ctx, "testuser", "hash")
+	s.Require().NoError(err)
+}
+
+// TearDownTest runs after each test
+func (s *TokenTestSuite) TearDownTest() {
+	if s.db != nil {
+		s.db.Close()
+	}
+}
+
+func (s *TokenTestSuite) TestCreateAndUseToken() {
+	token, err := s.db.CreateAPIToken(s.ctx, s.user.ID, "Shortcuts", models.ScopeRead, "abc123")
+	s.Require().NoError(err)
+	s.NotZero(token.ID)
+	s.Equal("Shortcuts", token.Name)
+	s.Equal(models.ScopeRead, token.Scope)
+	s.False(token.CreatedAt.IsZero())
+	s.Nil(token.LastUsedAt)
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	user, used, err := s.db.UseAPIToken(s.ctx, "abc123", now)
+	s.Require().NoError(err)
+	s.Equal(s.user.ID, user.ID)
+	s.Equal(token.ID, used.ID)
+	s.Require().NotNil(used.LastUsedAt)
+	s.True(now.Equal(*used.LastUsedAt))
+
+	_, _, err = s.db.UseAPIToken(s.ctx, "unknown", now)
+	s.ErrorIs(err, ErrNotFound)
+}
+
+func (s *TokenTestSuite) TestListAndRevokeTokens() {
+	other, err := s.db.CreateUser(s.ctx, "other", "hash")
+	s.Require().NoError(err)
+	first, err := s.db.CreateAPIToken(s.ctx, s.user.ID, "Backup", models.ScopeRead, "hash1")
+	s.Require().NoError(err)
+	second, err := s.db.CreateAPIToken(s.ctx, s.user.ID, "Shortcuts", models.ScopeReadWrite, "hash2")
+	s.Require().NoError(err)
+	_, err = s.db.CreateAPIToken(s.ctx, other.ID, "Theirs", models.ScopeRead, "hash3")
+	s.Require().NoError(err)
+
+	tokens, err := s.db.ListAPITokens(s.ctx, s.user.ID)
+	s.Require().NoError(err)
+	s.Require().Len(tokens, 2)
+	s.Equal(second.ID, tokens[0].ID, "newest first")
+	s.Equal(first.ID, tokens[1].ID)
+
+	s.ErrorIs(s.db.RevokeAPIToken(s.ctx, other.ID, first.ID), ErrNotFound, "tokens of other users cannot be revoked")
+	s.Require().NoError(s.db.RevokeAPIToken(s.ctx, s.user.ID, first.ID))
+	_, _, err = s.db.UseAPIToken(s.ctx, "hash1", time.Now())
+	s.ErrorIs(err, ErrNotFound)
+
+	tokens, err = s.db.ListAPITokens(s.ctx, s.user.ID)
+	s.Require().NoError(err)
+	s.Len(tokens, 1)
+}
+
+// TestTokenSuite runs the API token test suite
+func TestTokenSuite(t *testing.T) {
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &TokenTestSuite{dsn: dsn})
+	})
+}
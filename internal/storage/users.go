@@ -1,28 +1,34 @@
 package storage
 
-import "expense-tracker/internal/models"
+import (
+	"context"
+
+	"expense-tracker/internal/models"
+)
 
 // CreateUser creates a new user with the given username and password hash.
-func (db *DB) CreateUser(username, passwordHash string) (*models.User, error) {
-	result, err := db.conn.Exec(
-		"INSERT INTO users (username, password_hash) VALUES (?, ?)",
-		username, passwordHash,
-	)
-	if err != nil {
-		return nil, err
-	}
+func (db *DB) CreateUser(ctx context.Context, username, passwordHash string) (*models.User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 
-	id, err := result.LastInsertId()
+	var id int64
+	err := db.conn.QueryRowContext(ctx,
+		"INSERT INTO users (username, password_hash) VALUES (?, ?) RETURNING id",
+		username, passwordHash,
+	).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
 
-	return db.GetUserByID(id)
+	return db.GetUserByID(ctx, id)
 }
 
 // GetUserByID retrieves a user by ID.
-func (db *DB) GetUserByID(id int64) (*models.User, error) {
-	row := db.conn.QueryRow(
+func (db *DB) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	row := db.read.QueryRowContext(ctx,
 		"SELECT id, username, password_hash, created_at FROM users WHERE id = ?",
 		id,
 	)
@@ -35,8 +41,11 @@ func (db *DB) GetUserByID(id int64) (*models.User, error) {
 }
 
 // GetUserByUsername retrieves a user by username.
-func (db *DB) GetUserByUsername(username string) (*models.User, error) {
-	row := db.conn.QueryRow(
+func (db *DB) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	row := db.read.QueryRowContext(ctx,
 		"SELECT id, username, password_hash, created_at FROM users WHERE username = ?",
 		username,
 	)
@@ -49,8 +58,11 @@ func (db *DB) GetUserByUsername(username string) (*models.User, error) {
 }
 
 // UserCount returns the number of users in the database.
-func (db *DB) UserCount() (int, error) {
+func (db *DB) UserCount(ctx context.Context) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	var count int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	err := db.read.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
 	return count, err
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"testing"
 
 	"expense-tracker/internal/auth"
@@ -11,12 +12,15 @@ import (
 // UserTestSuite provides a test suite for user operations
 type UserTestSuite struct {
 	suite.Suite
-	db *DB
+	dsn string // Database under test, see runOnBackends
+	ctx context.Context
+	db  *DB
 }
 
 // SetupTest runs before each test
 func (s *UserTestSuite) SetupTest() {
-	db, err := NewDB(":memory:")
+	s.ctx = context.Background()
+	db, err := newTestDB(s.ctx, s.dsn)
 	s.Require().NoError(err, "failed to create test database")
 	s.db = db
 }
@@ -32,7 +36,7 @@ func (s *UserTestSuite) TestCreateUser() {
 	passwordHash, err := auth.HashPassword("testpassword")
 	s.Require().NoError(err)
 
-	user, err := s.db.CreateUser("johndoe", passwordHash)
+	user, err := s.db.CreateUser(s.ctx, "johndoe", passwordHash)
 	s.Require().NoError(err)
 	s.NotNil(user)
 	s.Positive(user.ID)
@@ -46,11 +50,11 @@ func (s *UserTestSuite) TestCreateUserDuplicateUsername() {
 	s.Require().NoError(err)
 
 	// Create first user
-	_, err = s.db.CreateUser("johndoe", passwordHash)
+	_, err = s.db.CreateUser(s.ctx, "johndoe", passwordHash)
 	s.Require().NoError(err)
 
 	// Try to create second user with same username
-	_, err = s.db.CreateUser("johndoe", passwordHash)
+	_, err = s.db.CreateUser(s.ctx, "johndoe", passwordHash)
 	s.Error(err, "expected error when creating user with duplicate username")
 }
 
@@ -59,11 +63,11 @@ func (s *UserTestSuite) TestGetUserByID() {
 	s.Require().NoError(err)
 
 	// Create a user
-	createdUser, err := s.db.CreateUser("janedoe", passwordHash)
+	createdUser, err := s.db.CreateUser(s.ctx, "janedoe", passwordHash)
 	s.Require().NoError(err)
 
 	// Retrieve the user by ID
-	retrievedUser, err := s.db.GetUserByID(createdUser.ID)
+	retrievedUser, err := s.db.GetUserByID(s.ctx, createdUser.ID)
 	s.Require().NoError(err)
 	s.Equal(createdUser.ID, retrievedUser.ID)
 	s.Equal(createdUser.Username, retrievedUser.Username)
@@ -72,7 +76,7 @@ func (s *UserTestSuite) TestGetUserByID() {
 
 func (s *UserTestSuite) TestGetUserByIDNotFound() {
 	// Try to get a user that doesn't exist
-	_, err := s.db.GetUserByID(99999)
+	_, err := s.db.GetUserByID(s.ctx, 99999)
 	s.Error(err, "expected error when getting non-existent user")
 }
 
@@ -81,11 +85,11 @@ func (s *UserTestSuite) TestGetUserByUsername() {
 	s.Require().NoError(err)
 
 	// Create a user
-	createdUser, err := s.db.CreateUser("bobsmith", passwordHash)
+	createdUser, err := s.db.CreateUser(s.ctx, "bobsmith", passwordHash)
 	s.Require().NoError(err)
 
 	// Retrieve the user by username
-	retrievedUser, err := s.db.GetUserByUsername("bobsmith")
+	retrievedUser, err := s.db.GetUserByUsername(s.ctx, "bobsmith")
 	s.Require().NoError(err)
 	s.Equal(createdUser.ID, retrievedUser.ID)
 	s.Equal(createdUser.Username, retrievedUser.Username)
@@ -94,7 +98,7 @@ func (s *UserTestSuite) TestGetUserByUsername() {
 
 func (s *UserTestSuite) TestGetUserByUsernameNotFound() {
 	// Try to get a user that doesn't exist
-	_, err := s.db.GetUserByUsername("nonexistent")
+	_, err := s.db.GetUserByUsername(s.ctx, "nonexistent")
 	s.Error(err, "expected error when getting non-existent user")
 }
 
@@ -103,36 +107,38 @@ func (s *UserTestSuite) TestUserCount() {
 	s.Require().NoError(err)
 
 	// Initially should have 0 users
-	count, err := s.db.UserCount()
+	count, err := s.db.UserCount(s.ctx)
 	s.Require().NoError(err)
 	s.Equal(0, count)
 
 	// Create first user
-	_, err = s.db.CreateUser("user1", passwordHash)
+	_, err = s.db.CreateUser(s.ctx, "user1", passwordHash)
 	s.Require().NoError(err)
 
-	count, err = s.db.UserCount()
+	count, err = s.db.UserCount(s.ctx)
 	s.Require().NoError(err)
 	s.Equal(1, count)
 
 	// Create second user
-	_, err = s.db.CreateUser("user2", passwordHash)
+	_, err = s.db.CreateUser(s.ctx, "user2", passwordHash)
 	s.Require().NoError(err)
 
-	count, err = s.db.UserCount()
+	count, err = s.db.UserCount(s.ctx)
 	s.Require().NoError(err)
 	s.Equal(2, count)
 
 	// Create third user
-	_, err = s.db.CreateUser("user3", passwordHash)
+	_, err = s.db.CreateUser(s.ctx, "user3", passwordHash)
 	s.Require().NoError(err)
 
-	count, err = s.db.UserCount()
+	count, err = s.db.UserCount(s.ctx)
 	s.Require().NoError(err)
 	s.Equal(3, count)
 }
 
 // Test suite runner
 func TestUserSuite(t *testing.T) {
-	suite.Run(t, new(UserTestSuite))
+	runOnBackends(t, func(t *testing.T, dsn string) {
+		suite.Run(t, &UserTestSuite{dsn: dsn})
+	})
 }